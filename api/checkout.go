@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/checkout_service"
+	"github.com/ninjadotorg/handshake-exchange/service"
+	"io/ioutil"
+)
+
+type CheckoutApi struct {
+}
+
+func (api CheckoutApi) ReceiveCallback(context *gin.Context) {
+	// Signature is computed over the raw body, so read it before decoding
+	data, err := ioutil.ReadAll(context.Request.Body)
+	if api_error.PropagateErrorAndAbort(context, api_error.InvalidRequestBody, err) != nil {
+		return
+	}
+	if !checkout_service.VerifyWebhookSignature(data, context.GetHeader(bean.CHECKOUT_WEBHOOK_SIGNATURE_HEADER)) {
+		api_error.AbortWithValidateErrorSimple(context, api_error.InvalidSignature)
+		return
+	}
+
+	var body bean.CheckoutWebhookEvent
+	err = json.Unmarshal(data, &body)
+	if api_error.PropagateErrorAndAbort(context, api_error.InvalidRequestBody, err) != nil {
+		return
+	}
+
+	ccEvent, ce := service.CreditCardServiceInst.HandleCheckoutEvent(body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, ccEvent)
+}
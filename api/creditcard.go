@@ -16,7 +16,7 @@ func (api CreditCardApi) GetProposeInstantOffer(context *gin.Context) {
 	amount := context.DefaultQuery("amount", "")
 	currency := context.DefaultQuery("currency", "")
 
-	offer, ce := service.CreditCardServiceInst.GetProposeInstantOffer(amount, currency)
+	offer, ce := service.CreditCardServiceInst.GetProposeInstantOffer(common.GetUserId(context), amount, currency)
 	if ce.ContextValidate(context) {
 		return
 	}
@@ -24,6 +24,17 @@ func (api CreditCardApi) GetProposeInstantOffer(context *gin.Context) {
 	bean.SuccessResponse(context, offer)
 }
 
+func (api CreditCardApi) GetCCGateway(context *gin.Context) {
+	amount := context.DefaultQuery("amount", "")
+
+	gateway, ce := service.CreditCardServiceInst.GetCCGateway(common.GetUserId(context), amount)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, gateway)
+}
+
 func (api CreditCardApi) PayInstantOffer(context *gin.Context) {
 	userId := common.GetUserId(context)
 	chainId := common.GetChainId(context)
@@ -48,6 +59,39 @@ func (api CreditCardApi) PayInstantOffer(context *gin.Context) {
 	bean.SuccessResponse(context, offer)
 }
 
+func (api CreditCardApi) AuthenticateInstantOffer(context *gin.Context) {
+	userId := common.GetUserId(context)
+
+	var body bean.InstantOfferAuthenticationRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	offer, ce := service.CreditCardServiceInst.AuthenticateInstantOffer(userId, body.PaymentToken)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, offer)
+}
+
+func (api CreditCardApi) RefundInstantOffer(context *gin.Context) {
+	userId := context.Param("userId")
+	offerId := context.Param("offerId")
+
+	var body bean.InstantOfferRefundRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	offer, ce := service.CreditCardServiceInst.RefundInstantOffer(userId, offerId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, offer)
+}
+
 func (api CreditCardApi) GetInstantOffers(context *gin.Context) {
 	userId := common.GetUserId(context)
 
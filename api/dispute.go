@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/service"
+)
+
+type DisputeApi struct {
+}
+
+func (api DisputeApi) OpenOfferDispute(context *gin.Context) {
+	userId := common.GetUserId(context)
+	offerId := context.Param("offerId")
+
+	var body bean.DisputeRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	// status: shake->disputed
+	dispute, ce := service.DisputeServiceInst.OpenOfferDispute(userId, offerId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, dispute)
+}
+
+func (api DisputeApi) OpenOfferStoreShakeDispute(context *gin.Context) {
+	userId := common.GetUserId(context)
+	offerId := context.Param("offerId")
+	offerShakeId := context.Param("offerShakeId")
+
+	var body bean.DisputeRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	// status: shake->disputed
+	dispute, ce := service.DisputeServiceInst.OpenOfferStoreShakeDispute(userId, offerId, offerShakeId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, dispute)
+}
+
+func (api DisputeApi) ListDisputes(context *gin.Context) {
+	userId := common.GetUserId(context)
+	status := context.DefaultQuery("status", bean.DISPUTE_STATUS_OPEN)
+	startAt, limit := common.ExtractTimePagingParams(context)
+
+	to, ce := service.DisputeServiceInst.ListDisputes(userId, status, limit, startAt)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessPagingResponse(context, to.Objects, to.CanMove, to.Page)
+}
+
+func (api DisputeApi) GetDispute(context *gin.Context) {
+	userId := common.GetUserId(context)
+	disputeId := context.Param("disputeId")
+
+	dispute, ce := service.DisputeServiceInst.GetDispute(userId, disputeId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, dispute)
+}
+
+func (api DisputeApi) ResolveDispute(context *gin.Context) {
+	userId := common.GetUserId(context)
+	disputeId := context.Param("disputeId")
+
+	var body bean.DisputeRulingRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	// status: disputed->completed or rejected, through completing or rejecting for ETH
+	dispute, ce := service.DisputeServiceInst.ResolveDispute(userId, disputeId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, dispute)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/service/email"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
+	"net/http"
+)
+
+type EmailTemplateApi struct {
+}
+
+func (api EmailTemplateApi) ListTemplates(context *gin.Context) {
+	bean.SuccessResponse(context, map[string]interface{}{
+		"templates": email.TemplateKeys(),
+		"languages": translation.Languages,
+	})
+}
+
+// Renders the template with email.SampleData, as HTML so it can be opened in a browser
+func (api EmailTemplateApi) PreviewTemplate(context *gin.Context) {
+	templateKey := context.Param("templateKey")
+	language := context.DefaultQuery("language", translation.DEFAULT_LANGUAGE)
+
+	if _, ok := email.TemplateName[templateKey]; !ok {
+		api_error.AbortWithValidateErrorSimple(context, api_error.InvalidRequestParam)
+		return
+	}
+
+	body, err := email.Render(templateKey, language, email.SampleData)
+	if api_error.PropagateErrorAndAbort(context, api_error.UnexpectedError, err) != nil {
+		return
+	}
+
+	context.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+}
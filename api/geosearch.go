@@ -0,0 +1,42 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/service"
+	"strconv"
+)
+
+type GeoSearchApi struct {
+}
+
+func (api GeoSearchApi) Search(context *gin.Context) {
+	latitude, latErr := strconv.ParseFloat(context.DefaultQuery("lat", ""), 64)
+	longitude, lngErr := strconv.ParseFloat(context.DefaultQuery("lng", ""), 64)
+	if latErr != nil || lngErr != nil {
+		api_error.AbortWithValidateErrorSimple(context, api_error.InvalidQueryParam)
+		return
+	}
+	radius, _ := strconv.ParseFloat(context.DefaultQuery("radius", "0"), 64)
+	limit, _ := strconv.Atoi(context.DefaultQuery("limit", "0"))
+	currency := context.DefaultQuery("currency", "")
+	offerType := context.DefaultQuery("type", "")
+	fiatCurrency := context.DefaultQuery("fiat_currency", "")
+
+	entries, ce := service.GeoSearchServiceInst.Search(latitude, longitude, radius, currency, offerType, fiatCurrency, limit)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, entries)
+}
+
+func (api GeoSearchApi) RebuildIndex(context *gin.Context) {
+	count, ce := service.GeoSearchServiceInst.RebuildIndex()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, count)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/service"
+)
+
+type KYCApi struct {
+}
+
+func (api KYCApi) SubmitKYC(context *gin.Context) {
+	userId := common.GetUserId(context)
+
+	var body bean.KYCSubmissionRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	// status: pending, then approved or rejected when the verifier decides right away
+	submission, ce := service.KYCServiceInst.SubmitKYC(userId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, submission)
+}
+
+func (api KYCApi) ListUserKYCSubmissions(context *gin.Context) {
+	userId := common.GetUserId(context)
+
+	submissions, ce := service.KYCServiceInst.ListUserKYCSubmissions(userId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, submissions)
+}
+
+func (api KYCApi) ListTierLimits(context *gin.Context) {
+	limits, ce := service.KYCServiceInst.ListTierLimits()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, limits)
+}
+
+func (api KYCApi) ListKYCSubmissions(context *gin.Context) {
+	userId := common.GetUserId(context)
+	status := context.DefaultQuery("status", bean.KYC_STATUS_PENDING)
+	startAt, limit := common.ExtractTimePagingParams(context)
+
+	to, ce := service.KYCServiceInst.ListKYCSubmissions(userId, status, limit, startAt)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessPagingResponse(context, to.Objects, to.CanMove, to.Page)
+}
+
+func (api KYCApi) GetKYCSubmission(context *gin.Context) {
+	userId := common.GetUserId(context)
+	submissionId := context.Param("submissionId")
+
+	submission, ce := service.KYCServiceInst.GetKYCSubmission(userId, submissionId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, submission)
+}
+
+func (api KYCApi) ReviewKYCSubmission(context *gin.Context) {
+	userId := common.GetUserId(context)
+	submissionId := context.Param("submissionId")
+
+	var body bean.KYCReviewRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	// status: pending->approved or rejected
+	submission, ce := service.KYCServiceInst.ReviewKYCSubmission(userId, submissionId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, submission)
+}
@@ -6,10 +6,9 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/common"
 	"github.com/ninjadotorg/handshake-exchange/dao"
-	"github.com/ninjadotorg/handshake-exchange/integration/coinbase_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/openexchangerates_service"
-	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
 	"github.com/ninjadotorg/handshake-exchange/service"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
 	"github.com/shopspring/decimal"
 	"strings"
 )
@@ -33,32 +32,16 @@ func (api MiscApi) UpdateCurrencyRates(context *gin.Context) {
 }
 
 // CRON JOB
+// Also refreshes the cached oracle prices that quotes are served from
 func (api MiscApi) UpdateCryptoRates(context *gin.Context) {
 	//rates, err := coinapi_service.GetExchangeRate()
 	//if api_error.PropagateErrorAndAbort(context, api_error.ExternalApiFailed, err) != nil {
 	//	return
 	//}
 
-	allRates := make([]bean.CryptoRate, 0)
-	for _, currency := range []string{bean.BTC.Code, bean.ETH.Code, bean.LTC.Code, bean.BCH.Code} {
-		rates := make([]bean.CryptoRate, 0)
-		resp, _ := coinbase_service.GetBuyPrice(currency)
-		buy, _ := decimal.NewFromString(resp.Amount)
-		buyFloat, _ := buy.Float64()
-		rate := bean.CryptoRate{
-			From:     currency,
-			To:       bean.USD.Code,
-			Buy:      buyFloat,
-			Sell:     0,
-			Exchange: bean.INSTANT_OFFER_PROVIDER_COINBASE,
-		}
-		rates = append(rates, rate)
-		allRates = append(allRates, rate)
-
-		err := dao.MiscDaoInst.UpdateCryptoRates(map[string][]bean.CryptoRate{currency: rates})
-		if api_error.PropagateErrorAndAbort(context, api_error.UpdateDataFailed, err) != nil {
-			return
-		}
+	allRates, ce := service.PriceOracleServiceInst.UpdateCryptoRates()
+	if ce.ContextValidate(context) {
+		return
 	}
 
 	bean.SuccessResponse(context, allRates)
@@ -128,29 +111,30 @@ func (api MiscApi) GetSystemConfig(context *gin.Context) {
 func (api MiscApi) GetCryptoRate(context *gin.Context) {
 	currency := context.Param("currency")
 
-	resp, err := coinbase_service.GetBuyPrice(currency)
-	if api_error.PropagateErrorAndAbort(context, api_error.GetDataFailed, err) != nil {
+	price, ce := service.PriceOracleServiceInst.GetPrice(currency, bean.OFFER_TYPE_BUY)
+	if ce.ContextValidate(context) {
 		return
 	}
 
-	bean.SuccessResponse(context, resp)
+	bean.SuccessResponse(context, oraclePriceToAmount(price))
 }
 
 func (api MiscApi) GetCryptoRateAll(context *gin.Context) {
 	currency := context.Param("currency")
 	rateType := context.DefaultQuery("type", "buy")
 	var resp1, resp2 interface{}
-	var err error
 	if rateType == "buy" {
-		resp1, err = coinbase_service.GetBuyPrice(currency)
-		if api_error.PropagateErrorAndAbort(context, api_error.GetDataFailed, err) != nil {
+		price, ce := service.PriceOracleServiceInst.GetPrice(currency, bean.OFFER_TYPE_BUY)
+		if ce.ContextValidate(context) {
 			return
 		}
+		resp1 = oraclePriceToAmount(price)
 	} else {
-		resp2, err = coinbase_service.GetSellPrice(currency)
-		if api_error.PropagateErrorAndAbort(context, api_error.GetDataFailed, err) != nil {
+		price, ce := service.PriceOracleServiceInst.GetPrice(currency, bean.OFFER_TYPE_SELL)
+		if ce.ContextValidate(context) {
 			return
 		}
+		resp2 = oraclePriceToAmount(price)
 	}
 
 	bean.SuccessResponse(context, map[string]interface{}{
@@ -159,6 +143,20 @@ func (api MiscApi) GetCryptoRateAll(context *gin.Context) {
 	})
 }
 
+func (api MiscApi) GetOraclePrices(context *gin.Context) {
+	currency := context.Param("currency")
+
+	prices := make([]bean.OraclePrice, 0)
+	for _, priceType := range []string{bean.OFFER_TYPE_BUY, bean.OFFER_TYPE_SELL} {
+		to := dao.MiscDaoInst.GetOraclePriceFromCache(currency, priceType)
+		if to.Found {
+			prices = append(prices, to.Object.(bean.OraclePrice))
+		}
+	}
+
+	bean.SuccessResponse(context, prices)
+}
+
 func (api MiscApi) GetCryptoQuote(context *gin.Context) {
 	type quoteStruct struct {
 		Type         string
@@ -167,6 +165,8 @@ func (api MiscApi) GetCryptoQuote(context *gin.Context) {
 		FiatCurrency string
 		FiatAmount   string
 		Price        string
+		QuoteId      string
+		ExpiredAt    int64
 	}
 
 	quoteType := context.DefaultQuery("type", "")
@@ -181,23 +181,58 @@ func (api MiscApi) GetCryptoQuote(context *gin.Context) {
 		FiatCurrency: fiatCurrency,
 	}
 
-	_, fiatPrice, fiatAmount, err := service.OfferServiceInst.GetQuote(quoteType, amountStr, currency, fiatCurrency)
-	if api_error.PropagateErrorAndAbort(context, api_error.GetDataFailed, err) != nil {
+	price, fiatPrice, fiatAmount, ce := service.OfferServiceInst.GetQuote(quoteType, amountStr, currency, fiatCurrency)
+	if ce.ContextValidate(context) {
+		return
+	}
+	quote.Price = service.RoundFiatAmount(fiatCurrency, fiatPrice).String()
+	quote.FiatAmount = service.RoundFiatAmount(fiatCurrency, fiatAmount).String()
+
+	userId := common.GetUserId(context)
+	if userId == "" {
+		bean.SuccessResponse(context, quote)
+		return
+	}
+	// Shake with this quote id to keep the price until it expires
+	lockedQuote, ce := service.QuoteServiceInst.LockQuote(userId, bean.LockedQuote{
+		Type:         quoteType,
+		Amount:       amountStr,
+		Currency:     currency,
+		FiatCurrency: fiatCurrency,
+		FiatAmount:   quote.FiatAmount,
+		Price:        quote.Price,
+		PriceUSD:     price.Round(bean.USD.Decimal).String(),
+	})
+	if ce.ContextValidate(context) {
 		return
 	}
-	quote.Price = fiatPrice.Round(2).String()
-	quote.FiatAmount = fiatAmount.Round(2).String()
+	quote.QuoteId = lockedQuote.Id
+	quote.ExpiredAt = lockedQuote.ExpiredAt
 
 	bean.SuccessResponse(context, quote)
 }
 
 func (api MiscApi) GetAllCryptoQuotes(context *gin.Context) {
-	fiatCurrencyStr := context.DefaultQuery("fiat_currency", "RUB,VND,PHP,CAD,USD,EUR,HKD")
+	fiatCurrencyStr := context.DefaultQuery("fiat_currency", "")
 	fiatCurrencies := strings.Split(fiatCurrencyStr, ",")
+	if fiatCurrencyStr == "" {
+		// All enabled currencies from the catalogue
+		to := dao.MiscDaoInst.ListFiatCurrenciesFromCache()
+		if to.ContextValidate(context) {
+			return
+		}
+		fiatCurrencies = make([]string, 0)
+		for _, obj := range to.Objects {
+			fiatCurrencies = append(fiatCurrencies, obj.(bean.FiatCurrency).Code)
+		}
+	}
 
 	quotes := make([]interface{}, 0)
 	for _, fiatCurrency := range fiatCurrencies {
-		quotesTmp := service.OfferServiceInst.GetAllQuotes(fiatCurrency)
+		quotesTmp, ce := service.OfferServiceInst.GetAllQuotes(fiatCurrency)
+		if ce.ContextValidate(context) {
+			return
+		}
 		quotes = append(quotes, quotesTmp...)
 	}
 
@@ -234,6 +269,27 @@ func (api MiscApi) FinishCryptoTransfer(context *gin.Context) {
 	bean.SuccessResponse(context, true)
 }
 
+// CRON JOB
+func (api MiscApi) SendCryptoPayouts(context *gin.Context) {
+	batches, ce := service.PayoutServiceInst.SendPayoutBatches(bean.BTC.Code)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, batches)
+}
+
+func (api MiscApi) RetryCryptoPayout(context *gin.Context) {
+	payoutId := context.Param("payoutId")
+
+	payout, ce := service.PayoutServiceInst.RetryPayout(payoutId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, payout)
+}
+
 // CRON JOB
 //func (api MiscApi) ExpireOfferHandshakes(context *gin.Context) {
 //	err := dao.OfferDaoInst.UpdateExpiredHandshake()
@@ -264,6 +320,24 @@ func (api MiscApi) GetCCLimits(context *gin.Context) {
 	bean.SuccessResponse(context, to.Objects)
 }
 
+func (api MiscApi) UpdateFiatCurrencies(context *gin.Context) {
+	objs, err := dao.MiscDaoInst.LoadFiatCurrencyToCache()
+	if api_error.PropagateErrorAndAbort(context, api_error.UpdateDataFailed, err) != nil {
+		return
+	}
+
+	bean.SuccessResponse(context, objs)
+}
+
+func (api MiscApi) GetFiatCurrencies(context *gin.Context) {
+	to := dao.MiscDaoInst.ListFiatCurrenciesFromCache()
+	if to.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, to.Objects)
+}
+
 // CRON JOB
 func (api MiscApi) UpdateUserCCLimitTracks(context *gin.Context) {
 	// country := context.Param("country")
@@ -362,6 +436,8 @@ func (api MiscApi) StartApp(context *gin.Context) {
 	api.UpdateSystemFee(context)
 	api.UpdateSystemConfig(context)
 	api.UpdateCCLimits(context)
+	api.UpdateFiatCurrencies(context)
+	OrderBookApi{}.RebuildOrderBook(context)
 	OnChainApi{}.StartOnChainOfferBlock(context)
 	OnChainApi{}.StartOnChainOfferStoreBlock(context)
 }
@@ -401,9 +477,12 @@ func (api MiscApi) TestEmail(context *gin.Context) {
 
 func (api MiscApi) RemoveSolr(context *gin.Context) {
 	id := context.Param("id")
-	resp, _ := solr_service.DeleteObject(id)
+	err := search.DeleteDocument(id)
+	if api_error.PropagateErrorAndAbort(context, api_error.ExternalApiFailed, err) != nil {
+		return
+	}
 
-	bean.SuccessResponse(context, resp)
+	bean.SuccessResponse(context, true)
 }
 
 func (api MiscApi) ScriptUpdateTxCount(context *gin.Context) {
@@ -411,7 +490,30 @@ func (api MiscApi) ScriptUpdateTxCount(context *gin.Context) {
 	bean.SuccessResponse(context, "ok")
 }
 
-func (api MiscApi) ScriptUpdateAllOfferStoreSolr(context *gin.Context) {
-	service.OfferStoreServiceInst.ScriptUpdateOfferStoreSolr()
-	bean.SuccessResponse(context, "ok")
+// CRON JOB
+// delete_orphans=1 only removes tracked documents, ids missing from the tracker are never cleaned up
+func (api MiscApi) RunSearchReindex(context *gin.Context) {
+	deleteOrphans := context.DefaultQuery("delete_orphans", "") == "1"
+	job, ce := service.SearchReindexServiceInst.RunSearchReindex(deleteOrphans)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, job)
+}
+
+func (api MiscApi) GetSearchReindex(context *gin.Context) {
+	job, ce := service.SearchReindexServiceInst.GetSearchReindexJob()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, job)
+}
+
+func oraclePriceToAmount(price bean.OraclePrice) bean.CoinbaseAmount {
+	return bean.CoinbaseAmount{
+		Amount:   decimal.NewFromFloat(price.Price).Round(2).String(),
+		Currency: bean.USD.Code,
+	}
 }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service"
+)
+
+type NotificationApi struct {
+}
+
+func (api NotificationApi) ListDeadLetters(context *gin.Context) {
+	startAt, limit := common.ExtractTimePagingParams(context)
+
+	to := dao.NotificationDaoInst.ListNotificationDeadLetters(limit, startAt)
+	if to.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessPagingResponse(context, to.Objects, to.CanMove, to.Page)
+}
+
+func (api NotificationApi) GetDelivery(context *gin.Context) {
+	deliveryId := context.Param("deliveryId")
+
+	delivery, ce := service.NotificationDeliveryServiceInst.GetNotificationDelivery(deliveryId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, delivery)
+}
+
+func (api NotificationApi) ResendDeadLetter(context *gin.Context) {
+	deliveryId := context.Param("deliveryId")
+
+	delivery, ce := service.NotificationDeliveryServiceInst.ResendNotification(deliveryId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, delivery)
+}
+
+func (api NotificationApi) RelayOutbox(context *gin.Context) {
+	count, ce := service.OutboxServiceInst.RelayOutboxEvents()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, count)
+}
+
+func (api NotificationApi) RetryDeliveries(context *gin.Context) {
+	count, ce := service.NotificationDeliveryServiceInst.RetryNotificationDeliveries()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, count)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/service"
+)
+
+type OrderBookApi struct {
+}
+
+func (api OrderBookApi) GetOrderBook(context *gin.Context) {
+	currency := context.Param("currency")
+	fiatCurrency := context.Param("fiat")
+
+	orderBook, ce := service.OrderBookServiceInst.GetOrderBook(currency, fiatCurrency)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, orderBook)
+}
+
+func (api OrderBookApi) RebuildOrderBook(context *gin.Context) {
+	count, ce := service.OrderBookServiceInst.RebuildOrderBook()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, count)
+}
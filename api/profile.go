@@ -6,8 +6,8 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/common"
 	"github.com/ninjadotorg/handshake-exchange/dao"
-	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
 	"github.com/ninjadotorg/handshake-exchange/service"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
 	"strconv"
 )
 
@@ -28,6 +28,40 @@ func (api ProfileApi) AddProfile(context *gin.Context) {
 	bean.SuccessResponse(context, body)
 }
 
+func (api ProfileApi) UpdateProfile(context *gin.Context) {
+	userId := common.GetUserId(context)
+
+	var body bean.ProfileUpdateRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	if body.Language == "" && body.NotificationPreferences == nil {
+		api_error.AbortWithValidateErrorSimple(context, api_error.InvalidRequestBody)
+		return
+	}
+
+	var profile bean.Profile
+	var ce service.SimpleContextError
+	if body.NotificationPreferences != nil {
+		profile, ce = service.UserServiceInst.UpdateNotificationPreferences(userId, *body.NotificationPreferences)
+		if ce.ContextValidate(context) {
+			return
+		}
+	}
+	if body.Language != "" {
+		profile, ce = service.UserServiceInst.UpdateProfileLanguage(userId, body.Language)
+		if ce.ContextValidate(context) {
+			return
+		}
+	}
+	if profile.CreditCard.Token != "" {
+		profile.CreditCard.Token = "true"
+	}
+
+	bean.SuccessResponse(context, profile)
+}
+
 func (api ProfileApi) UpdateProfileOffline(context *gin.Context) {
 	userId := common.GetUserId(context)
 	offline := context.Param("offline")
@@ -36,7 +70,7 @@ func (api ProfileApi) UpdateProfileOffline(context *gin.Context) {
 	if to.Found {
 		offer := to.Object.(bean.OfferStore)
 		offer.Offline = offline
-		solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, bean.OfferStoreItem{}))
+		search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, bean.OfferStoreItem{}))
 	}
 	if to.Error != nil {
 		if to.ContextValidate(context) {
@@ -88,6 +122,61 @@ func (api ProfileApi) GetCCLimit(context *gin.Context) {
 	bean.SuccessResponse(context, userCCLimit)
 }
 
+func (api ProfileApi) ListCreditCards(context *gin.Context) {
+	userId := common.GetUserId(context)
+
+	cards, ce := service.UserServiceInst.ListCreditCards(userId)
+	if ce.ContextValidate(context) {
+		return
+	}
+	for i := range cards {
+		cards[i].Token = ""
+	}
+
+	bean.SuccessResponse(context, cards)
+}
+
+func (api ProfileApi) AddCreditCard(context *gin.Context) {
+	userId := common.GetUserId(context)
+
+	var body bean.UserCreditCardRequest
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	card, ce := service.CreditCardServiceInst.AddCreditCard(userId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, card)
+}
+
+func (api ProfileApi) RemoveCreditCard(context *gin.Context) {
+	userId := common.GetUserId(context)
+	cardId := context.Param("cardId")
+
+	ce := service.UserServiceInst.RemoveCreditCard(userId, cardId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, true)
+}
+
+func (api ProfileApi) SetDefaultCreditCard(context *gin.Context) {
+	userId := common.GetUserId(context)
+	cardId := context.Param("cardId")
+
+	card, ce := service.UserServiceInst.SetDefaultCreditCard(userId, cardId)
+	if ce.ContextValidate(context) {
+		return
+	}
+	card.Token = ""
+
+	bean.SuccessResponse(context, card)
+}
+
 func (api ProfileApi) ListTransactionCounts(context *gin.Context) {
 	userId := common.GetUserId(context)
 	to := dao.TransactionDaoInst.ListTransactionCounts(userId)
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
+	"strconv"
+)
+
+type SearchApi struct {
+}
+
+func (api SearchApi) Search(context *gin.Context) {
+	query := bean.SearchQuery{
+		Text:         context.DefaultQuery("q", ""),
+		FeedType:     context.DefaultQuery("feed_type", ""),
+		FiatCurrency: context.DefaultQuery("fiat_currency", ""),
+	}
+	query.Limit, _ = strconv.Atoi(context.DefaultQuery("limit", "0"))
+	if query.Limit <= 0 {
+		query.Limit = bean.GEO_SEARCH_LIMIT_DEFAULT
+	}
+
+	latStr := context.DefaultQuery("lat", "")
+	lngStr := context.DefaultQuery("lng", "")
+	if latStr != "" || lngStr != "" {
+		latitude, latErr := strconv.ParseFloat(latStr, 64)
+		longitude, lngErr := strconv.ParseFloat(lngStr, 64)
+		if latErr != nil || lngErr != nil {
+			api_error.AbortWithValidateErrorSimple(context, api_error.InvalidQueryParam)
+			return
+		}
+		query.Location = &bean.SearchLocation{Latitude: latitude, Longitude: longitude}
+		query.Radius, _ = strconv.ParseFloat(context.DefaultQuery("radius", "0"), 64)
+		if query.Radius <= 0 {
+			query.Radius = bean.GEO_SEARCH_RADIUS_DEFAULT
+		}
+		if query.Radius > bean.GEO_SEARCH_RADIUS_MAX {
+			query.Radius = bean.GEO_SEARCH_RADIUS_MAX
+		}
+	}
+
+	docs, err := search.Search(query)
+	if err == search.ErrSearchNotSupported {
+		api_error.AbortWithValidateErrorSimple(context, api_error.SearchNotSupported)
+		return
+	}
+	if api_error.PropagateErrorAndAbort(context, api_error.GetDataFailed, err) != nil {
+		return
+	}
+
+	bean.SuccessResponse(context, docs)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/service"
+)
+
+type TradingBotApi struct {
+}
+
+func (api TradingBotApi) ListTradingBots(context *gin.Context) {
+	bots, ce := service.TradingBotServiceInst.ListTradingBots()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, bots)
+}
+
+func (api TradingBotApi) GetTradingBot(context *gin.Context) {
+	botId := context.Param("botId")
+
+	bot, ce := service.TradingBotServiceInst.GetTradingBot(botId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, bot)
+}
+
+func (api TradingBotApi) AddTradingBot(context *gin.Context) {
+	var body bean.TradingBot
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	bot, ce := service.TradingBotServiceInst.AddTradingBot(body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, bot)
+}
+
+func (api TradingBotApi) UpdateTradingBot(context *gin.Context) {
+	botId := context.Param("botId")
+
+	var body bean.TradingBot
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	bot, ce := service.TradingBotServiceInst.UpdateTradingBot(botId, body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, bot)
+}
+
+func (api TradingBotApi) RemoveTradingBot(context *gin.Context) {
+	botId := context.Param("botId")
+
+	bot, ce := service.TradingBotServiceInst.RemoveTradingBot(botId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, bot)
+}
+
+func (api TradingBotApi) GetTradingBotStatus(context *gin.Context) {
+	botId := context.Param("botId")
+
+	status, ce := service.TradingBotServiceInst.GetTradingBotStatus(botId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, status)
+}
+
+// CRON JOB
+func (api TradingBotApi) RefreshTradingBots(context *gin.Context) {
+	bots, ce := service.TradingBotServiceInst.RefreshTradingBots()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, bots)
+}
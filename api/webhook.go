@@ -0,0 +1,67 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service"
+)
+
+type WebhookApi struct {
+}
+
+func (api WebhookApi) AddSubscription(context *gin.Context) {
+	var body bean.WebhookSubscription
+	if common.ValidateBody(context, &body) != nil {
+		return
+	}
+
+	subscription, ce := service.WebhookSubscriptionServiceInst.AddSubscription(body)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, subscription)
+}
+
+func (api WebhookApi) ListSubscriptions(context *gin.Context) {
+	subscriptions, ce := service.WebhookSubscriptionServiceInst.ListSubscriptions()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, subscriptions)
+}
+
+func (api WebhookApi) RemoveSubscription(context *gin.Context) {
+	subscriptionId := context.Param("subscriptionId")
+
+	ce := service.WebhookSubscriptionServiceInst.RemoveSubscription(subscriptionId)
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, true)
+}
+
+func (api WebhookApi) ListDeliveries(context *gin.Context) {
+	subscriptionId := context.Param("subscriptionId")
+	startAt, limit := common.ExtractTimePagingParams(context)
+
+	to := dao.WebhookDaoInst.ListWebhookDeliveries(subscriptionId, limit, startAt)
+	if to.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessPagingResponse(context, to.Objects, to.CanMove, to.Page)
+}
+
+func (api WebhookApi) RetryDeliveries(context *gin.Context) {
+	count, ce := service.WebhookSubscriptionServiceInst.RetryWebhookDeliveries()
+	if ce.ContextValidate(context) {
+		return
+	}
+
+	bean.SuccessResponse(context, count)
+}
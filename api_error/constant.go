@@ -41,6 +41,22 @@ const OfferStoreNotEnoughBalance = "OfferStoreNotEnoughBalance"
 const OfferStoreAlreadyReviewed = "OfferStoreAlreadyReviewed"
 const InvalidFreeStartAmount = "InvalidFreeStartAmount"
 const RegisterFreeStartFailed = "RegisterFreeStartFailed"
+const PriceIsStale = "PriceIsStale"
+const UnsupportedFiatCurrency = "UnsupportedFiatCurrency"
+const QuoteExpired = "QuoteExpired"
+const InvalidQuote = "InvalidQuote"
+const SearchNotSupported = "SearchNotSupported"
+const DisputeNotSupported = "DisputeNotSupported"
+const NotArbitrator = "NotArbitrator"
+const DisputeAlreadyResolved = "DisputeAlreadyResolved"
+const InvalidSignature = "InvalidSignature"
+const CardAuthenticationFailed = "CardAuthenticationFailed"
+const RefundAmountInvalid = "RefundAmountInvalid"
+const KYCPending = "KYCPending"
+const NotKYCReviewer = "NotKYCReviewer"
+const KYCAlreadyReviewed = "KYCAlreadyReviewed"
+const KYCTierLimitExceeded = "KYCTierLimitExceeded"
+const KYCSelfReview = "KYCSelfReview"
 
 var CodeMessage = map[string]struct {
 	StatusCode int
@@ -86,4 +102,20 @@ var CodeMessage = map[string]struct {
 	InvalidFreeStartAmount:         {http.StatusBadRequest, -319, "Invalid free start amount"},
 	RegisterFreeStartFailed:        {http.StatusBadRequest, -320, "Register free start failed"},
 	OfferStoreShakeActiveExist:     {http.StatusBadRequest, -321, "There is offer store shake active"},
+	PriceIsStale:                   {http.StatusBadRequest, -322, "Price is out of date, please try again later"},
+	UnsupportedFiatCurrency:        {http.StatusBadRequest, -323, "This fiat currency is not supported"},
+	QuoteExpired:                   {http.StatusBadRequest, -324, "Quote is expired, please get a new one"},
+	InvalidQuote:                   {http.StatusBadRequest, -325, "Quote is invalid"},
+	SearchNotSupported:             {http.StatusBadRequest, -326, "Search is not supported by this indexer"},
+	DisputeNotSupported:            {http.StatusBadRequest, -327, "Dispute is not supported for this offer"},
+	NotArbitrator:                  {http.StatusForbidden, -328, "Only arbitrators can rule on disputes"},
+	DisputeAlreadyResolved:         {http.StatusBadRequest, -329, "Dispute is already resolved"},
+	InvalidSignature:               {http.StatusUnauthorized, -330, "Signature is invalid"},
+	CardAuthenticationFailed:       {http.StatusBadRequest, -331, "Card authentication failed"},
+	RefundAmountInvalid:            {http.StatusBadRequest, -332, "Refund amount is invalid"},
+	KYCPending:                     {http.StatusBadRequest, -333, "Identity verification is pending"},
+	NotKYCReviewer:                 {http.StatusForbidden, -334, "Only KYC reviewers can review submissions"},
+	KYCAlreadyReviewed:             {http.StatusBadRequest, -335, "KYC submission is already reviewed"},
+	KYCTierLimitExceeded:           {http.StatusBadRequest, -336, "Amount is over the limit of your verification tier"},
+	KYCSelfReview:                  {http.StatusForbidden, -337, "Reviewers can not review their own submission"},
 }
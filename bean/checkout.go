@@ -19,6 +19,10 @@ type CheckOutCardIdPaymentRequest struct {
 	AutoCapture string `json:"autoCapture"`
 	Description string `json:"description"`
 	Descriptor  string `json:"descriptor"`
+	ChargeMode  int    `json:"chargeMode,omitempty"`
+	AttemptN3D  bool   `json:"attemptN3D,omitempty"`
+	SuccessUrl  string `json:"successUrl,omitempty"`
+	FailUrl     string `json:"failUrl,omitempty"`
 }
 
 type CheckoutCard struct {
@@ -38,6 +42,18 @@ type CheckoutCardPaymentResponse struct {
 	Status               string               `json:"status"`
 	AuthCode             string               `json:"authCode"`
 	Card                 CheckoutCardResponse `json:"card"`
+	ChargeMode           int                  `json:"chargeMode"`
+	RedirectUrl          string               `json:"redirectUrl"`
+}
+
+// A 3-D Secure charge comes back as a payment token with a redirect, the card holder authenticates with the issuer first
+func (response CheckoutCardPaymentResponse) RequiresAuthentication() bool {
+	return response.RedirectUrl != ""
+}
+
+type CheckoutCardTokenResponse struct {
+	Id   string               `json:"id"`
+	Card CheckoutCardResponse `json:"card"`
 }
 
 type CheckoutCardResponse struct {
@@ -51,6 +67,16 @@ type CheckoutCardResponse struct {
 	AVSCheck    string `json:"avsCheck"`
 }
 
+const CHECKOUT_STATUS_AUTHORISED = "Authorised"
+const CHECKOUT_STATUS_VOIDED = "Voided"
+
+const CHECKOUT_CHARGE_MODE_NON_3D = 1
+const CHECKOUT_CHARGE_MODE_3D = 2
+
+type CheckoutRefundRequest struct {
+	Value int64 `json:"value"`
+}
+
 type CheckoutCard2ndStepResponse struct {
 	Id                   string `json:"id"`
 	OriginalId           string `json:"originalId"`
@@ -61,3 +87,41 @@ type CheckoutCard2ndStepResponse struct {
 	ResponseCode         string `json:"responseCode"`
 	Status               string `json:"status"`
 }
+
+const CHECKOUT_WEBHOOK_SIGNATURE_HEADER = "Cko-Signature"
+
+const CHECKOUT_EVENT_CHARGE_REFUNDED = "charge.refunded"
+const CHECKOUT_EVENT_CHARGE_RETRIEVAL = "charge.retrieval"
+const CHECKOUT_EVENT_CHARGE_CHARGEBACK = "charge.chargeback"
+
+// The card transaction status each handled event moves to, anything else Checkout sends is acknowledged and ignored
+var CheckoutEventCCTransactionStatus = map[string]string{
+	CHECKOUT_EVENT_CHARGE_REFUNDED:   CC_TRANSACTION_STATUS_REFUNDED,
+	CHECKOUT_EVENT_CHARGE_RETRIEVAL:  CC_TRANSACTION_STATUS_DISPUTED,
+	CHECKOUT_EVENT_CHARGE_CHARGEBACK: CC_TRANSACTION_STATUS_CHARGEBACK,
+}
+
+type CheckoutWebhookEvent struct {
+	EventType string                 `json:"eventType"`
+	Message   CheckoutWebhookMessage `json:"message"`
+}
+
+type CheckoutWebhookMessage struct {
+	Id              string `json:"id" firestore:"id"`
+	OriginalId      string `json:"originalId" firestore:"original_id"`
+	Created         string `json:"created" firestore:"created"`
+	Email           string `json:"email" firestore:"email"`
+	Value           int64  `json:"value" firestore:"value"`
+	Currency        string `json:"currency" firestore:"currency"`
+	Status          string `json:"status" firestore:"status"`
+	ResponseMessage string `json:"responseMessage" firestore:"response_message"`
+	ResponseCode    string `json:"responseCode" firestore:"response_code"`
+}
+
+// Refunds and chargebacks come with their own id, the charge we stored is the original one
+func (message CheckoutWebhookMessage) ChargeId() string {
+	if message.OriginalId != "" {
+		return message.OriginalId
+	}
+	return message.Id
+}
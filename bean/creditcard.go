@@ -2,31 +2,44 @@ package bean
 
 import (
 	"cloud.google.com/go/firestore"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
+const CC_TRANSACTION_STATUS_PENDING_AUTHENTICATION = "pending_authentication"
+const CC_TRANSACTION_STATUS_FAILED = "failed"
 const CC_TRANSACTION_STATUS_PURCHASED = "purchased"
 const CC_TRANSACTION_STATUS_CAPTURED = "captured"
 const CC_TRANSACTION_STATUS_REFUNDED = "refunded"
+const CC_TRANSACTION_STATUS_PARTIALLY_REFUNDED = "partially_refunded"
+const CC_TRANSACTION_STATUS_DISPUTED = "disputed"
+const CC_TRANSACTION_STATUS_CHARGEBACK = "chargeback"
 const CC_TRANSACTION_TYPE = "instant_buy"
 
 const CC_PROVIDER_STRIPE = "stripe"
 const CC_PROVIDER_CHECKOUT = "checkout"
 
+// Authorised and voided right away when a card is added without a purchase
+var CC_VERIFICATION_AMOUNT = decimal.NewFromFloat(1).Round(2)
+
 type CCTransaction struct {
-	Id           string      `json:"id" firestore:"id"`
-	UID          string      `json:"uid" firestore:"uid"`
-	Amount       string      `json:"amount" firestore:"amount"`
-	Currency     string      `json:"currency" firestore:"currency"`
-	Status       string      `json:"-" firestore:"status"`
-	Provider     string      `json:"-" firestore:"provider"`
-	ProviderData interface{} `json:"-" firestore:"provider_data"`
-	ExternalId   string      `json:"-" firestore:"external_id"`
-	Type         string      `json:"-" firestore:"type"`
-	DataRef      string      `json:"-" firestore:"data_ref"`
-	Email        string      `json:"email" firestore:"email"`
-	CreatedAt    time.Time   `json:"created_at" firestore:"created_at"`
-	UpdatedAt    time.Time   `json:"updated_at" firestore:"updated_at"`
+	Id              string      `json:"id" firestore:"id"`
+	UID             string      `json:"uid" firestore:"uid"`
+	Amount          string      `json:"amount" firestore:"amount"`
+	Currency        string      `json:"currency" firestore:"currency"`
+	Status          string      `json:"-" firestore:"status"`
+	Provider        string      `json:"-" firestore:"provider"`
+	ProviderData    interface{} `json:"-" firestore:"provider_data"`
+	ExternalId      string      `json:"-" firestore:"external_id"`
+	Type            string      `json:"-" firestore:"type"`
+	DataRef         string      `json:"-" firestore:"data_ref"`
+	CardId          string      `json:"-" firestore:"card_id"`
+	RefundedAmount  string      `json:"refunded_amount" firestore:"refunded_amount"`
+	RefundingAmount string      `json:"-" firestore:"refunding_amount"`
+	RefundAmounts   []string    `json:"-" firestore:"refund_amounts"`
+	Email           string      `json:"email" firestore:"email"`
+	CreatedAt       time.Time   `json:"created_at" firestore:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at" firestore:"updated_at"`
 }
 
 func (cc CCTransaction) GetAddCCTransaction() map[string]interface{} {
@@ -41,6 +54,7 @@ func (cc CCTransaction) GetAddCCTransaction() map[string]interface{} {
 		"external_id":   cc.ExternalId,
 		"type":          cc.Type,
 		"data_ref":      cc.DataRef,
+		"card_id":       cc.CardId,
 		"created_at":    firestore.ServerTimestamp,
 	}
 }
@@ -48,10 +62,20 @@ func (cc CCTransaction) GetAddCCTransaction() map[string]interface{} {
 func (cc CCTransaction) GetUpdateCCTransaction() map[string]interface{} {
 	return map[string]interface{}{
 		"data_ref":   cc.DataRef,
+		"card_id":    cc.CardId,
 		"updated_at": firestore.ServerTimestamp,
 	}
 }
 
+func (cc CCTransaction) GetUpdateAuthentication() map[string]interface{} {
+	return map[string]interface{}{
+		"status":        cc.Status,
+		"external_id":   cc.ExternalId,
+		"provider_data": cc.ProviderData,
+		"updated_at":    firestore.ServerTimestamp,
+	}
+}
+
 func (cc CCTransaction) GetUpdateStatus() map[string]interface{} {
 	return map[string]interface{}{
 		"status":        cc.Status,
@@ -60,13 +84,85 @@ func (cc CCTransaction) GetUpdateStatus() map[string]interface{} {
 	}
 }
 
+func (cc CCTransaction) GetUpdateRefund() map[string]interface{} {
+	return map[string]interface{}{
+		"status":           cc.Status,
+		"refunded_amount":  cc.RefundedAmount,
+		"refunding_amount": cc.RefundingAmount,
+		"refund_amounts":   cc.RefundAmounts,
+		"provider_data":    cc.ProviderData,
+		"updated_at":       firestore.ServerTimestamp,
+	}
+}
+
+// Refunding is what was sent to the provider and not recorded yet, refund amounts has every refund we asked for
+func (cc CCTransaction) GetUpdateRefunding() map[string]interface{} {
+	return map[string]interface{}{
+		"refunding_amount": cc.RefundingAmount,
+		"refund_amounts":   cc.RefundAmounts,
+		"updated_at":       firestore.ServerTimestamp,
+	}
+}
+
+// Charges that went through and were not given back already, a disputed charge is settled by the provider
+func (cc CCTransaction) IsRefundable() bool {
+	return cc.Status == CC_TRANSACTION_STATUS_CAPTURED || cc.Status == CC_TRANSACTION_STATUS_PARTIALLY_REFUNDED
+}
+
+// Value is in minor units, as providers send it
+func (cc CCTransaction) IsOwnRefund(value int64) bool {
+	for _, refundAmount := range cc.RefundAmounts {
+		amount, err := decimal.NewFromString(refundAmount)
+		if err == nil && amount.Mul(decimal.NewFromFloat(100)).Round(0).IntPart() == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (cc CCTransaction) GetPageValue() interface{} {
 	return cc.CreatedAt
 }
 
+// Provider side events on a card transaction, disputes, chargebacks and refunds
+type CCTransactionEvent struct {
+	Id               string      `json:"id" firestore:"id"`
+	UID              string      `json:"uid" firestore:"uid"`
+	Provider         string      `json:"provider" firestore:"provider"`
+	EventType        string      `json:"event_type" firestore:"event_type"`
+	ChargeId         string      `json:"charge_id" firestore:"charge_id"`
+	CCTransactionRef string      `json:"cc_transaction_ref" firestore:"cc_transaction_ref"`
+	InstantOfferRef  string      `json:"instant_offer_ref" firestore:"instant_offer_ref"`
+	Amount           string      `json:"amount" firestore:"amount"`
+	Currency         string      `json:"currency" firestore:"currency"`
+	CardLocked       bool        `json:"card_locked" firestore:"card_locked"`
+	ProviderData     interface{} `json:"provider_data" firestore:"provider_data"`
+	CreatedAt        time.Time   `json:"created_at" firestore:"created_at"`
+}
+
+func (event CCTransactionEvent) GetAddCCTransactionEvent() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 event.Id,
+		"uid":                event.UID,
+		"provider":           event.Provider,
+		"event_type":         event.EventType,
+		"charge_id":          event.ChargeId,
+		"cc_transaction_ref": event.CCTransactionRef,
+		"instant_offer_ref":  event.InstantOfferRef,
+		"amount":             event.Amount,
+		"currency":           event.Currency,
+		"card_locked":        event.CardLocked,
+		"provider_data":      event.ProviderData,
+		"created_at":         firestore.ServerTimestamp,
+	}
+}
+
+const INSTANT_OFFER_STATUS_PENDING_AUTHENTICATION = "pending_authentication"
 const INSTANT_OFFER_STATUS_PROCESSING = "processing"
 const INSTANT_OFFER_STATUS_SUCCESS = "success"
 const INSTANT_OFFER_STATUS_CANCELLED = "cancelled"
+const INSTANT_OFFER_STATUS_REFUNDED = "refunded"
+const INSTANT_OFFER_STATUS_PARTIALLY_REFUNDED = "partially_refunded"
 
 const INSTANT_OFFER_TYPE_BUY = "buy"
 
@@ -75,6 +171,9 @@ const INSTANT_OFFER_PROVIDER_GDAX = "gdax"
 
 const INSTANT_OFFER_PAYMENT_METHOD_CC = "creditcard"
 
+// Seconds the card holder has to come back from the issuer before the offer is cancelled
+const INSTANT_OFFER_AUTHENTICATION_DURATION = 15 * 60
+
 type InstantOffer struct {
 	Id                   string      `json:"id" firestore:"id"`
 	UID                  string      `json:"uid" firestore:"uid"`
@@ -103,6 +202,10 @@ type InstantOffer struct {
 	FCM                  string      `json:"fcm" firestore:"fcm"`
 	Language             string      `json:"language" firestore:"language"`
 	ChainId              int64       `json:"chain_id" firestore:"chain_id"`
+	QuoteId              string      `json:"quote_id" firestore:"quote_id"`
+	QuoteExpiredAt       int64       `json:"quote_expired_at" firestore:"-"`
+	RedirectUrl          string      `json:"redirect_url,omitempty" firestore:"redirect_url"`
+	RefundedFiatAmount   string      `json:"refunded_fiat_amount,omitempty" firestore:"refunded_fiat_amount"`
 	CreatedAt            time.Time   `json:"created_at" firestore:"created_at"`
 	UpdatedAt            time.Time   `json:"updated_at" firestore:"updated_at"`
 }
@@ -112,7 +215,9 @@ type CreditCardInfo struct {
 	ExpirationDate string `json:"expiration_date"`
 	CVV            string `json:"cvv"`
 	Token          string `json:"token"`
+	CardId         string `json:"card_id"`
 	Save           bool   `json:"save"`
+	Provider       string `json:"provider"`
 }
 
 type InstantOfferRequest struct {
@@ -147,6 +252,8 @@ func (offer InstantOffer) GetAddInstantOffer() map[string]interface{} {
 		"language":           offer.Language,
 		"fcm":                offer.FCM,
 		"chain_id":           offer.ChainId,
+		"quote_id":           offer.QuoteId,
+		"redirect_url":       offer.RedirectUrl,
 		"created_at":         firestore.ServerTimestamp,
 	}
 }
@@ -160,6 +267,14 @@ func (offer InstantOffer) GetUpdate() map[string]interface{} {
 	}
 }
 
+func (offer InstantOffer) GetUpdateRefund() map[string]interface{} {
+	return map[string]interface{}{
+		"status":               offer.Status,
+		"refunded_fiat_amount": offer.RefundedFiatAmount,
+		"updated_at":           firestore.ServerTimestamp,
+	}
+}
+
 func (offer InstantOffer) GetNotificationUpdate() map[string]interface{} {
 	return map[string]interface{}{
 		"id":     offer.Id,
@@ -197,3 +312,40 @@ func (offer PendingInstantOffer) GetAddInstantOffer() map[string]interface{} {
 		"created_at":        firestore.ServerTimestamp,
 	}
 }
+
+// Instant offers waiting for the card holder to finish 3-D Secure, the card is only saved and tracked once it is authorised
+type PendingAuthenticationInstantOffer struct {
+	Id               string    `json:"id" firestore:"id"`
+	UID              string    `json:"uid" firestore:"uid"`
+	InstantOffer     string    `json:"instant_offer" firestore:"instant_offer"`
+	InstantOfferRef  string    `json:"instant_offer_ref" firestore:"instant_offer_ref"`
+	CCTransactionRef string    `json:"cc_transaction_ref" firestore:"cc_transaction_ref"`
+	SaveCard         bool      `json:"save_card" firestore:"save_card"`
+	CardToken        string    `json:"card_token" firestore:"card_token"`
+	CardId           string    `json:"card_id" firestore:"card_id"`
+	CreatedAt        time.Time `json:"created_at" firestore:"created_at"`
+}
+
+func (offer PendingAuthenticationInstantOffer) GetAddInstantOffer() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 offer.Id,
+		"uid":                offer.UID,
+		"instant_offer":      offer.InstantOffer,
+		"instant_offer_ref":  offer.InstantOfferRef,
+		"cc_transaction_ref": offer.CCTransactionRef,
+		"save_card":          offer.SaveCard,
+		"card_token":         offer.CardToken,
+		"card_id":            offer.CardId,
+		"created_at":         firestore.ServerTimestamp,
+	}
+}
+
+// Amount is in the charged fiat currency, empty refunds whatever is left on the charge
+type InstantOfferRefundRequest struct {
+	Amount string `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+type InstantOfferAuthenticationRequest struct {
+	PaymentToken string `json:"payment_token" validate:"required"`
+}
@@ -1,5 +1,10 @@
 package bean
 
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
 const CURRENCY_CRYPTO = "crypto"
 const CURRENCY_FIAT = "fiat"
 
@@ -61,3 +66,46 @@ var CurrencyMapping = map[string]Currency{
 	ETH.Code: ETH,
 	// LTC.Code: LTC,
 }
+
+const FIAT_SYMBOL_POSITION_BEFORE = "before"
+const FIAT_SYMBOL_POSITION_AFTER = "after"
+
+type FiatCurrency struct {
+	Code           string `json:"code" firestore:"code"`
+	Name           string `json:"name" firestore:"name"`
+	Decimal        int32  `json:"decimal" firestore:"decimal"`
+	Symbol         string `json:"symbol" firestore:"symbol"`
+	SymbolPosition string `json:"symbol_position" firestore:"symbol_position"`
+	Enabled        bool   `json:"enabled" firestore:"enabled"`
+}
+
+// The catalogue until fiat_currencies has enabled currencies, same as CurrencyMapping
+var DefaultFiatCurrencies = []FiatCurrency{
+	{
+		Code:           USD.Code,
+		Name:           USD.Name,
+		Decimal:        USD.Decimal,
+		Symbol:         "$",
+		SymbolPosition: FIAT_SYMBOL_POSITION_BEFORE,
+		Enabled:        true,
+	},
+}
+
+func (currency FiatCurrency) Round(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(currency.Decimal)
+}
+
+func (currency FiatCurrency) FormatAmount(amount decimal.Decimal) string {
+	return amount.StringFixed(currency.Decimal)
+}
+
+func (currency FiatCurrency) Display(amount decimal.Decimal) string {
+	symbol := currency.Symbol
+	if symbol == "" {
+		symbol = currency.Code
+	}
+	if currency.SymbolPosition == FIAT_SYMBOL_POSITION_AFTER {
+		return fmt.Sprintf("%s %s", currency.FormatAmount(amount), symbol)
+	}
+	return fmt.Sprintf("%s%s", symbol, currency.FormatAmount(amount))
+}
@@ -0,0 +1,92 @@
+package bean
+
+import (
+	"cloud.google.com/go/firestore"
+	"strings"
+	"time"
+)
+
+const DISPUTE_TYPE_OFFER = "offer"
+const DISPUTE_TYPE_OFFER_STORE_SHAKE = "offer_store_shake"
+
+const DISPUTE_STATUS_OPEN = "open"
+const DISPUTE_STATUS_RESOLVING = "resolving"
+const DISPUTE_STATUS_RESOLVED = "resolved"
+
+// Release pays the crypto out to the buyer, refund returns it to the seller
+const DISPUTE_RULING_RELEASE = "release"
+const DISPUTE_RULING_REFUND = "refund"
+
+// Comma separated user ids allowed to rule on disputes
+const CONFIG_DISPUTE_ARBITRATORS = "DISPUTE_ARBITRATORS"
+
+type Dispute struct {
+	Id            string    `json:"id" firestore:"id"`
+	Type          string    `json:"type" firestore:"type"`
+	OfferId       string    `json:"offer_id" firestore:"offer_id"`
+	OfferShakeId  string    `json:"offer_shake_id" firestore:"offer_shake_id"`
+	Currency      string    `json:"currency" firestore:"currency"`
+	UID           string    `json:"uid" firestore:"uid"`
+	SellerUID     string    `json:"seller_uid" firestore:"seller_uid"`
+	BuyerUID      string    `json:"buyer_uid" firestore:"buyer_uid"`
+	Reason        string    `json:"reason" firestore:"reason"`
+	Status        string    `json:"status" firestore:"status"`
+	Ruling        string    `json:"ruling" firestore:"ruling"`
+	Note          string    `json:"note" firestore:"note"`
+	ArbitratorUID string    `json:"arbitrator_uid" firestore:"arbitrator_uid"`
+	TxHash        string    `json:"tx_hash" firestore:"tx_hash"`
+	CreatedAt     time.Time `json:"created_at" firestore:"created_at"`
+	ResolvedAt    time.Time `json:"resolved_at" firestore:"resolved_at"`
+}
+
+type DisputeRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type DisputeRulingRequest struct {
+	Ruling string `json:"ruling" validate:"required,oneof=release refund"`
+	Note   string `json:"note"`
+}
+
+func (dispute Dispute) GetAddDispute() map[string]interface{} {
+	return map[string]interface{}{
+		"id":             dispute.Id,
+		"type":           dispute.Type,
+		"offer_id":       dispute.OfferId,
+		"offer_shake_id": dispute.OfferShakeId,
+		"currency":       dispute.Currency,
+		"uid":            dispute.UID,
+		"seller_uid":     dispute.SellerUID,
+		"buyer_uid":      dispute.BuyerUID,
+		"reason":         dispute.Reason,
+		"status":         DISPUTE_STATUS_OPEN,
+		"created_at":     firestore.ServerTimestamp,
+	}
+}
+
+func (dispute Dispute) GetUpdateResolving() map[string]interface{} {
+	return map[string]interface{}{
+		"status":         strings.ToLower(dispute.Status),
+		"ruling":         dispute.Ruling,
+		"arbitrator_uid": dispute.ArbitratorUID,
+	}
+}
+
+func (dispute Dispute) GetUpdateRuling() map[string]interface{} {
+	return map[string]interface{}{
+		"status":         strings.ToLower(dispute.Status),
+		"ruling":         dispute.Ruling,
+		"note":           dispute.Note,
+		"arbitrator_uid": dispute.ArbitratorUID,
+		"tx_hash":        dispute.TxHash,
+		"resolved_at":    firestore.ServerTimestamp,
+	}
+}
+
+func (dispute Dispute) GetPageValue() interface{} {
+	return dispute.CreatedAt
+}
+
+func (dispute Dispute) IsParty(userId string) bool {
+	return userId != "" && (userId == dispute.SellerUID || userId == dispute.BuyerUID)
+}
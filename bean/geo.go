@@ -0,0 +1,32 @@
+package bean
+
+import "cloud.google.com/go/firestore"
+
+const GEO_SEARCH_RADIUS_DEFAULT = 10.0
+const GEO_SEARCH_RADIUS_MAX = 500.0
+const GEO_SEARCH_LIMIT_DEFAULT = 50
+
+type GeoIndexEntry struct {
+	OrderBookEntry
+	Geohash   string  `json:"-" firestore:"geohash"`
+	Latitude  float64 `json:"latitude" firestore:"latitude"`
+	Longitude float64 `json:"longitude" firestore:"longitude"`
+	Distance  float64 `json:"distance" firestore:"-"`
+}
+
+func (entry GeoIndexEntry) GetAddGeoIndexEntry() map[string]interface{} {
+	return map[string]interface{}{
+		"id":            entry.Id,
+		"source":        entry.Source,
+		"source_id":     entry.SourceId,
+		"type":          entry.Type,
+		"currency":      entry.Currency,
+		"fiat_currency": entry.FiatCurrency,
+		"amount":        entry.Amount,
+		"percentage":    entry.Percentage,
+		"geohash":       entry.Geohash,
+		"latitude":      entry.Latitude,
+		"longitude":     entry.Longitude,
+		"updated_at":    firestore.ServerTimestamp,
+	}
+}
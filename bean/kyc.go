@@ -0,0 +1,104 @@
+package bean
+
+import (
+	"cloud.google.com/go/firestore"
+	"time"
+)
+
+// Basic is checked identity data, full also has checked identity documents
+const KYC_TIER_NONE = 0
+const KYC_TIER_BASIC = 1
+const KYC_TIER_FULL = 2
+
+const KYC_STATUS_PENDING = "pending"
+const KYC_STATUS_APPROVED = "approved"
+const KYC_STATUS_REJECTED = "rejected"
+
+// JSON list of KYCTierLimit, nothing is capped while it is not set
+const CONFIG_KYC_TIER_LIMITS = "KYC_TIER_LIMITS"
+
+// Comma separated user ids allowed to review KYC submissions
+const CONFIG_KYC_REVIEWERS = "KYC_REVIEWERS"
+
+type KYCDocument struct {
+	Type string `json:"type" firestore:"type" validate:"required,oneof=passport id_card driver_license proof_of_address selfie"`
+	Url  string `json:"url" firestore:"url" validate:"required,url"`
+}
+
+type KYCSubmission struct {
+	Id             string        `json:"id" firestore:"id"`
+	UID            string        `json:"uid" firestore:"uid"`
+	Tier           int64         `json:"tier" firestore:"tier"`
+	Status         string        `json:"status" firestore:"status"`
+	FirstName      string        `json:"first_name" firestore:"first_name"`
+	LastName       string        `json:"last_name" firestore:"last_name"`
+	DateOfBirth    string        `json:"date_of_birth" firestore:"date_of_birth"`
+	Country        string        `json:"country" firestore:"country"`
+	Address        string        `json:"address" firestore:"address"`
+	DocumentNumber string        `json:"document_number" firestore:"document_number"`
+	Documents      []KYCDocument `json:"documents" firestore:"documents"`
+	Verifier       string        `json:"verifier" firestore:"verifier"`
+	VerifierRef    string        `json:"-" firestore:"verifier_ref"`
+	Reason         string        `json:"reason" firestore:"reason"`
+	ReviewerUID    string        `json:"reviewer_uid" firestore:"reviewer_uid"`
+	CreatedAt      time.Time     `json:"created_at" firestore:"created_at"`
+	ReviewedAt     time.Time     `json:"reviewed_at" firestore:"reviewed_at"`
+}
+
+type KYCSubmissionRequest struct {
+	FirstName      string        `json:"first_name" validate:"required"`
+	LastName       string        `json:"last_name" validate:"required"`
+	DateOfBirth    string        `json:"date_of_birth" validate:"required"`
+	Country        string        `json:"country" validate:"required"`
+	Address        string        `json:"address"`
+	DocumentNumber string        `json:"document_number"`
+	Documents      []KYCDocument `json:"documents" validate:"dive"`
+}
+
+// Tier is what the reviewer grants, empty grants the tier that was asked for
+type KYCReviewRequest struct {
+	Status string `json:"status" validate:"required,oneof=approved rejected"`
+	Tier   int64  `json:"tier" validate:"min=0,max=2"`
+	Reason string `json:"reason"`
+}
+
+func (submission KYCSubmission) GetAddKYCSubmission() map[string]interface{} {
+	return map[string]interface{}{
+		"id":              submission.Id,
+		"uid":             submission.UID,
+		"tier":            submission.Tier,
+		"status":          KYC_STATUS_PENDING,
+		"first_name":      submission.FirstName,
+		"last_name":       submission.LastName,
+		"date_of_birth":   submission.DateOfBirth,
+		"country":         submission.Country,
+		"address":         submission.Address,
+		"document_number": submission.DocumentNumber,
+		"documents":       submission.Documents,
+		"verifier":        submission.Verifier,
+		"created_at":      firestore.ServerTimestamp,
+	}
+}
+
+func (submission KYCSubmission) GetUpdateReview() map[string]interface{} {
+	return map[string]interface{}{
+		"tier":         submission.Tier,
+		"status":       submission.Status,
+		"verifier_ref": submission.VerifierRef,
+		"reason":       submission.Reason,
+		"reviewer_uid": submission.ReviewerUID,
+		"reviewed_at":  firestore.ServerTimestamp,
+	}
+}
+
+func (submission KYCSubmission) GetPageValue() interface{} {
+	return submission.CreatedAt
+}
+
+// Amounts are crypto amounts per currency, a currency that is not listed is not capped
+type KYCTierLimit struct {
+	Tier              int64             `json:"tier"`
+	MaxCCLimitLevel   int64             `json:"max_cc_limit_level"`
+	OfferAmounts      map[string]string `json:"offer_amounts"`
+	OfferStoreAmounts map[string]string `json:"offer_store_amounts"`
+}
@@ -1,6 +1,9 @@
 package bean
 
-import "cloud.google.com/go/firestore"
+import (
+	"cloud.google.com/go/firestore"
+	"time"
+)
 
 type CurrencyRate struct {
 	From string  `json:"from" firestore:"from"`
@@ -29,6 +32,21 @@ const CONFIG_OFFER_STORE_FREE_START = "OFFER_STORE_FREE_START"
 const OFFER_STORE_FREE_START_ON = "1"
 const OFFER_STORE_FREE_START_OFF = "0"
 
+const CONFIG_BTC_PAYOUT_BATCH_WINDOW = "BTC_PAYOUT_BATCH_WINDOW"
+const CONFIG_BTC_PAYOUT_BATCH_SIZE = "BTC_PAYOUT_BATCH_SIZE"
+const BTC_PAYOUT_BATCH_WINDOW_DEFAULT = 300
+const BTC_PAYOUT_BATCH_SIZE_DEFAULT = 50
+
+const CONFIG_PRICE_ORACLE_METHOD = "PRICE_ORACLE_METHOD"
+const CONFIG_PRICE_ORACLE_MAX_DEVIATION = "PRICE_ORACLE_MAX_DEVIATION"
+const PRICE_ORACLE_MAX_DEVIATION_DEFAULT = "0.05"
+
+const CONFIG_PRICE_MAX_AGE = "PRICE_MAX_AGE"
+const PRICE_MAX_AGE_DEFAULT = 300
+
+const CONFIG_QUOTE_TTL = "QUOTE_TTL"
+const QUOTE_TTL_DEFAULT = 60
+
 type SystemFee struct {
 	Key   string  `json:"key" firestore:"key"`
 	Value float64 `json:"value" firestore:"value"`
@@ -36,29 +54,144 @@ type SystemFee struct {
 }
 
 type CryptoRate struct {
-	From     string  `json:"from" firestore:"from"`
-	To       string  `json:"to" firestore:"to"`
-	Exchange string  `json:"exchange" firestore:"exchange"`
-	Buy      float64 `json:"buy" firestore:"buy"`
-	Sell     float64 `json:"sell" firestore:"sell"`
+	From       string   `json:"from" firestore:"from"`
+	To         string   `json:"to" firestore:"to"`
+	Exchange   string   `json:"exchange" firestore:"exchange"`
+	Buy        float64  `json:"buy" firestore:"buy"`
+	Sell       float64  `json:"sell" firestore:"sell"`
+	BuyVolume  float64  `json:"-" firestore:"buy_volume"`
+	SellVolume float64  `json:"-" firestore:"sell_volume"`
+	Sources    []string `json:"sources,omitempty" firestore:"sources"`
+}
+
+const PRICE_ORACLE_EXCHANGE = "oracle"
+const PRICE_ORACLE_SOURCE_COINBASE = "COINBASE"
+const PRICE_ORACLE_METHOD_MEDIAN = "median"
+const PRICE_ORACLE_METHOD_VWAP = "vwap"
+
+type OraclePriceSource struct {
+	Exchange string  `json:"exchange"`
+	Price    float64 `json:"price"`
+	Volume   float64 `json:"volume"`
+}
+
+type OraclePrice struct {
+	Currency  string              `json:"currency"`
+	Type      string              `json:"type"`
+	Method    string              `json:"method"`
+	Price     float64             `json:"price"`
+	Sources   []OraclePriceSource `json:"sources"`
+	Rejected  []OraclePriceSource `json:"rejected"`
+	UpdatedAt int64               `json:"updated_at"`
+}
+
+type LockedQuote struct {
+	Id            string `json:"id"`
+	UID           string `json:"uid"`
+	Type          string `json:"type"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	FiatCurrency  string `json:"fiat_currency"`
+	FiatAmount    string `json:"fiat_amount"`
+	Price         string `json:"price"`
+	PriceUSD      string `json:"price_usd"`
+	Fee           string `json:"fee,omitempty"`
+	FeePercentage string `json:"fee_percentage,omitempty"`
+	ExpiredAt     int64  `json:"expired_at"`
 }
 
+const TRADING_BOT_STATUS_IDLE = "idle"
+const TRADING_BOT_STATUS_WAITING_DEPOSIT = "waiting_deposit"
+const TRADING_BOT_STATUS_ACTIVE = "active"
+const TRADING_BOT_STATUS_TRADING = "trading"
+const TRADING_BOT_STATUS_FAILED = "failed"
+const TRADING_BOT_STATUS_DISABLED = "disabled"
+
+const TRADING_BOT_DURATION_DEFAULT = 3600
+
 type TradingBot struct {
-	UID       string `json:"uid" firestore:"uid"`
-	Id        string `json:"id" firestore:"id"`
-	Enabled   bool   `json:"enabled" firestore:"enabled"`
-	Currency  string `json:"currency" firestore:"currency"`
-	Type      string `json:"type" firestore:"type"`
-	MinAmount string `json:"min_amount" firestore:"min_amount"`
-	MaxAmount string `json:"max_amount" firestore:"max_amount"`
-	Price     string `json:"price" firestore:"price"`
-	Duration  int    `json:"duration" firestore:"duration"`
+	UID          string    `json:"uid" firestore:"uid" validate:"required"`
+	Id           string    `json:"id" firestore:"id"`
+	Enabled      bool      `json:"enabled" firestore:"enabled"`
+	Currency     string    `json:"currency" firestore:"currency" validate:"required"`
+	FiatCurrency string    `json:"fiat_currency" firestore:"fiat_currency" validate:"required"`
+	Type         string    `json:"type" firestore:"type" validate:"required,oneof=buy sell"`
+	MinAmount    string    `json:"min_amount" firestore:"min_amount" validate:"required"`
+	MaxAmount    string    `json:"max_amount" firestore:"max_amount" validate:"required"`
+	Price        string    `json:"price" firestore:"price"`
+	Duration     int       `json:"duration" firestore:"duration"`
+	Address      string    `json:"address" firestore:"address"`
+	AutoComplete bool      `json:"auto_complete" firestore:"auto_complete"`
+	Status       string    `json:"status" firestore:"status"`
+	OfferId      string    `json:"offer_id" firestore:"offer_id"`
+	OfferStatus  string    `json:"offer_status" firestore:"offer_status"`
+	LastError    string    `json:"last_error" firestore:"last_error"`
+	RefreshedAt  time.Time `json:"refreshed_at" firestore:"refreshed_at"`
+	CreatedAt    time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+func (bot TradingBot) GetAddTradingBot() map[string]interface{} {
+	return map[string]interface{}{
+		"id":            bot.Id,
+		"uid":           bot.UID,
+		"enabled":       bot.Enabled,
+		"currency":      bot.Currency,
+		"fiat_currency": bot.FiatCurrency,
+		"type":          bot.Type,
+		"min_amount":    bot.MinAmount,
+		"max_amount":    bot.MaxAmount,
+		"price":         bot.Price,
+		"duration":      bot.Duration,
+		"address":       bot.Address,
+		"auto_complete": bot.AutoComplete,
+		"status":        bot.Status,
+		"created_at":    firestore.ServerTimestamp,
+	}
+}
+
+func (bot TradingBot) GetUpdateTradingBot() map[string]interface{} {
+	return map[string]interface{}{
+		"enabled":       bot.Enabled,
+		"fiat_currency": bot.FiatCurrency,
+		"min_amount":    bot.MinAmount,
+		"max_amount":    bot.MaxAmount,
+		"price":         bot.Price,
+		"duration":      bot.Duration,
+		"address":       bot.Address,
+		"auto_complete": bot.AutoComplete,
+		"updated_at":    firestore.ServerTimestamp,
+	}
+}
+
+func (bot TradingBot) GetUpdateStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"status":       bot.Status,
+		"offer_id":     bot.OfferId,
+		"offer_status": bot.OfferStatus,
+		"last_error":   bot.LastError,
+		"refreshed_at": firestore.ServerTimestamp,
+	}
 }
 
 type TradingBotPendingOffer struct {
-	Offer     string `json:"-" firestore:"offer"`
-	OfferRef  string `json:"-" firestore:"offer_ref"`
-	CreatedAt string `json:"-" firestore:"created_at"`
+	Offer     string    `json:"offer" firestore:"offer"`
+	OfferRef  string    `json:"-" firestore:"offer_ref"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+}
+
+func (offer TradingBotPendingOffer) GetAddTradingBotPendingOffer() map[string]interface{} {
+	return map[string]interface{}{
+		"offer":      offer.Offer,
+		"offer_ref":  offer.OfferRef,
+		"created_at": firestore.ServerTimestamp,
+	}
+}
+
+type TradingBotStatus struct {
+	Bot           TradingBot               `json:"bot"`
+	Offer         interface{}              `json:"offer"`
+	PendingOffers []TradingBotPendingOffer `json:"pending_offers"`
 }
 
 type CCLimit struct {
@@ -127,3 +260,90 @@ func (transfer CryptoPendingTransfer) GetAddCryptoPendingTransfer() map[string]i
 		"created_at":  firestore.ServerTimestamp,
 	}
 }
+
+const CRYPTO_PAYOUT_STATUS_PENDING = "pending"
+const CRYPTO_PAYOUT_STATUS_SENDING = "sending"
+const CRYPTO_PAYOUT_STATUS_SENT = "sent"
+const CRYPTO_PAYOUT_STATUS_FAILED = "failed"
+
+type CryptoPayout struct {
+	Id             string    `json:"id" firestore:"id"`
+	WalletProvider string    `json:"wallet_provider" firestore:"wallet_provider"`
+	DataType       string    `json:"data_type" firestore:"data_type"`
+	DataRef        string    `json:"data_ref" firestore:"data_ref"`
+	UID            string    `json:"uid" firestore:"uid"`
+	Address        string    `json:"address" firestore:"address"`
+	Description    string    `json:"description" firestore:"description"`
+	Amount         string    `json:"amount" firestore:"amount"`
+	Currency       string    `json:"currency" firestore:"currency"`
+	Status         string    `json:"status" firestore:"status"`
+	BatchId        string    `json:"batch_id" firestore:"batch_id"`
+	TxHash         string    `json:"tx_hash" firestore:"tx_hash"`
+	Error          string    `json:"error" firestore:"error"`
+	CreatedAt      time.Time `json:"created_at" firestore:"created_at"`
+}
+
+func (payout CryptoPayout) GetAddCryptoPayout() map[string]interface{} {
+	return map[string]interface{}{
+		"id":              payout.Id,
+		"wallet_provider": payout.WalletProvider,
+		"data_type":       payout.DataType,
+		"data_ref":        payout.DataRef,
+		"uid":             payout.UID,
+		"address":         payout.Address,
+		"description":     payout.Description,
+		"amount":          payout.Amount,
+		"currency":        payout.Currency,
+		"status":          CRYPTO_PAYOUT_STATUS_PENDING,
+		"created_at":      firestore.ServerTimestamp,
+	}
+}
+
+func (payout CryptoPayout) GetUpdateSent() map[string]interface{} {
+	return map[string]interface{}{
+		"status":     payout.Status,
+		"batch_id":   payout.BatchId,
+		"tx_hash":    payout.TxHash,
+		"updated_at": firestore.ServerTimestamp,
+	}
+}
+
+func (payout CryptoPayout) GetUpdateStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"status":     payout.Status,
+		"updated_at": firestore.ServerTimestamp,
+	}
+}
+
+func (payout CryptoPayout) GetUpdateFailed() map[string]interface{} {
+	return map[string]interface{}{
+		"status":     payout.Status,
+		"error":      payout.Error,
+		"updated_at": firestore.ServerTimestamp,
+	}
+}
+
+type CryptoPayoutBatch struct {
+	Id               string      `json:"id" firestore:"id"`
+	WalletProvider   string      `json:"wallet_provider" firestore:"wallet_provider"`
+	Currency         string      `json:"currency" firestore:"currency"`
+	Amount           string      `json:"amount" firestore:"amount"`
+	ExternalId       string      `json:"external_id" firestore:"external_id"`
+	TxHash           string      `json:"tx_hash" firestore:"tx_hash"`
+	ProviderResponse interface{} `json:"provider_response" firestore:"provider_response"`
+	Payouts          []string    `json:"payouts" firestore:"payouts"`
+}
+
+func (batch CryptoPayoutBatch) GetAddCryptoPayoutBatch() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                batch.Id,
+		"wallet_provider":   batch.WalletProvider,
+		"currency":          batch.Currency,
+		"amount":            batch.Amount,
+		"external_id":       batch.ExternalId,
+		"tx_hash":           batch.TxHash,
+		"provider_response": batch.ProviderResponse,
+		"payouts":           batch.Payouts,
+		"created_at":        firestore.ServerTimestamp,
+	}
+}
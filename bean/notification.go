@@ -0,0 +1,119 @@
+package bean
+
+import (
+	"cloud.google.com/go/firestore"
+	"time"
+)
+
+const NOTIFICATION_TYPE_OFFER = "offer"
+const NOTIFICATION_TYPE_INSTANT_OFFER = "instant_offer"
+const NOTIFICATION_TYPE_OFFER_STORE = "offer_store"
+const NOTIFICATION_TYPE_OFFER_STORE_SHAKE = "offer_store_shake"
+
+const NOTIFICATION_CHANNEL_EMAIL = "email"
+const NOTIFICATION_CHANNEL_FIREBASE = "firebase"
+const NOTIFICATION_CHANNEL_SOLR = "solr"
+const NOTIFICATION_CHANNEL_FCM = "fcm"
+const NOTIFICATION_CHANNEL_SMS = "sms"
+
+// Order matters, Send*Notification returns errors in this order
+var NotificationChannels = []string{
+	NOTIFICATION_CHANNEL_EMAIL,
+	NOTIFICATION_CHANNEL_FIREBASE,
+	NOTIFICATION_CHANNEL_SOLR,
+	NOTIFICATION_CHANNEL_FCM,
+	NOTIFICATION_CHANNEL_SMS,
+}
+
+const NOTIFICATION_DELIVERY_STATUS_DELIVERED = "delivered"
+const NOTIFICATION_DELIVERY_STATUS_RETRYING = "retrying"
+const NOTIFICATION_DELIVERY_STATUS_FAILED = "failed"
+
+const CONFIG_NOTIFICATION_RETRY_MAX_ATTEMPTS = "NOTIFICATION_RETRY_MAX_ATTEMPTS"
+const CONFIG_NOTIFICATION_RETRY_BACKOFF = "NOTIFICATION_RETRY_BACKOFF"
+const NOTIFICATION_RETRY_MAX_ATTEMPTS_DEFAULT = 5
+const NOTIFICATION_RETRY_BACKOFF_DEFAULT = 2
+
+// Retries are run by cron, a claimed delivery is left alone for the lease so an overlapping run skips it
+const NOTIFICATION_RETRY_LIMIT = 100
+const NOTIFICATION_RETRY_LEASE = 60
+
+// Deferred users are the ones quiet hours held the channel back for, the retry only sends to them
+type NotificationChannelDelivery struct {
+	Status        string    `json:"status" firestore:"status"`
+	Attempts      int       `json:"attempts" firestore:"attempts"`
+	LastError     string    `json:"last_error" firestore:"last_error"`
+	DeferredUIDs  []string  `json:"deferred_uids" firestore:"deferred_uids"`
+	DeferredUntil time.Time `json:"deferred_until" firestore:"deferred_until"`
+	UpdatedAt     time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+type NotificationDelivery struct {
+	Id       string                                 `json:"id" firestore:"id"`
+	Type     string                                 `json:"type" firestore:"type"`
+	RefId    string                                 `json:"ref_id" firestore:"ref_id"`
+	Status   string                                 `json:"status" firestore:"status"`
+	Channels map[string]NotificationChannelDelivery `json:"channels" firestore:"channels"`
+	// Payload, only the one matching Type is set
+	Offer           *Offer           `json:"-" firestore:"offer,omitempty"`
+	InstantOffer    *InstantOffer    `json:"-" firestore:"instant_offer,omitempty"`
+	OfferStore      *OfferStore      `json:"-" firestore:"offer_store,omitempty"`
+	OfferStoreItem  *OfferStoreItem  `json:"-" firestore:"offer_store_item,omitempty"`
+	OfferStoreShake *OfferStoreShake `json:"-" firestore:"offer_store_shake,omitempty"`
+	NextAttemptAt   time.Time        `json:"next_attempt_at" firestore:"next_attempt_at"`
+	CreatedAt       time.Time        `json:"created_at" firestore:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at" firestore:"updated_at"`
+}
+
+func (delivery NotificationDelivery) GetAddNotificationDelivery() map[string]interface{} {
+	obj := map[string]interface{}{
+		"id":              delivery.Id,
+		"type":            delivery.Type,
+		"ref_id":          delivery.RefId,
+		"status":          delivery.Status,
+		"channels":        delivery.Channels,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"created_at":      firestore.ServerTimestamp,
+		"updated_at":      firestore.ServerTimestamp,
+	}
+	if delivery.Offer != nil {
+		obj["offer"] = delivery.Offer
+	}
+	if delivery.InstantOffer != nil {
+		obj["instant_offer"] = delivery.InstantOffer
+	}
+	if delivery.OfferStore != nil {
+		obj["offer_store"] = delivery.OfferStore
+	}
+	if delivery.OfferStoreItem != nil {
+		obj["offer_store_item"] = delivery.OfferStoreItem
+	}
+	if delivery.OfferStoreShake != nil {
+		obj["offer_store_shake"] = delivery.OfferStoreShake
+	}
+
+	return obj
+}
+
+func (delivery NotificationDelivery) GetUpdateNotificationDelivery() map[string]interface{} {
+	return map[string]interface{}{
+		"status":          delivery.Status,
+		"channels":        delivery.Channels,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"updated_at":      firestore.ServerTimestamp,
+	}
+}
+
+func (delivery NotificationDelivery) FailedChannels() []string {
+	channels := make([]string, 0)
+	for _, channel := range NotificationChannels {
+		if channelDelivery, ok := delivery.Channels[channel]; ok && channelDelivery.Status != NOTIFICATION_DELIVERY_STATUS_DELIVERED {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+func (delivery NotificationDelivery) GetPageValue() interface{} {
+	return delivery.CreatedAt
+}
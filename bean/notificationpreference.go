@@ -0,0 +1,161 @@
+package bean
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const NOTIFICATION_CATEGORY_ACTIVITY = "activity"
+const NOTIFICATION_CATEGORY_SHAKE = "shake"
+const NOTIFICATION_CATEGORY_COMPLETE = "complete"
+const NOTIFICATION_CATEGORY_REJECT = "reject"
+const NOTIFICATION_CATEGORY_MARKETING = "marketing"
+
+var NotificationCategories = []string{
+	NOTIFICATION_CATEGORY_ACTIVITY,
+	NOTIFICATION_CATEGORY_SHAKE,
+	NOTIFICATION_CATEGORY_COMPLETE,
+	NOTIFICATION_CATEGORY_REJECT,
+	NOTIFICATION_CATEGORY_MARKETING,
+}
+
+// Channels the user can turn off, Firebase and search keep the exchange state in sync and always go out
+var NotificationPreferenceChannels = []string{
+	NOTIFICATION_CHANNEL_EMAIL,
+	NOTIFICATION_CHANNEL_FCM,
+	NOTIFICATION_CHANNEL_SMS,
+}
+
+const NOTIFICATION_QUIET_HOURS_FORMAT = "15:04"
+
+type NotificationQuietHours struct {
+	Start    string `json:"start" firestore:"start" validate:"required"`
+	End      string `json:"end" firestore:"end" validate:"required"`
+	Timezone string `json:"timezone" firestore:"timezone"`
+}
+
+// Missing keys mean enabled, so profiles without preferences keep receiving everything
+type NotificationPreferences struct {
+	Channels   map[string]bool         `json:"channels" firestore:"channels"`
+	Categories map[string]bool         `json:"categories" firestore:"categories"`
+	QuietHours *NotificationQuietHours `json:"quiet_hours" firestore:"quiet_hours"`
+}
+
+type ProfileUpdateRequest struct {
+	Language                string                   `json:"language"`
+	NotificationPreferences *NotificationPreferences `json:"notification_preferences"`
+}
+
+func (preferences NotificationPreferences) Validate() error {
+	for channel := range preferences.Channels {
+		if !containsString(NotificationPreferenceChannels, channel) {
+			return fmt.Errorf("unknown notification channel %s", channel)
+		}
+	}
+	for category := range preferences.Categories {
+		if !containsString(NotificationCategories, category) {
+			return fmt.Errorf("unknown notification category %s", category)
+		}
+	}
+	if preferences.QuietHours != nil {
+		return preferences.QuietHours.Validate()
+	}
+
+	return nil
+}
+
+func (preferences NotificationPreferences) Allows(channel string, category string) bool {
+	if enabled, ok := preferences.Channels[channel]; ok && !enabled {
+		return false
+	}
+	if enabled, ok := preferences.Categories[category]; ok && !enabled {
+		return false
+	}
+
+	return true
+}
+
+// Quiet hours only hold back push and SMS until they end, emails do not wake anyone up
+func (preferences NotificationPreferences) QuietUntil(channel string, now time.Time) (time.Time, bool) {
+	if channel == NOTIFICATION_CHANNEL_EMAIL || preferences.QuietHours == nil || !preferences.QuietHours.Contains(now) {
+		return time.Time{}, false
+	}
+
+	return preferences.QuietHours.EndAfter(now), true
+}
+
+func (preferences NotificationPreferences) GetUpdateNotificationPreferences() map[string]interface{} {
+	return map[string]interface{}{
+		"notification_preferences": preferences,
+	}
+}
+
+func (quietHours NotificationQuietHours) Validate() error {
+	if _, err := time.Parse(NOTIFICATION_QUIET_HOURS_FORMAT, quietHours.Start); err != nil {
+		return errors.New("quiet hours start must be HH:MM")
+	}
+	if _, err := time.Parse(NOTIFICATION_QUIET_HOURS_FORMAT, quietHours.End); err != nil {
+		return errors.New("quiet hours end must be HH:MM")
+	}
+	if _, err := time.LoadLocation(quietHours.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %s", quietHours.Timezone)
+	}
+
+	return nil
+}
+
+// Start after end wraps midnight, 22:00 to 07:00 is quiet overnight
+func (quietHours NotificationQuietHours) Contains(now time.Time) bool {
+	start, err := time.Parse(NOTIFICATION_QUIET_HOURS_FORMAT, quietHours.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(NOTIFICATION_QUIET_HOURS_FORMAT, quietHours.End)
+	if err != nil {
+		return false
+	}
+	location, err := time.LoadLocation(quietHours.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
+}
+
+// The first end of the quiet hours after now, in UTC
+func (quietHours NotificationQuietHours) EndAfter(now time.Time) time.Time {
+	end, err := time.Parse(NOTIFICATION_QUIET_HOURS_FORMAT, quietHours.End)
+	if err != nil {
+		return now
+	}
+	location, err := time.LoadLocation(quietHours.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	endAt := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, location)
+	if !endAt.After(local) {
+		endAt = endAt.AddDate(0, 0, 1)
+	}
+
+	return endAt.UTC()
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
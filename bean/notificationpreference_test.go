@@ -0,0 +1,81 @@
+package bean
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNotificationPreferencesAllows(t *testing.T) {
+	preferences := NotificationPreferences{
+		Channels:   map[string]bool{NOTIFICATION_CHANNEL_SMS: false, NOTIFICATION_CHANNEL_FCM: true},
+		Categories: map[string]bool{NOTIFICATION_CATEGORY_MARKETING: false},
+	}
+
+	assert.True(t, preferences.Allows(NOTIFICATION_CHANNEL_FCM, NOTIFICATION_CATEGORY_SHAKE))
+	assert.False(t, preferences.Allows(NOTIFICATION_CHANNEL_SMS, NOTIFICATION_CATEGORY_SHAKE))
+	assert.False(t, preferences.Allows(NOTIFICATION_CHANNEL_FCM, NOTIFICATION_CATEGORY_MARKETING))
+	// Missing keys mean enabled
+	assert.True(t, preferences.Allows(NOTIFICATION_CHANNEL_EMAIL, NOTIFICATION_CATEGORY_COMPLETE))
+	assert.True(t, NotificationPreferences{}.Allows(NOTIFICATION_CHANNEL_SMS, NOTIFICATION_CATEGORY_MARKETING))
+}
+
+func TestNotificationQuietHoursContains(t *testing.T) {
+	quietHours := NotificationQuietHours{Start: "13:00", End: "14:30", Timezone: "UTC"}
+
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 1, 12, 59, 0, 0, time.UTC)))
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 1, 13, 0, 0, 0, time.UTC)))
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 1, 14, 29, 0, 0, time.UTC)))
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 1, 14, 30, 0, 0, time.UTC)))
+}
+
+func TestNotificationQuietHoursContainsWrapsMidnight(t *testing.T) {
+	quietHours := NotificationQuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"}
+
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 1, 22, 0, 0, 0, time.UTC)))
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 1, 23, 59, 0, 0, time.UTC)))
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 2, 6, 59, 0, 0, time.UTC)))
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC)))
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 2, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 1, 21, 59, 0, 0, time.UTC)))
+}
+
+// 22:00 to 07:00 in Ho Chi Minh City is 15:00 to 00:00 UTC
+func TestNotificationQuietHoursContainsUsesTimezone(t *testing.T) {
+	quietHours := NotificationQuietHours{Start: "22:00", End: "07:00", Timezone: "Asia/Ho_Chi_Minh"}
+
+	assert.True(t, quietHours.Contains(time.Date(2018, 6, 1, 15, 0, 0, 0, time.UTC)))
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 1, 14, 59, 0, 0, time.UTC)))
+	assert.False(t, quietHours.Contains(time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestNotificationQuietHoursEndAfter(t *testing.T) {
+	quietHours := NotificationQuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"}
+
+	// Before midnight the quiet hours end the next day
+	assert.Equal(t, time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC), quietHours.EndAfter(time.Date(2018, 6, 1, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC), quietHours.EndAfter(time.Date(2018, 6, 2, 1, 0, 0, 0, time.UTC)))
+
+	quietHours.Timezone = "Asia/Ho_Chi_Minh"
+	assert.Equal(t, time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC), quietHours.EndAfter(time.Date(2018, 6, 1, 16, 0, 0, 0, time.UTC)))
+}
+
+func TestNotificationPreferencesQuietUntil(t *testing.T) {
+	preferences := NotificationPreferences{
+		QuietHours: &NotificationQuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"},
+	}
+	night := time.Date(2018, 6, 1, 23, 0, 0, 0, time.UTC)
+
+	until, quiet := preferences.QuietUntil(NOTIFICATION_CHANNEL_FCM, night)
+	assert.True(t, quiet)
+	assert.Equal(t, time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC), until)
+
+	// Emails go out at any hour
+	_, quiet = preferences.QuietUntil(NOTIFICATION_CHANNEL_EMAIL, night)
+	assert.False(t, quiet)
+	_, quiet = preferences.QuietUntil(NOTIFICATION_CHANNEL_SMS, time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC))
+	assert.False(t, quiet)
+	_, quiet = NotificationPreferences{}.QuietUntil(NOTIFICATION_CHANNEL_FCM, night)
+	assert.False(t, quiet)
+}
@@ -28,6 +28,7 @@ const OFFER_STATUS_CANCELLED = "cancelled"
 const OFFER_STATUS_PRE_SHAKE_FAILED = "pre_shake_failed"
 const OFFER_STATUS_REJECTING = "rejecting"
 const OFFER_STATUS_REJECTED = "rejected"
+const OFFER_STATUS_DISPUTED = "disputed"
 
 var MIN_ETH = decimal.NewFromFloat(0.01).Round(2)
 var MIN_BTC = decimal.NewFromFloat(0.001).Round(3)
@@ -65,6 +66,7 @@ type Offer struct {
 	ToEmail          string           `json:"to_email" firestore:"to_email"`
 	ToLanguage       string           `json:"to_language" firestore:"to_language"`
 	ToFCM            string           `json:"to_fcm" firestore:"to_fcm"`
+	ToContactPhone   string           `json:"to_contact_phone" firestore:"to_contact_phone"`
 	ContactPhone     string           `json:"contact_phone" firestore:"contact_phone"`
 	ContactInfo      string           `json:"contact_info" firestore:"contact_info" validate:"required"`
 	SystemAddress    string           `json:"system_address" firestore:"system_address"`
@@ -74,6 +76,7 @@ type Offer struct {
 	Provider         string           `json:"provider" firestore:"provider"`
 	ProviderData     interface{}      `json:"provider_data" firestore:"provider_data"`
 	WalletProvider   string           `json:"wallet_provider" firestore:"wallet_provider"`
+	PayoutTxHash     string           `json:"payout_tx_hash" firestore:"payout_tx_hash"`
 	Fee              string           `json:"-" firestore:"fee"`
 	FeePercentage    string           `json:"-" firestore:"fee_percentage"`
 	Reward           string           `json:"-" firestore:"reward"`
@@ -165,6 +168,7 @@ func (offer Offer) GetUpdateOfferShake() map[string]interface{} {
 		"to_language":      offer.ToLanguage,
 		"to_username":      offer.Username,
 		"to_chat_username": offer.ToChatUsername,
+		"to_contact_phone": offer.ToContactPhone,
 		"user_address":     offer.UserAddress,
 		"refund_address":   offer.RefundAddress,
 		"to_uid":           offer.ToUID,
@@ -253,6 +257,8 @@ type OfferShakeRequest struct {
 	ChatUsername string `json:"chat_username"`
 	Language     string `json:"language"`
 	FCM          string `json:"fcm"`
+	ContactPhone string `json:"contact_phone"`
+	QuoteId      string `json:"quote_id"`
 }
 
 const OFFER_ADDRESS_MAP_OFFER = "offer"
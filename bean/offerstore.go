@@ -277,6 +277,7 @@ const OFFER_STORE_SHAKE_STATUS_REJECTING = "rejecting"
 const OFFER_STORE_SHAKE_STATUS_REJECTED = "rejected"
 const OFFER_STORE_SHAKE_STATUS_COMPLETING = "completing"
 const OFFER_STORE_SHAKE_STATUS_COMPLETED = "completed"
+const OFFER_STORE_SHAKE_STATUS_DISPUTED = "disputed"
 
 type OfferStoreShake struct {
 	Id               string      `json:"id" firestore:"id"`
@@ -307,8 +308,10 @@ type OfferStoreShake struct {
 	WalletProvider   string      `json:"-" firestore:"wallet_provider"`
 	Provider         string      `json:"-" firestore:"provider"`
 	ProviderData     interface{} `json:"-" firestore:"provider_data"`
+	PayoutTxHash     string      `json:"payout_tx_hash" firestore:"payout_tx_hash"`
 	ChainId          int64       `json:"-" firestore:"chain_id"`
 	FreeStart        string      `json:"free_start" firestore:"free_start"`
+	QuoteId          string      `json:"quote_id" firestore:"quote_id"`
 	Longitude        float64     `json:"longitude" firestore:"longitude"`
 	Latitude         float64     `json:"latitude" firestore:"latitude"`
 	CreatedAt        time.Time   `json:"created_at" firestore:"created_at"`
@@ -343,6 +346,7 @@ func (offer OfferStoreShake) GetAddOfferStoreShake() map[string]interface{} {
 		"action_uid":        offer.ActionUID,
 		"chain_id":          offer.ChainId,
 		"free_start":        offer.FreeStart,
+		"quote_id":          offer.QuoteId,
 		"provider":          offer.Provider,
 		"provider_data":     offer.ProviderData,
 		"latitude":          offer.Latitude,
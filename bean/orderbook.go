@@ -0,0 +1,64 @@
+package bean
+
+import "fmt"
+
+const ORDER_BOOK_SOURCE_OFFER = "offer"
+const ORDER_BOOK_SOURCE_OFFER_STORE = "offer_store"
+
+// Type is from the maker side, sell entries are what takers can buy
+type OrderBookEntry struct {
+	Id           string `json:"id" firestore:"id"`
+	Source       string `json:"source" firestore:"source"`
+	SourceId     string `json:"source_id" firestore:"source_id"`
+	Type         string `json:"type" firestore:"type"`
+	Currency     string `json:"currency" firestore:"currency"`
+	FiatCurrency string `json:"fiat_currency" firestore:"fiat_currency"`
+	Amount       string `json:"amount" firestore:"amount"`
+	Percentage   string `json:"percentage" firestore:"percentage"`
+}
+
+type OrderBookLevel struct {
+	Price      string `json:"price"`
+	Size       string `json:"size"`
+	StoreCount int    `json:"store_count"`
+}
+
+type OrderBook struct {
+	Currency     string           `json:"currency"`
+	FiatCurrency string           `json:"fiat_currency"`
+	Buys         []OrderBookLevel `json:"buys"`
+	Sells        []OrderBookLevel `json:"sells"`
+	UpdatedAt    int64            `json:"updated_at"`
+}
+
+func NewOrderBookEntryFromOffer(offer Offer) OrderBookEntry {
+	return OrderBookEntry{
+		Id:           fmt.Sprintf("%s.%s", ORDER_BOOK_SOURCE_OFFER, offer.Id),
+		Source:       ORDER_BOOK_SOURCE_OFFER,
+		SourceId:     offer.Id,
+		Type:         offer.Type,
+		Currency:     offer.Currency,
+		FiatCurrency: offer.FiatCurrency,
+		Amount:       offer.Amount,
+		Percentage:   offer.Percentage,
+	}
+}
+
+func NewOrderBookEntryFromOfferStore(offer OfferStore, item OfferStoreItem, offerType string) OrderBookEntry {
+	entry := OrderBookEntry{
+		Id:           fmt.Sprintf("%s.%s.%s", ORDER_BOOK_SOURCE_OFFER_STORE, offer.Id, offerType),
+		Source:       ORDER_BOOK_SOURCE_OFFER_STORE,
+		SourceId:     offer.Id,
+		Type:         offerType,
+		Currency:     item.Currency,
+		FiatCurrency: offer.FiatCurrency,
+		Amount:       item.SellBalance,
+		Percentage:   item.SellPercentage,
+	}
+	if offerType == OFFER_TYPE_BUY {
+		entry.Amount = item.BuyBalance
+		entry.Percentage = item.BuyPercentage
+	}
+
+	return entry
+}
@@ -0,0 +1,113 @@
+package bean
+
+import (
+	"cloud.google.com/go/firestore"
+	"fmt"
+	"time"
+)
+
+const OUTBOX_EVENT_STATUS_PENDING = "pending"
+const OUTBOX_EVENT_STATUS_FAILED = "failed"
+
+const CONFIG_OUTBOX_RELAY_DELAY = "OUTBOX_RELAY_DELAY"
+const OUTBOX_RELAY_DELAY_DEFAULT = 30
+const OUTBOX_RELAY_LIMIT = 100
+const OUTBOX_EVENT_MAX_ATTEMPTS = 5
+
+// Event types are the notification types, the relay hands events to the notification dispatcher
+type OutboxEvent struct {
+	Id              string           `json:"id" firestore:"id"`
+	Key             string           `json:"key" firestore:"key"`
+	Type            string           `json:"type" firestore:"type"`
+	RefId           string           `json:"ref_id" firestore:"ref_id"`
+	ParentId        string           `json:"parent_id" firestore:"parent_id"`
+	Status          string           `json:"status" firestore:"status"`
+	Attempts        int              `json:"attempts" firestore:"attempts"`
+	LastError       string           `json:"last_error" firestore:"last_error"`
+	Offer           *Offer           `json:"-" firestore:"offer,omitempty"`
+	InstantOffer    *InstantOffer    `json:"-" firestore:"instant_offer,omitempty"`
+	OfferStore      *OfferStore      `json:"-" firestore:"offer_store,omitempty"`
+	OfferStoreItem  *OfferStoreItem  `json:"-" firestore:"offer_store_item,omitempty"`
+	OfferStoreShake *OfferStoreShake `json:"-" firestore:"offer_store_shake,omitempty"`
+	CreatedAt       time.Time        `json:"created_at" firestore:"created_at"`
+}
+
+func (event OutboxEvent) GetAddOutboxEvent() map[string]interface{} {
+	obj := map[string]interface{}{
+		"id":         event.Id,
+		"key":        event.Key,
+		"type":       event.Type,
+		"ref_id":     event.RefId,
+		"parent_id":  event.ParentId,
+		"status":     OUTBOX_EVENT_STATUS_PENDING,
+		"attempts":   0,
+		"last_error": "",
+		"created_at": firestore.ServerTimestamp,
+	}
+	if event.Offer != nil {
+		obj["offer"] = event.Offer
+	}
+	if event.InstantOffer != nil {
+		obj["instant_offer"] = event.InstantOffer
+	}
+	if event.OfferStore != nil {
+		obj["offer_store"] = event.OfferStore
+	}
+	if event.OfferStoreItem != nil {
+		obj["offer_store_item"] = event.OfferStoreItem
+	}
+	if event.OfferStoreShake != nil {
+		obj["offer_store_shake"] = event.OfferStoreShake
+	}
+
+	return obj
+}
+
+func (event OutboxEvent) GetUpdateAttempt() map[string]interface{} {
+	return map[string]interface{}{
+		"status":     event.Status,
+		"attempts":   event.Attempts,
+		"last_error": event.LastError,
+	}
+}
+
+// Keys are derived from the object state, so the inline notification can acknowledge the event it matches.
+// Ids are given when the event is written, a change to the same state again is a new event
+func NewOutboxEventFromOffer(offer Offer) OutboxEvent {
+	return OutboxEvent{
+		Key:   fmt.Sprintf("%s_%s_%s", NOTIFICATION_TYPE_OFFER, offer.Id, offer.Status),
+		Type:  NOTIFICATION_TYPE_OFFER,
+		RefId: offer.Id,
+		Offer: &offer,
+	}
+}
+
+func NewOutboxEventFromInstantOffer(offer InstantOffer) OutboxEvent {
+	return OutboxEvent{
+		Key:          fmt.Sprintf("%s_%s_%s", NOTIFICATION_TYPE_INSTANT_OFFER, offer.Id, offer.Status),
+		Type:         NOTIFICATION_TYPE_INSTANT_OFFER,
+		RefId:        offer.Id,
+		ParentId:     offer.UID,
+		InstantOffer: &offer,
+	}
+}
+
+func NewOutboxEventFromOfferStore(offer OfferStore, item OfferStoreItem) OutboxEvent {
+	return OutboxEvent{
+		Key:            fmt.Sprintf("%s_%s_%s_%s", NOTIFICATION_TYPE_OFFER_STORE, offer.Id, item.Currency, item.Status),
+		Type:           NOTIFICATION_TYPE_OFFER_STORE,
+		RefId:          offer.Id,
+		OfferStore:     &offer,
+		OfferStoreItem: &item,
+	}
+}
+
+func NewOutboxEventFromOfferStoreShake(offerShake OfferStoreShake, offerStoreId string) OutboxEvent {
+	return OutboxEvent{
+		Key:             fmt.Sprintf("%s_%s_%s", NOTIFICATION_TYPE_OFFER_STORE_SHAKE, offerShake.Id, offerShake.Status),
+		Type:            NOTIFICATION_TYPE_OFFER_STORE_SHAKE,
+		RefId:           offerShake.Id,
+		ParentId:        offerStoreId,
+		OfferStoreShake: &offerShake,
+	}
+}
@@ -0,0 +1,26 @@
+package bean
+
+const CC_PROVIDER_FAKE = "fake"
+
+// JSON value, {"default": "checkout", "rules": [{"provider": "stripe", "countries": ["US"]}, {"provider": "stripe", "percentage": 20}]}
+const CONFIG_CC_GATEWAY_ROUTING = "CC_GATEWAY_ROUTING"
+
+// Rules are checked in order and the first match wins, a new card with nothing matching goes to Default
+type PaymentRouting struct {
+	Default string               `json:"default"`
+	Rules   []PaymentRoutingRule `json:"rules"`
+}
+
+// Every condition that is set has to hold, Percentage sends that share of users to Provider
+type PaymentRoutingRule struct {
+	Provider   string   `json:"provider"`
+	Countries  []string `json:"countries"`
+	MinAmount  string   `json:"min_amount"`
+	MaxAmount  string   `json:"max_amount"`
+	Percentage int64    `json:"percentage"`
+}
+
+// Provider the client tokenizes a new card with
+type CCGateway struct {
+	Provider string `json:"provider"`
+}
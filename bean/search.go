@@ -0,0 +1,527 @@
+package bean
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SearchLocation struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+}
+
+type SearchDocument struct {
+	Id           string          `json:"id"`
+	Type         int             `json:"type"`
+	State        int             `json:"state"`
+	Status       int             `json:"status"`
+	Hid          int64           `json:"hid"`
+	IsPrivate    int             `json:"is_private"`
+	InitUserId   int             `json:"init_user_id"`
+	ChainId      int64           `json:"chain_id"`
+	ShakeUserIds []int           `json:"shake_user_ids"`
+	TextSearch   []string        `json:"text_search"`
+	ExtraData    string          `json:"extra_data"`
+	FeedType     string          `json:"feed_type"`
+	OfferType    string          `json:"offer_type"`
+	FiatCurrency string          `json:"fiat_currency"`
+	Location     *SearchLocation `json:"location"`
+	Offline      int             `json:"offline"`
+	Review       float64         `json:"review"`
+	ReviewCount  int             `json:"review_count"`
+	SellETH      float64         `json:"sell_eth"`
+	BuyETH       float64         `json:"buy_eth"`
+	SellBTC      float64         `json:"sell_btc"`
+	BuyBTC       float64         `json:"buy_btc"`
+	InitAt       int64           `json:"init_at"`
+	LastUpdateAt int64           `json:"last_update_at"`
+}
+
+type SearchLog struct {
+	Id             string `json:"id"`
+	UID            string `json:"uid"`
+	RequestMethod  string `json:"request_method"`
+	RequestURL     string `json:"request_url"`
+	RequestData    string `json:"request_data"`
+	ResponseStatus int    `json:"response_status"`
+	ResponseData   string `json:"response_data"`
+	Date           string `json:"date"`
+	UpdateAt       int64  `json:"update_at"`
+}
+
+type SearchQuery struct {
+	Text         string
+	FeedType     string
+	FiatCurrency string
+	Location     *SearchLocation
+	Radius       float64
+	Limit        int
+}
+
+// Shared indices namespace documents by service
+func GetSearchObjectId(id string) string {
+	return fmt.Sprintf("exchange_%s", id)
+}
+
+type SearchOfferExtraData struct {
+	Id               string   `json:"id"`
+	FeedType         string   `json:"feed_type"`
+	Type             string   `json:"type"`
+	Amount           string   `json:"amount"`
+	Currency         string   `json:"currency"`
+	FiatCurrency     string   `json:"fiat_currency"`
+	FiatAmount       string   `json:"fiat_amount"`
+	TotalAmount      string   `json:"total_amount"`
+	PhysicalItem     string   `json:"physical_item"`
+	PhysicalQuantity int64    `json:"physical_quantity"`
+	PhysicalItemDocs []string `json:"physical_item_docs"`
+	Fee              string   `json:"fee"`
+	Reward           string   `json:"reward"`
+	Price            string   `json:"price"`
+	Percentage       string   `json:"percentage"`
+	FeePercentage    string   `json:"fee_percentage"`
+	RewardPercentage string   `json:"reward_percentage"`
+	ContactPhone     string   `json:"contact_phone"`
+	ContactInfo      string   `json:"contact_info"`
+	Email            string   `json:"email"`
+	Username         string   `json:"username"`
+	ChatUsername     string   `json:"chat_username"`
+	ToEmail          string   `json:"to_email"`
+	ToUsername       string   `json:"to_username"`
+	ToChatUsername   string   `json:"to_chat_username"`
+	SystemAddress    string   `json:"system_address"`
+	Status           string   `json:"status"`
+	Success          int64    `json:"success"`
+	Failed           int64    `json:"failed"`
+}
+
+var offerStatusMap = map[string]int{
+	OFFER_STATUS_CREATED:          0,
+	OFFER_STATUS_ACTIVE:           1,
+	OFFER_STATUS_CLOSING:          2,
+	OFFER_STATUS_CLOSED:           3,
+	OFFER_STATUS_SHAKING:          4,
+	OFFER_STATUS_SHAKE:            5,
+	OFFER_STATUS_COMPLETING:       6,
+	OFFER_STATUS_COMPLETED:        7,
+	OFFER_STATUS_PRE_SHAKING:      8,
+	OFFER_STATUS_PRE_SHAKE:        9,
+	OFFER_STATUS_REJECTING:        10,
+	OFFER_STATUS_REJECTED:         11,
+	OFFER_STATUS_CANCELLING:       12,
+	OFFER_STATUS_CANCELLED:        13,
+	OFFER_STATUS_CREATE_FAILED:    14,
+	OFFER_STATUS_PRE_SHAKE_FAILED: 15,
+}
+
+type SearchInstantOfferExtraData struct {
+	Id            string `json:"id"`
+	FeedType      string `json:"feed_type"`
+	Type          string `json:"type"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	FiatCurrency  string `json:"fiat_currency"`
+	FiatAmount    string `json:"fiat_amount"`
+	FeePercentage string `json:"fee_percentage"`
+	Status        string `json:"status"`
+	Email         string `json:"email"`
+}
+
+var instantOfferStatusMap = map[string]int{
+	INSTANT_OFFER_STATUS_PROCESSING: 0,
+	INSTANT_OFFER_STATUS_SUCCESS:    1,
+	INSTANT_OFFER_STATUS_CANCELLED:  2,
+}
+
+func NewSearchDocumentFromOffer(offer Offer) (doc SearchDocument) {
+	doc.Id = offer.Id
+	// Need to duplicate to another feed for tracking
+	if (offer.Status == OFFER_STATUS_CANCELLED || offer.Status == OFFER_STATUS_PRE_SHAKE_FAILED) && offer.ToUID != "" {
+		doc.Id = offer.Id + SEARCH_DOCUMENT_CANCELLED_SUFFIX
+	}
+	doc.Type = 6
+	if offer.Status == OFFER_STATUS_ACTIVE {
+		doc.State = 1
+		doc.IsPrivate = 0
+	} else {
+		doc.State = 0
+		doc.IsPrivate = 1
+	}
+	doc.Status = offerStatusMap[offer.Status]
+	doc.Hid = offer.Hid
+	doc.ChainId = offer.ChainId
+	userId, _ := strconv.Atoi(offer.UID)
+	doc.InitUserId = userId
+	if offer.ToUID != "" {
+		userId, _ := strconv.Atoi(offer.ToUID)
+		doc.ShakeUserIds = []int{userId}
+	} else {
+		doc.ShakeUserIds = make([]int, 0)
+	}
+	doc.TextSearch = make([]string, 0)
+	doc.Location = &SearchLocation{Latitude: offer.Latitude, Longitude: offer.Longitude}
+	doc.InitAt = offer.CreatedAt.Unix()
+	doc.LastUpdateAt = time.Now().UTC().Unix()
+
+	feedType := "exchange"
+	if len(offer.Tags) > 0 {
+		feedType = fmt.Sprintf("exchange_%s", offer.Tags[0])
+	}
+	doc.FeedType = feedType
+	if offer.PhysicalItem != "" {
+		doc.TextSearch = strings.Split(offer.PhysicalItem, " ")
+	}
+	doc.OfferType = offer.Type
+
+	percentage, _ := decimal.NewFromString(offer.Percentage)
+	feePercentage, _ := decimal.NewFromString(offer.FeePercentage)
+	rewardPercentage, _ := decimal.NewFromString(offer.RewardPercentage)
+	feePercentage = feePercentage.Add(rewardPercentage)
+	fee, _ := decimal.NewFromString(offer.Fee)
+	reward, _ := decimal.NewFromString(offer.Reward)
+	fee = fee.Add(reward)
+
+	extraData := SearchOfferExtraData{
+		Id:               offer.Id,
+		FeedType:         feedType,
+		Type:             offer.Type,
+		Amount:           offer.Amount,
+		TotalAmount:      offer.TotalAmount,
+		Currency:         offer.Currency,
+		FiatAmount:       offer.FiatAmount,
+		FiatCurrency:     offer.FiatCurrency,
+		Price:            offer.Price,
+		PhysicalItem:     offer.PhysicalItem,
+		PhysicalQuantity: offer.PhysicalQuantity,
+		PhysicalItemDocs: offer.PhysicalItemDocs,
+		Fee:              fee.String(),
+		Reward:           offer.Reward,
+		FeePercentage:    feePercentage.Mul(decimal.NewFromFloat(100)).String(),
+		RewardPercentage: rewardPercentage.Mul(decimal.NewFromFloat(100)).String(),
+		Percentage:       percentage.Mul(decimal.NewFromFloat(100)).String(),
+		ContactInfo:      offer.ContactInfo,
+		ContactPhone:     offer.ContactPhone,
+		Email:            offer.Email,
+		Username:         offer.Username,
+		ChatUsername:     offer.ChatUsername,
+		ToEmail:          offer.ToEmail,
+		ToUsername:       offer.ToUsername,
+		ToChatUsername:   offer.ToChatUsername,
+		SystemAddress:    offer.SystemAddress,
+		Status:           offer.Status,
+		Success:          offer.TransactionCount.Success,
+		Failed:           offer.TransactionCount.Failed,
+	}
+	b, _ := json.Marshal(&extraData)
+	doc.ExtraData = string(b)
+
+	return
+}
+
+func NewSearchDocumentFromInstantOffer(offer InstantOffer) (doc SearchDocument) {
+	doc.Id = offer.Id
+	doc.Type = 2
+	doc.State = 0
+	doc.IsPrivate = 1
+	doc.Status = instantOfferStatusMap[offer.Status]
+	doc.Hid = 0
+	doc.ChainId = offer.ChainId
+	userId, _ := strconv.Atoi(offer.UID)
+	doc.InitUserId = userId
+	doc.ShakeUserIds = make([]int, 0)
+	doc.TextSearch = make([]string, 0)
+	doc.InitAt = offer.CreatedAt.Unix()
+	doc.LastUpdateAt = time.Now().UTC().Unix()
+
+	doc.FeedType = "instant"
+	doc.OfferType = "buy"
+
+	feePercentage, _ := decimal.NewFromString(offer.FeePercentage)
+	extraData := SearchInstantOfferExtraData{
+		Id:            offer.Id,
+		FeedType:      "instant",
+		Type:          "buy",
+		Amount:        offer.Amount,
+		Currency:      offer.Currency,
+		FiatAmount:    offer.FiatAmount,
+		FiatCurrency:  offer.FiatCurrency,
+		FeePercentage: feePercentage.Mul(decimal.NewFromFloat(100)).String(),
+		Status:        offer.Status,
+		Email:         offer.Email,
+	}
+	b, _ := json.Marshal(&extraData)
+	doc.ExtraData = string(b)
+
+	return
+}
+
+var instantOfferStoreStatusMap = map[string]int{
+	OFFER_STORE_STATUS_CREATED: 0,
+	OFFER_STORE_STATUS_ACTIVE:  1,
+	OFFER_STORE_STATUS_CLOSING: 2,
+	OFFER_STORE_STATUS_CLOSED:  3,
+}
+
+type SearchOfferStoreExtraData struct {
+	Id            string                                  `json:"id"`
+	FeedType      string                                  `json:"feed_type"`
+	Type          string                                  `json:"type"`
+	ItemFlags     map[string]bool                         `json:"item_flags"`
+	Username      string                                  `json:"username"`
+	Email         string                                  `json:"email"`
+	ContactPhone  string                                  `json:"contact_phone"`
+	ContactInfo   string                                  `json:"contact_info"`
+	ChatUsername  string                                  `json:"chat_username"`
+	FiatCurrency  string                                  `json:"fiat_currency"`
+	Status        string                                  `json:"status"`
+	Success       int64                                   `json:"success"`
+	Failed        int64                                   `json:"failed"`
+	ItemSnapshots map[string]SearchOfferStoreItemSnapshot `json:"items"`
+}
+
+type SearchOfferStoreItemSnapshot struct {
+	Currency       string `json:"currency"`
+	SellAmountMin  string `json:"sell_amount_min"`
+	SellAmount     string `json:"sell_amount"`
+	SellBalance    string `json:"sell_balance"`
+	SellPercentage string `json:"sell_percentage"`
+	BuyAmountMin   string `json:"buy_amount_min"`
+	BuyAmount      string `json:"buy_amount"`
+	BuyBalance     string `json:"buy_balance"`
+	BuyPercentage  string `json:"buy_percentage"`
+	SystemAddress  string `json:"system_address"`
+	UserAddress    string `json:"user_address"`
+	ChatUsername   string `json:"chat_username"`
+	Status         string `json:"status"`
+	SubStatus      string `json:"sub_status"`
+	FreeStart      string `json:"free_start"`
+	CreatedAt      int64  `json:"created_at"`
+	UpdatedAt      int64  `json:"updated_at"`
+}
+
+func NewSearchDocumentFromOfferStore(offer OfferStore, item OfferStoreItem) (doc SearchDocument) {
+	doc.Id = offer.Id
+	doc.Type = 2
+	if offer.Status == OFFER_STATUS_ACTIVE {
+		doc.State = 1
+		doc.IsPrivate = 0
+	} else {
+		doc.State = 0
+		doc.IsPrivate = 1
+	}
+	doc.Status = instantOfferStoreStatusMap[offer.Status]
+	doc.Hid = offer.Hid
+	doc.ChainId = offer.ChainId
+	userId, _ := strconv.Atoi(offer.UID)
+	doc.InitUserId = userId
+	doc.ShakeUserIds = make([]int, 0)
+	doc.TextSearch = make([]string, 0)
+
+	doc.Location = &SearchLocation{Latitude: offer.Latitude, Longitude: offer.Longitude}
+	doc.InitAt = offer.CreatedAt.Unix()
+	doc.LastUpdateAt = time.Now().UTC().Unix()
+
+	doc.FeedType = "offer_store"
+	// Nothing now
+	doc.OfferType = ""
+	doc.FiatCurrency = offer.FiatCurrency
+	doc.Offline = 0
+	if offer.Offline == "1" {
+		doc.Offline = 1
+	}
+	doc.Review = 0
+	doc.ReviewCount = int(offer.ReviewCount)
+	if offer.ReviewCount > 0 {
+		doc.Review = float64(offer.Review) / float64(offer.ReviewCount)
+	}
+
+	var items = map[string]SearchOfferStoreItemSnapshot{}
+	for key, value := range offer.ItemSnapshots {
+		sellPercentage, _ := decimal.NewFromString(value.SellPercentage)
+		buyPercentage, _ := decimal.NewFromString(value.BuyPercentage)
+
+		sellBalance := value.SellBalance
+		if key == item.Currency {
+			sellBalance = item.SellBalance
+		}
+		status := value.Status
+		if key == item.Currency {
+			status = item.Status
+		}
+
+		if key == BTC.Code {
+			doc.BuyBTC, _ = buyPercentage.Float64()
+			doc.SellBTC, _ = sellPercentage.Float64()
+		} else if key == ETH.Code {
+			doc.BuyETH, _ = buyPercentage.Float64()
+			doc.SellETH, _ = sellPercentage.Float64()
+		}
+
+		items[key] = SearchOfferStoreItemSnapshot{
+			Currency:       value.Currency,
+			SellAmountMin:  value.SellAmountMin,
+			SellAmount:     value.SellAmount,
+			SellBalance:    sellBalance,
+			SellPercentage: sellPercentage.Mul(decimal.NewFromFloat(100)).String(),
+			BuyAmountMin:   value.BuyAmountMin,
+			BuyAmount:      value.BuyAmount,
+			BuyBalance:     value.BuyBalance,
+			BuyPercentage:  buyPercentage.Mul(decimal.NewFromFloat(100)).String(),
+			SystemAddress:  value.SystemAddress,
+			UserAddress:    value.UserAddress,
+			Status:         status,
+			SubStatus:      value.SubStatus,
+			FreeStart:      value.FreeStart,
+			CreatedAt:      value.CreatedAt.Unix(),
+			UpdatedAt:      time.Now().UTC().Unix(),
+		}
+	}
+
+	extraData := SearchOfferStoreExtraData{
+		Id:           offer.Id,
+		FeedType:     "offer_store",
+		Type:         "",
+		ItemFlags:    offer.ItemFlags,
+		ContactInfo:  offer.ContactInfo,
+		ContactPhone: offer.ContactPhone,
+		Email:        offer.Email,
+		Username:     offer.Username,
+		ChatUsername: offer.ChatUsername,
+		Status:       offer.Status,
+		FiatCurrency: offer.FiatCurrency,
+		Success:      offer.TransactionCount.Success,
+		Failed:       offer.TransactionCount.Failed,
+
+		ItemSnapshots: items,
+	}
+	b, _ := json.Marshal(&extraData)
+	doc.ExtraData = string(b)
+
+	return
+}
+
+type SearchOfferStoreShakeExtraData struct {
+	Id               string `json:"id"`
+	OffChainId       string `json:"off_chain_id"`
+	FeedType         string `json:"feed_type"`
+	Type             string `json:"type"`
+	Amount           string `json:"amount"`
+	Currency         string `json:"currency"`
+	FiatCurrency     string `json:"fiat_currency"`
+	FiatAmount       string `json:"fiat_amount"`
+	TotalAmount      string `json:"total_amount"`
+	Fee              string `json:"fee"`
+	Reward           string `json:"reward"`
+	Price            string `json:"price"`
+	Percentage       string `json:"percentage"`
+	FeePercentage    string `json:"fee_percentage"`
+	RewardPercentage string `json:"reward_percentage"`
+	ContactPhone     string `json:"contact_phone"`
+	ContactInfo      string `json:"contact_info"`
+	Email            string `json:"email"`
+	Username         string `json:"username"`
+	ChatUsername     string `json:"chat_username"`
+	ToEmail          string `json:"to_email"`
+	ToUsername       string `json:"to_username"`
+	ToChatUsername   string `json:"to_chat_username"`
+	ToContactPhone   string `json:"to_contact_phone"`
+	SystemAddress    string `json:"system_address"`
+	UserAddress      string `json:"user_address"`
+	Status           string `json:"status"`
+	Success          int64  `json:"success"`
+	Failed           int64  `json:"failed"`
+	FreeStart        string `json:"free_start"`
+}
+
+var offerStoreSHakeStatusMap = map[string]int{
+	OFFER_STORE_SHAKE_STATUS_PRE_SHAKING: 0,
+	OFFER_STORE_SHAKE_STATUS_PRE_SHAKE:   1,
+	OFFER_STORE_SHAKE_STATUS_SHAKING:     2,
+	OFFER_STORE_SHAKE_STATUS_SHAKE:       3,
+	OFFER_STORE_SHAKE_STATUS_REJECTING:   4,
+	OFFER_STORE_SHAKE_STATUS_REJECTED:    5,
+	OFFER_STORE_SHAKE_STATUS_COMPLETING:  6,
+	OFFER_STORE_SHAKE_STATUS_COMPLETED:   7,
+	OFFER_STORE_SHAKE_STATUS_CANCELLING:  8,
+	OFFER_STORE_SHAKE_STATUS_CANCELLED:   9,
+}
+
+func NewSearchDocumentFromOfferStoreShake(offer OfferStoreShake, offerStore OfferStore) (doc SearchDocument) {
+	doc.Id = offer.Id
+	doc.Type = 2
+	doc.State = 0
+	doc.IsPrivate = 1
+	doc.Status = offerStoreSHakeStatusMap[offer.Status]
+	doc.Hid = offerStore.Hid
+	doc.ChainId = offer.ChainId
+	storeUID, _ := strconv.Atoi(offerStore.UID)
+	doc.InitUserId = storeUID
+	userId, _ := strconv.Atoi(offer.UID)
+	doc.ShakeUserIds = []int{userId}
+	doc.TextSearch = make([]string, 0)
+	doc.Location = &SearchLocation{Latitude: offer.Latitude, Longitude: offer.Longitude}
+	doc.InitAt = offer.CreatedAt.Unix()
+	doc.LastUpdateAt = time.Now().UTC().Unix()
+
+	doc.FeedType = "offer_store_shake"
+	// Nothing now
+	doc.OfferType = ""
+	doc.FiatCurrency = offer.FiatCurrency
+
+	percentage, _ := decimal.NewFromString(offerStore.ItemSnapshots[offer.Currency].SellPercentage)
+	if offer.Type == OFFER_TYPE_BUY {
+		percentage, _ = decimal.NewFromString(offerStore.ItemSnapshots[offer.Currency].BuyPercentage)
+	}
+
+	feePercentage, _ := decimal.NewFromString(offer.FeePercentage)
+	rewardPercentage, _ := decimal.NewFromString(offer.RewardPercentage)
+	feePercentage = feePercentage.Add(rewardPercentage)
+	fee, _ := decimal.NewFromString(offer.Fee)
+	reward, _ := decimal.NewFromString(offer.Reward)
+	fee = fee.Add(reward)
+
+	userAddress := offer.UserAddress
+	if userAddress == "" {
+		userAddress = offerStore.ItemSnapshots[offer.Currency].UserAddress
+	}
+	extraData := SearchOfferStoreShakeExtraData{
+		Id:               offer.Id,
+		OffChainId:       offer.OffChainId,
+		FeedType:         doc.FeedType,
+		Type:             offer.Type,
+		Amount:           offer.Amount,
+		TotalAmount:      offer.TotalAmount,
+		Currency:         offer.Currency,
+		FiatAmount:       offer.FiatAmount,
+		FiatCurrency:     offer.FiatCurrency,
+		Price:            offer.Price,
+		Fee:              fee.String(),
+		Reward:           offer.Reward,
+		FeePercentage:    feePercentage.Mul(decimal.NewFromFloat(100)).String(),
+		RewardPercentage: rewardPercentage.Mul(decimal.NewFromFloat(100)).String(),
+		Percentage:       percentage.Mul(decimal.NewFromFloat(100)).String(),
+		ContactInfo:      offerStore.ContactInfo,
+		ContactPhone:     offerStore.ContactPhone,
+		Email:            offerStore.Email,
+		Username:         offerStore.Username,
+		ChatUsername:     offerStore.ChatUsername,
+		ToEmail:          offer.Email,
+		ToUsername:       offer.Username,
+		ToChatUsername:   offer.ChatUsername,
+		ToContactPhone:   offer.ContactPhone,
+		SystemAddress:    offer.SystemAddress,
+		UserAddress:      userAddress,
+		Status:           offer.Status,
+		FreeStart:        offer.FreeStart,
+		Success:          offerStore.TransactionCount.Success,
+		Failed:           offerStore.TransactionCount.Failed,
+	}
+	b, _ := json.Marshal(&extraData)
+	doc.ExtraData = string(b)
+
+	return
+}
@@ -0,0 +1,90 @@
+package bean
+
+import (
+	"cloud.google.com/go/firestore"
+	"strings"
+	"time"
+)
+
+const SEARCH_REINDEX_STATUS_RUNNING = "running"
+const SEARCH_REINDEX_STATUS_COMPLETED = "completed"
+
+const SEARCH_REINDEX_PHASE_OFFERS = "offers"
+const SEARCH_REINDEX_PHASE_OFFER_STORES = "offer_stores"
+const SEARCH_REINDEX_PHASE_ORPHANS = "orphans"
+
+const CONFIG_SEARCH_REINDEX_BATCH_SIZE = "SEARCH_REINDEX_BATCH_SIZE"
+const CONFIG_SEARCH_REINDEX_BATCHES_PER_RUN = "SEARCH_REINDEX_BATCHES_PER_RUN"
+const SEARCH_REINDEX_BATCH_SIZE_DEFAULT = 100
+const SEARCH_REINDEX_BATCHES_PER_RUN_DEFAULT = 20
+const SEARCH_REINDEX_ERROR_LIMIT = 50
+
+const SEARCH_DOCUMENT_CANCELLED_SUFFIX = "_cancelled"
+
+// Feed types a reindex rebuilds, anything else in the index is left alone
+var SearchReindexFeedTypePrefixes = []string{"exchange", "offer_store"}
+
+type SearchReindexJob struct {
+	Id                   string    `json:"id" firestore:"id"`
+	Status               string    `json:"status" firestore:"status"`
+	Phase                string    `json:"phase" firestore:"phase"`
+	Cursor               string    `json:"cursor" firestore:"cursor"`
+	DeleteOrphans        bool      `json:"delete_orphans" firestore:"delete_orphans"`
+	OfferCount           int64     `json:"offer_count" firestore:"offer_count"`
+	OfferStoreCount      int64     `json:"offer_store_count" firestore:"offer_store_count"`
+	OfferStoreShakeCount int64     `json:"offer_store_shake_count" firestore:"offer_store_shake_count"`
+	OrphanCount          int64     `json:"orphan_count" firestore:"orphan_count"`
+	ErrorCount           int64     `json:"error_count" firestore:"error_count"`
+	Errors               []string  `json:"errors" firestore:"errors"`
+	CreatedAt            time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+func (job SearchReindexJob) GetAddSearchReindexJob() map[string]interface{} {
+	return map[string]interface{}{
+		"id":             job.Id,
+		"status":         job.Status,
+		"phase":          job.Phase,
+		"cursor":         job.Cursor,
+		"delete_orphans": job.DeleteOrphans,
+		"errors":         make([]string, 0),
+		"created_at":     firestore.ServerTimestamp,
+		"updated_at":     firestore.ServerTimestamp,
+	}
+}
+
+func (job SearchReindexJob) GetUpdateSearchReindexJob() map[string]interface{} {
+	return map[string]interface{}{
+		"status":                  job.Status,
+		"phase":                   job.Phase,
+		"cursor":                  job.Cursor,
+		"offer_count":             job.OfferCount,
+		"offer_store_count":       job.OfferStoreCount,
+		"offer_store_shake_count": job.OfferStoreShakeCount,
+		"orphan_count":            job.OrphanCount,
+		"error_count":             job.ErrorCount,
+		"errors":                  job.Errors,
+		"updated_at":              firestore.ServerTimestamp,
+	}
+}
+
+func (job *SearchReindexJob) AddError(err error) {
+	job.ErrorCount += 1
+	if len(job.Errors) < SEARCH_REINDEX_ERROR_LIMIT {
+		job.Errors = append(job.Errors, err.Error())
+	}
+}
+
+// Cancelled duplicates belong to the offer they were copied from
+func GetSearchDocumentSourceId(id string) string {
+	return strings.TrimSuffix(id, SEARCH_DOCUMENT_CANCELLED_SUFFIX)
+}
+
+func IsSearchReindexFeedType(feedType string) bool {
+	for _, prefix := range SearchReindexFeedTypePrefixes {
+		if strings.HasPrefix(feedType, prefix) {
+			return true
+		}
+	}
+	return false
+}
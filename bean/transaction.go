@@ -2,12 +2,14 @@ package bean
 
 import (
 	"cloud.google.com/go/firestore"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
 const TRANSACTION_TYPE_BUY = "buy"
 const TRANSACTION_TYPE_SELL = "sell"
 const TRANSACTION_TYPE_INSTANT_BUY = "instant_buy"
+const TRANSACTION_TYPE_INSTANT_BUY_REFUND = "instant_buy_refund"
 
 const TRANSACTION_STATUS_SUCCESS = "success"
 const TRANSACTION_STATUS_PENDING = "pending"
@@ -109,6 +111,36 @@ func NewTransactionFromInstantOffer(offer InstantOffer) Transaction {
 	return fromTransaction
 }
 
+// The crypto amount is the share of the offer the refunded fiat stands for
+func NewTransactionFromInstantOfferRefund(offer InstantOffer, fiatAmount string) Transaction {
+	amount := decimal.Zero
+	offerAmount, amountErr := decimal.NewFromString(offer.Amount)
+	offerFiatAmount, offerFiatErr := decimal.NewFromString(offer.FiatAmount)
+	refundFiatAmount, refundFiatErr := decimal.NewFromString(fiatAmount)
+	if amountErr == nil && offerFiatErr == nil && refundFiatErr == nil && offerFiatAmount.GreaterThan(decimal.Zero) {
+		currency, ok := CurrencyMapping[offer.Currency]
+		if !ok {
+			currency = BTC
+		}
+		amount = offerAmount.Mul(refundFiatAmount).Div(offerFiatAmount).Round(currency.Decimal)
+	}
+
+	return Transaction{
+		Amount:          amount.String(),
+		TotalAmount:     amount.String(),
+		Currency:        offer.Currency,
+		FiatAmount:      fiatAmount,
+		TotalFiatAmount: fiatAmount,
+		FiatCurrency:    offer.FiatCurrency,
+		Price:           offer.Price,
+		Type:            TRANSACTION_TYPE_INSTANT_BUY_REFUND,
+		Status:          TRANSACTION_STATUS_SUCCESS,
+		From:            offer.UID,
+		Offer:           offer.Id,
+		IsOriginal:      true,
+	}
+}
+
 func (transaction Transaction) GetAddTransaction() map[string]interface{} {
 	return map[string]interface{}{
 		"amount":            transaction.Amount,
@@ -19,6 +19,11 @@ type Profile struct {
 	ActiveOffers      map[string]bool `json:"-" firestore:"active_offers"`
 	ActiveOfferStores map[string]bool `json:"-" firestore:"active_offer_stores"`
 	OfferRejectLock   OfferRejectLock `json:"offer_reject_lock" firestore:"offer_reject_lock"`
+	Language          string          `json:"language" firestore:"language"`
+	KYCTier           int64           `json:"kyc_tier" firestore:"kyc_tier"`
+	KYCStatus         string          `json:"kyc_status" firestore:"kyc_status"`
+
+	NotificationPreferences NotificationPreferences `json:"notification_preferences" firestore:"notification_preferences"`
 }
 
 type OfferRejectLock struct {
@@ -63,6 +68,21 @@ func (profile Profile) GetUpdateOfferStoreProfile() map[string]interface{} {
 	}
 }
 
+func (profile Profile) GetUpdateLanguage() map[string]interface{} {
+	return map[string]interface{}{
+		"language":   profile.Language,
+		"updated_at": firestore.ServerTimestamp,
+	}
+}
+
+func (profile Profile) GetUpdateKYC() map[string]interface{} {
+	return map[string]interface{}{
+		"kyc_tier":   profile.KYCTier,
+		"kyc_status": profile.KYCStatus,
+		"updated_at": firestore.ServerTimestamp,
+	}
+}
+
 func (profile Profile) GetUpdateOfferRejectLock() map[string]interface{} {
 	return map[string]interface{}{
 		"offer_reject_lock": profile.OfferRejectLock.GetAddOfferRejectLock(),
@@ -70,23 +90,57 @@ func (profile Profile) GetUpdateOfferRejectLock() map[string]interface{} {
 	}
 }
 
+// Profile.CreditCard is a copy of the default card, the wallet is users/{uid}/credit_cards
 type UserCreditCard struct {
-	CCNumber       string `json:"cc_number" firestore:"cc_number"`
-	ExpirationDate string `json:"expiration_date" firestore:"expiration_date"`
-	Token          string `json:"token" firestore:"token"`
+	Id             string    `json:"id" firestore:"id"`
+	CCNumber       string    `json:"cc_number" firestore:"cc_number"`
+	ExpirationDate string    `json:"expiration_date" firestore:"expiration_date"`
+	Token          string    `json:"token,omitempty" firestore:"token"`
+	Provider       string    `json:"-" firestore:"provider"`
+	Default        bool      `json:"default" firestore:"default"`
+	CreatedAt      time.Time `json:"created_at" firestore:"created_at"`
+}
+
+func (user UserCreditCard) GetAddUserCreditCard() map[string]interface{} {
+	return map[string]interface{}{
+		"id":              user.Id,
+		"cc_number":       user.CCNumber,
+		"expiration_date": user.ExpirationDate,
+		"token":           user.Token,
+		"provider":        user.Provider,
+		"default":         user.Default,
+		"created_at":      firestore.ServerTimestamp,
+	}
+}
+
+func (user UserCreditCard) GetUpdateDefault() map[string]interface{} {
+	return map[string]interface{}{
+		"default":    user.Default,
+		"updated_at": firestore.ServerTimestamp,
+	}
 }
 
 func (user UserCreditCard) GetUpdateProfileCreditCard() map[string]interface{} {
 	return map[string]interface{}{
 		"credit_card": map[string]interface{}{
+			"id":              user.Id,
 			"cc_number":       user.CCNumber,
 			"expiration_date": user.ExpirationDate,
 			"token":           user.Token,
+			"provider":        user.Provider,
 		},
 	}
 }
 
+type UserCreditCardRequest struct {
+	CCNum          string `json:"cc_num" validate:"required"`
+	ExpirationDate string `json:"expiration_date" validate:"required"`
+	CVV            string `json:"cvv" validate:"required"`
+}
+
+// Each card keeps its own usage, the user is held to the sum of all of them
 type UserCreditCardLimit struct {
+	Id       string    `json:"-" firestore:"-"`
 	Level    int64     `json:"level" firestore:"level"`
 	Amount   string    `json:"amount" firestore:"amount"`
 	Limit    int64     `json:"limit" firestore:"limit"`
@@ -0,0 +1,286 @@
+package bean
+
+import (
+	"cloud.google.com/go/firestore"
+	"time"
+)
+
+const WEBHOOK_EVENT_OFFER_ACTIVE = "offer.active"
+const WEBHOOK_EVENT_OFFER_SHAKE = "offer.shake"
+const WEBHOOK_EVENT_OFFER_COMPLETED = "offer.completed"
+const WEBHOOK_EVENT_OFFER_REJECTED = "offer.rejected"
+const WEBHOOK_EVENT_OFFER_CLOSED = "offer.closed"
+const WEBHOOK_EVENT_OFFER_DISPUTED = "offer.disputed"
+const WEBHOOK_EVENT_OFFER_STORE_ITEM_ACTIVE = "offer_store.item_active"
+const WEBHOOK_EVENT_OFFER_STORE_ITEM_CLOSED = "offer_store.item_closed"
+const WEBHOOK_EVENT_SHAKE_CREATED = "shake.created"
+const WEBHOOK_EVENT_SHAKE_COMPLETED = "shake.completed"
+const WEBHOOK_EVENT_SHAKE_REJECTED = "shake.rejected"
+const WEBHOOK_EVENT_SHAKE_CANCELLED = "shake.cancelled"
+const WEBHOOK_EVENT_SHAKE_DISPUTED = "shake.disputed"
+const WEBHOOK_EVENT_INSTANT_OFFER_SUCCESS = "instant_offer.success"
+const WEBHOOK_EVENT_INSTANT_OFFER_CANCELLED = "instant_offer.cancelled"
+const WEBHOOK_EVENT_INSTANT_OFFER_REFUNDED = "instant_offer.refunded"
+
+var WebhookEvents = []string{
+	WEBHOOK_EVENT_OFFER_ACTIVE,
+	WEBHOOK_EVENT_OFFER_SHAKE,
+	WEBHOOK_EVENT_OFFER_COMPLETED,
+	WEBHOOK_EVENT_OFFER_REJECTED,
+	WEBHOOK_EVENT_OFFER_CLOSED,
+	WEBHOOK_EVENT_OFFER_DISPUTED,
+	WEBHOOK_EVENT_OFFER_STORE_ITEM_ACTIVE,
+	WEBHOOK_EVENT_OFFER_STORE_ITEM_CLOSED,
+	WEBHOOK_EVENT_SHAKE_CREATED,
+	WEBHOOK_EVENT_SHAKE_COMPLETED,
+	WEBHOOK_EVENT_SHAKE_REJECTED,
+	WEBHOOK_EVENT_SHAKE_CANCELLED,
+	WEBHOOK_EVENT_SHAKE_DISPUTED,
+	WEBHOOK_EVENT_INSTANT_OFFER_SUCCESS,
+	WEBHOOK_EVENT_INSTANT_OFFER_CANCELLED,
+	WEBHOOK_EVENT_INSTANT_OFFER_REFUNDED,
+}
+
+const WEBHOOK_DELIVERY_STATUS_DELIVERED = "delivered"
+const WEBHOOK_DELIVERY_STATUS_RETRYING = "retrying"
+const WEBHOOK_DELIVERY_STATUS_FAILED = "failed"
+
+const CONFIG_WEBHOOK_RETRY_MAX_ATTEMPTS = "WEBHOOK_RETRY_MAX_ATTEMPTS"
+const CONFIG_WEBHOOK_RETRY_BACKOFF = "WEBHOOK_RETRY_BACKOFF"
+const WEBHOOK_RETRY_MAX_ATTEMPTS_DEFAULT = 5
+const WEBHOOK_RETRY_BACKOFF_DEFAULT = 5
+const WEBHOOK_RETRY_LIMIT = 100
+const WEBHOOK_RETRY_LEASE = 60
+
+const WEBHOOK_SIGNATURE_HEADER = "X-Handshake-Signature"
+const WEBHOOK_TIMESTAMP_HEADER = "X-Handshake-Timestamp"
+const WEBHOOK_EVENT_HEADER = "X-Handshake-Event"
+const WEBHOOK_DELIVERY_HEADER = "X-Handshake-Delivery"
+
+type WebhookSubscription struct {
+	Id        string    `json:"id" firestore:"id"`
+	Name      string    `json:"name" firestore:"name" validate:"required"`
+	Url       string    `json:"url" firestore:"url" validate:"required,url"`
+	Events    []string  `json:"events" firestore:"events" validate:"required,min=1"`
+	Secret    string    `json:"secret,omitempty" firestore:"secret"`
+	Active    bool      `json:"active" firestore:"active"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+}
+
+func (subscription WebhookSubscription) GetAddWebhookSubscription() map[string]interface{} {
+	return map[string]interface{}{
+		"id":         subscription.Id,
+		"name":       subscription.Name,
+		"url":        subscription.Url,
+		"events":     subscription.Events,
+		"secret":     subscription.Secret,
+		"active":     true,
+		"created_at": firestore.ServerTimestamp,
+	}
+}
+
+func (subscription WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range subscription.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event ids are stable per object state, so a partner can drop the duplicates at least once delivery produces
+type WebhookEvent struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookDelivery struct {
+	Id             string    `json:"id" firestore:"id"`
+	SubscriptionId string    `json:"subscription_id" firestore:"subscription_id"`
+	EventId        string    `json:"event_id" firestore:"event_id"`
+	EventType      string    `json:"event_type" firestore:"event_type"`
+	Url            string    `json:"url" firestore:"url"`
+	Payload        string    `json:"payload" firestore:"payload"`
+	Status         string    `json:"status" firestore:"status"`
+	Attempts       int       `json:"attempts" firestore:"attempts"`
+	ResponseCode   int       `json:"response_code" firestore:"response_code"`
+	LastError      string    `json:"last_error" firestore:"last_error"`
+	NextAttemptAt  time.Time `json:"next_attempt_at" firestore:"next_attempt_at"`
+	CreatedAt      time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+func (delivery WebhookDelivery) GetAddWebhookDelivery() map[string]interface{} {
+	return map[string]interface{}{
+		"id":              delivery.Id,
+		"subscription_id": delivery.SubscriptionId,
+		"event_id":        delivery.EventId,
+		"event_type":      delivery.EventType,
+		"url":             delivery.Url,
+		"payload":         delivery.Payload,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_code":   delivery.ResponseCode,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"created_at":      firestore.ServerTimestamp,
+		"updated_at":      firestore.ServerTimestamp,
+	}
+}
+
+func (delivery WebhookDelivery) GetUpdateWebhookDelivery() map[string]interface{} {
+	return map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_code":   delivery.ResponseCode,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"updated_at":      firestore.ServerTimestamp,
+	}
+}
+
+func (delivery WebhookDelivery) GetPageValue() interface{} {
+	return delivery.CreatedAt
+}
+
+func GetOfferWebhookEvent(status string) string {
+	switch status {
+	case OFFER_STATUS_ACTIVE:
+		return WEBHOOK_EVENT_OFFER_ACTIVE
+	case OFFER_STATUS_SHAKE:
+		return WEBHOOK_EVENT_OFFER_SHAKE
+	case OFFER_STATUS_COMPLETED:
+		return WEBHOOK_EVENT_OFFER_COMPLETED
+	case OFFER_STATUS_REJECTED:
+		return WEBHOOK_EVENT_OFFER_REJECTED
+	case OFFER_STATUS_CLOSED:
+		return WEBHOOK_EVENT_OFFER_CLOSED
+	case OFFER_STATUS_DISPUTED:
+		return WEBHOOK_EVENT_OFFER_DISPUTED
+	}
+	return ""
+}
+
+func GetOfferStoreItemWebhookEvent(status string) string {
+	switch status {
+	case OFFER_STORE_ITEM_STATUS_ACTIVE:
+		return WEBHOOK_EVENT_OFFER_STORE_ITEM_ACTIVE
+	case OFFER_STORE_ITEM_STATUS_CLOSED:
+		return WEBHOOK_EVENT_OFFER_STORE_ITEM_CLOSED
+	}
+	return ""
+}
+
+func GetOfferStoreShakeWebhookEvent(status string) string {
+	switch status {
+	case OFFER_STORE_SHAKE_STATUS_SHAKE:
+		return WEBHOOK_EVENT_SHAKE_CREATED
+	case OFFER_STORE_SHAKE_STATUS_COMPLETED:
+		return WEBHOOK_EVENT_SHAKE_COMPLETED
+	case OFFER_STORE_SHAKE_STATUS_REJECTED:
+		return WEBHOOK_EVENT_SHAKE_REJECTED
+	case OFFER_STORE_SHAKE_STATUS_CANCELLED:
+		return WEBHOOK_EVENT_SHAKE_CANCELLED
+	case OFFER_STORE_SHAKE_STATUS_DISPUTED:
+		return WEBHOOK_EVENT_SHAKE_DISPUTED
+	}
+	return ""
+}
+
+func GetInstantOfferWebhookEvent(status string) string {
+	switch status {
+	case INSTANT_OFFER_STATUS_SUCCESS:
+		return WEBHOOK_EVENT_INSTANT_OFFER_SUCCESS
+	case INSTANT_OFFER_STATUS_CANCELLED:
+		return WEBHOOK_EVENT_INSTANT_OFFER_CANCELLED
+	case INSTANT_OFFER_STATUS_REFUNDED, INSTANT_OFFER_STATUS_PARTIALLY_REFUNDED:
+		return WEBHOOK_EVENT_INSTANT_OFFER_REFUNDED
+	}
+	return ""
+}
+
+// Payloads go to third parties, so they carry the trade state but no contact details or push tokens
+func NewWebhookEventFromOffer(offer Offer) WebhookEvent {
+	return WebhookEvent{
+		Id:        NewOutboxEventFromOffer(offer).Key,
+		Type:      GetOfferWebhookEvent(offer.Status),
+		CreatedAt: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"id":             offer.Id,
+			"hid":            offer.Hid,
+			"type":           offer.Type,
+			"status":         offer.Status,
+			"currency":       offer.Currency,
+			"amount":         offer.Amount,
+			"total_amount":   offer.TotalAmount,
+			"price":          offer.Price,
+			"fiat_currency":  offer.FiatCurrency,
+			"fiat_amount":    offer.FiatAmount,
+			"uid":            offer.UID,
+			"to_uid":         offer.ToUID,
+			"payout_tx_hash": offer.PayoutTxHash,
+		},
+	}
+}
+
+func NewWebhookEventFromInstantOffer(offer InstantOffer) WebhookEvent {
+	return WebhookEvent{
+		Id:        NewOutboxEventFromInstantOffer(offer).Key,
+		Type:      GetInstantOfferWebhookEvent(offer.Status),
+		CreatedAt: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"id":                   offer.Id,
+			"type":                 offer.Type,
+			"status":               offer.Status,
+			"currency":             offer.Currency,
+			"amount":               offer.Amount,
+			"price":                offer.Price,
+			"fiat_currency":        offer.FiatCurrency,
+			"fiat_amount":          offer.FiatAmount,
+			"refunded_fiat_amount": offer.RefundedFiatAmount,
+			"uid":                  offer.UID,
+			"address":              offer.Address,
+		},
+	}
+}
+
+func NewWebhookEventFromOfferStore(offer OfferStore, item OfferStoreItem) WebhookEvent {
+	return WebhookEvent{
+		Id:        NewOutboxEventFromOfferStore(offer, item).Key,
+		Type:      GetOfferStoreItemWebhookEvent(item.Status),
+		CreatedAt: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"offer_store_id": offer.Id,
+			"currency":       item.Currency,
+			"status":         item.Status,
+			"sell_amount":    item.SellAmount,
+			"sell_balance":   item.SellBalance,
+			"buy_amount":     item.BuyAmount,
+			"buy_balance":    item.BuyBalance,
+		},
+	}
+}
+
+func NewWebhookEventFromOfferStoreShake(offerShake OfferStoreShake, offerStoreId string) WebhookEvent {
+	return WebhookEvent{
+		Id:        NewOutboxEventFromOfferStoreShake(offerShake, offerStoreId).Key,
+		Type:      GetOfferStoreShakeWebhookEvent(offerShake.Status),
+		CreatedAt: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"id":             offerShake.Id,
+			"hid":            offerShake.Hid,
+			"offer_store_id": offerStoreId,
+			"type":           offerShake.Type,
+			"status":         offerShake.Status,
+			"currency":       offerShake.Currency,
+			"amount":         offerShake.Amount,
+			"total_amount":   offerShake.TotalAmount,
+			"price":          offerShake.Price,
+			"fiat_currency":  offerShake.FiatCurrency,
+			"fiat_amount":    offerShake.FiatAmount,
+			"payout_tx_hash": offerShake.PayoutTxHash,
+		},
+	}
+}
@@ -0,0 +1,23 @@
+package bean
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWebhookEventIdIsStablePerState(t *testing.T) {
+	offer := Offer{Id: "offer1", Status: OFFER_STATUS_SHAKE}
+	event := NewWebhookEventFromOffer(offer)
+
+	assert.NotEmpty(t, event.Id)
+	assert.Equal(t, event.Id, NewWebhookEventFromOffer(offer).Id)
+
+	offer.Status = OFFER_STATUS_COMPLETED
+	assert.NotEqual(t, event.Id, NewWebhookEventFromOffer(offer).Id)
+}
+
+func TestWebhookEventIdsAreSet(t *testing.T) {
+	assert.NotEmpty(t, NewWebhookEventFromInstantOffer(InstantOffer{Id: "instant1", Status: INSTANT_OFFER_STATUS_SUCCESS}).Id)
+	assert.NotEmpty(t, NewWebhookEventFromOfferStore(OfferStore{Id: "store1"}, OfferStoreItem{Currency: "ETH", Status: OFFER_STORE_ITEM_STATUS_ACTIVE}).Id)
+	assert.NotEmpty(t, NewWebhookEventFromOfferStoreShake(OfferStoreShake{Id: "shake1", Status: OFFER_STORE_SHAKE_STATUS_SHAKE}, "store1").Id)
+}
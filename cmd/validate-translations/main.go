@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/service/email"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
+	"os"
+	"path/filepath"
+)
+
+// Reports email templates and translation keys each language is missing, exits 1 when anything is missing
+func main() {
+	translationDir := flag.String("translations", "./translations", "translation file directory")
+	templateDir := flag.String("templates", "./templates", "email template directory")
+	flag.Parse()
+
+	email.TemplateDir = filepath.Clean(*templateDir) + "/"
+	if err := translation.LoadTranslations(*translationDir); err != nil {
+		fmt.Println("Load translations failed", err)
+		os.Exit(1)
+	}
+
+	missingCount := 0
+	for _, language := range translation.Languages {
+		missingTemplates := email.MissingTemplates(language)
+		missingIds := make([]string, 0)
+		if language != translation.DEFAULT_LANGUAGE {
+			missingIds = translation.MissingTranslationIds(language)
+		}
+
+		fmt.Printf("%s: %d missing templates, %d missing translations\n", language, len(missingTemplates), len(missingIds))
+		for _, item := range missingTemplates {
+			fmt.Printf("  template %s\n", item)
+		}
+		for _, item := range missingIds {
+			fmt.Printf("  translation %s\n", item)
+		}
+		missingCount += len(missingTemplates) + len(missingIds)
+	}
+
+	if missingCount > 0 {
+		os.Exit(1)
+	}
+}
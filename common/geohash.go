@@ -0,0 +1,114 @@
+package common
+
+import "math"
+
+const GeohashPrecision = 9
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+const earthRadiusKm = 6371.0
+
+func GeohashEncode(latitude float64, longitude float64, precision int) string {
+	latRange := []float64{-90, 90}
+	lonRange := []float64{-180, 180}
+
+	geohash := make([]byte, 0, precision)
+	bit := 0
+	ch := 0
+	even := true
+	for len(geohash) < precision {
+		if even {
+			mid := (lonRange[0] + lonRange[1]) / 2
+			if longitude >= mid {
+				ch |= 1 << uint(4-bit)
+				lonRange[0] = mid
+			} else {
+				lonRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if latitude >= mid {
+				ch |= 1 << uint(4-bit)
+				latRange[0] = mid
+			} else {
+				latRange[1] = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			geohash = append(geohash, geohashBase32[ch])
+			bit = 0
+			ch = 0
+		}
+	}
+
+	return string(geohash)
+}
+
+// Cell size in degrees for a geohash precision
+func GeohashCellSize(precision int) (latSize float64, lonSize float64) {
+	bits := uint(precision * 5)
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// Geohash buckets covering the bounding box of a circle, at most around 16 of them
+func GeohashRadiusBuckets(latitude float64, longitude float64, radiusKm float64) []string {
+	latDelta := radiusKm / 111.32
+	lonDelta := 180.0
+	if cos := math.Cos(latitude * math.Pi / 180); cos > 0.00001 {
+		lonDelta = math.Min(radiusKm/(111.32*cos), 180)
+	}
+
+	precision := 1
+	for p := GeohashPrecision; p >= 1; p-- {
+		latSize, lonSize := GeohashCellSize(p)
+		cells := (math.Ceil(2*latDelta/latSize) + 1) * (math.Ceil(2*lonDelta/lonSize) + 1)
+		if cells <= 16 {
+			precision = p
+			break
+		}
+	}
+
+	latSize, lonSize := GeohashCellSize(precision)
+	minLat := math.Max(latitude-latDelta, -90)
+	maxLat := math.Min(latitude+latDelta, 90)
+	minLon := longitude - lonDelta
+	maxLon := longitude + lonDelta
+
+	seen := map[string]bool{}
+	buckets := make([]string, 0)
+	for lat := minLat; lat <= maxLat+latSize; lat += latSize {
+		for lon := minLon; lon <= maxLon+lonSize; lon += lonSize {
+			cellLat := math.Min(lat, maxLat)
+			cellLon := math.Min(lon, maxLon)
+			// Wrap around the date line
+			if cellLon >= 180 {
+				cellLon -= 360
+			} else if cellLon < -180 {
+				cellLon += 360
+			}
+			bucket := GeohashEncode(cellLat, cellLon, precision)
+			if !seen[bucket] {
+				seen[bucket] = true
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+
+	return buckets
+}
+
+// Great-circle distance in km
+func GeoDistance(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
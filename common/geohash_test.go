@@ -0,0 +1,53 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGeohashEncode(t *testing.T) {
+	assert.Equal(t, "u4pruydqqvj", GeohashEncode(57.64911, 10.40744, 11))
+	assert.Equal(t, "ezs42", GeohashEncode(42.6, -5.6, 5))
+	assert.Equal(t, "s00000000", GeohashEncode(0, 0, GeohashPrecision))
+}
+
+func TestGeohashEncodePrefix(t *testing.T) {
+	full := GeohashEncode(10.7769, 106.7009, GeohashPrecision)
+	for precision := 1; precision < GeohashPrecision; precision++ {
+		assert.True(t, strings.HasPrefix(full, GeohashEncode(10.7769, 106.7009, precision)))
+	}
+}
+
+func TestGeohashRadiusBucketsCoverNeighbours(t *testing.T) {
+	latitude, longitude := 10.7769, 106.7009
+	buckets := GeohashRadiusBuckets(latitude, longitude, 5)
+	assert.True(t, len(buckets) > 0)
+	assert.True(t, len(buckets) <= 16)
+
+	// Points at the edge of the radius in every direction land in one of the buckets
+	precision := len(buckets[0])
+	for _, point := range [][]float64{
+		{latitude, longitude},
+		{latitude + 0.044, longitude},
+		{latitude - 0.044, longitude},
+		{latitude, longitude + 0.045},
+		{latitude, longitude - 0.045},
+	} {
+		assert.Contains(t, buckets, GeohashEncode(point[0], point[1], precision))
+	}
+}
+
+func TestGeohashRadiusBucketsWrapDateLine(t *testing.T) {
+	buckets := GeohashRadiusBuckets(0, 179.99, 10)
+	precision := len(buckets[0])
+
+	assert.Contains(t, buckets, GeohashEncode(0, 179.99, precision))
+	assert.Contains(t, buckets, GeohashEncode(0, -179.99, precision))
+}
+
+func TestGeoDistance(t *testing.T) {
+	assert.Equal(t, float64(0), GeoDistance(48.8566, 2.3522, 48.8566, 2.3522))
+	// Paris to London
+	assert.InDelta(t, 343.5, GeoDistance(48.8566, 2.3522, 51.5074, -0.1278), 1)
+}
@@ -1,6 +1,10 @@
 package common
 
-import "github.com/shopspring/decimal"
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"strconv"
+)
 
 func StringToDecimal(value string) decimal.Decimal {
 	number, _ := decimal.NewFromString(value)
@@ -10,3 +14,21 @@ func StringToDecimal(value string) decimal.Decimal {
 func DecimalToFiatString(value decimal.Decimal) string {
 	return value.Round(2).String()
 }
+
+// Card expiration date is MM/YY, year comes back with the century
+func SplitExpirationDate(date string) (month string, year string, err error) {
+	if len(date) != 5 || date[2] != '/' {
+		err = errors.New("expiration date is not MM/YY")
+		return
+	}
+	monthNumber, monthErr := strconv.Atoi(date[:2])
+	_, yearErr := strconv.Atoi(date[3:])
+	if monthErr != nil || yearErr != nil || monthNumber < 1 || monthNumber > 12 {
+		err = errors.New("expiration date is not MM/YY")
+		return
+	}
+	month = date[:2]
+	year = "20" + date[3:]
+
+	return
+}
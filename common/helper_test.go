@@ -0,0 +1,20 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplitExpirationDate(t *testing.T) {
+	month, year, err := SplitExpirationDate("09/27")
+	assert.Nil(t, err)
+	assert.Equal(t, "09", month)
+	assert.Equal(t, "2027", year)
+}
+
+func TestSplitExpirationDateInvalid(t *testing.T) {
+	for _, date := range []string{"", "9/27", "0927", "09-27", "13/27", "00/27", "ab/cd", "09/2027"} {
+		_, _, err := SplitExpirationDate(date)
+		assert.NotNil(t, err, date)
+	}
+}
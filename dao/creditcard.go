@@ -4,11 +4,42 @@ import (
 	"cloud.google.com/go/firestore"
 	"context"
 	"fmt"
+	"github.com/go-errors/errors"
 	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
 	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"github.com/shopspring/decimal"
 	"google.golang.org/api/iterator"
 )
 
+type CreditCardDaoInterface interface {
+	AddCCTransaction(ccTran bean.CCTransaction) (bean.CCTransaction, error)
+	UpdateCCTransaction(ccTran bean.CCTransaction) (bean.CCTransaction, error)
+	UpdateCCTransactionStatus(ccTran bean.CCTransaction) (bean.CCTransaction, error)
+	ListCCTransactions(userId string, limit int, startAt interface{}) (t TransferObject)
+	GetCCTransaction(userId string, ccTranId string) TransferObject
+	GetCCTransactionByPath(path string) (t TransferObject)
+	GetCCTransactionByExternalId(userId string, externalId string) (t TransferObject)
+	AddCCTransactionEvent(ccTran bean.CCTransaction, event bean.CCTransactionEvent) error
+	AddInstantOffer(offer bean.InstantOffer, transaction bean.Transaction, providerId string) (bean.InstantOffer, error)
+	AddPendingAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction,
+		pendingOffer bean.PendingAuthenticationInstantOffer) (bean.InstantOffer, bean.CCTransaction, error)
+	GetPendingAuthenticationInstantOffer(userId string, offerId string) (t TransferObject)
+	ClaimPendingAuthenticationInstantOffer(userId string, offerId string) (bean.PendingAuthenticationInstantOffer, error)
+	ListPendingAuthenticationInstantOffer() ([]bean.PendingAuthenticationInstantOffer, error)
+	UpdateCCTransactionAuthentication(ccTran bean.CCTransaction) (bean.CCTransaction, error)
+	CancelPendingAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction) (bean.InstantOffer, error)
+	UpdateInstantOffer(offer bean.InstantOffer, transaction bean.Transaction) (bean.InstantOffer, error)
+	ReserveCCTransactionRefund(ccTran bean.CCTransaction, amount decimal.Decimal) (bean.CCTransaction, error)
+	ReleaseCCTransactionRefund(ccTran bean.CCTransaction, amount decimal.Decimal) error
+	AddInstantOfferRefund(offer bean.InstantOffer, ccTran bean.CCTransaction, amount decimal.Decimal) (bean.InstantOffer, bean.CCTransaction, error)
+	ListInstantOffers(userId string, currency string, limit int, startAt interface{}) (t TransferObject)
+	GetInstantOffer(userId string, instantOfferId string) TransferObject
+	GetInstantOfferByPath(path string) (t TransferObject)
+	ListPendingInstantOffer() ([]bean.PendingInstantOffer, error)
+	UpdateNotificationInstantOffer(offer bean.InstantOffer) error
+}
+
 type CreditCardDao struct {
 }
 
@@ -59,11 +90,54 @@ func (dao CreditCardDao) GetCCTransactionByPath(path string) (t TransferObject)
 	return
 }
 
+func (dao CreditCardDao) GetCCTransactionByExternalId(userId string, externalId string) (t TransferObject) {
+	// users/{uid}/cc_transactions
+	ListObjects(GetCCTransactionPath(userId), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("external_id", "==", externalId).Limit(1)
+	}, snapshotToCCTransaction)
+	if !t.HasError() {
+		if len(t.Objects) > 0 {
+			t.Object = t.Objects[0]
+		} else {
+			t.Found = false
+		}
+	}
+
+	return
+}
+
+// Event, transaction status and card lock land together, a replayed webhook overwrites the same event
+func (dao CreditCardDao) AddCCTransactionEvent(ccTran bean.CCTransaction, event bean.CCTransactionEvent) error {
+	dbClient := firebase_service.FirestoreClient
+
+	batch := dbClient.Batch()
+	batch.Set(dbClient.Doc(GetCCTransactionEventItemPath(ccTran.UID, ccTran.Id, event.Id)), event.GetAddCCTransactionEvent())
+	batch.Set(dbClient.Doc(GetCCTransactionItemPath(ccTran.UID, ccTran.Id)), map[string]interface{}{
+		"status":     ccTran.Status,
+		"updated_at": firestore.ServerTimestamp,
+	}, firestore.MergeAll)
+	if event.CardLocked {
+		batch.Set(dbClient.Doc(GetUserPath(ccTran.UID)), map[string]interface{}{
+			"credit_card_status": bean.CREDIT_CARD_STATUS_DISPUTED,
+		}, firestore.MergeAll)
+	}
+
+	_, err := batch.Commit(context.Background())
+
+	return err
+}
+
 func (dao CreditCardDao) AddInstantOffer(offer bean.InstantOffer, transaction bean.Transaction, providerId string) (bean.InstantOffer, error) {
 	dbClient := firebase_service.FirestoreClient
 
-	docRef := dbClient.Collection(GetInstantOfferPath(offer.UID)).NewDoc()
-	offer.Id = docRef.ID
+	var docRef *firestore.DocumentRef
+	if offer.Id == "" {
+		docRef = dbClient.Collection(GetInstantOfferPath(offer.UID)).NewDoc()
+		offer.Id = docRef.ID
+	} else {
+		// Offer was created while waiting for 3-D Secure
+		docRef = dbClient.Doc(GetInstantOfferItemPath(offer.UID, offer.Id))
+	}
 
 	pendingOffer := bean.PendingInstantOffer{
 		UID:             offer.UID,
@@ -85,6 +159,105 @@ func (dao CreditCardDao) AddInstantOffer(offer bean.InstantOffer, transaction be
 	batch.Set(docRef, offer.GetAddInstantOffer())
 	batch.Set(docPendingRef, pendingOffer.GetAddInstantOffer())
 	batch.Set(docTransactionRef, transaction.GetAddTransaction())
+	batch.Delete(dbClient.Doc(GetPendingAuthenticationInstantOfferItemPath(pendingOfferId)))
+	addOutboxEvent(batch, bean.NewOutboxEventFromInstantOffer(offer))
+
+	_, err := batch.Commit(context.Background())
+
+	return offer, err
+}
+
+// Transaction, offer and pending record are written together, so an abandoned 3-D Secure always has something to expire
+func (dao CreditCardDao) AddPendingAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction,
+	pendingOffer bean.PendingAuthenticationInstantOffer) (bean.InstantOffer, bean.CCTransaction, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	docRef := dbClient.Collection(GetInstantOfferPath(offer.UID)).NewDoc()
+	offer.Id = docRef.ID
+	docCCTranRef := dbClient.Collection(GetCCTransactionPath(ccTran.UID)).NewDoc()
+	ccTran.Id = docCCTranRef.ID
+
+	offer.PaymentMethodRef = GetCCTransactionItemPath(ccTran.UID, ccTran.Id)
+	ccTran.DataRef = GetInstantOfferItemPath(offer.UID, offer.Id)
+
+	pendingOffer.Id = fmt.Sprintf("%s-%s", offer.UID, offer.Id)
+	pendingOffer.UID = offer.UID
+	pendingOffer.InstantOffer = offer.Id
+	pendingOffer.InstantOfferRef = ccTran.DataRef
+	pendingOffer.CCTransactionRef = offer.PaymentMethodRef
+
+	batch := dbClient.Batch()
+	batch.Set(docRef, offer.GetAddInstantOffer())
+	batch.Set(docCCTranRef, ccTran.GetAddCCTransaction())
+	batch.Set(dbClient.Doc(GetPendingAuthenticationInstantOfferItemPath(pendingOffer.Id)), pendingOffer.GetAddInstantOffer())
+
+	_, err := batch.Commit(context.Background())
+
+	return offer, ccTran, err
+}
+
+func (dao CreditCardDao) GetPendingAuthenticationInstantOffer(userId string, offerId string) (t TransferObject) {
+	// pending_authentication_instant_offers/{uid-id}
+	GetObject(GetPendingAuthenticationInstantOfferItemPath(fmt.Sprintf("%s-%s", userId, offerId)), &t, snapshotToPendingAuthenticationInstantOffer)
+	return
+}
+
+// Deleting the pending record is the claim, the issuer return and the expiry can not both go on with the same charge
+func (dao CreditCardDao) ClaimPendingAuthenticationInstantOffer(userId string, offerId string) (bean.PendingAuthenticationInstantOffer, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetPendingAuthenticationInstantOfferItemPath(fmt.Sprintf("%s-%s", userId, offerId)))
+
+	var pendingOffer bean.PendingAuthenticationInstantOffer
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		pendingOffer = snapshotToPendingAuthenticationInstantOffer(doc).(bean.PendingAuthenticationInstantOffer)
+		return tx.Delete(docRef)
+	})
+
+	return pendingOffer, err
+}
+
+func (dao CreditCardDao) ListPendingAuthenticationInstantOffer() ([]bean.PendingAuthenticationInstantOffer, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// pending_authentication_instant_offers
+	iter := dbClient.Collection(GetPendingAuthenticationInstantOfferPath()).Documents(context.Background())
+	offers := make([]bean.PendingAuthenticationInstantOffer, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return offers, err
+		}
+		offers = append(offers, snapshotToPendingAuthenticationInstantOffer(doc).(bean.PendingAuthenticationInstantOffer))
+	}
+
+	return offers, nil
+}
+
+func (dao CreditCardDao) UpdateCCTransactionAuthentication(ccTran bean.CCTransaction) (bean.CCTransaction, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetCCTransactionItemPath(ccTran.UID, ccTran.Id))
+
+	_, err := docRef.Set(context.Background(), ccTran.GetUpdateAuthentication(), firestore.MergeAll)
+
+	return ccTran, err
+}
+
+func (dao CreditCardDao) CancelPendingAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction) (bean.InstantOffer, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	batch := dbClient.Batch()
+	batch.Set(dbClient.Doc(GetInstantOfferItemPath(offer.UID, offer.Id)), offer.GetUpdate(), firestore.MergeAll)
+	batch.Set(dbClient.Doc(GetCCTransactionItemPath(ccTran.UID, ccTran.Id)), ccTran.GetUpdateAuthentication(), firestore.MergeAll)
+	batch.Delete(dbClient.Doc(GetPendingAuthenticationInstantOfferItemPath(fmt.Sprintf("%s-%s", offer.UID, offer.Id))))
+
 	_, err := batch.Commit(context.Background())
 
 	return offer, err
@@ -103,11 +276,116 @@ func (dao CreditCardDao) UpdateInstantOffer(offer bean.InstantOffer, transaction
 	batch.Set(docRef, offer.GetUpdate(), firestore.MergeAll)
 	batch.Delete(pendingOfferDocRef)
 	batch.Set(docTransactionRef, transaction.GetUpdateStatus(), firestore.MergeAll)
+	addOutboxEvent(batch, bean.NewOutboxEventFromInstantOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return offer, err
 }
 
+// Refund transaction, charge and offer land together so the refunded amounts always match the transactions
+// The amount is held on the transaction until the provider answers, concurrent refunds can not give back more than was charged
+func (dao CreditCardDao) ReserveCCTransactionRefund(ccTran bean.CCTransaction, amount decimal.Decimal) (bean.CCTransaction, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetCCTransactionItemPath(ccTran.UID, ccTran.Id))
+
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		doc.DataTo(&ccTran)
+		if !ccTran.IsRefundable() {
+			return errors.New("Not refundable")
+		}
+		refunding := common.StringToDecimal(ccTran.RefundingAmount).Add(amount)
+		if common.StringToDecimal(ccTran.RefundedAmount).Add(refunding).GreaterThan(common.StringToDecimal(ccTran.Amount)) {
+			return errors.New("Over refundable amount")
+		}
+		ccTran.RefundingAmount = refunding.String()
+		ccTran.RefundAmounts = append(ccTran.RefundAmounts, amount.String())
+
+		return tx.Set(docRef, ccTran.GetUpdateRefunding(), firestore.MergeAll)
+	})
+
+	return ccTran, err
+}
+
+// Gives back a reservation the provider turned down
+func (dao CreditCardDao) ReleaseCCTransactionRefund(ccTran bean.CCTransaction, amount decimal.Decimal) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetCCTransactionItemPath(ccTran.UID, ccTran.Id))
+
+	return dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		doc.DataTo(&ccTran)
+		releaseCCTransactionRefunding(&ccTran, amount)
+		for i, refundAmount := range ccTran.RefundAmounts {
+			if common.StringToDecimal(refundAmount).Equal(amount) {
+				ccTran.RefundAmounts = append(ccTran.RefundAmounts[:i], ccTran.RefundAmounts[i+1:]...)
+				break
+			}
+		}
+
+		return tx.Set(docRef, ccTran.GetUpdateRefunding(), firestore.MergeAll)
+	})
+}
+
+// Moves a reserved refund to refunded, the offer and its refund transaction are written with it
+func (dao CreditCardDao) AddInstantOfferRefund(offer bean.InstantOffer, ccTran bean.CCTransaction, amount decimal.Decimal) (bean.InstantOffer, bean.CCTransaction, error) {
+	dbClient := firebase_service.FirestoreClient
+	offerRef := dbClient.Doc(GetInstantOfferItemPath(offer.UID, offer.Id))
+	ccTranRef := dbClient.Doc(GetCCTransactionItemPath(ccTran.UID, ccTran.Id))
+	providerData := ccTran.ProviderData
+
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ccTranRef)
+		if err != nil {
+			return err
+		}
+		doc.DataTo(&ccTran)
+		ccTran.ProviderData = providerData
+		// The amount stays in refund amounts, the provider event for this refund is matched against it
+		releaseCCTransactionRefunding(&ccTran, amount)
+
+		refunded := common.StringToDecimal(ccTran.RefundedAmount).Add(amount)
+		ccTran.RefundedAmount = refunded.String()
+		offer.RefundedFiatAmount = refunded.String()
+		if !refunded.LessThan(common.StringToDecimal(ccTran.Amount)) {
+			ccTran.Status = bean.CC_TRANSACTION_STATUS_REFUNDED
+			offer.Status = bean.INSTANT_OFFER_STATUS_REFUNDED
+		} else {
+			ccTran.Status = bean.CC_TRANSACTION_STATUS_PARTIALLY_REFUNDED
+			offer.Status = bean.INSTANT_OFFER_STATUS_PARTIALLY_REFUNDED
+		}
+
+		if err = tx.Set(offerRef, offer.GetUpdateRefund(), firestore.MergeAll); err != nil {
+			return err
+		}
+		if err = tx.Set(ccTranRef, ccTran.GetUpdateRefund(), firestore.MergeAll); err != nil {
+			return err
+		}
+		transaction := bean.NewTransactionFromInstantOfferRefund(offer, amount.String())
+		if err = tx.Set(dbClient.Collection(GetTransactionPath(offer.UID)).NewDoc(), transaction.GetAddTransaction()); err != nil {
+			return err
+		}
+		return addOutboxEventTx(tx, bean.NewOutboxEventFromInstantOffer(offer))
+	})
+
+	return offer, ccTran, err
+}
+
+func releaseCCTransactionRefunding(ccTran *bean.CCTransaction, amount decimal.Decimal) {
+	refunding := common.StringToDecimal(ccTran.RefundingAmount).Sub(amount)
+	if refunding.LessThan(common.Zero) {
+		refunding = common.Zero
+	}
+	ccTran.RefundingAmount = refunding.String()
+}
+
 func (dao CreditCardDao) ListInstantOffers(userId string, currency string, limit int, startAt interface{}) (t TransferObject) {
 	ListPagingObjects(GetInstantOfferPath(userId), &t, limit, startAt, func(collRef *firestore.CollectionRef) firestore.Query {
 		query := collRef.Where("currency", "==", currency).OrderBy("created_at", firestore.Desc)
@@ -167,6 +445,10 @@ func GetCCTransactionItemPath(userId string, id string) string {
 	return fmt.Sprintf("%s/%s", GetCCTransactionPath(userId), id)
 }
 
+func GetCCTransactionEventItemPath(userId string, ccTranId string, id string) string {
+	return fmt.Sprintf("%s/events/%s", GetCCTransactionItemPath(userId, ccTranId), id)
+}
+
 func GetInstantOfferPath(userId string) string {
 	return fmt.Sprintf("users/%s/instant_offers", userId)
 }
@@ -183,6 +465,14 @@ func GetPendingInstantOfferItemPath(pendingOfferId string) string {
 	return fmt.Sprintf("%s/%s", GetPendingInstantOfferPath(), pendingOfferId)
 }
 
+func GetPendingAuthenticationInstantOfferPath() string {
+	return "pending_authentication_instant_offers"
+}
+
+func GetPendingAuthenticationInstantOfferItemPath(pendingOfferId string) string {
+	return fmt.Sprintf("%s/%s", GetPendingAuthenticationInstantOfferPath(), pendingOfferId)
+}
+
 // Firebase
 func GetNotificationInstantOfferItemPath(userId string, offerId string) string {
 	return fmt.Sprintf("users/%s/offers/instant_%s", userId, offerId)
@@ -203,3 +493,11 @@ func snapshotToInstantOffer(snapshot *firestore.DocumentSnapshot) interface{} {
 
 	return obj
 }
+
+func snapshotToPendingAuthenticationInstantOffer(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.PendingAuthenticationInstantOffer
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+
+	return obj
+}
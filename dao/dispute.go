@@ -0,0 +1,201 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+)
+
+type DisputeDaoInterface interface {
+	AddOfferDispute(offer bean.Offer, dispute bean.Dispute) (bean.Dispute, error)
+	AddOfferStoreShakeDispute(offerStoreId string, offerShake bean.OfferStoreShake, dispute bean.Dispute) (bean.Dispute, error)
+	UpdateDisputedOfferStatus(offerId string, status string) error
+	UpdateDisputedOfferStoreShakeStatus(offerStoreId string, offerShakeId string, status string) error
+	ClaimDisputeRuling(dispute bean.Dispute) (bean.Dispute, error)
+	ReleaseDisputeRuling(dispute bean.Dispute) error
+	UpdateDisputeRuling(dispute bean.Dispute) error
+	GetDispute(disputeId string) (t TransferObject)
+	ListDisputes(status string, limit int, startAt interface{}) (t TransferObject)
+}
+
+type DisputeDao struct {
+}
+
+// The offer freezes and the dispute is recorded in one transaction, so there is never a disputed offer without its dispute.
+// Only a shaken offer can be disputed, the parties may have completed or rejected it since it was read
+func (dao DisputeDao) AddOfferDispute(offer bean.Offer, dispute bean.Dispute) (bean.Dispute, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetDisputePath()).NewDoc()
+	offerRef := dbClient.Doc(GetOfferItemPath(offer.Id))
+	dispute.Id = docRef.ID
+
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(offerRef)
+		if err != nil {
+			return err
+		}
+		status, err := doc.DataAt("status")
+		if err != nil {
+			return err
+		}
+		if status != bean.OFFER_STATUS_SHAKE {
+			return errors.New("Offer is not shaken")
+		}
+
+		if err = tx.Set(docRef, dispute.GetAddDispute()); err != nil {
+			return err
+		}
+		if err = tx.Set(offerRef, offer.GetChangeStatus(), firestore.MergeAll); err != nil {
+			return err
+		}
+		return addOutboxEventTx(tx, bean.NewOutboxEventFromOffer(offer))
+	})
+
+	return dispute, err
+}
+
+func (dao DisputeDao) AddOfferStoreShakeDispute(offerStoreId string, offerShake bean.OfferStoreShake, dispute bean.Dispute) (bean.Dispute, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetDisputePath()).NewDoc()
+	offerShakeRef := dbClient.Doc(GetOfferStoreShakeItemPath(offerStoreId, offerShake.Id))
+	dispute.Id = docRef.ID
+
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(offerShakeRef)
+		if err != nil {
+			return err
+		}
+		status, err := doc.DataAt("status")
+		if err != nil {
+			return err
+		}
+		if status != bean.OFFER_STORE_SHAKE_STATUS_SHAKE {
+			return errors.New("Offer shake is not shaken")
+		}
+
+		if err = tx.Set(docRef, dispute.GetAddDispute()); err != nil {
+			return err
+		}
+		if err = tx.Set(offerShakeRef, offerShake.GetChangeStatus(), firestore.MergeAll); err != nil {
+			return err
+		}
+		return addOutboxEventTx(tx, bean.NewOutboxEventFromOfferStoreShake(offerShake, offerStoreId))
+	})
+
+	return dispute, err
+}
+
+// Ruling hands the offer back to the complete and reject paths, which only accept shake.
+// No outbox event, the path that runs next emits the final status
+func (dao DisputeDao) UpdateDisputedOfferStatus(offerId string, status string) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetOfferItemPath(offerId))
+
+	_, err := docRef.Set(context.Background(), map[string]interface{}{
+		"status":     status,
+		"updated_at": firestore.ServerTimestamp,
+	}, firestore.MergeAll)
+
+	return err
+}
+
+func (dao DisputeDao) UpdateDisputedOfferStoreShakeStatus(offerStoreId string, offerShakeId string, status string) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetOfferStoreShakeItemPath(offerStoreId, offerShakeId))
+
+	_, err := docRef.Set(context.Background(), map[string]interface{}{
+		"status":     status,
+		"updated_at": firestore.ServerTimestamp,
+	}, firestore.MergeAll)
+
+	return err
+}
+
+// Moves an open dispute to resolving with its ruling, only the arbitrator who claimed it may run the ruling
+func (dao DisputeDao) ClaimDisputeRuling(dispute bean.Dispute) (bean.Dispute, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetDisputeItemPath(dispute.Id))
+
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		status, err := doc.DataAt("status")
+		if err != nil {
+			return err
+		}
+		if status != bean.DISPUTE_STATUS_OPEN {
+			return errors.New("Dispute is not open")
+		}
+		dispute.Status = bean.DISPUTE_STATUS_RESOLVING
+		return tx.Set(docRef, dispute.GetUpdateResolving(), firestore.MergeAll)
+	})
+
+	return dispute, err
+}
+
+// A ruling that failed before anything was paid out opens the dispute again
+func (dao DisputeDao) ReleaseDisputeRuling(dispute bean.Dispute) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetDisputeItemPath(dispute.Id))
+
+	return dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		status, err := doc.DataAt("status")
+		if err != nil {
+			return err
+		}
+		if status != bean.DISPUTE_STATUS_RESOLVING {
+			return errors.New("Dispute is not resolving")
+		}
+		dispute.Status = bean.DISPUTE_STATUS_OPEN
+		dispute.Ruling = ""
+		dispute.ArbitratorUID = ""
+		return tx.Set(docRef, dispute.GetUpdateResolving(), firestore.MergeAll)
+	})
+}
+
+func (dao DisputeDao) UpdateDisputeRuling(dispute bean.Dispute) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetDisputeItemPath(dispute.Id))
+
+	_, err := docRef.Set(context.Background(), dispute.GetUpdateRuling(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao DisputeDao) GetDispute(disputeId string) (t TransferObject) {
+	// disputes/{id}
+	GetObject(GetDisputeItemPath(disputeId), &t, snapshotToDispute)
+	return
+}
+
+func (dao DisputeDao) ListDisputes(status string, limit int, startAt interface{}) (t TransferObject) {
+	// disputes
+	ListPagingObjects(GetDisputePath(), &t, limit, startAt, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("status", "==", status).OrderBy("created_at", firestore.Desc)
+	}, snapshotToDispute)
+	return
+}
+
+func GetDisputePath() string {
+	return "disputes"
+}
+
+func GetDisputeItemPath(disputeId string) string {
+	return fmt.Sprintf("%s/%s", GetDisputePath(), disputeId)
+}
+
+func snapshotToDispute(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.Dispute
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
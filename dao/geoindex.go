@@ -0,0 +1,133 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"google.golang.org/api/iterator"
+)
+
+type GeoIndexDao struct {
+}
+
+func (dao GeoIndexDao) UpdateOfferEntry(offer bean.Offer) error {
+	entry := newGeoIndexEntry(bean.NewOrderBookEntryFromOffer(offer), offer.Latitude, offer.Longitude)
+	if offer.Status == bean.OFFER_STATUS_ACTIVE && hasLocation(offer.Latitude, offer.Longitude) {
+		return dao.setEntry(entry)
+	}
+	return dao.removeEntry(entry)
+}
+
+func (dao GeoIndexDao) UpdateOfferStoreEntries(offer bean.OfferStore, item bean.OfferStoreItem) error {
+	for _, offerType := range []string{bean.OFFER_TYPE_SELL, bean.OFFER_TYPE_BUY} {
+		entry := newGeoIndexEntry(bean.NewOrderBookEntryFromOfferStore(offer, item, offerType), offer.Latitude, offer.Longitude)
+		// A store has an item per currency, each gets its own entry
+		entry.Id = fmt.Sprintf("%s.%s", entry.Id, item.Currency)
+		var err error
+		if offer.Status == bean.OFFER_STORE_STATUS_ACTIVE && item.Status == bean.OFFER_STORE_ITEM_STATUS_ACTIVE &&
+			common.StringToDecimal(entry.Amount).GreaterThan(common.Zero) && hasLocation(offer.Latitude, offer.Longitude) {
+			err = dao.setEntry(entry)
+		} else {
+			err = dao.removeEntry(entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dao GeoIndexDao) ListEntriesByGeohash(buckets []string) ([]bean.GeoIndexEntry, error) {
+	dbClient := firebase_service.FirestoreClient
+	entries := make([]bean.GeoIndexEntry, 0)
+
+	for _, bucket := range buckets {
+		// geo_index, everything with the bucket as prefix
+		iter := dbClient.Collection(GetGeoIndexPath()).
+			Where("geohash", ">=", bucket).Where("geohash", "<", bucket+"~").Documents(context.Background())
+		for {
+			var entry bean.GeoIndexEntry
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return entries, err
+			}
+			doc.DataTo(&entry)
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (dao GeoIndexDao) RebuildEntries() (count int, err error) {
+	offerStores, err := listActiveOfferStores()
+	if err != nil {
+		return
+	}
+	for _, offer := range offerStores {
+		for _, item := range offer.ItemSnapshots {
+			if err = dao.UpdateOfferStoreEntries(offer, item); err != nil {
+				return
+			}
+			count += 1
+		}
+	}
+
+	offers, err := listActiveOffers()
+	if err != nil {
+		return
+	}
+	for _, offer := range offers {
+		if err = dao.UpdateOfferEntry(offer); err != nil {
+			return
+		}
+		count += 1
+	}
+
+	return
+}
+
+func (dao GeoIndexDao) setEntry(entry bean.GeoIndexEntry) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetGeoIndexItemPath(entry.Id))
+
+	_, err := docRef.Set(context.Background(), entry.GetAddGeoIndexEntry())
+
+	return err
+}
+
+func (dao GeoIndexDao) removeEntry(entry bean.GeoIndexEntry) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetGeoIndexItemPath(entry.Id))
+
+	_, err := docRef.Delete(context.Background())
+
+	return err
+}
+
+func newGeoIndexEntry(entry bean.OrderBookEntry, latitude float64, longitude float64) bean.GeoIndexEntry {
+	return bean.GeoIndexEntry{
+		OrderBookEntry: entry,
+		Geohash:        common.GeohashEncode(latitude, longitude, common.GeohashPrecision),
+		Latitude:       latitude,
+		Longitude:      longitude,
+	}
+}
+
+func hasLocation(latitude float64, longitude float64) bool {
+	return latitude != 0 || longitude != 0
+}
+
+func GetGeoIndexPath() string {
+	return "geo_index"
+}
+
+func GetGeoIndexItemPath(id string) string {
+	return fmt.Sprintf("%s/%s", GetGeoIndexPath(), id)
+}
@@ -7,3 +7,12 @@ var TransactionDaoInst = TransactionDao{}
 var OfferDaoInst = OfferDao{}
 var OfferStoreDaoInst = OfferStoreDao{}
 var OnChainDaoInst = OnChainDao{}
+var TradingBotDaoInst = TradingBotDao{}
+var OrderBookDaoInst = OrderBookDao{}
+var GeoIndexDaoInst = GeoIndexDao{}
+var SearchReindexDaoInst = SearchReindexDao{}
+var NotificationDaoInst = NotificationDao{}
+var OutboxDaoInst = OutboxDao{}
+var WebhookDaoInst = WebhookDao{}
+var DisputeDaoInst = DisputeDao{}
+var KYCDaoInst = KYCDao{}
@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+)
+
+type KYCDaoInterface interface {
+	AddKYCSubmission(submission bean.KYCSubmission, profile bean.Profile) (bean.KYCSubmission, error)
+	UpdateKYCReview(submission bean.KYCSubmission, profile bean.Profile) error
+	GetKYCSubmission(submissionId string) (t TransferObject)
+	ListKYCSubmissions(status string, limit int, startAt interface{}) (t TransferObject)
+	ListUserKYCSubmissions(userId string) (t TransferObject)
+}
+
+type KYCDao struct {
+}
+
+// The profile is marked pending with the submission, so a user has one submission in review at a time
+func (dao KYCDao) AddKYCSubmission(submission bean.KYCSubmission, profile bean.Profile) (bean.KYCSubmission, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetKYCSubmissionPath()).NewDoc()
+	submission.Id = docRef.ID
+
+	batch := dbClient.Batch()
+	batch.Set(docRef, submission.GetAddKYCSubmission())
+	batch.Set(dbClient.Doc(GetUserPath(profile.UserId)), profile.GetUpdateKYC(), firestore.MergeAll)
+
+	_, err := batch.Commit(context.Background())
+
+	return submission, err
+}
+
+func (dao KYCDao) UpdateKYCReview(submission bean.KYCSubmission, profile bean.Profile) error {
+	dbClient := firebase_service.FirestoreClient
+
+	batch := dbClient.Batch()
+	batch.Set(dbClient.Doc(GetKYCSubmissionItemPath(submission.Id)), submission.GetUpdateReview(), firestore.MergeAll)
+	batch.Set(dbClient.Doc(GetUserPath(profile.UserId)), profile.GetUpdateKYC(), firestore.MergeAll)
+
+	_, err := batch.Commit(context.Background())
+
+	return err
+}
+
+func (dao KYCDao) GetKYCSubmission(submissionId string) (t TransferObject) {
+	// kyc_submissions/{id}
+	GetObject(GetKYCSubmissionItemPath(submissionId), &t, snapshotToKYCSubmission)
+	return
+}
+
+func (dao KYCDao) ListKYCSubmissions(status string, limit int, startAt interface{}) (t TransferObject) {
+	// kyc_submissions
+	ListPagingObjects(GetKYCSubmissionPath(), &t, limit, startAt, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("status", "==", status).OrderBy("created_at", firestore.Asc)
+	}, snapshotToKYCSubmission)
+	return
+}
+
+func (dao KYCDao) ListUserKYCSubmissions(userId string) (t TransferObject) {
+	// kyc_submissions
+	ListObjects(GetKYCSubmissionPath(), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("uid", "==", userId).OrderBy("created_at", firestore.Desc)
+	}, snapshotToKYCSubmission)
+	return
+}
+
+func GetKYCSubmissionPath() string {
+	return "kyc_submissions"
+}
+
+func GetKYCSubmissionItemPath(submissionId string) string {
+	return fmt.Sprintf("%s/%s", GetKYCSubmissionPath(), submissionId)
+}
+
+func snapshotToKYCSubmission(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.KYCSubmission
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
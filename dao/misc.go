@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/go-errors/errors"
 	"github.com/ninjadotorg/handshake-exchange/api_error"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
@@ -14,6 +15,7 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"time"
 )
 
 type MiscDao struct {
@@ -119,6 +121,32 @@ func (dao MiscDao) GetCryptoRateFromCache(currency string, exchange string) (t T
 	return
 }
 
+func (dao MiscDao) UpdateOraclePrice(price bean.OraclePrice) error {
+	b, _ := json.Marshal(&price)
+	key := GetOraclePriceItemCacheKey(fmt.Sprintf("%s.%s", price.Currency, price.Type))
+
+	return cache.RedisClient.Set(key, string(b), 0).Err()
+}
+
+func (dao MiscDao) GetOraclePriceFromCache(currency string, priceType string) (t TransferObject) {
+	GetCacheObject(GetOraclePriceItemCacheKey(fmt.Sprintf("%s.%s", currency, priceType)), &t, func(val string) interface{} {
+		var price bean.OraclePrice
+		json.Unmarshal([]byte(val), &price)
+		return price
+	})
+
+	return
+}
+
+// False when the quote was already used, the mark goes away once the quote has expired anyway
+func (dao MiscDao) UseLockedQuote(signature string, expiresIn time.Duration) (bool, error) {
+	return cache.RedisClient.SetNX(GetUsedQuoteItemCacheKey(signature), 1, expiresIn).Result()
+}
+
+func (dao MiscDao) ReleaseLockedQuote(signature string) error {
+	return cache.RedisClient.Del(GetUsedQuoteItemCacheKey(signature)).Err()
+}
+
 func (dao MiscDao) LoadSystemFeeToCache() ([]bean.SystemFee, error) {
 	dbClient := firebase_service.FirestoreClient
 
@@ -226,6 +254,100 @@ func (dao MiscDao) GetCCLimitFromCache() (t TransferObject) {
 	return
 }
 
+func (dao MiscDao) LoadFiatCurrencyToCache() ([]bean.FiatCurrency, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// fiat_currencies/
+	iter := dbClient.Collection(GetFiatCurrencyPath()).Documents(context.Background())
+	objs := make([]bean.FiatCurrency, 0)
+	fields := map[string]interface{}{}
+
+	for {
+		var obj bean.FiatCurrency
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return objs, err
+		}
+		doc.DataTo(&obj)
+		if !obj.Enabled {
+			continue
+		}
+		objs = append(objs, obj)
+
+		b, _ := json.Marshal(&obj)
+		fields[obj.Code] = string(b)
+	}
+
+	// To cache, the whole catalogue lives in one hash so reads never scan keys
+	if err := cache.RedisClient.Del(GetFiatCurrencyCacheKey()).Err(); err != nil {
+		return objs, err
+	}
+	if len(fields) > 0 {
+		if err := cache.RedisClient.HMSet(GetFiatCurrencyCacheKey(), fields).Err(); err != nil {
+			return objs, err
+		}
+	}
+
+	return objs, nil
+}
+
+func (dao MiscDao) GetFiatCurrencyFromCache(code string) (t TransferObject) {
+	objs, err := listFiatCurrenciesFromCache()
+	if err != nil {
+		t.SetError(api_error.GetDataFailed, err)
+		return
+	}
+	for _, obj := range objs {
+		if obj.Code == code {
+			t.Object = obj
+			t.Found = true
+		}
+	}
+
+	return
+}
+
+func (dao MiscDao) ListFiatCurrenciesFromCache() (t TransferObject) {
+	objs, err := listFiatCurrenciesFromCache()
+	if err != nil {
+		t.SetError(api_error.GetDataFailed, err)
+		return
+	}
+
+	t.Found = true
+	sort.Slice(objs[:], func(i, j int) bool {
+		return objs[i].Code < objs[j].Code
+	})
+	for _, value := range objs {
+		t.Objects = append(t.Objects, value)
+	}
+
+	return
+}
+
+// An empty catalogue falls back to the hard-coded currencies
+func listFiatCurrenciesFromCache() ([]bean.FiatCurrency, error) {
+	objs := make([]bean.FiatCurrency, 0)
+	values, err := cache.RedisClient.HGetAll(GetFiatCurrencyCacheKey()).Result()
+	if err != nil {
+		return objs, err
+	}
+	for _, value := range values {
+		var obj bean.FiatCurrency
+		if json.Unmarshal([]byte(value), &obj) == nil {
+			objs = append(objs, obj)
+		}
+	}
+	if len(values) == 0 {
+		objs = append(objs, bean.DefaultFiatCurrencies...)
+	}
+
+	return objs, nil
+}
+
 func (dao MiscDao) GetCCLimitByLevelFromCache(level string) (t TransferObject) {
 	GetCacheObject(GetCCLimitCacheKey(level), &t, func(val string) interface{} {
 		var obj bean.CCLimit
@@ -303,10 +425,174 @@ func (dao MiscDao) AddCryptoTransferLog(log bean.CryptoTransferLog) (bean.Crypto
 	return log, err
 }
 
+func (dao MiscDao) AddCryptoPayout(payout bean.CryptoPayout) (bean.CryptoPayout, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetCryptoPayoutPath()).NewDoc()
+	payout.Id = docRef.ID
+	payout.Status = bean.CRYPTO_PAYOUT_STATUS_PENDING
+
+	_, err := docRef.Set(context.Background(), payout.GetAddCryptoPayout())
+
+	return payout, err
+}
+
+func (dao MiscDao) ListPendingCryptoPayouts(currency string) ([]bean.CryptoPayout, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// crypto_payouts
+	iter := dbClient.Collection(GetCryptoPayoutPath()).
+		Where("status", "==", bean.CRYPTO_PAYOUT_STATUS_PENDING).
+		Where("currency", "==", currency).
+		OrderBy("created_at", firestore.Asc).Documents(context.Background())
+	payouts := make([]bean.CryptoPayout, 0)
+
+	for {
+		var payout bean.CryptoPayout
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return payouts, err
+		}
+		doc.DataTo(&payout)
+		payouts = append(payouts, payout)
+	}
+
+	return payouts, nil
+}
+
+// Moves pending payouts to sending, only the payouts returned may be sent
+func (dao MiscDao) ClaimCryptoPayouts(payouts []bean.CryptoPayout) ([]bean.CryptoPayout, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	var claimed []bean.CryptoPayout
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = make([]bean.CryptoPayout, 0)
+		docRefs := make([]*firestore.DocumentRef, 0)
+		for _, payout := range payouts {
+			docRefs = append(docRefs, dbClient.Doc(GetCryptoPayoutItemPath(payout.Id)))
+		}
+		docs, err := tx.GetAll(docRefs)
+		if err != nil {
+			return err
+		}
+		for i, doc := range docs {
+			status, err := doc.DataAt("status")
+			if err != nil {
+				return err
+			}
+			if status != bean.CRYPTO_PAYOUT_STATUS_PENDING {
+				continue
+			}
+			payout := payouts[i]
+			payout.Status = bean.CRYPTO_PAYOUT_STATUS_SENDING
+			if err = tx.Set(docRefs[i], payout.GetUpdateStatus(), firestore.MergeAll); err != nil {
+				return err
+			}
+			claimed = append(claimed, payout)
+		}
+		return nil
+	})
+
+	return claimed, err
+}
+
+// Failed and sending payouts are only put back once someone checked the wallet did not send them
+func (dao MiscDao) RetryCryptoPayout(payoutId string) (bean.CryptoPayout, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetCryptoPayoutItemPath(payoutId))
+
+	var payout bean.CryptoPayout
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		doc.DataTo(&payout)
+		if payout.Status != bean.CRYPTO_PAYOUT_STATUS_FAILED && payout.Status != bean.CRYPTO_PAYOUT_STATUS_SENDING {
+			return errors.New("payout is not failed")
+		}
+		payout.Status = bean.CRYPTO_PAYOUT_STATUS_PENDING
+		return tx.Set(docRef, payout.GetUpdateStatus(), firestore.MergeAll)
+	})
+
+	return payout, err
+}
+
+func (dao MiscDao) AddCryptoPayoutBatch(payoutBatch bean.CryptoPayoutBatch, payouts []bean.CryptoPayout) (bean.CryptoPayoutBatch, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetCryptoPayoutBatchPath()).NewDoc()
+	payoutBatch.Id = docRef.ID
+
+	batch := dbClient.Batch()
+	for _, payout := range payouts {
+		payout.Status = bean.CRYPTO_PAYOUT_STATUS_SENT
+		payout.BatchId = payoutBatch.Id
+		payout.TxHash = payoutBatch.TxHash
+		payoutBatch.Payouts = append(payoutBatch.Payouts, payout.Id)
+		batch.Set(dbClient.Doc(GetCryptoPayoutItemPath(payout.Id)), payout.GetUpdateSent(), firestore.MergeAll)
+
+		// Offer or offer store shake gets the shared tx hash
+		batch.Set(dbClient.Doc(payout.DataRef), map[string]interface{}{
+			"payout_tx_hash": payoutBatch.TxHash,
+			"updated_at":     firestore.ServerTimestamp,
+		}, firestore.MergeAll)
+
+		logRef := dbClient.Collection(GetCryptoTransferPath(payout.UID)).NewDoc()
+		pendingId := fmt.Sprintf("%s-%s", payout.UID, logRef.ID)
+		batch.Set(logRef, bean.CryptoTransferLog{
+			Id:               logRef.ID,
+			Provider:         payout.WalletProvider,
+			ProviderResponse: payoutBatch.ProviderResponse,
+			ExternalId:       payoutBatch.ExternalId,
+			DataType:         payout.DataType,
+			DataRef:          payout.DataRef,
+			UID:              payout.UID,
+			Description:      payout.Description,
+			Amount:           payout.Amount,
+			Currency:         payout.Currency,
+		}.GetAddLog())
+		batch.Set(dbClient.Doc(GetCryptoPendingTransferItemPath(pendingId)), bean.CryptoPendingTransfer{
+			Id:         pendingId,
+			Provider:   payout.WalletProvider,
+			ExternalId: payoutBatch.ExternalId,
+			TxHash:     payoutBatch.TxHash,
+			DataType:   payout.DataType,
+			DataRef:    payout.DataRef,
+			UID:        payout.UID,
+			Amount:     payout.Amount,
+			Currency:   payout.Currency,
+		}.GetAddCryptoPendingTransfer())
+	}
+	batch.Set(docRef, payoutBatch.GetAddCryptoPayoutBatch())
+	_, err := batch.Commit(context.Background())
+
+	return payoutBatch, err
+}
+
+func (dao MiscDao) UpdateCryptoPayoutFailed(payouts []bean.CryptoPayout, errMessage string) error {
+	dbClient := firebase_service.FirestoreClient
+
+	batch := dbClient.Batch()
+	for _, payout := range payouts {
+		payout.Status = bean.CRYPTO_PAYOUT_STATUS_FAILED
+		payout.Error = errMessage
+		batch.Set(dbClient.Doc(GetCryptoPayoutItemPath(payout.Id)), payout.GetUpdateFailed(), firestore.MergeAll)
+	}
+	_, err := batch.Commit(context.Background())
+
+	return err
+}
+
 func GetCurrencyRateItemPath(currency string) string {
 	return fmt.Sprintf("currency_rates/%s", currency)
 }
 
+func GetUsedQuoteItemCacheKey(signature string) string {
+	return fmt.Sprintf("handshake_exchange.used_quotes.%s", signature)
+}
+
 func GetCurrencyRateItemCacheKey(currency string) string {
 	return fmt.Sprintf("handshake_exchange.currency_rates.%s", currency)
 }
@@ -319,6 +605,10 @@ func GetCryptoRateItemCacheKey(currency string) string {
 	return fmt.Sprintf("handshake_exchange.crypto_rates.%s", currency)
 }
 
+func GetOraclePriceItemCacheKey(key string) string {
+	return fmt.Sprintf("handshake_exchange.oracle_prices.%s", key)
+}
+
 func GetSystemFeePath() string {
 	return "system_fees"
 }
@@ -339,6 +629,14 @@ func GetCCLimitCacheKey(level string) string {
 	return fmt.Sprintf("handshake_exchange.cc_limits.%s", level)
 }
 
+func GetFiatCurrencyPath() string {
+	return "fiat_currencies"
+}
+
+func GetFiatCurrencyCacheKey() string {
+	return "handshake_exchange.fiat_currencies"
+}
+
 func GetSystemConfigPath() string {
 	return "system_configs"
 }
@@ -358,3 +656,15 @@ func GetCryptoPendingTransferPath() string {
 func GetCryptoPendingTransferItemPath(id string) string {
 	return fmt.Sprintf("crypto_pending_transfers/%s", id)
 }
+
+func GetCryptoPayoutPath() string {
+	return "crypto_payouts"
+}
+
+func GetCryptoPayoutItemPath(id string) string {
+	return fmt.Sprintf("crypto_payouts/%s", id)
+}
+
+func GetCryptoPayoutBatchPath() string {
+	return "crypto_payout_batches"
+}
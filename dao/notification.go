@@ -0,0 +1,143 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"time"
+)
+
+type NotificationDao struct {
+}
+
+func (dao NotificationDao) GetNotificationDelivery(deliveryId string) (t TransferObject) {
+	// notification_deliveries/{id}
+	GetObject(GetNotificationDeliveryItemPath(deliveryId), &t, snapshotToNotificationDelivery)
+	return
+}
+
+func (dao NotificationDao) AddNotificationDelivery(delivery bean.NotificationDelivery) (bean.NotificationDelivery, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetNotificationDeliveryPath()).NewDoc()
+	delivery.Id = docRef.ID
+
+	_, err := docRef.Set(context.Background(), delivery.GetAddNotificationDelivery())
+
+	return delivery, err
+}
+
+func (dao NotificationDao) UpdateNotificationDelivery(delivery bean.NotificationDelivery) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetNotificationDeliveryItemPath(delivery.Id))
+
+	_, err := docRef.Set(context.Background(), delivery.GetUpdateNotificationDelivery(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao NotificationDao) ListDueNotificationDeliveries(dueBefore time.Time, limit int) ([]bean.NotificationDelivery, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// notification_deliveries
+	docs, err := dbClient.Collection(GetNotificationDeliveryPath()).
+		Where("status", "==", bean.NOTIFICATION_DELIVERY_STATUS_RETRYING).
+		Where("next_attempt_at", "<=", dueBefore).
+		OrderBy("next_attempt_at", firestore.Asc).
+		Limit(limit).
+		Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]bean.NotificationDelivery, 0)
+	for _, doc := range docs {
+		deliveries = append(deliveries, snapshotToNotificationDelivery(doc).(bean.NotificationDelivery))
+	}
+
+	return deliveries, nil
+}
+
+// Pushes the next attempt out to leaseUntil, only one retry run gets a due delivery
+func (dao NotificationDao) ClaimNotificationDeliveryRetry(delivery bean.NotificationDelivery, leaseUntil time.Time) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetNotificationDeliveryItemPath(delivery.Id))
+
+	return dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		var current bean.NotificationDelivery
+		if err = doc.DataTo(&current); err != nil {
+			return err
+		}
+		if current.Status != bean.NOTIFICATION_DELIVERY_STATUS_RETRYING || current.NextAttemptAt.After(time.Now().UTC()) {
+			return errors.New("Notification delivery is not due")
+		}
+		return tx.Set(docRef, map[string]interface{}{
+			"next_attempt_at": leaseUntil,
+		}, firestore.MergeAll)
+	})
+}
+
+func (dao NotificationDao) AddNotificationDeadLetter(delivery bean.NotificationDelivery) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetNotificationDeliveryItemPath(delivery.Id))
+	deadLetterDocRef := dbClient.Doc(GetNotificationDeadLetterItemPath(delivery.Id))
+
+	batch := dbClient.Batch()
+	batch.Set(docRef, delivery.GetUpdateNotificationDelivery(), firestore.MergeAll)
+	batch.Set(deadLetterDocRef, delivery.GetAddNotificationDelivery())
+	_, err := batch.Commit(context.Background())
+
+	return err
+}
+
+func (dao NotificationDao) ListNotificationDeadLetters(limit int, startAt interface{}) (t TransferObject) {
+	// notification_dead_letters
+	ListPagingObjects(GetNotificationDeadLetterPath(), &t, limit, startAt, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.OrderBy("created_at", firestore.Desc)
+	}, snapshotToNotificationDelivery)
+	return
+}
+
+func (dao NotificationDao) GetNotificationDeadLetter(deliveryId string) (t TransferObject) {
+	// notification_dead_letters/{id}
+	GetObject(GetNotificationDeadLetterItemPath(deliveryId), &t, snapshotToNotificationDelivery)
+	return
+}
+
+func (dao NotificationDao) RemoveNotificationDeadLetter(deliveryId string) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetNotificationDeadLetterItemPath(deliveryId))
+
+	_, err := docRef.Delete(context.Background())
+
+	return err
+}
+
+func GetNotificationDeliveryPath() string {
+	return "notification_deliveries"
+}
+
+func GetNotificationDeliveryItemPath(deliveryId string) string {
+	return fmt.Sprintf("%s/%s", GetNotificationDeliveryPath(), deliveryId)
+}
+
+func GetNotificationDeadLetterPath() string {
+	return "notification_dead_letters"
+}
+
+func GetNotificationDeadLetterItemPath(deliveryId string) string {
+	return fmt.Sprintf("%s/%s", GetNotificationDeadLetterPath(), deliveryId)
+}
+
+func snapshotToNotificationDelivery(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.NotificationDelivery
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
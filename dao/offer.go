@@ -52,6 +52,8 @@ func (dao OfferDao) AddOffer(offer bean.Offer, profile bean.Profile) (bean.Offer
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return offer, err
@@ -151,6 +153,10 @@ func (dao OfferDao) UpdateOffer(offer bean.Offer, updateData map[string]interfac
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	if _, ok := updateData["status"]; ok {
+		addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+	}
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -168,6 +174,8 @@ func (dao OfferDao) UpdateOfferActive(offer bean.Offer) error {
 		batch.Delete(addressMapDocRef)
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -208,6 +216,8 @@ func (dao OfferDao) UpdateOfferShaking(offer bean.Offer) error {
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -224,6 +234,8 @@ func (dao OfferDao) UpdateOfferShake(offer bean.Offer) error {
 		batch.Delete(addressMapDocRef)
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -260,6 +272,8 @@ func (dao OfferDao) UpdateOfferClose(offer bean.Offer, profile bean.Profile) err
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -293,6 +307,8 @@ func (dao OfferDao) UpdateOfferReject(offer bean.Offer, profile bean.Profile, tr
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -318,6 +334,8 @@ func (dao OfferDao) UpdateOfferCompleted(offer bean.Offer, profile bean.Profile,
 	batch.Set(trans2DocRef, trans2.GetAddTransaction(), firestore.MergeAll)
 	// batch.Delete(transferDocRef)
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOffer(offer))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
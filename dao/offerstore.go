@@ -72,6 +72,8 @@ func (dao OfferStoreDao) AddOfferStore(offer bean.OfferStore, item bean.OfferSto
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStore(offer, item))
+
 	_, err := batch.Commit(context.Background())
 
 	return offer, err
@@ -138,6 +140,8 @@ func (dao OfferStoreDao) AddOfferStoreItem(offer bean.OfferStore, item bean.Offe
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStore(offer, item))
+
 	_, err := batch.Commit(context.Background())
 
 	return item, err
@@ -264,7 +268,8 @@ func (dao OfferStoreDao) RefillBalanceOfferStoreItem(offer bean.OfferStore, item
 	return err
 }
 
-func (dao OfferStoreDao) RemoveOfferStoreItem(offer bean.OfferStore, item bean.OfferStoreItem, profile bean.Profile) error {
+// Failed store rollbacks do not notify, so they pass notify false and enqueue nothing
+func (dao OfferStoreDao) RemoveOfferStoreItem(offer bean.OfferStore, item bean.OfferStoreItem, profile bean.Profile, notify bool) error {
 	dbClient := firebase_service.FirestoreClient
 
 	profileDocRef := dbClient.Doc(GetUserPath(offer.UID))
@@ -276,12 +281,16 @@ func (dao OfferStoreDao) RemoveOfferStoreItem(offer bean.OfferStore, item bean.O
 	batch.Set(docRef, offer.GetUpdateOfferStoreChangeItem(), firestore.MergeAll)
 	batch.Set(profileDocRef, profile.GetUpdateOfferStoreProfile(), firestore.MergeAll)
 
+	if notify {
+		addOutboxEvent(batch, bean.NewOutboxEventFromOfferStore(offer, item))
+	}
+
 	_, err := batch.Commit(context.Background())
 
 	return err
 }
 
-func (dao OfferStoreDao) UpdateOfferStoreItemActive(offer bean.OfferStore, item bean.OfferStoreItem) error {
+func (dao OfferStoreDao) UpdateOfferStoreItemActive(offer bean.OfferStore, item bean.OfferStoreItem, notify bool) error {
 	dbClient := firebase_service.FirestoreClient
 
 	docRef := dbClient.Doc(GetOfferStoreItemPath(offer.Id))
@@ -295,6 +304,10 @@ func (dao OfferStoreDao) UpdateOfferStoreItemActive(offer bean.OfferStore, item
 		batch.Delete(addressMapDocRef)
 	}
 
+	if notify {
+		addOutboxEvent(batch, bean.NewOutboxEventFromOfferStore(offer, item))
+	}
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -326,6 +339,8 @@ func (dao OfferStoreDao) UpdateOfferStoreItemClosing(offer bean.OfferStore, item
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStore(offer, item))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -343,6 +358,8 @@ func (dao OfferStoreDao) UpdateOfferStoreItemClosed(offer bean.OfferStore, item
 	batch.Set(docItemRef, item.GetUpdateOfferStoreItemClosed(), firestore.MergeAll)
 	batch.Set(profileDocRef, profile.GetUpdateOfferStoreProfile(), firestore.MergeAll)
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStore(offer, item))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -422,6 +439,8 @@ func (dao OfferStoreDao) AddOfferStoreShake(offer bean.OfferStore, offerShake be
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStoreShake(offerShake, offer.Id))
+
 	_, err := batch.Commit(context.Background())
 
 	return offerShake, err
@@ -456,6 +475,10 @@ func (dao OfferStoreDao) UpdateOfferStoreShake(offerId string, offerShake bean.O
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	if _, ok := updateData["status"]; ok {
+		addOutboxEvent(batch, bean.NewOutboxEventFromOfferStoreShake(offerShake, offerId))
+	}
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -485,6 +508,8 @@ func (dao OfferStoreDao) UpdateOfferStoreShakeReject(offer bean.OfferStore, offe
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStoreShake(offerShake, offer.Id))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -514,6 +539,8 @@ func (dao OfferStoreDao) UpdateOfferStoreShakeComplete(offer bean.OfferStore, of
 		}.GetAddOfferOnChainActionTracking())
 	}
 
+	addOutboxEvent(batch, bean.NewOutboxEventFromOfferStoreShake(offerShake, offer.Id))
+
 	_, err := batch.Commit(context.Background())
 
 	return err
@@ -578,6 +605,7 @@ func (dao OfferStoreDao) UpdateOfferStoreShakeBalance(offer bean.OfferStore, ite
 
 		offer.ItemSnapshots[item.Currency] = *item
 		err = tx.Set(offerStoreRef, offer.GetUpdateOfferStoreChangeSnapshot(), firestore.MergeAll)
+		// No outbox event, the shake write that always follows enqueues it
 		return err
 
 	})
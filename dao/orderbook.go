@@ -0,0 +1,130 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"encoding/json"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/service/cache"
+)
+
+type OrderBookDao struct {
+}
+
+func (dao OrderBookDao) UpdateOfferEntry(offer bean.Offer) error {
+	entry := bean.NewOrderBookEntryFromOffer(offer)
+	if offer.Status == bean.OFFER_STATUS_ACTIVE {
+		return dao.setEntry(entry)
+	}
+	return dao.removeEntry(entry)
+}
+
+func (dao OrderBookDao) UpdateOfferStoreEntries(offer bean.OfferStore, item bean.OfferStoreItem) error {
+	for _, offerType := range []string{bean.OFFER_TYPE_SELL, bean.OFFER_TYPE_BUY} {
+		entry := bean.NewOrderBookEntryFromOfferStore(offer, item, offerType)
+		var err error
+		if offer.Status == bean.OFFER_STORE_STATUS_ACTIVE && item.Status == bean.OFFER_STORE_ITEM_STATUS_ACTIVE &&
+			common.StringToDecimal(entry.Amount).GreaterThan(common.Zero) {
+			err = dao.setEntry(entry)
+		} else {
+			err = dao.removeEntry(entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dao OrderBookDao) ListEntries(currency string) ([]bean.OrderBookEntry, error) {
+	entries := make([]bean.OrderBookEntry, 0)
+	values, err := cache.RedisClient.HGetAll(GetOrderBookCacheKey(currency)).Result()
+	if err != nil {
+		return entries, err
+	}
+	for _, value := range values {
+		var entry bean.OrderBookEntry
+		if json.Unmarshal([]byte(value), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (dao OrderBookDao) RebuildEntries() (count int, err error) {
+	ClearCache(GetOrderBookCacheKey("*"))
+
+	offerStores, err := listActiveOfferStores()
+	if err != nil {
+		return
+	}
+	for _, offer := range offerStores {
+		for _, item := range offer.ItemSnapshots {
+			if err = dao.UpdateOfferStoreEntries(offer, item); err != nil {
+				return
+			}
+			count += 1
+		}
+	}
+
+	offers, err := listActiveOffers()
+	if err != nil {
+		return
+	}
+	for _, offer := range offers {
+		if err = dao.UpdateOfferEntry(offer); err != nil {
+			return
+		}
+		count += 1
+	}
+
+	return
+}
+
+func (dao OrderBookDao) setEntry(entry bean.OrderBookEntry) error {
+	b, _ := json.Marshal(&entry)
+	return cache.RedisClient.HSet(GetOrderBookCacheKey(entry.Currency), entry.Id, string(b)).Err()
+}
+
+func (dao OrderBookDao) removeEntry(entry bean.OrderBookEntry) error {
+	return cache.RedisClient.HDel(GetOrderBookCacheKey(entry.Currency), entry.Id).Err()
+}
+
+func listActiveOfferStores() ([]bean.OfferStore, error) {
+	offers := make([]bean.OfferStore, 0)
+	var t TransferObject
+	ListObjects(GetOfferStorePath(), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("status", "==", bean.OFFER_STORE_STATUS_ACTIVE)
+	}, snapshotToOfferStore)
+	if t.HasError() {
+		return offers, t.Error
+	}
+	for _, obj := range t.Objects {
+		offers = append(offers, obj.(bean.OfferStore))
+	}
+
+	return offers, nil
+}
+
+func listActiveOffers() ([]bean.Offer, error) {
+	offers := make([]bean.Offer, 0)
+	var t TransferObject
+	ListObjects(GetOfferPath(), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("status", "==", bean.OFFER_STATUS_ACTIVE)
+	}, snapshotToOffer)
+	if t.HasError() {
+		return offers, t.Error
+	}
+	for _, obj := range t.Objects {
+		offers = append(offers, obj.(bean.Offer))
+	}
+
+	return offers, nil
+}
+
+func GetOrderBookCacheKey(currency string) string {
+	return fmt.Sprintf("handshake_exchange.order_books.%s", currency)
+}
@@ -0,0 +1,112 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"google.golang.org/api/iterator"
+	"time"
+)
+
+type OutboxDao struct {
+}
+
+// Status changing writes enqueue their event in the same batch, so the event exists if and only if the write does.
+// Only writes followed by an inline notification enqueue, the event is the relay's copy of that notification
+func addOutboxEvent(batch *firestore.WriteBatch, event bean.OutboxEvent) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetOutboxEventPath()).NewDoc()
+	event.Id = docRef.ID
+	batch.Set(docRef, event.GetAddOutboxEvent())
+}
+
+func addOutboxEventTx(tx *firestore.Transaction, event bean.OutboxEvent) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetOutboxEventPath()).NewDoc()
+	event.Id = docRef.ID
+	return tx.Set(docRef, event.GetAddOutboxEvent())
+}
+
+func (dao OutboxDao) ListPendingOutboxEvents(createdBefore time.Time, limit int) ([]bean.OutboxEvent, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// outbox_events
+	iter := dbClient.Collection(GetOutboxEventPath()).
+		Where("status", "==", bean.OUTBOX_EVENT_STATUS_PENDING).
+		Where("created_at", "<", createdBefore).
+		OrderBy("created_at", firestore.Asc).
+		Limit(limit).
+		Documents(context.Background())
+
+	events := make([]bean.OutboxEvent, 0)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, snapshotToOutboxEvent(doc).(bean.OutboxEvent))
+	}
+
+	return events, nil
+}
+
+func (dao OutboxDao) UpdateOutboxEventAttempt(event bean.OutboxEvent) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetOutboxEventItemPath(event.Id))
+
+	_, err := docRef.Set(context.Background(), event.GetUpdateAttempt(), firestore.MergeAll)
+
+	return err
+}
+
+// One notification acknowledges one event, the oldest pending one written for the same change
+func (dao OutboxDao) AckOutboxEvent(eventKey string) error {
+	dbClient := firebase_service.FirestoreClient
+	query := dbClient.Collection(GetOutboxEventPath()).
+		Where("key", "==", eventKey).
+		Where("status", "==", bean.OUTBOX_EVENT_STATUS_PENDING).
+		OrderBy("created_at", firestore.Asc).
+		Limit(1)
+
+	return dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if err = tx.Delete(doc.Ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (dao OutboxDao) RemoveOutboxEvent(eventId string) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetOutboxEventItemPath(eventId))
+
+	_, err := docRef.Delete(context.Background())
+
+	return err
+}
+
+func GetOutboxEventPath() string {
+	return "outbox_events"
+}
+
+func GetOutboxEventItemPath(eventId string) string {
+	return fmt.Sprintf("%s/%s", GetOutboxEventPath(), eventId)
+}
+
+func snapshotToOutboxEvent(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.OutboxEvent
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"github.com/ninjadotorg/handshake-exchange/service/cache"
+	"time"
+)
+
+// Seen ids only matter until the job finishes, a stale job should not keep them forever
+const SEARCH_REINDEX_SEEN_TTL = 7 * 24 * time.Hour
+
+type SearchReindexDao struct {
+}
+
+func (dao SearchReindexDao) GetLatestSearchReindexJob() (t TransferObject) {
+	// search_reindex_jobs
+	ListObjects(GetSearchReindexJobPath(), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.OrderBy("created_at", firestore.Desc).Limit(1)
+	}, snapshotToSearchReindexJob)
+	if t.Error == nil {
+		t.Found = len(t.Objects) > 0
+		if t.Found {
+			t.Object = t.Objects[0]
+		}
+	}
+
+	return
+}
+
+func (dao SearchReindexDao) AddSearchReindexJob(job bean.SearchReindexJob) (bean.SearchReindexJob, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetSearchReindexJobPath()).NewDoc()
+	job.Id = docRef.ID
+
+	_, err := docRef.Set(context.Background(), job.GetAddSearchReindexJob())
+
+	return job, err
+}
+
+func (dao SearchReindexDao) UpdateSearchReindexJob(job bean.SearchReindexJob) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetSearchReindexJobItemPath(job.Id))
+
+	_, err := docRef.Set(context.Background(), job.GetUpdateSearchReindexJob(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao SearchReindexDao) ListOfferPage(cursor string, limit int) ([]bean.Offer, error) {
+	offers := make([]bean.Offer, 0)
+	docs, err := listPageByDocumentId(GetOfferPath(), cursor, limit)
+	if err != nil {
+		return offers, err
+	}
+	for _, doc := range docs {
+		offers = append(offers, snapshotToOffer(doc).(bean.Offer))
+	}
+
+	return offers, nil
+}
+
+func (dao SearchReindexDao) ListOfferStorePage(cursor string, limit int) ([]bean.OfferStore, error) {
+	offers := make([]bean.OfferStore, 0)
+	docs, err := listPageByDocumentId(GetOfferStorePath(), cursor, limit)
+	if err != nil {
+		return offers, err
+	}
+	for _, doc := range docs {
+		offer := snapshotToOfferStore(doc).(bean.OfferStore)
+		offer.Id = doc.Ref.ID
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+func (dao SearchReindexDao) AddSeenDocumentIds(jobId string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	key := GetSearchReindexSeenCacheKey(jobId)
+	members := make([]interface{}, 0)
+	for _, id := range ids {
+		members = append(members, id)
+	}
+	err := cache.RedisClient.SAdd(key, members...).Err()
+	if err == nil {
+		err = cache.RedisClient.Expire(key, SEARCH_REINDEX_SEEN_TTL).Err()
+	}
+
+	return err
+}
+
+func (dao SearchReindexDao) ListSeenDocumentIds(jobId string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	ids, err := cache.RedisClient.SMembers(GetSearchReindexSeenCacheKey(jobId)).Result()
+	for _, id := range ids {
+		seen[id] = true
+	}
+
+	return seen, err
+}
+
+func (dao SearchReindexDao) RemoveSeenDocumentIds(jobId string) error {
+	return cache.RedisClient.Del(GetSearchReindexSeenCacheKey(jobId)).Err()
+}
+
+func listPageByDocumentId(collectionPath string, cursor string, limit int) ([]*firestore.DocumentSnapshot, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	query := dbClient.Collection(collectionPath).OrderBy(firestore.DocumentID, firestore.Asc)
+	if cursor != "" {
+		query = query.StartAfter(cursor)
+	}
+
+	return query.Limit(limit).Documents(context.Background()).GetAll()
+}
+
+func GetSearchReindexJobPath() string {
+	return "search_reindex_jobs"
+}
+
+func GetSearchReindexJobItemPath(jobId string) string {
+	return fmt.Sprintf("%s/%s", GetSearchReindexJobPath(), jobId)
+}
+
+func GetSearchReindexSeenCacheKey(jobId string) string {
+	return fmt.Sprintf("handshake_exchange.search_reindex.%s", jobId)
+}
+
+func snapshotToSearchReindexJob(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.SearchReindexJob
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
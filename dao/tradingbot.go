@@ -0,0 +1,150 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"google.golang.org/api/iterator"
+)
+
+type TradingBotDao struct {
+}
+
+func (dao TradingBotDao) ListTradingBots() ([]bean.TradingBot, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// trading_bots
+	iter := dbClient.Collection(GetTradingBotPath()).Documents(context.Background())
+	bots := make([]bean.TradingBot, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return bots, err
+		}
+		bots = append(bots, snapshotToTradingBot(doc).(bean.TradingBot))
+	}
+
+	return bots, nil
+}
+
+func (dao TradingBotDao) GetTradingBot(botId string) (t TransferObject) {
+	// trading_bots/{id}
+	GetObject(GetTradingBotItemPath(botId), &t, snapshotToTradingBot)
+	return
+}
+
+func (dao TradingBotDao) AddTradingBot(bot bean.TradingBot) (bean.TradingBot, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetTradingBotPath()).NewDoc()
+	bot.Id = docRef.ID
+
+	_, err := docRef.Set(context.Background(), bot.GetAddTradingBot())
+
+	return bot, err
+}
+
+func (dao TradingBotDao) UpdateTradingBot(bot bean.TradingBot) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetTradingBotItemPath(bot.Id))
+
+	_, err := docRef.Set(context.Background(), bot.GetUpdateTradingBot(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao TradingBotDao) UpdateTradingBotStatus(bot bean.TradingBot) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetTradingBotItemPath(bot.Id))
+
+	_, err := docRef.Set(context.Background(), bot.GetUpdateStatus(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao TradingBotDao) RemoveTradingBot(bot bean.TradingBot) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetTradingBotItemPath(bot.Id))
+
+	_, err := docRef.Delete(context.Background())
+
+	return err
+}
+
+func (dao TradingBotDao) AddTradingBotPendingOffer(bot bean.TradingBot, offer bean.Offer) error {
+	dbClient := firebase_service.FirestoreClient
+
+	pendingOffer := bean.TradingBotPendingOffer{
+		Offer:    offer.Id,
+		OfferRef: GetOfferItemPath(offer.Id),
+	}
+	docRef := dbClient.Doc(GetTradingBotItemPath(bot.Id))
+	pendingDocRef := dbClient.Doc(GetTradingBotPendingOfferItemPath(bot.Id, offer.Id))
+
+	batch := dbClient.Batch()
+	batch.Set(pendingDocRef, pendingOffer.GetAddTradingBotPendingOffer())
+	batch.Set(docRef, bot.GetUpdateStatus(), firestore.MergeAll)
+	_, err := batch.Commit(context.Background())
+
+	return err
+}
+
+func (dao TradingBotDao) ListTradingBotPendingOffers(botId string) ([]bean.TradingBotPendingOffer, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// trading_bots/{id}/pending_offers
+	iter := dbClient.Collection(GetTradingBotPendingOfferPath(botId)).OrderBy("created_at", firestore.Asc).Documents(context.Background())
+	offers := make([]bean.TradingBotPendingOffer, 0)
+
+	for {
+		var offer bean.TradingBotPendingOffer
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return offers, err
+		}
+		doc.DataTo(&offer)
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+func (dao TradingBotDao) RemoveTradingBotPendingOffer(botId string, offerId string) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetTradingBotPendingOfferItemPath(botId, offerId))
+
+	_, err := docRef.Delete(context.Background())
+
+	return err
+}
+
+func GetTradingBotPath() string {
+	return "trading_bots"
+}
+
+func GetTradingBotItemPath(botId string) string {
+	return fmt.Sprintf("%s/%s", GetTradingBotPath(), botId)
+}
+
+func GetTradingBotPendingOfferPath(botId string) string {
+	return fmt.Sprintf("%s/pending_offers", GetTradingBotItemPath(botId))
+}
+
+func GetTradingBotPendingOfferItemPath(botId string, offerId string) string {
+	return fmt.Sprintf("%s/%s", GetTradingBotPendingOfferPath(botId), offerId)
+}
+
+func snapshotToTradingBot(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.TradingBot
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
@@ -13,18 +13,29 @@ import (
 type UserDaoInterface interface {
 	GetProfile(userId string) (t TransferObject)
 	AddProfile(profile bean.Profile) error
-	UpdateProfileCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit) error
+	ListCreditCards(userId string) (t TransferObject)
+	GetCreditCard(userId string, cardId string) (t TransferObject)
+	AddCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit, resetTrack bool) (bean.UserCreditCard, error)
+	MigrateProfileCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit, resetTrack bool) (bean.UserCreditCard, error)
+	RemoveCreditCard(userId string, creditCard bean.UserCreditCard, newDefault *bean.UserCreditCard) error
+	SetDefaultCreditCard(userId string, creditCard bean.UserCreditCard, previousId string) error
 	UpdateProfileOfferRejectLock(profile bean.Profile) error
 	UpdateUserCCLimitAmount(userId string, token string, amount decimal.Decimal) error
 	UpdateUserCCLimitTracks() (userIds []string, t TransferObject)
 	GetCCLimit(userId string, token string) (t TransferObject)
+	ListCCLimits(userId string) (t TransferObject)
 	GetUserCCLimitEndTracks() (t TransferObject)
-	UpgradeCCLimitLevel(userId string, token string, limit bean.UserCreditCardLimit) error
+	UpgradeCCLimitLevel(userId string, limitIds []string, limit bean.UserCreditCardLimit) error
+	UpdateProfileNotificationPreferences(userId string, preferences bean.NotificationPreferences) error
+	UpdateProfileLanguage(profile bean.Profile) error
 }
 
 type UserDao struct {
 }
 
+// Wallet id of the card a profile had before the wallet
+const PROFILE_CREDIT_CARD_ID = "profile"
+
 func (dao UserDao) GetProfile(userId string) (t TransferObject) {
 	// users/{uid}
 	GetObject(GetUserPath(userId), &t, func(snapshot *firestore.DocumentSnapshot) interface{} {
@@ -49,23 +60,131 @@ func (dao UserDao) AddProfile(profile bean.Profile) error {
 	return err
 }
 
-func (dao UserDao) UpdateProfileCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit) error {
+func (dao UserDao) ListCreditCards(userId string) (t TransferObject) {
+	// users/{uid}/credit_cards
+	ListObjects(GetUserCreditCardPath(userId), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		query := collRef.OrderBy("created_at", firestore.Asc)
+		return query
+	}, snapshotToUserCreditCard)
+
+	return
+}
+
+func (dao UserDao) GetCreditCard(userId string, cardId string) (t TransferObject) {
+	// users/{uid}/credit_cards/{id}
+	GetObject(GetUserCreditCardItemPath(userId, cardId), &t, snapshotToUserCreditCard)
+	return
+}
+
+// The card limit is keyed by the card id, the track only restarts for the first card of the user
+func (dao UserDao) AddCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit, resetTrack bool) (bean.UserCreditCard, error) {
 	dbClient := firebase_service.FirestoreClient
 
 	batch := dbClient.Batch()
 
-	profileRef := dbClient.Collection("users").Doc(userId)
-	userCCLimitRef := dbClient.Doc(GetUserCCLimitItemPath(userId, creditCard.Token))
-	userCCLimitTrackRef := dbClient.Doc(GetUserCCLimitTrackItemPath(userId))
+	docRef := dbClient.Collection(GetUserCreditCardPath(userId)).NewDoc()
+	creditCard.Id = docRef.ID
+	userCCLimitRef := dbClient.Doc(GetUserCCLimitItemPath(userId, creditCard.Id))
 
-	batch.Set(profileRef, creditCard.GetUpdateProfileCreditCard(), firestore.MergeAll)
+	batch.Set(docRef, creditCard.GetAddUserCreditCard())
 	batch.Set(userCCLimitRef, userCCLimit.GetAddUserCreditCardLimit(), firestore.MergeAll)
-	batch.Set(userCCLimitTrackRef, bean.UserCreditCardLimitTrack{
-		UID:      userId,
-		Level:    userCCLimit.Level,
-		Duration: userCCLimit.Duration,
-		Left:     userCCLimit.Duration,
-	}.GetAddUserCreditCardLimitTrack())
+	if creditCard.Default {
+		profileRef := dbClient.Doc(GetUserPath(userId))
+		batch.Set(profileRef, creditCard.GetUpdateProfileCreditCard(), firestore.MergeAll)
+	}
+	if resetTrack {
+		userCCLimitTrackRef := dbClient.Doc(GetUserCCLimitTrackItemPath(userId))
+		batch.Set(userCCLimitTrackRef, bean.UserCreditCardLimitTrack{
+			UID:      userId,
+			Level:    userCCLimit.Level,
+			Duration: userCCLimit.Duration,
+			Left:     userCCLimit.Duration,
+		}.GetAddUserCreditCardLimitTrack())
+	}
+
+	_, err := batch.Commit(context.Background())
+
+	return creditCard, err
+}
+
+// The profile card becomes the wallet card with a fixed id, so it is only written once however many reads race on it
+func (dao UserDao) MigrateProfileCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit, resetTrack bool) (bean.UserCreditCard, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	creditCard.Id = PROFILE_CREDIT_CARD_ID
+	docRef := dbClient.Doc(GetUserCreditCardItemPath(userId, creditCard.Id))
+	userCCLimitRef := dbClient.Doc(GetUserCCLimitItemPath(userId, creditCard.Id))
+	profileRef := dbClient.Doc(GetUserPath(userId))
+	userCCLimitTrackRef := dbClient.Doc(GetUserCCLimitTrackItemPath(userId))
+
+	err := dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err == nil {
+			creditCard = snapshotToUserCreditCard(doc).(bean.UserCreditCard)
+			return nil
+		}
+		if err = common.CheckNotFound(err); err != nil {
+			return err
+		}
+
+		if err = tx.Set(docRef, creditCard.GetAddUserCreditCard()); err != nil {
+			return err
+		}
+		if err = tx.Set(userCCLimitRef, userCCLimit.GetAddUserCreditCardLimit(), firestore.MergeAll); err != nil {
+			return err
+		}
+		if err = tx.Set(profileRef, creditCard.GetUpdateProfileCreditCard(), firestore.MergeAll); err != nil {
+			return err
+		}
+		if resetTrack {
+			return tx.Set(userCCLimitTrackRef, bean.UserCreditCardLimitTrack{
+				UID:      userId,
+				Level:    userCCLimit.Level,
+				Duration: userCCLimit.Duration,
+				Left:     userCCLimit.Duration,
+			}.GetAddUserCreditCardLimitTrack())
+		}
+		return nil
+	})
+
+	return creditCard, err
+}
+
+// The card limit stays, what was spent on the card still counts until the limit period ends.
+// The profile only changes when the default card is removed
+func (dao UserDao) RemoveCreditCard(userId string, creditCard bean.UserCreditCard, newDefault *bean.UserCreditCard) error {
+	dbClient := firebase_service.FirestoreClient
+
+	batch := dbClient.Batch()
+
+	batch.Delete(dbClient.Doc(GetUserCreditCardItemPath(userId, creditCard.Id)))
+	profileRef := dbClient.Doc(GetUserPath(userId))
+	if newDefault != nil {
+		newDefault.Default = true
+		batch.Set(dbClient.Doc(GetUserCreditCardItemPath(userId, newDefault.Id)), newDefault.GetUpdateDefault(), firestore.MergeAll)
+		batch.Set(profileRef, newDefault.GetUpdateProfileCreditCard(), firestore.MergeAll)
+	} else if creditCard.Default {
+		batch.Set(profileRef, map[string]interface{}{
+			"credit_card": firestore.Delete,
+		}, firestore.MergeAll)
+	}
+
+	_, err := batch.Commit(context.Background())
+
+	return err
+}
+
+func (dao UserDao) SetDefaultCreditCard(userId string, creditCard bean.UserCreditCard, previousId string) error {
+	dbClient := firebase_service.FirestoreClient
+
+	batch := dbClient.Batch()
+
+	if previousId != "" && previousId != creditCard.Id {
+		batch.Set(dbClient.Doc(GetUserCreditCardItemPath(userId, previousId)), bean.UserCreditCard{}.GetUpdateDefault(), firestore.MergeAll)
+	}
+	creditCard.Default = true
+	batch.Set(dbClient.Doc(GetUserCreditCardItemPath(userId, creditCard.Id)), creditCard.GetUpdateDefault(), firestore.MergeAll)
+	batch.Set(dbClient.Doc(GetUserPath(userId)), creditCard.GetUpdateProfileCreditCard(), firestore.MergeAll)
 
 	_, err := batch.Commit(context.Background())
 
@@ -107,28 +226,53 @@ func (dao UserDao) UpdateProfileOfferRejectLock(profile bean.Profile) error {
 	return err
 }
 
+func (dao UserDao) UpdateProfileNotificationPreferences(userId string, preferences bean.NotificationPreferences) error {
+	dbClient := firebase_service.FirestoreClient
+
+	profileRef := dbClient.Doc(GetUserPath(userId))
+	_, err := profileRef.Set(context.Background(), preferences.GetUpdateNotificationPreferences(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao UserDao) UpdateProfileLanguage(profile bean.Profile) error {
+	dbClient := firebase_service.FirestoreClient
+
+	profileRef := dbClient.Doc(GetUserPath(profile.UserId))
+	_, err := profileRef.Set(context.Background(), profile.GetUpdateLanguage(), firestore.MergeAll)
+
+	return err
+}
+
 func (dao UserDao) GetCCLimit(userId string, token string) (t TransferObject) {
 	// users/{uid}/cc_limit/{token}
 	GetObject(GetUserCCLimitItemPath(userId, token), &t, snapshotUserCCLimit)
 	return
 }
 
-func (dao UserDao) UpgradeCCLimitLevel(userId string, token string, limit bean.UserCreditCardLimit) error {
+func (dao UserDao) ListCCLimits(userId string) (t TransferObject) {
+	// users/{uid}/cc_limit
+	ListObjects(GetUserCCLimitPath(userId), &t, nil, snapshotUserCCLimit)
+	return
+}
+
+func (dao UserDao) UpgradeCCLimitLevel(userId string, limitIds []string, limit bean.UserCreditCardLimit) error {
 	dbClient := firebase_service.FirestoreClient
 	batch := dbClient.Batch()
 
-	_, err := batch.Commit(context.Background())
-	docRef := dbClient.Doc(GetUserCCLimitItemPath(userId, token))
 	trackDocRef := dbClient.Doc(GetUserCCLimitTrackItemPath(userId))
 
-	batch.Set(docRef, limit.GetUpdateLevel(), firestore.MergeAll)
+	for _, limitId := range limitIds {
+		docRef := dbClient.Doc(GetUserCCLimitItemPath(userId, limitId))
+		batch.Set(docRef, limit.GetUpdateLevel(), firestore.MergeAll)
+	}
 	batch.Set(trackDocRef, bean.UserCreditCardLimitTrack{
 		UID:      userId,
 		Level:    limit.Level,
 		Duration: limit.Duration,
 		Left:     limit.Duration,
 	}.GetAddUserCreditCardLimitTrack())
-	batch.Commit(context.Background())
+	_, err := batch.Commit(context.Background())
 
 	return err
 }
@@ -169,6 +313,18 @@ func GetUserPath(userId string) string {
 	return fmt.Sprintf("users/%s", userId)
 }
 
+func GetUserCreditCardPath(userId string) string {
+	return fmt.Sprintf("users/%s/credit_cards", userId)
+}
+
+func GetUserCreditCardItemPath(userId string, cardId string) string {
+	return fmt.Sprintf("users/%s/credit_cards/%s", userId, cardId)
+}
+
+func GetUserCCLimitPath(userId string) string {
+	return fmt.Sprintf("users/%s/cc_limit", userId)
+}
+
 func GetUserCCLimitItemPath(userId string, token string) string {
 	return fmt.Sprintf("users/%s/cc_limit/%s", userId, token)
 }
@@ -184,6 +340,13 @@ func GetUserCCLimitTrackItemPath(userId string) string {
 func snapshotUserCCLimit(snapshot *firestore.DocumentSnapshot) interface{} {
 	var obj bean.UserCreditCardLimit
 	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
+
+func snapshotToUserCreditCard(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.UserCreditCard
+	snapshot.DataTo(&obj)
 	return obj
 }
 
@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
+	"time"
+)
+
+type WebhookDao struct {
+}
+
+func (dao WebhookDao) AddWebhookSubscription(subscription bean.WebhookSubscription) (bean.WebhookSubscription, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetWebhookSubscriptionPath()).NewDoc()
+	subscription.Id = docRef.ID
+	subscription.Active = true
+
+	_, err := docRef.Set(context.Background(), subscription.GetAddWebhookSubscription())
+
+	return subscription, err
+}
+
+func (dao WebhookDao) GetWebhookSubscription(subscriptionId string) (t TransferObject) {
+	// webhook_subscriptions/{id}
+	GetObject(GetWebhookSubscriptionItemPath(subscriptionId), &t, snapshotToWebhookSubscription)
+	return
+}
+
+func (dao WebhookDao) ListWebhookSubscriptions() (t TransferObject) {
+	// webhook_subscriptions
+	ListObjects(GetWebhookSubscriptionPath(), &t, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.Where("active", "==", true)
+	}, snapshotToWebhookSubscription)
+	return
+}
+
+func (dao WebhookDao) RemoveWebhookSubscription(subscriptionId string) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetWebhookSubscriptionItemPath(subscriptionId))
+
+	// Keep the document, the delivery log hangs off it
+	_, err := docRef.Set(context.Background(), map[string]interface{}{
+		"active":     false,
+		"updated_at": firestore.ServerTimestamp,
+	}, firestore.MergeAll)
+
+	return err
+}
+
+func (dao WebhookDao) AddWebhookDelivery(delivery bean.WebhookDelivery) (bean.WebhookDelivery, error) {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Collection(GetWebhookDeliveryPath(delivery.SubscriptionId)).NewDoc()
+	delivery.Id = docRef.ID
+
+	_, err := docRef.Set(context.Background(), delivery.GetAddWebhookDelivery())
+
+	return delivery, err
+}
+
+func (dao WebhookDao) UpdateWebhookDelivery(delivery bean.WebhookDelivery) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetWebhookDeliveryItemPath(delivery.SubscriptionId, delivery.Id))
+
+	_, err := docRef.Set(context.Background(), delivery.GetUpdateWebhookDelivery(), firestore.MergeAll)
+
+	return err
+}
+
+func (dao WebhookDao) ListWebhookDeliveries(subscriptionId string, limit int, startAt interface{}) (t TransferObject) {
+	// webhook_subscriptions/{id}/deliveries
+	ListPagingObjects(GetWebhookDeliveryPath(subscriptionId), &t, limit, startAt, func(collRef *firestore.CollectionRef) firestore.Query {
+		return collRef.OrderBy("created_at", firestore.Desc)
+	}, snapshotToWebhookDelivery)
+	return
+}
+
+func (dao WebhookDao) ListDueWebhookDeliveries(subscriptionId string, dueBefore time.Time, limit int) ([]bean.WebhookDelivery, error) {
+	dbClient := firebase_service.FirestoreClient
+
+	// webhook_subscriptions/{id}/deliveries
+	docs, err := dbClient.Collection(GetWebhookDeliveryPath(subscriptionId)).
+		Where("status", "==", bean.WEBHOOK_DELIVERY_STATUS_RETRYING).
+		Where("next_attempt_at", "<=", dueBefore).
+		OrderBy("next_attempt_at", firestore.Asc).
+		Limit(limit).
+		Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]bean.WebhookDelivery, 0)
+	for _, doc := range docs {
+		deliveries = append(deliveries, snapshotToWebhookDelivery(doc).(bean.WebhookDelivery))
+	}
+
+	return deliveries, nil
+}
+
+// Pushes the next attempt out to leaseUntil, only one retry run gets a due delivery
+func (dao WebhookDao) ClaimWebhookDeliveryRetry(delivery bean.WebhookDelivery, leaseUntil time.Time) error {
+	dbClient := firebase_service.FirestoreClient
+	docRef := dbClient.Doc(GetWebhookDeliveryItemPath(delivery.SubscriptionId, delivery.Id))
+
+	return dbClient.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		var current bean.WebhookDelivery
+		if err = doc.DataTo(&current); err != nil {
+			return err
+		}
+		if current.Status != bean.WEBHOOK_DELIVERY_STATUS_RETRYING || current.NextAttemptAt.After(time.Now().UTC()) {
+			return errors.New("Webhook delivery is not due")
+		}
+		return tx.Set(docRef, map[string]interface{}{
+			"next_attempt_at": leaseUntil,
+		}, firestore.MergeAll)
+	})
+}
+
+func GetWebhookSubscriptionPath() string {
+	return "webhook_subscriptions"
+}
+
+func GetWebhookSubscriptionItemPath(subscriptionId string) string {
+	return fmt.Sprintf("%s/%s", GetWebhookSubscriptionPath(), subscriptionId)
+}
+
+func GetWebhookDeliveryPath(subscriptionId string) string {
+	return fmt.Sprintf("%s/deliveries", GetWebhookSubscriptionItemPath(subscriptionId))
+}
+
+func GetWebhookDeliveryItemPath(subscriptionId string, deliveryId string) string {
+	return fmt.Sprintf("%s/%s", GetWebhookDeliveryPath(subscriptionId), deliveryId)
+}
+
+func snapshotToWebhookSubscription(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.WebhookSubscription
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
+
+func snapshotToWebhookDelivery(snapshot *firestore.DocumentSnapshot) interface{} {
+	var obj bean.WebhookDelivery
+	snapshot.DataTo(&obj)
+	obj.Id = snapshot.Ref.ID
+	return obj
+}
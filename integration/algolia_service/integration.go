@@ -35,13 +35,17 @@ func AddObject(uri string, body interface{}) (*grequests.Response, error) {
 }
 
 func UpdateObject(uri string, body interface{}) (*grequests.Response, error) {
+	arrBody := make([]interface{}, 1)
+	arrBody[0] = body
+
+	return UpdateObjects(uri, arrBody)
+}
+
+func UpdateObjects(uri string, arrBody []interface{}) (*grequests.Response, error) {
 	host := os.Getenv("ALGOLIA_SERVICE_URL")
 	url := fmt.Sprintf("%s/objects", host)
 
 	bodyStr := ""
-	arrBody := make([]interface{}, 1)
-	arrBody[0] = body
-
 	b, errBody := json.Marshal(&arrBody)
 	if errBody != nil {
 		return nil, errBody
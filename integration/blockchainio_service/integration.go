@@ -9,6 +9,7 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/shopspring/decimal"
 	"math/big"
+	"net/url"
 	"os"
 )
 
@@ -85,6 +86,28 @@ func (c *BlockChainIOClient) SendTransaction(address string, amount decimal.Deci
 	return response.TxHash, err
 }
 
+func (c *BlockChainIOClient) SendManyTransaction(recipients map[string]decimal.Decimal) (string, error) {
+	c.initialize()
+
+	// recipients={"address": satoshi, ...}
+	satoshiRecipients := map[string]int64{}
+	for address, amount := range recipients {
+		satoshiRecipients[address] = amount.Mul(BTC_IN_SATOSHI).IntPart()
+	}
+	b, err := json.Marshal(&satoshiRecipients)
+	if err != nil {
+		return "", err
+	}
+
+	var response bean.BlockChainIoPayment
+	resp, err := c.post(fmt.Sprintf("/merchant/%s/sendmany?password=%s&api_code=%s&recipients=%s", c.guid, c.apiPassword, c.apiKey, url.QueryEscape(string(b))))
+	if err == nil {
+		resp.JSON(&response)
+	}
+
+	return response.TxHash, err
+}
+
 func (c *BlockChainIOClient) GenerateAddress(offerId string) (string, error) {
 	c.initialize()
 
@@ -2,15 +2,22 @@ package checkout_service
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/levigross/grequests"
 	"github.com/ninjadotorg/handshake-exchange/api_error"
 	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
 	"github.com/shopspring/decimal"
 	"os"
+	"strings"
 )
 
+const customerEmailFormat = "user.%s@shake.ninja"
+
 type CheckoutClient struct {
 	url       string
 	apiKey    string
@@ -35,7 +42,16 @@ func (c CheckoutClient) buildHeader() map[string]string {
 
 func (c CheckoutClient) Post(uri string, body interface{}) (*grequests.Response, error) {
 	c.Initialize()
+	return c.post(uri, c.apiSecret, body)
+}
+
+// Card tokens are issued against the public key, everything else uses the secret one
+func (c CheckoutClient) PostPublic(uri string, body interface{}) (*grequests.Response, error) {
+	c.Initialize()
+	return c.post(uri, c.apiKey, body)
+}
 
+func (c CheckoutClient) post(uri string, authorization string, body interface{}) (*grequests.Response, error) {
 	url := c.url + uri
 
 	bodyStr := ""
@@ -49,6 +65,7 @@ func (c CheckoutClient) Post(uri string, body interface{}) (*grequests.Response,
 	r := bytes.NewReader([]byte(bodyStr))
 
 	headers := c.buildHeader()
+	headers["Authorization"] = authorization
 	ro := &grequests.RequestOptions{Headers: headers, RequestBody: r}
 	resp, err := grequests.Post(url, ro)
 
@@ -59,17 +76,49 @@ func (c CheckoutClient) Post(uri string, body interface{}) (*grequests.Response,
 	return resp, err
 }
 
+func (c CheckoutClient) Get(uri string) (*grequests.Response, error) {
+	c.Initialize()
+
+	url := c.url + uri
+
+	headers := c.buildHeader()
+	ro := &grequests.RequestOptions{Headers: headers}
+	resp, err := grequests.Get(url, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Ok != true {
+		return nil, api_error.NewErrorCustom(api_error.ExternalApiFailed, resp.String(), nil)
+	}
+
+	return resp, err
+}
+
+// With CHECKOUT_3DS_ENABLED the issuer decides, enrolled cards get a redirect and the rest fall back to a normal charge
+func setupAuthentication(request *bean.CheckOutCardIdPaymentRequest) {
+	if os.Getenv("CHECKOUT_3DS_ENABLED") != "true" {
+		return
+	}
+	frontendHost := os.Getenv("FRONTEND_HOST")
+	request.ChargeMode = bean.CHECKOUT_CHARGE_MODE_3D
+	request.AttemptN3D = true
+	request.SuccessUrl = fmt.Sprintf("%s/instant-buy/authenticated", frontendHost)
+	request.FailUrl = fmt.Sprintf("%s/instant-buy/authentication-failed", frontendHost)
+}
+
 func ChargeCardToken(userId string, cardToken string, amount decimal.Decimal, statement string, description string) (bean.CheckoutCardPaymentResponse, error) {
 	client := CheckoutClient{}
 	cardPaymentRequest := bean.CheckOutCardIdPaymentRequest{
 		CardToken:   cardToken,
-		Email:       fmt.Sprintf("user.%s@shake.ninja", userId),
+		Email:       CustomerEmail(userId),
 		Currency:    bean.USD.Code,
 		Value:       amount.Mul(decimal.NewFromFloat(100).Round(0)).IntPart(),
 		AutoCapture: "n",
 		Description: description,
 		// Descriptor: statement,
 	}
+	setupAuthentication(&cardPaymentRequest)
 	var response bean.CheckoutCardPaymentResponse
 	resp, err := client.Post("/v2/charges/token", cardPaymentRequest)
 
@@ -84,13 +133,14 @@ func ChargeCardId(userId string, token string, amount decimal.Decimal, statement
 	client := CheckoutClient{}
 	cardPaymentRequest := bean.CheckOutCardIdPaymentRequest{
 		CardId:      token,
-		Email:       fmt.Sprintf("user.%s@shake.ninja", userId),
+		Email:       CustomerEmail(userId),
 		Currency:    bean.USD.Code,
 		Value:       amount.Mul(decimal.NewFromFloat(100).Round(0)).IntPart(),
 		AutoCapture: "n",
 		Description: description,
 		// Descriptor: statement,
 	}
+	setupAuthentication(&cardPaymentRequest)
 	var response bean.CheckoutCardPaymentResponse
 	resp, err := client.Post("/v2/charges/card", cardPaymentRequest)
 
@@ -102,8 +152,10 @@ func ChargeCardId(userId string, token string, amount decimal.Decimal, statement
 }
 
 func ChargeCard(userId string, cardNum string, date string, cvv string, amount decimal.Decimal, statement string, description string) (bean.CheckoutCardPaymentResponse, error) {
-	month := date[:2]
-	year := "20" + date[3:]
+	month, year, err := common.SplitExpirationDate(date)
+	if err != nil {
+		return bean.CheckoutCardPaymentResponse{}, err
+	}
 
 	client := CheckoutClient{}
 
@@ -114,7 +166,7 @@ func ChargeCard(userId string, cardNum string, date string, cvv string, amount d
 			Number:      cardNum,
 			CVV:         cvv,
 		},
-		Email:       fmt.Sprintf("user.%s@shake.ninja", userId),
+		Email:       CustomerEmail(userId),
 		Currency:    bean.USD.Code,
 		Value:       amount.Mul(decimal.NewFromFloat(100).Round(0)).IntPart(),
 		AutoCapture: "n",
@@ -131,6 +183,42 @@ func ChargeCard(userId string, cardNum string, date string, cvv string, amount d
 	return response, err
 }
 
+func CreateCardToken(cardNum string, date string, cvv string) (bean.CheckoutCardTokenResponse, error) {
+	month, year, err := common.SplitExpirationDate(date)
+	if err != nil {
+		return bean.CheckoutCardTokenResponse{}, err
+	}
+
+	client := CheckoutClient{}
+	card := bean.CheckoutCard{
+		ExpiryMonth: month,
+		ExpiryYear:  year,
+		Number:      cardNum,
+		CVV:         cvv,
+	}
+	var response bean.CheckoutCardTokenResponse
+	resp, err := client.PostPublic("/v2/tokens/card", card)
+
+	if err == nil {
+		resp.JSON(&response)
+	}
+
+	return response, err
+}
+
+// The return from the issuer only carries the payment token, the charge it turned into is read back here
+func VerifyCharge(paymentToken string) (bean.CheckoutCardPaymentResponse, error) {
+	client := CheckoutClient{}
+	var response bean.CheckoutCardPaymentResponse
+	resp, err := client.Get(fmt.Sprintf("/v2/charges/%s", paymentToken))
+
+	if err == nil {
+		resp.JSON(&response)
+	}
+
+	return response, err
+}
+
 func Capture(chargeId string) (bean.CheckoutCard2ndStepResponse, error) {
 	client := CheckoutClient{}
 	var response bean.CheckoutCard2ndStepResponse
@@ -166,3 +254,46 @@ func Refund(chargeId string) (bean.CheckoutCard2ndStepResponse, error) {
 
 	return response, err
 }
+
+func RefundAmount(chargeId string, amount decimal.Decimal) (bean.CheckoutCard2ndStepResponse, error) {
+	client := CheckoutClient{}
+	refundRequest := bean.CheckoutRefundRequest{
+		Value: amount.Mul(decimal.NewFromFloat(100)).Round(0).IntPart(),
+	}
+	var response bean.CheckoutCard2ndStepResponse
+	resp, err := client.Post(fmt.Sprintf("/v2/charges/%s/refund", chargeId), refundRequest)
+
+	if err == nil {
+		resp.JSON(&response)
+	}
+
+	return response, err
+}
+
+// Checkout only knows the user by this email, webhooks are mapped back to the user with UserIdFromCustomerEmail
+func CustomerEmail(userId string) string {
+	return fmt.Sprintf(customerEmailFormat, userId)
+}
+
+func UserIdFromCustomerEmail(email string) string {
+	prefix := "user."
+	suffix := strings.TrimPrefix(customerEmailFormat, "user.%s")
+	if !strings.HasPrefix(email, prefix) || !strings.HasSuffix(email, suffix) || len(email) <= len(prefix)+len(suffix) {
+		return ""
+	}
+	return email[len(prefix) : len(email)-len(suffix)]
+}
+
+// Cko-Signature is the hex HMAC-SHA256 of the raw body keyed with the webhook secret.
+// The secret is set on the webhook in the Checkout hub, without one every callback is refused
+func VerifyWebhookSignature(body []byte, signature string) bool {
+	secret := os.Getenv("CHECKOUT_WEBHOOK_SECRET")
+	if secret == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
@@ -0,0 +1,44 @@
+package checkout_service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+const webhookBody = `{"eventType":"charge.dispute.received","message":{"id":"charge_1"}}`
+
+// HMAC-SHA256 of webhookBody keyed with "whsec"
+const webhookSignature = "1394ae38f3bfe2105d62607ca9d464e672c1813e5012283738aef6ade029a73e"
+
+func TestUserIdFromCustomerEmail(t *testing.T) {
+	assert.Equal(t, "abc123", UserIdFromCustomerEmail(CustomerEmail("abc123")))
+	assert.Equal(t, "abc123", UserIdFromCustomerEmail("user.abc123@shake.ninja"))
+
+	assert.Equal(t, "", UserIdFromCustomerEmail("abc123@shake.ninja"))
+	assert.Equal(t, "", UserIdFromCustomerEmail("user.abc123@other.example"))
+	assert.Equal(t, "", UserIdFromCustomerEmail("user.@shake.ninja"))
+	assert.Equal(t, "", UserIdFromCustomerEmail(""))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	os.Setenv("CHECKOUT_WEBHOOK_SECRET", "whsec")
+	defer os.Setenv("CHECKOUT_WEBHOOK_SECRET", "")
+
+	assert.True(t, VerifyWebhookSignature([]byte(webhookBody), webhookSignature))
+	assert.True(t, VerifyWebhookSignature([]byte(webhookBody), strings.ToUpper(webhookSignature)))
+
+	assert.False(t, VerifyWebhookSignature([]byte(webhookBody+" "), webhookSignature))
+	assert.False(t, VerifyWebhookSignature([]byte(webhookBody), ""))
+	assert.False(t, VerifyWebhookSignature([]byte(webhookBody), "not-hex"))
+}
+
+// The API secret key is not a webhook secret
+func TestVerifyWebhookSignatureRequiresWebhookSecret(t *testing.T) {
+	os.Setenv("CHECKOUT_WEBHOOK_SECRET", "")
+	os.Setenv("CHECKOUT_SECRET_KEY", "whsec")
+	defer os.Setenv("CHECKOUT_SECRET_KEY", "")
+
+	assert.False(t, VerifyWebhookSignature([]byte(webhookBody), webhookSignature))
+}
@@ -45,3 +45,45 @@ func GetExchangeRate() (map[string][]bean.CryptoRate, error) {
 
 	return result, err
 }
+
+func GetCurrencyExchangeRate(currency string) ([]bean.CryptoRate, error) {
+	symbol := fmt.Sprintf("_SPOT_%s_USD", currency)
+	url := fmt.Sprintf("https://rest.coinapi.io/v1/quotes/current?filter_symbol_id=%s", symbol)
+	headers := map[string]string{
+		"X-CoinAPI-Key": os.Getenv("COINAPI_API_KEY"),
+	}
+	ro := &grequests.RequestOptions{Headers: headers}
+	resp, err := grequests.Get(url, ro)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok != true {
+		return nil, api_error.NewErrorCustom(api_error.ExternalApiFailed, resp.String(), nil)
+	}
+
+	var data []map[string]interface{}
+	result := make([]bean.CryptoRate, 0)
+	resp.JSON(&data)
+	for _, item := range data {
+		symbolId, _ := item["symbol_id"].(string)
+		if !strings.HasSuffix(symbolId, symbol) {
+			continue
+		}
+		// Buyer takes the ask, seller takes the bid
+		buy, _ := item["ask_price"].(float64)
+		sell, _ := item["bid_price"].(float64)
+		buyVolume, _ := item["ask_size"].(float64)
+		sellVolume, _ := item["bid_size"].(float64)
+		result = append(result, bean.CryptoRate{
+			From:       currency,
+			To:         bean.USD.Code,
+			Buy:        buy,
+			Sell:       sell,
+			BuyVolume:  buyVolume,
+			SellVolume: sellVolume,
+			Exchange:   strings.Replace(symbolId, symbol, "", -1),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package smtp_service
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+type SMTPClient struct {
+	host     string
+	port     string
+	username string
+	password string
+	insecure bool
+}
+
+func (c *SMTPClient) Initialize() {
+	c.host = os.Getenv("SMTP_HOST")
+	c.port = os.Getenv("SMTP_PORT")
+	if c.port == "" {
+		c.port = "587"
+	}
+	c.username = os.Getenv("SMTP_USERNAME")
+	c.password = os.Getenv("SMTP_PASSWORD")
+	c.insecure = os.Getenv("SMTP_INSECURE") == "true"
+}
+
+// STARTTLS is required, SMTP_INSECURE=true lets local relays without TLS through
+func SendEmail(fromName string, fromAddress string, toName string, toAddress string, subject string, body string) error {
+	client := SMTPClient{}
+	client.Initialize()
+
+	conn, err := smtp.Dial(net.JoinHostPort(client.host, client.port))
+	if err != nil {
+		return api_error.PropagateError(api_error.ExternalApiFailed, err)
+	}
+	defer conn.Close()
+
+	if ok, _ := conn.Extension("STARTTLS"); ok {
+		if err = conn.StartTLS(&tls.Config{ServerName: client.host}); err != nil {
+			return api_error.PropagateError(api_error.ExternalApiFailed, err)
+		}
+	} else if !client.insecure {
+		return api_error.PropagateError(api_error.ExternalApiFailed, errors.New("smtp server does not offer STARTTLS"))
+	}
+	if client.username != "" {
+		if err = conn.Auth(smtp.PlainAuth("", client.username, client.password, client.host)); err != nil {
+			return api_error.PropagateError(api_error.ExternalApiFailed, err)
+		}
+	}
+
+	if err = conn.Mail(fromAddress); err != nil {
+		return api_error.PropagateError(api_error.ExternalApiFailed, err)
+	}
+	if err = conn.Rcpt(toAddress); err != nil {
+		return api_error.PropagateError(api_error.ExternalApiFailed, err)
+	}
+	writer, err := conn.Data()
+	if err != nil {
+		return api_error.PropagateError(api_error.ExternalApiFailed, err)
+	}
+	_, err = writer.Write([]byte(BuildMessage(fromName, fromAddress, toName, toAddress, subject, body)))
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return api_error.PropagateError(api_error.ExternalApiFailed, err)
+	}
+
+	return conn.Quit()
+}
+
+func BuildMessage(fromName string, fromAddress string, toName string, toAddress string, subject string, body string) string {
+	headers := []string{
+		fmt.Sprintf("From: %s", formatAddress(fromName, fromAddress)),
+		fmt.Sprintf("To: %s", formatAddress(toName, toAddress)),
+		fmt.Sprintf("Subject: %s", mime.QEncoding.Encode("utf-8", subject)),
+		fmt.Sprintf("Date: %s", time.Now().Format(time.RFC1123Z)),
+		fmt.Sprintf("Message-ID: %s", newMessageId(fromAddress)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/html; charset=\"utf-8\"",
+	}
+
+	return strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+}
+
+func formatAddress(name string, address string) string {
+	if name == "" {
+		return address
+	}
+	return fmt.Sprintf("%s <%s>", mime.QEncoding.Encode("utf-8", name), address)
+}
+
+func newMessageId(fromAddress string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 && at < len(fromAddress)-1 {
+		domain = fromAddress[at+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
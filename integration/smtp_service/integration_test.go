@@ -0,0 +1,35 @@
+package smtp_service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessageHeaders(t *testing.T) {
+	message := BuildMessage("Ninja", "no-reply@ninja.example", "", "taker@ninja.example", "Offer rejected", "<p>Hi</p>")
+
+	parts := strings.SplitN(message, "\r\n\r\n", 2)
+	assert.Equal(t, "<p>Hi</p>", parts[1])
+
+	headers := strings.Split(parts[0], "\r\n")
+	assert.Contains(t, headers, "From: Ninja <no-reply@ninja.example>")
+	assert.Contains(t, headers, "To: taker@ninja.example")
+
+	var date, messageId string
+	for _, header := range headers {
+		if strings.HasPrefix(header, "Date: ") {
+			date = header
+		}
+		if strings.HasPrefix(header, "Message-ID: ") {
+			messageId = header
+		}
+	}
+	assert.NotEmpty(t, date)
+	assert.True(t, strings.HasSuffix(messageId, "@ninja.example>"))
+}
+
+func TestNewMessageIdIsUnique(t *testing.T) {
+	assert.NotEqual(t, newMessageId("no-reply@ninja.example"), newMessageId("no-reply@ninja.example"))
+	assert.True(t, strings.HasSuffix(newMessageId(""), "@localhost>"))
+}
@@ -10,6 +10,13 @@ import (
 )
 
 func UpdateObject(body interface{}) (*grequests.Response, error) {
+	arrBody := make([]interface{}, 1)
+	arrBody[0] = body
+
+	return UpdateObjects(arrBody)
+}
+
+func UpdateObjects(arrBody []interface{}) (*grequests.Response, error) {
 	type addBodyStruct struct {
 		Add []interface{} `json:"add"`
 	}
@@ -18,8 +25,6 @@ func UpdateObject(body interface{}) (*grequests.Response, error) {
 
 	bodyStr := ""
 
-	arrBody := make([]interface{}, 1)
-	arrBody[0] = body
 	addBody := addBodyStruct{
 		Add: arrBody,
 	}
@@ -1,6 +1,7 @@
 package stripe_service
 
 import (
+	"github.com/ninjadotorg/handshake-exchange/common"
 	"github.com/shopspring/decimal"
 	"github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/client"
@@ -14,8 +15,10 @@ func CreateToken(cardNum string, date string, cvv string) (string, error) {
 	sc := &client.API{}
 	sc.Init(os.Getenv("STRIPE_SECRET_KEY"), nil)
 
-	month := date[:2]
-	year := "20" + date[3:]
+	month, year, err := common.SplitExpirationDate(date)
+	if err != nil {
+		return "", err
+	}
 
 	tokenParams := &stripe.TokenParams{
 		Card: &stripe.CardParams{
@@ -75,6 +78,15 @@ func Refund(chargeId string) (*stripe.Refund, error) {
 	return r, err
 }
 
+func RefundAmount(chargeId string, amount decimal.Decimal) (*stripe.Refund, error) {
+	sc := &client.API{}
+	sc.Init(os.Getenv("STRIPE_SECRET_KEY"), nil)
+
+	stripeAmount := amount.Round(2).Mul(decimal.NewFromFloat(100)).IntPart()
+	r, err := sc.Refunds.New(&stripe.RefundParams{Charge: chargeId, Amount: uint64(stripeAmount)})
+	return r, err
+}
+
 func Capture(chargeId string) (*stripe.Charge, error) {
 	sc := &client.API{}
 	sc.Init(os.Getenv("STRIPE_SECRET_KEY"), nil)
@@ -0,0 +1,58 @@
+package twilio_service
+
+import (
+	"fmt"
+	"github.com/levigross/grequests"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"os"
+)
+
+type TwilioClient struct {
+	url        string
+	accountSid string
+	authToken  string
+	from       string
+}
+
+func (c *TwilioClient) Initialize() {
+	c.url = os.Getenv("TWILIO_URL")
+	if c.url == "" {
+		c.url = "https://api.twilio.com"
+	}
+	c.accountSid = os.Getenv("TWILIO_ACCOUNT_SID")
+	c.authToken = os.Getenv("TWILIO_AUTH_TOKEN")
+	c.from = os.Getenv("TWILIO_FROM_NUMBER")
+}
+
+func (c TwilioClient) PostForm(uri string, data map[string]string) (*grequests.Response, error) {
+	c.Initialize()
+
+	url := c.url + uri
+	ro := &grequests.RequestOptions{
+		Auth: []string{c.accountSid, c.authToken},
+		Data: data,
+	}
+	resp, err := grequests.Post(url, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Ok != true {
+		return nil, api_error.NewErrorCustom(api_error.ExternalApiFailed, resp.String(), nil)
+	}
+
+	return resp, err
+}
+
+func SendMessage(to string, body string) error {
+	client := TwilioClient{}
+	client.Initialize()
+
+	_, err := client.PostForm(fmt.Sprintf("/2010-04-01/Accounts/%s/Messages.json", client.accountSid), map[string]string{
+		"From": client.from,
+		"To":   to,
+		"Body": body,
+	})
+
+	return err
+}
@@ -12,11 +12,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/natefinch/lumberjack"
-	"github.com/nicksnyder/go-i18n/i18n"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/integration/firebase_service"
-	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
 	"github.com/ninjadotorg/handshake-exchange/service/cache"
+	"github.com/ninjadotorg/handshake-exchange/service/email"
+	"github.com/ninjadotorg/handshake-exchange/service/kyc"
+	"github.com/ninjadotorg/handshake-exchange/service/payment"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
+	"github.com/ninjadotorg/handshake-exchange/service/sms"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
 	"github.com/ninjadotorg/handshake-exchange/url"
 	"io"
 	"io/ioutil"
@@ -66,11 +70,16 @@ func main() {
 	redisPassword := os.Getenv("REDIS_PASSWORD")
 	sessionPrefix := os.Getenv("SESSION_PREFIX")
 	cache.InitializeRedisClient(redisHost, redisPassword)
+	search.InitializeIndexer(os.Getenv("SEARCH_INDEXER"))
+	sms.InitializeProvider(os.Getenv("SMS_PROVIDER"))
+	payment.InitializeGateways(os.Getenv("PAYMENT_FAKE_GATEWAY") == "true")
+	kyc.InitializeVerifier(os.Getenv("KYC_VERIFIER"))
 	// End
 
 	// Load translation
-	i18n.MustLoadTranslationFile("./translations/en-US.flat.yaml")
-	//i18n.MustLoadTranslationFile("./translations/zh-HK.flat.yaml")
+	translation.MustLoadTranslations("./translations")
+	email.MustLoadTemplates()
+	email.InitializeTransport(os.Getenv("EMAIL_TRANSPORT"), os.Getenv("EMAIL_SINK_DIR"))
 	// End
 
 	// DB
@@ -107,6 +116,12 @@ func main() {
 	cronJobUrl.Create(router)
 	creditCardUrl := url.CreditCardUrl{}
 	creditCardUrl.Create(router)
+	orderBookUrl := url.OrderBookUrl{}
+	orderBookUrl.Create(router)
+	disputeUrl := url.DisputeUrl{}
+	disputeUrl.Create(router)
+	kycUrl := url.KYCUrl{}
+	kycUrl.Create(router)
 
 	log.Printf(":%s", os.Getenv("SERVICE_PORT"))
 	router.Run(fmt.Sprintf(":%s", os.Getenv("SERVICE_PORT")))
@@ -150,7 +165,7 @@ func RouterMiddleware() gin.HandlerFunc {
 
 			b, _ := json.Marshal(&body)
 			r, _ := json.Marshal(&responseData)
-			solr_service.UpdateObject(bean.SolrLogObject{
+			search.UpdateLog(bean.SearchLog{
 				Id:             docId,
 				UID:            userId,
 				RequestMethod:  requestMethod,
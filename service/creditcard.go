@@ -10,52 +10,107 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/integration/checkout_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/crypto_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/gdax_service"
+	"github.com/ninjadotorg/handshake-exchange/service/email"
 	"github.com/ninjadotorg/handshake-exchange/service/notification"
+	"github.com/ninjadotorg/handshake-exchange/service/payment"
 	"github.com/shopspring/decimal"
+	"log"
 	"os"
 	"strconv"
 	"time"
 )
 
 type CreditCardService struct {
-	dao      *dao.CreditCardDao
+	dao      dao.CreditCardDaoInterface
 	miscDao  *dao.MiscDao
 	userDao  *dao.UserDao
 	transDao *dao.TransactionDao
 }
 
-func (s CreditCardService) GetProposeInstantOffer(amountStr string, currency string) (offer bean.InstantOffer, ce SimpleContextError) {
-	cryptoRateTO := s.miscDao.GetCryptoRateFromCache(currency, bean.INSTANT_OFFER_PROVIDER_COINBASE)
-	if ce.FeedDaoTransfer(api_error.GetDataFailed, cryptoRateTO) {
+// Anonymous proposals only show the price, a quote is locked for a signed in user
+func (s CreditCardService) GetProposeInstantOffer(userId string, amountStr string, currency string) (offer bean.InstantOffer, ce SimpleContextError) {
+	cryptoPrice, priceCE := PriceOracleServiceInst.GetCachedPrice(currency, bean.OFFER_TYPE_BUY)
+	if ce.FeedContextErrorDefault(priceCE) {
 		return
 	}
-	cryptoRate := cryptoRateTO.Object.(bean.CryptoRate)
 	systemFeeTO := s.miscDao.GetSystemFeeFromCache(bean.FEE_KEY_INSTANT_BUY_CRYPTO)
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, systemFeeTO) {
 		return
 	}
 	systemFee := systemFeeTO.Object.(bean.SystemFee)
+	// Instant buy is only charged in USD
+	fiatCurrency := GetFiatCurrency(s.miscDao, bean.USD.Code, &ce)
+	if ce.HasError() {
+		return
+	}
 
-	price := decimal.NewFromFloat(cryptoRate.Buy).Round(2)
+	price := fiatCurrency.Round(decimal.NewFromFloat(cryptoPrice.Price))
 	amount, _ := decimal.NewFromString(amountStr)
 	totalWOFee := amount.Mul(price)
 	feePercentage := decimal.NewFromFloat(systemFee.Value).Round(10)
 	total, fee := dao.AddFeePercentage(totalWOFee, feePercentage)
 
-	offer.FiatAmount = total.Round(2).String()
-	offer.FiatCurrency = bean.USD.Code
+	offer.FiatAmount = fiatCurrency.FormatAmount(total)
+	offer.FiatCurrency = fiatCurrency.Code
 	offer.Amount = amountStr
 	offer.Currency = currency
 	offer.Price = price.String()
-	offer.Fee = fee.Round(2).String()
+	offer.Fee = fiatCurrency.FormatAmount(fee)
 	offer.FeePercentage = feePercentage.String()
+	if userId == "" {
+		return
+	}
+
+	quote, quoteCE := QuoteServiceInst.LockQuote(userId, bean.LockedQuote{
+		Type:          bean.OFFER_TYPE_BUY,
+		Amount:        offer.Amount,
+		Currency:      offer.Currency,
+		FiatCurrency:  offer.FiatCurrency,
+		FiatAmount:    offer.FiatAmount,
+		Price:         offer.Price,
+		PriceUSD:      offer.Price,
+		Fee:           offer.Fee,
+		FeePercentage: offer.FeePercentage,
+	})
+	if ce.FeedContextErrorDefault(quoteCE) {
+		return
+	}
+	offer.QuoteId = quote.Id
+	offer.QuoteExpiredAt = quote.ExpiredAt
+
+	return
+}
+
+func (s CreditCardService) getLockedInstantOffer(userId string, offerBody bean.InstantOffer) (offer bean.InstantOffer, ce SimpleContextError) {
+	quote, quoteCE := QuoteServiceInst.GetMatchedLockedQuote(userId, offerBody.QuoteId, bean.OFFER_TYPE_BUY, offerBody.Amount,
+		offerBody.Currency, bean.USD.Code)
+	if ce.FeedContextErrorDefault(quoteCE) {
+		return
+	}
+
+	offer.FiatAmount = quote.FiatAmount
+	offer.FiatCurrency = quote.FiatCurrency
+	offer.Amount = quote.Amount
+	offer.Currency = quote.Currency
+	offer.Price = quote.Price
+	offer.Fee = quote.Fee
+	offer.FeePercentage = quote.FeePercentage
+	offer.QuoteId = quote.Id
+	offer.QuoteExpiredAt = quote.ExpiredAt
 
 	return
 }
 
 func (s CreditCardService) PayInstantOffer(userId string, offerBody bean.InstantOffer) (offer bean.InstantOffer, ce SimpleContextError) {
-	offerTest, testOfferCE := s.GetProposeInstantOffer(offerBody.Amount, offerBody.Currency)
-	if ce.FeedContextError(api_error.GetDataFailed, testOfferCE) {
+	var offerTest bean.InstantOffer
+	var testOfferCE SimpleContextError
+	if offerBody.QuoteId != "" {
+		// Honor the locked price until the quote expires
+		offerTest, testOfferCE = s.getLockedInstantOffer(userId, offerBody)
+	} else {
+		offerTest, testOfferCE = s.GetProposeInstantOffer("", offerBody.Amount, offerBody.Currency)
+	}
+	if ce.FeedContextErrorDefault(testOfferCE) {
 		return
 	}
 	if offerTest.FiatAmount != offerBody.FiatAmount {
@@ -66,6 +121,10 @@ func (s CreditCardService) PayInstantOffer(userId string, offerBody bean.Instant
 		ce.SetStatusKey(api_error.InvalidRequestBody)
 		return
 	}
+	if offerBody.FiatCurrency != "" && offerTest.FiatCurrency != offerBody.FiatCurrency {
+		ce.SetStatusKey(api_error.UnsupportedFiatCurrency)
+		return
+	}
 	profileTO := s.userDao.GetProfile(userId)
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, profileTO) {
 		return
@@ -92,96 +151,214 @@ func (s CreditCardService) PayInstantOffer(userId string, offerBody bean.Instant
 		}
 	}
 
+	// Fail before charging, fulfillment reads the mode again
 	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_KEY_CC_MODE)
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, systemConfigTO) {
 		return
 	}
-	systemConfig := systemConfigTO.Object.(bean.SystemConfig)
 
 	var err error
 	var paymentMethodData bean.CreditCardInfo
 	b, _ := json.Marshal(&offerBody.PaymentMethodData)
 	json.Unmarshal(b, &paymentMethodData)
 
+	// The limit is for all cards of the user, a new card does not start a fresh one
+	ccLimitCE := UserServiceInst.CheckCCLimit(offerBody.UID, offerBody.FiatAmount)
+	if ccLimitCE.HasError() {
+		ce.SetError(api_error.CCOverLimit, ccLimitCE.Error)
+		return
+	}
+
 	saveCard := false
-	var token string
-	//if paymentMethodData.Token == "" {
-	//	token, err = stripe_service.CreateToken(paymentMethodData.CCNum, paymentMethodData.ExpirationDate, paymentMethodData.CVV)
-	//	if ce.SetError(api_error.ExternalApiFailed, err) {
-	//		return
-	//	}
-	//	saveCard = true
-	//}
-	if paymentMethodData.Token == "true" {
-		ccLimitCE := UserServiceInst.CheckCCLimit(offerBody.UID, offerBody.FiatAmount)
-		if ccLimitCE.HasError() {
-			ce.SetError(api_error.CCOverLimit, ccLimitCE.Error)
+	var card bean.UserCreditCard
+	// Token "true" is the default card from before card ids, any other token has to be a card of the wallet
+	// so its usage counts on that card's limit
+	if paymentMethodData.CardId != "" || paymentMethodData.Token == "true" {
+		var cardCE SimpleContextError
+		card, cardCE = UserServiceInst.GetCreditCard(userId, paymentMethodData.CardId)
+		if ce.FeedContextErrorDefault(cardCE); ce.HasError() {
+			return
+		}
+		paymentMethodData.Token = card.Token
+	} else if paymentMethodData.Token != "" {
+		var cardCE SimpleContextError
+		card, cardCE = UserServiceInst.GetCreditCardByToken(userId, paymentMethodData.Token)
+		if ce.FeedContextErrorDefault(cardCE); ce.HasError() {
 			return
 		}
-		paymentMethodData.Token = profile.CreditCard.Token
 	}
 
 	fiatAmount, _ := decimal.NewFromString(offerBody.FiatAmount)
 	statement := ""
 	description := fmt.Sprintf("User %s buys %s %s", offer.UID, offerBody.Amount, offerBody.Currency)
 
-	// chargeResponse, err := stripe_service.Charge(token, paymentMethodData.Token, fiatAmount, statement, description)
-	chargeStatus := "failed"
-	chargeId := ""
-	var chargeResponse interface{}
+	gateway := s.selectGateway(userId, card, &paymentMethodData, fiatAmount, &ce)
+	if ce.HasError() {
+		return
+	}
+	if offerTest.QuoteId != "" {
+		if ce.FeedContextErrorDefault(QuoteServiceInst.UseLockedQuote(offerTest.QuoteId, offerTest.QuoteExpiredAt)) {
+			return
+		}
+		defer QuoteServiceInst.ReleaseLockedQuoteOnError(offerTest.QuoteId, &ce)
+	}
+	chargeResult, err := gateway.Authorize(payment.ChargeRequest{
+		UID:         userId,
+		CardToken:   paymentMethodData.CCNum,
+		CardId:      paymentMethodData.Token,
+		Amount:      fiatAmount,
+		Currency:    bean.USD.Code,
+		Statement:   statement,
+		Description: description,
+	})
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		return
+	}
+	if chargeResult.Status == payment.CHARGE_STATUS_DECLINED {
+		ce.SetStatusKey(api_error.ExternalApiFailed)
+		return
+	}
+	redirectUrl := chargeResult.RedirectUrl
 	if paymentMethodData.Token == "" {
-		checkOutResponse, err := checkout_service.ChargeCardToken(userId, paymentMethodData.CCNum, fiatAmount, statement, description)
-		fmt.Println(checkOutResponse)
-		fmt.Println(err)
-		if err == nil {
-			if checkOutResponse.Status == "Authorised" {
-				chargeStatus = checkOutResponse.Status
-				chargeResponse = checkOutResponse
-				chargeId = checkOutResponse.Id
-				token = checkOutResponse.Card.Id
-
-				paymentMethodData.CCNum = checkOutResponse.Card.Last4
-				paymentMethodData.ExpirationDate = fmt.Sprintf("%s/%s",
-					checkOutResponse.Card.ExpiryMonth, checkOutResponse.Card.ExpiryYear[2:])
-
-				saveCard = true
-			}
-		} else {
-			if ce.SetError(api_error.ExternalApiFailed, err) {
-				return
-			}
+		// Card details of a 3-D Secure charge come back once it is verified
+		saveCard = true
+		card = bean.UserCreditCard{
+			CCNumber:       chargeResult.Card.Last4,
+			ExpirationDate: chargeResult.Card.ExpirationDate,
+			Token:          chargeResult.Card.Id,
+			Provider:       gateway.Name(),
+		}
+	}
+
+	var ccTran bean.CCTransaction
+	setupCCTransaction(&ccTran, offerBody, gateway.Name(), chargeResult.ProviderData, chargeResult.Id)
+
+	if redirectUrl != "" {
+		// Fulfillment continues in AuthenticateInstantOffer once the card holder is back from the issuer
+		ccTran.Status = bean.CC_TRANSACTION_STATUS_PENDING_AUTHENTICATION
+		setupInstantOffer(&offerBody, offerTest, bean.GdaxOrderResponse{})
+		offerBody.Status = bean.INSTANT_OFFER_STATUS_PENDING_AUTHENTICATION
+		offerBody.PaymentMethod = bean.INSTANT_OFFER_PAYMENT_METHOD_CC
+		offerBody.RedirectUrl = redirectUrl
+		offerBody.CreatedAt = time.Now().UTC()
+		offer, _, err = s.dao.AddPendingAuthenticationInstantOffer(offerBody, ccTran, bean.PendingAuthenticationInstantOffer{
+			SaveCard:  saveCard,
+			CardToken: card.Token,
+			CardId:    card.Id,
+		})
+		if ce.SetError(api_error.AddDataFailed, err) {
+			return
 		}
 	} else {
-		checkOutResponse, err := checkout_service.ChargeCardId(userId, paymentMethodData.Token, fiatAmount, statement, description)
-		if err == nil {
-			if checkOutResponse.Status == "Authorised" {
-				chargeStatus = checkOutResponse.Status
-				chargeResponse = checkOutResponse
-				chargeId = checkOutResponse.Id
-			}
-		} else {
-			if ce.SetError(api_error.ExternalApiFailed, err) {
-				return
-			}
+		ccTran, err = s.dao.AddCCTransaction(ccTran)
+		if ce.SetError(api_error.AddDataFailed, err) {
+			s.voidCCTransaction(&ccTran, &ce)
+			return
 		}
+		offer = s.fulfillInstantOffer(offerBody, offerTest, ccTran, card, saveCard, &ce)
 	}
 
-	if chargeStatus == "failed" {
-		ce.SetStatusKey(api_error.ExternalApiFailed)
+	paymentMethodData.CCNum = ""
+	paymentMethodData.CVV = ""
+	paymentMethodData.Token = ""
+	paymentMethodData.CardId = card.Id
+	offer.PaymentMethodData = paymentMethodData
+
+	return
+}
+
+// The issuer sends the card holder back with the payment token, it has to belong to one of the user's pending charges
+func (s CreditCardService) AuthenticateInstantOffer(userId string, paymentToken string) (offer bean.InstantOffer, ce SimpleContextError) {
+	ccTranTO := s.dao.GetCCTransactionByExternalId(userId, paymentToken)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, ccTranTO); ce.HasError() {
 		return
 	}
+	ccTran := ccTranTO.Object.(bean.CCTransaction)
 
-	// Make buy order
-	isSuccess := false
+	offerTO := s.dao.GetInstantOfferByPath(ccTran.DataRef)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, offerTO); ce.HasError() {
+		return
+	}
+	offer = offerTO.Object.(bean.InstantOffer)
+	if offer.Status != bean.INSTANT_OFFER_STATUS_PENDING_AUTHENTICATION || ccTran.Status != bean.CC_TRANSACTION_STATUS_PENDING_AUTHENTICATION {
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
+	// Whoever claims the pending record owns the charge, a second return or the expiry gets nothing
+	pendingOffer, err := s.dao.ClaimPendingAuthenticationInstantOffer(offer.UID, offer.Id)
+	if err != nil {
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
 
-	var ccTran bean.CCTransaction
+	chargeResult, err := payment.GetGateway(ccTran.Provider).VerifyAuthentication(paymentToken)
+	if err != nil || chargeResult.Status != payment.CHARGE_STATUS_AUTHORISED {
+		if err != nil {
+			log.Println("3-D Secure verification failed", ccTran.Id, err)
+		}
+		offer = s.cancelAuthenticationInstantOffer(offer, ccTran, chargeResult, &ce)
+		if ce.HasError() {
+			return
+		}
+		ce.SetStatusKey(api_error.CardAuthenticationFailed)
+		return
+	}
+	ccTran.ProviderData = chargeResult.ProviderData
+
+	// From here on the charge is a normal authorised one, capture and void use its id
+	ccTran.Status = bean.CC_TRANSACTION_STATUS_PURCHASED
+	ccTran.ExternalId = chargeResult.Id
+	ccTran, err = s.dao.UpdateCCTransactionAuthentication(ccTran)
+	if err != nil {
+		offer = s.cancelAuthenticationInstantOffer(offer, ccTran, chargeResult, &ce)
+		ce.SetError(api_error.UpdateDataFailed, err)
+		return
+	}
+
+	card := bean.UserCreditCard{
+		Id:       pendingOffer.CardId,
+		Token:    pendingOffer.CardToken,
+		Provider: ccTran.Provider,
+	}
+	saveCard := pendingOffer.SaveCard && chargeResult.Card.Id != ""
+	if saveCard {
+		card.Token = chargeResult.Card.Id
+		card.CCNumber = chargeResult.Card.Last4
+		card.ExpirationDate = chargeResult.Card.ExpirationDate
+	}
+
+	offerBody := offer
+	offerBody.RedirectUrl = ""
+	offer = s.fulfillInstantOffer(offerBody, offer, ccTran, card, saveCard, &ce)
+	if ce.HasError() {
+		// Nothing is left to expire the offer, the charge was voided if the order could not be placed
+		offerBody.Status = bean.INSTANT_OFFER_STATUS_CANCELLED
+		if ccTranTO := s.dao.GetCCTransactionByPath(offerBody.PaymentMethodRef); !ccTranTO.HasError() {
+			ccTran = ccTranTO.Object.(bean.CCTransaction)
+		}
+		offer, _ = s.dao.CancelPendingAuthenticationInstantOffer(offerBody, ccTran)
+		return
+	}
+
+	offer.PaymentMethodData = bean.CreditCardInfo{}
+
+	return
+}
+
+// Buys from inventory or GDAX for an authorised charge, the charge is voided if the order can not be placed
+func (s CreditCardService) fulfillInstantOffer(offerBody bean.InstantOffer, offerTest bean.InstantOffer, ccTran bean.CCTransaction,
+	card bean.UserCreditCard, saveCard bool, ce *SimpleContextError) (offer bean.InstantOffer) {
+	var err error
+	isSuccess := false
 	var gdaxResponse bean.GdaxOrderResponse
 
-	setupCCTransaction(&ccTran, offerBody, chargeResponse, chargeId)
-	ccTran, err = s.dao.AddCCTransaction(ccTran)
-	ccMode := systemConfig.Value
-	if ce.SetError(api_error.AddDataFailed, err) {
-	} else {
+	amount, _ := decimal.NewFromString(offerBody.Amount)
+	fiatAmount, _ := decimal.NewFromString(offerBody.FiatAmount)
+
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_KEY_CC_MODE)
+	if !ce.FeedDaoTransfer(api_error.GetDataFailed, systemConfigTO) {
+		systemConfig := systemConfigTO.Object.(bean.SystemConfig)
+		ccMode := systemConfig.Value
 		// There is not enough balance in inventory, use gdax
 		if ccMode == bean.CC_MODE_INVENTORY {
 			balance, err := crypto_service.GetBalance(offerBody.Currency)
@@ -209,53 +386,50 @@ func (s CreditCardService) PayInstantOffer(userId string, offerBody bean.Instant
 
 	if !isSuccess {
 		// If failed, do refund
-		// stripeRefund, err := stripe_service.Refund(ccTran.ExternalId)
-		checkOutVoid, err := checkout_service.Void(ccTran.ExternalId)
-		if ce.SetError(api_error.ExternalApiFailed, err) {
-			return
-		}
-		if checkOutVoid.Status != "Voided" {
-			ce.SetStatusKey(api_error.ExternalApiFailed)
-			return
-		}
-		ccTran.ProviderData = checkOutVoid
-		ccTran.Status = bean.CC_TRANSACTION_STATUS_REFUNDED
-		s.dao.UpdateCCTransactionStatus(ccTran)
-	} else {
-		setupInstantOffer(&offerBody, offerTest, gdaxResponse)
-		offerBody.PaymentMethod = bean.INSTANT_OFFER_PAYMENT_METHOD_CC
-		offerBody.PaymentMethodRef = dao.GetCCTransactionItemPath(offerBody.UID, ccTran.Id)
-
-		transaction := bean.NewTransactionFromInstantOffer(offerBody)
-		offerBody.CreatedAt = time.Now().UTC()
-		offer, err = s.dao.AddInstantOffer(offerBody, transaction, gdaxResponse.Id)
-		if ce.SetError(api_error.AddDataFailed, err) {
-			return
-		}
-		ccTran.DataRef = dao.GetInstantOfferItemPath(offer.UID, offer.Id)
-		s.dao.UpdateCCTransaction(ccTran)
+		s.voidCCTransaction(&ccTran, ce)
+		return
 	}
 
-	if isSuccess {
-		if saveCard {
-			token, _ = s.saveCreditCard(userId, paymentMethodData, token)
-		} else {
-			token = paymentMethodData.Token
+	setupInstantOffer(&offerBody, offerTest, gdaxResponse)
+	offerBody.PaymentMethod = bean.INSTANT_OFFER_PAYMENT_METHOD_CC
+	offerBody.PaymentMethodRef = dao.GetCCTransactionItemPath(offerBody.UID, ccTran.Id)
+
+	transaction := bean.NewTransactionFromInstantOffer(offerBody)
+	offerBody.CreatedAt = time.Now().UTC()
+	offer, err = s.dao.AddInstantOffer(offerBody, transaction, gdaxResponse.Id)
+	if ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	if saveCard {
+		card, err = s.saveCreditCard(offer.UID, card)
+		if err != nil {
+			log.Println("Save credit card failed", offer.UID, err)
 		}
-		// Update CC Track amount
-		s.userDao.UpdateUserCCLimitAmount(userId, token, fiatAmount)
+	}
+	ccTran.DataRef = dao.GetInstantOfferItemPath(offer.UID, offer.Id)
+	ccTran.CardId = card.Id
+	s.dao.UpdateCCTransaction(ccTran)
 
-		notification.SendInstantOfferNotification(offer)
+	// Update CC Track amount
+	if card.Id != "" {
+		s.userDao.UpdateUserCCLimitAmount(offer.UID, card.Id, fiatAmount)
 	}
 
-	paymentMethodData.CCNum = ""
-	paymentMethodData.CVV = ""
-	paymentMethodData.Token = ""
-	offer.PaymentMethodData = paymentMethodData
+	notification.SendInstantOfferNotification(offer)
 
 	return
 }
 
+func (s CreditCardService) voidCCTransaction(ccTran *bean.CCTransaction, ce *SimpleContextError) {
+	voidResponse, err := payment.GetGateway(ccTran.Provider).Void(ccTran.ExternalId)
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		return
+	}
+	ccTran.ProviderData = voidResponse
+	ccTran.Status = bean.CC_TRANSACTION_STATUS_REFUNDED
+	s.dao.UpdateCCTransactionStatus(*ccTran)
+}
+
 func (s CreditCardService) FinishInstantOffers() (finishedInstantOffers []bean.InstantOffer, ce SimpleContextError) {
 	pendingOffers, err := s.dao.ListPendingInstantOffer()
 	if ce.SetError(api_error.GetDataFailed, err) {
@@ -301,9 +475,69 @@ func (s CreditCardService) FinishInstantOffers() (finishedInstantOffers []bean.I
 		}
 	}
 
+	s.expirePendingAuthenticationInstantOffers(&ce)
+
 	return
 }
 
+// A charge the card holder never came back for can still be authorised at the issuer, it is voided before the offer is cancelled
+func (s CreditCardService) expirePendingAuthenticationInstantOffers(ce *SimpleContextError) {
+	pendingOffers, err := s.dao.ListPendingAuthenticationInstantOffer()
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+	for _, pendingOffer := range pendingOffers {
+		if time.Now().UTC().Sub(pendingOffer.CreatedAt).Seconds() <= bean.INSTANT_OFFER_AUTHENTICATION_DURATION {
+			continue
+		}
+		offerTO := s.dao.GetInstantOfferByPath(pendingOffer.InstantOfferRef)
+		if offerTO.HasError() {
+			ce.SetError(api_error.GetDataFailed, offerTO.Error)
+			continue
+		}
+		ccTranTO := s.dao.GetCCTransactionByPath(pendingOffer.CCTransactionRef)
+		if ccTranTO.HasError() {
+			ce.SetError(api_error.GetDataFailed, ccTranTO.Error)
+			continue
+		}
+		offer := offerTO.Object.(bean.InstantOffer)
+		ccTran := ccTranTO.Object.(bean.CCTransaction)
+
+		if _, err = s.dao.ClaimPendingAuthenticationInstantOffer(pendingOffer.UID, pendingOffer.InstantOffer); err != nil {
+			// The card holder came back in the meantime
+			continue
+		}
+		chargeResult, err := payment.GetGateway(ccTran.Provider).VerifyAuthentication(ccTran.ExternalId)
+		if err != nil {
+			log.Println("3-D Secure verification failed", ccTran.Id, err)
+		}
+		s.cancelAuthenticationInstantOffer(offer, ccTran, chargeResult, ce)
+	}
+}
+
+// Only called with the pending record claimed, a void that fails is logged and the authorisation left to lapse
+func (s CreditCardService) cancelAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction,
+	chargeResult payment.ChargeResult, ce *SimpleContextError) bean.InstantOffer {
+	ccTran.Status = bean.CC_TRANSACTION_STATUS_FAILED
+	if chargeResult.ProviderData != nil {
+		ccTran.ProviderData = chargeResult.ProviderData
+	}
+	if chargeResult.Status == payment.CHARGE_STATUS_AUTHORISED {
+		ccTran.ExternalId = chargeResult.Id
+		var voidCE SimpleContextError
+		s.voidCCTransaction(&ccTran, &voidCE)
+		if voidCE.HasError() {
+			log.Println("Void of abandoned 3-D Secure charge failed", ccTran.Id, voidCE.Error)
+		}
+	}
+
+	offer.Status = bean.INSTANT_OFFER_STATUS_CANCELLED
+	offer, err := s.dao.CancelPendingAuthenticationInstantOffer(offer, ccTran)
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return offer
+}
+
 //func (s CreditCardService) saveCreditCard(userId string, paymentMethodData bean.CreditCardInfo) (string, error) {
 //	ccNum := paymentMethodData.CCNum[len(paymentMethodData.CCNum)-4:]
 //	profileTO := s.userDao.GetProfile(userId)
@@ -325,18 +559,243 @@ func (s CreditCardService) FinishInstantOffers() (finishedInstantOffers []bean.I
 //	return token, err
 //}
 
-func (s CreditCardService) saveCreditCard(userId string, paymentMethodData bean.CreditCardInfo, token string) (string, error) {
-	ccNum := paymentMethodData.CCNum[len(paymentMethodData.CCNum)-4:]
-	ccUserLimit, err := UserServiceInst.GetUserCCLimitFirstLevel()
-	if err == nil {
-		err = s.userDao.UpdateProfileCreditCard(userId, bean.UserCreditCard{
-			CCNumber:       ccNum,
-			ExpirationDate: paymentMethodData.ExpirationDate,
-			Token:          token,
-		}, ccUserLimit)
+// Admin refund of a captured charge, full when amount is empty, the CC limit usage is given back with it
+func (s CreditCardService) RefundInstantOffer(userId string, offerId string, body bean.InstantOfferRefundRequest) (offer bean.InstantOffer, ce SimpleContextError) {
+	offerTO := s.dao.GetInstantOffer(userId, offerId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, offerTO); ce.HasError() {
+		return
 	}
+	offer = offerTO.Object.(bean.InstantOffer)
 
-	return token, err
+	ccTranTO := s.dao.GetCCTransactionByPath(offer.PaymentMethodRef)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, ccTranTO); ce.HasError() {
+		return
+	}
+	ccTran := ccTranTO.Object.(bean.CCTransaction)
+	if !ccTran.IsRefundable() {
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
+
+	total := common.StringToDecimal(ccTran.Amount)
+	left := total.Sub(common.StringToDecimal(ccTran.RefundedAmount)).Sub(common.StringToDecimal(ccTran.RefundingAmount))
+	amount := left
+	if body.Amount != "" {
+		var err error
+		amount, err = decimal.NewFromString(body.Amount)
+		if ce.SetError(api_error.RefundAmountInvalid, err) {
+			return
+		}
+	}
+	if !amount.GreaterThan(common.Zero) || amount.GreaterThan(left) {
+		ce.SetStatusKey(api_error.RefundAmountInvalid)
+		return
+	}
+
+	// Held before the provider call, a concurrent or repeated refund finds less left to give back
+	ccTran, err := s.dao.ReserveCCTransactionRefund(ccTran, amount)
+	if ce.SetError(api_error.RefundAmountInvalid, err) {
+		return
+	}
+
+	refundResponse, err := payment.GetGateway(ccTran.Provider).Refund(ccTran.ExternalId, amount)
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		s.dao.ReleaseCCTransactionRefund(ccTran, amount)
+		return
+	}
+
+	ccTran.ProviderData = refundResponse
+	offer, ccTran, err = s.dao.AddInstantOfferRefund(offer, ccTran, amount)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+
+	// Decrease amount track
+	s.releaseCCLimit(ccTran, amount)
+
+	notification.SendInstantOfferNotification(offer)
+
+	return
+}
+
+// Verifies the card with an authorisation that is voided right away, cards asking for 3-D Secure are added with a purchase
+func (s CreditCardService) AddCreditCard(userId string, body bean.UserCreditCardRequest) (card bean.UserCreditCard, ce SimpleContextError) {
+	profileTO := s.userDao.GetProfile(userId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, profileTO); ce.HasError() {
+		return
+	}
+	profile := profileTO.Object.(bean.Profile)
+	if profile.CreditCardStatus != bean.CREDIT_CARD_STATUS_OK {
+		ce.SetStatusKey(api_error.InvalidCC)
+		return
+	}
+
+	paymentMethodData := bean.CreditCardInfo{
+		CCNum:          body.CCNum,
+		ExpirationDate: body.ExpirationDate,
+		CVV:            body.CVV,
+	}
+	gateway := s.selectGateway(userId, card, &paymentMethodData, bean.CC_VERIFICATION_AMOUNT, &ce)
+	if ce.HasError() {
+		return
+	}
+	chargeResult, err := gateway.Authorize(payment.ChargeRequest{
+		UID:         userId,
+		CardToken:   paymentMethodData.CCNum,
+		Amount:      bean.CC_VERIFICATION_AMOUNT,
+		Currency:    bean.USD.Code,
+		Description: fmt.Sprintf("User %s card verification", userId),
+	})
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		return
+	}
+	if chargeResult.Status == payment.CHARGE_STATUS_PENDING_AUTHENTICATION {
+		ce.SetStatusKey(api_error.CardAuthenticationFailed)
+		return
+	}
+	if chargeResult.Status != payment.CHARGE_STATUS_AUTHORISED {
+		ce.SetStatusKey(api_error.InvalidCC)
+		return
+	}
+	if _, err := gateway.Void(chargeResult.Id); err != nil {
+		log.Println("Void card verification failed", chargeResult.Id, err)
+	}
+
+	card, err = s.saveCreditCard(userId, bean.UserCreditCard{
+		CCNumber:       chargeResult.Card.Last4,
+		ExpirationDate: chargeResult.Card.ExpirationDate,
+		Token:          chargeResult.Card.Id,
+		Provider:       gateway.Name(),
+	})
+	if ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	card.Token = ""
+
+	return
+}
+
+func (s CreditCardService) saveCreditCard(userId string, card bean.UserCreditCard) (bean.UserCreditCard, error) {
+	if len(card.CCNumber) > 4 {
+		card.CCNumber = card.CCNumber[len(card.CCNumber)-4:]
+	}
+	return UserServiceInst.AddCreditCard(userId, card)
+}
+
+// Charges from before the wallet have no card id, their usage went on the profile card token
+func (s CreditCardService) releaseCCLimit(ccTran bean.CCTransaction, amount decimal.Decimal) {
+	limitId := ccTran.CardId
+	if limitId == "" {
+		profileTO := s.userDao.GetProfile(ccTran.UID)
+		if !profileTO.HasError() {
+			limitId = profileTO.Object.(bean.Profile).CreditCard.Token
+		}
+	}
+	if limitId != "" {
+		s.userDao.UpdateUserCCLimitAmount(ccTran.UID, limitId, amount.Mul(common.NegativeOne))
+	}
+}
+
+// Client side tokenization asks first which provider the card goes to, the token only works with that one
+func (s CreditCardService) GetCCGateway(userId string, amount string) (gateway bean.CCGateway, ce SimpleContextError) {
+	fiatAmount, err := decimal.NewFromString(amount)
+	if ce.SetError(api_error.InvalidRequestParam, err) {
+		return
+	}
+	gateway.Provider = s.routeGateway(userId, fiatAmount).Name()
+
+	return
+}
+
+// Saved cards only work with the provider that issued them, new cards are routed by config.
+// Client side tokens have to come from the provider the route gives, GetCCGateway hands it out before tokenizing
+func (s CreditCardService) selectGateway(userId string, card bean.UserCreditCard, paymentMethodData *bean.CreditCardInfo,
+	fiatAmount decimal.Decimal, ce *SimpleContextError) payment.Gateway {
+	if paymentMethodData.Token != "" {
+		return payment.GetGateway(card.Provider)
+	}
+
+	gateway := s.routeGateway(userId, fiatAmount)
+	if paymentMethodData.CVV == "" {
+		if paymentMethodData.Provider != gateway.Name() {
+			ce.SetStatusKey(api_error.InvalidCC)
+		}
+		return gateway
+	}
+
+	cardToken, err := gateway.Tokenize(*paymentMethodData)
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		return gateway
+	}
+	paymentMethodData.CCNum = cardToken
+	paymentMethodData.CVV = ""
+
+	return gateway
+}
+
+// The country is the one the user verified with KYC, not what the client sends
+func (s CreditCardService) routeGateway(userId string, fiatAmount decimal.Decimal) payment.Gateway {
+	var routing bean.PaymentRouting
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_CC_GATEWAY_ROUTING)
+	if !systemConfigTO.HasError() {
+		json.Unmarshal([]byte(systemConfigTO.Object.(bean.SystemConfig).Value), &routing)
+	}
+	country := KYCServiceInst.GetVerifiedCountry(userId)
+
+	return payment.GetGateway(payment.Route(routing, userId, country, fiatAmount))
+}
+
+// Disputes, chargebacks and refunds we did not issue lock the card, PayInstantOffer refuses it until ops clears the profile
+func (s CreditCardService) HandleCheckoutEvent(event bean.CheckoutWebhookEvent) (ccEvent bean.CCTransactionEvent, ce SimpleContextError) {
+	status, ok := bean.CheckoutEventCCTransactionStatus[event.EventType]
+	if !ok {
+		return
+	}
+	userId := checkout_service.UserIdFromCustomerEmail(event.Message.Email)
+	if userId == "" {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+	ccTranTO := s.dao.GetCCTransactionByExternalId(userId, event.Message.ChargeId())
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, ccTranTO); ce.HasError() {
+		return
+	}
+	ccTran := ccTranTO.Object.(bean.CCTransaction)
+	// Our own refunds already marked the transaction, a refund of any other amount came from somewhere else
+	ownRefund := status == bean.CC_TRANSACTION_STATUS_REFUNDED && ccTran.IsOwnRefund(event.Message.Value)
+
+	ccEvent = bean.CCTransactionEvent{
+		Id:               fmt.Sprintf("%s_%s", event.EventType, event.Message.Id),
+		UID:              userId,
+		Provider:         bean.CC_PROVIDER_CHECKOUT,
+		EventType:        event.EventType,
+		ChargeId:         event.Message.ChargeId(),
+		CCTransactionRef: dao.GetCCTransactionItemPath(userId, ccTran.Id),
+		InstantOfferRef:  ccTran.DataRef,
+		Amount:           ccTran.Amount,
+		Currency:         ccTran.Currency,
+		CardLocked:       !ownRefund,
+		ProviderData:     event.Message,
+	}
+	if !ownRefund {
+		ccTran.Status = status
+	}
+
+	err := s.dao.AddCCTransactionEvent(ccTran, ccEvent)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+
+	if ccEvent.CardLocked {
+		subject := fmt.Sprintf("[Instant buy] %s on charge %s", event.EventType, ccEvent.ChargeId)
+		body := fmt.Sprintf("User %s card is locked.<br/>Amount: %s %s<br/>Transaction: %s<br/>Instant offer: %s",
+			userId, ccTran.Amount, ccTran.Currency, ccEvent.CCTransactionRef, ccEvent.InstantOfferRef)
+		if err := email.SendOpsAlertEmail(subject, body); err != nil {
+			log.Println("Send ops alert failed", err)
+		}
+	}
+
+	return
 }
 
 func (s CreditCardService) finishInstantOffer(pendingOffer *bean.PendingInstantOffer, ccMode string,
@@ -355,13 +814,12 @@ func (s CreditCardService) finishInstantOffer(pendingOffer *bean.PendingInstantO
 		return
 	}
 	ccTran := ccTranTO.Object.(bean.CCTransaction)
-	// _, err := stripe_service.Capture(ccTran.ExternalId)
-	checkOutResp, err := checkout_service.Capture(ccTran.ExternalId)
+	captureResponse, err := payment.GetGateway(ccTran.Provider).Capture(ccTran.ExternalId)
 	if ce.SetError(api_error.ExternalApiFailed, err) {
 		return
 	}
 	ccTran.Status = bean.CC_TRANSACTION_STATUS_CAPTURED
-	ccTran.ProviderData = checkOutResp
+	ccTran.ProviderData = captureResponse
 
 	s.dao.UpdateCCTransactionStatus(ccTran)
 
@@ -421,13 +879,12 @@ func (s CreditCardService) cancelInstantOffer(pendingOffer *bean.PendingInstantO
 	}
 
 	ccTran := ccTranTO.Object.(bean.CCTransaction)
-	//_, err = stripe_service.Refund(ccTran.ExternalId)
-	checkOutResp, err := checkout_service.Void(ccTran.ExternalId)
+	voidResponse, err := payment.GetGateway(ccTran.Provider).Void(ccTran.ExternalId)
 	if ce.SetError(api_error.ExternalApiFailed, err) {
 		// return
 	} else {
 		ccTran.Status = bean.CC_TRANSACTION_STATUS_REFUNDED
-		ccTran.ProviderData = checkOutResp
+		ccTran.ProviderData = voidResponse
 		s.dao.UpdateCCTransactionStatus(ccTran)
 	}
 
@@ -448,13 +905,7 @@ func (s CreditCardService) cancelInstantOffer(pendingOffer *bean.PendingInstantO
 
 	// Decrease amount track
 	fiatAmount, _ := decimal.NewFromString(offer.FiatAmount)
-	profileTO := s.userDao.GetProfile(offer.UID)
-	if !profileTO.HasError() {
-		profile := profileTO.Object.(bean.Profile)
-		if profile.CreditCard.Token != "" {
-			s.userDao.UpdateUserCCLimitAmount(offer.UID, profile.CreditCard.Token, fiatAmount.Mul(common.NegativeOne))
-		}
-	}
+	s.releaseCCLimit(ccTran, fiatAmount)
 
 	notification.SendInstantOfferNotification(offer)
 }
@@ -488,9 +939,9 @@ func setupInstantOffer(offer *bean.InstantOffer, offerTest bean.InstantOffer, gd
 //	ccTran.ExternalId = stripeCharge.ID
 //}
 
-func setupCCTransaction(ccTran *bean.CCTransaction, offerBody bean.InstantOffer, chargeData interface{}, chargeId string) {
+func setupCCTransaction(ccTran *bean.CCTransaction, offerBody bean.InstantOffer, provider string, chargeData interface{}, chargeId string) {
 	ccTran.Status = bean.CC_TRANSACTION_STATUS_PURCHASED
-	ccTran.Provider = bean.CC_PROVIDER_CHECKOUT
+	ccTran.Provider = provider
 	ccTran.Currency = bean.USD.Code
 	ccTran.Amount = offerBody.FiatAmount
 	ccTran.UID = offerBody.UID
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/payment"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testGatewayName = "test_gateway"
+
+type CreditCardDaoFake struct {
+	offer         bean.InstantOffer
+	ccTran        bean.CCTransaction
+	pendingOffers []bean.PendingAuthenticationInstantOffer
+	claimed       map[string]bool
+	cancelled     []bean.CCTransaction
+}
+
+func newCreditCardDaoFake(offer bean.InstantOffer, ccTran bean.CCTransaction) *CreditCardDaoFake {
+	return &CreditCardDaoFake{
+		offer:   offer,
+		ccTran:  ccTran,
+		claimed: map[string]bool{},
+	}
+}
+
+func (dao *CreditCardDaoFake) AddCCTransaction(ccTran bean.CCTransaction) (bean.CCTransaction, error) {
+	return ccTran, nil
+}
+func (dao *CreditCardDaoFake) UpdateCCTransaction(ccTran bean.CCTransaction) (bean.CCTransaction, error) {
+	return ccTran, nil
+}
+func (dao *CreditCardDaoFake) UpdateCCTransactionStatus(ccTran bean.CCTransaction) (bean.CCTransaction, error) {
+	return ccTran, nil
+}
+func (dao *CreditCardDaoFake) ListCCTransactions(userId string, limit int, startAt interface{}) (t dao.TransferObject) {
+	return
+}
+func (dao *CreditCardDaoFake) GetCCTransaction(userId string, ccTranId string) (t dao.TransferObject) {
+	return
+}
+func (dao *CreditCardDaoFake) GetCCTransactionByPath(path string) (t dao.TransferObject) {
+	t.Found = true
+	t.Object = dao.ccTran
+	return
+}
+func (dao *CreditCardDaoFake) GetCCTransactionByExternalId(userId string, externalId string) (t dao.TransferObject) {
+	if dao.ccTran.UID == userId && dao.ccTran.ExternalId == externalId {
+		t.Found = true
+		t.Object = dao.ccTran
+	}
+	return
+}
+func (dao *CreditCardDaoFake) AddCCTransactionEvent(ccTran bean.CCTransaction, event bean.CCTransactionEvent) error {
+	return nil
+}
+func (dao *CreditCardDaoFake) AddInstantOffer(offer bean.InstantOffer, transaction bean.Transaction, providerId string) (bean.InstantOffer, error) {
+	return offer, nil
+}
+func (dao *CreditCardDaoFake) AddPendingAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction,
+	pendingOffer bean.PendingAuthenticationInstantOffer) (bean.InstantOffer, bean.CCTransaction, error) {
+	return offer, ccTran, nil
+}
+func (dao *CreditCardDaoFake) GetPendingAuthenticationInstantOffer(userId string, offerId string) (t dao.TransferObject) {
+	return
+}
+func (dao *CreditCardDaoFake) ClaimPendingAuthenticationInstantOffer(userId string, offerId string) (bean.PendingAuthenticationInstantOffer, error) {
+	if dao.claimed[offerId] {
+		return bean.PendingAuthenticationInstantOffer{}, errors.New("pending offer is gone")
+	}
+	dao.claimed[offerId] = true
+	return bean.PendingAuthenticationInstantOffer{UID: userId, InstantOffer: offerId}, nil
+}
+func (dao *CreditCardDaoFake) ListPendingAuthenticationInstantOffer() ([]bean.PendingAuthenticationInstantOffer, error) {
+	return dao.pendingOffers, nil
+}
+func (dao *CreditCardDaoFake) UpdateCCTransactionAuthentication(ccTran bean.CCTransaction) (bean.CCTransaction, error) {
+	return ccTran, nil
+}
+func (dao *CreditCardDaoFake) CancelPendingAuthenticationInstantOffer(offer bean.InstantOffer, ccTran bean.CCTransaction) (bean.InstantOffer, error) {
+	dao.cancelled = append(dao.cancelled, ccTran)
+	return offer, nil
+}
+func (dao *CreditCardDaoFake) UpdateInstantOffer(offer bean.InstantOffer, transaction bean.Transaction) (bean.InstantOffer, error) {
+	return offer, nil
+}
+func (dao *CreditCardDaoFake) ReserveCCTransactionRefund(ccTran bean.CCTransaction, amount decimal.Decimal) (bean.CCTransaction, error) {
+	return ccTran, nil
+}
+func (dao *CreditCardDaoFake) ReleaseCCTransactionRefund(ccTran bean.CCTransaction, amount decimal.Decimal) error {
+	return nil
+}
+func (dao *CreditCardDaoFake) AddInstantOfferRefund(offer bean.InstantOffer, ccTran bean.CCTransaction, amount decimal.Decimal) (bean.InstantOffer, bean.CCTransaction, error) {
+	return offer, ccTran, nil
+}
+func (dao *CreditCardDaoFake) ListInstantOffers(userId string, currency string, limit int, startAt interface{}) (t dao.TransferObject) {
+	return
+}
+func (dao *CreditCardDaoFake) GetInstantOffer(userId string, instantOfferId string) (t dao.TransferObject) {
+	return
+}
+func (dao *CreditCardDaoFake) GetInstantOfferByPath(path string) (t dao.TransferObject) {
+	t.Found = true
+	t.Object = dao.offer
+	return
+}
+func (dao *CreditCardDaoFake) ListPendingInstantOffer() ([]bean.PendingInstantOffer, error) {
+	return nil, nil
+}
+func (dao *CreditCardDaoFake) UpdateNotificationInstantOffer(offer bean.InstantOffer) error {
+	return nil
+}
+
+// Answers verification with a fixed status and remembers what it was asked to void
+type GatewayTest struct {
+	status string
+	voided *[]string
+}
+
+func newGatewayTest(status string) GatewayTest {
+	voided := make([]string, 0)
+	gateway := GatewayTest{status: status, voided: &voided}
+	payment.RegisterGateway(gateway)
+	return gateway
+}
+
+func (g GatewayTest) Name() string {
+	return testGatewayName
+}
+func (g GatewayTest) Tokenize(card bean.CreditCardInfo) (string, error) {
+	return "", nil
+}
+func (g GatewayTest) Authorize(request payment.ChargeRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, nil
+}
+func (g GatewayTest) VerifyAuthentication(paymentToken string) (payment.ChargeResult, error) {
+	return payment.ChargeResult{Id: "charge_" + paymentToken, Status: g.status}, nil
+}
+func (g GatewayTest) Capture(chargeId string) (interface{}, error) {
+	return nil, nil
+}
+func (g GatewayTest) Void(chargeId string) (interface{}, error) {
+	*g.voided = append(*g.voided, chargeId)
+	return nil, nil
+}
+func (g GatewayTest) Refund(chargeId string, amount decimal.Decimal) (interface{}, error) {
+	return nil, nil
+}
+
+func pendingAuthenticationTestData() (bean.InstantOffer, bean.CCTransaction) {
+	offer := bean.InstantOffer{
+		Id:     "offer_1",
+		UID:    "1",
+		Status: bean.INSTANT_OFFER_STATUS_PENDING_AUTHENTICATION,
+	}
+	ccTran := bean.CCTransaction{
+		Id:         "cc_1",
+		UID:        "1",
+		Status:     bean.CC_TRANSACTION_STATUS_PENDING_AUTHENTICATION,
+		Provider:   testGatewayName,
+		ExternalId: "pay_1",
+	}
+	return offer, ccTran
+}
+
+func TestAuthenticateInstantOfferAlreadyClaimed(t *testing.T) {
+	offer, ccTran := pendingAuthenticationTestData()
+	daoFake := newCreditCardDaoFake(offer, ccTran)
+	daoFake.claimed[offer.Id] = true
+	gateway := newGatewayTest(payment.CHARGE_STATUS_AUTHORISED)
+
+	serviceInst := CreditCardService{dao: daoFake}
+	_, ce := serviceInst.AuthenticateInstantOffer("1", "pay_1")
+	assert.Equal(t, api_error.OfferStatusInvalid, ce.StatusKey)
+	assert.Equal(t, 0, len(daoFake.cancelled))
+	assert.Equal(t, 0, len(*gateway.voided))
+}
+
+func TestAuthenticateInstantOfferDeclined(t *testing.T) {
+	offer, ccTran := pendingAuthenticationTestData()
+	daoFake := newCreditCardDaoFake(offer, ccTran)
+	gateway := newGatewayTest(payment.CHARGE_STATUS_DECLINED)
+
+	serviceInst := CreditCardService{dao: daoFake}
+	offer, ce := serviceInst.AuthenticateInstantOffer("1", "pay_1")
+	assert.Equal(t, api_error.CardAuthenticationFailed, ce.StatusKey)
+	assert.Equal(t, bean.INSTANT_OFFER_STATUS_CANCELLED, offer.Status)
+	assert.Equal(t, 1, len(daoFake.cancelled))
+	assert.Equal(t, bean.CC_TRANSACTION_STATUS_FAILED, daoFake.cancelled[0].Status)
+	assert.Equal(t, 0, len(*gateway.voided))
+
+	// A second return finds the record claimed
+	_, ce = serviceInst.AuthenticateInstantOffer("1", "pay_1")
+	assert.Equal(t, api_error.OfferStatusInvalid, ce.StatusKey)
+	assert.Equal(t, 1, len(daoFake.cancelled))
+}
+
+func TestExpirePendingAuthenticationVoidsAuthorisedCharge(t *testing.T) {
+	offer, ccTran := pendingAuthenticationTestData()
+	daoFake := newCreditCardDaoFake(offer, ccTran)
+	daoFake.pendingOffers = []bean.PendingAuthenticationInstantOffer{
+		{UID: "1", InstantOffer: offer.Id, CreatedAt: time.Now().UTC().Add(-time.Hour)},
+	}
+	gateway := newGatewayTest(payment.CHARGE_STATUS_AUTHORISED)
+
+	serviceInst := CreditCardService{dao: daoFake}
+	var ce SimpleContextError
+	serviceInst.expirePendingAuthenticationInstantOffers(&ce)
+	assert.False(t, ce.HasError())
+	assert.Equal(t, []string{"charge_pay_1"}, *gateway.voided)
+	assert.Equal(t, 1, len(daoFake.cancelled))
+	assert.Equal(t, bean.CC_TRANSACTION_STATUS_REFUNDED, daoFake.cancelled[0].Status)
+}
+
+func TestExpirePendingAuthenticationSkipsClaimedAndRecent(t *testing.T) {
+	offer, ccTran := pendingAuthenticationTestData()
+	daoFake := newCreditCardDaoFake(offer, ccTran)
+	daoFake.claimed["offer_2"] = true
+	daoFake.pendingOffers = []bean.PendingAuthenticationInstantOffer{
+		{UID: "1", InstantOffer: offer.Id, CreatedAt: time.Now().UTC()},
+		{UID: "1", InstantOffer: "offer_2", CreatedAt: time.Now().UTC().Add(-time.Hour)},
+	}
+	gateway := newGatewayTest(payment.CHARGE_STATUS_AUTHORISED)
+
+	serviceInst := CreditCardService{dao: daoFake}
+	var ce SimpleContextError
+	serviceInst.expirePendingAuthenticationInstantOffers(&ce)
+	assert.False(t, ce.HasError())
+	assert.Equal(t, 0, len(*gateway.voided))
+	assert.Equal(t, 0, len(daoFake.cancelled))
+}
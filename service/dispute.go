@@ -0,0 +1,313 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/integration/exchangehandshakeshop_service"
+	"github.com/ninjadotorg/handshake-exchange/service/notification"
+	"strings"
+)
+
+type DisputeService struct {
+	dao           dao.DisputeDaoInterface
+	miscDao       *dao.MiscDao
+	offerDao      *dao.OfferDao
+	offerStoreDao *dao.OfferStoreDao
+}
+
+func (s DisputeService) OpenOfferDispute(userId string, offerId string, body bean.DisputeRequest) (dispute bean.Dispute, ce SimpleContextError) {
+	offerPtr := GetOffer(*s.offerDao, offerId, &ce)
+	if ce.HasError() {
+		return
+	}
+	offer := *offerPtr
+
+	if userId != offer.UID && userId != offer.ToUID {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+	if offer.Status != bean.OFFER_STATUS_SHAKE {
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
+	// ETH offers are settled by the parties on chain, the exchange has no key to rule with
+	if offer.Currency != bean.BTC.Code {
+		ce.SetStatusKey(api_error.DisputeNotSupported)
+		return
+	}
+
+	dispute = bean.Dispute{
+		Type:     bean.DISPUTE_TYPE_OFFER,
+		OfferId:  offer.Id,
+		Currency: offer.Currency,
+		UID:      userId,
+		Reason:   body.Reason,
+	}
+	if offer.IsTypeSell() {
+		dispute.SellerUID, dispute.BuyerUID = offer.UID, offer.ToUID
+	} else {
+		dispute.SellerUID, dispute.BuyerUID = offer.ToUID, offer.UID
+	}
+
+	offer.Status = bean.OFFER_STATUS_DISPUTED
+	var err error
+	dispute, err = s.dao.AddOfferDispute(offer, dispute)
+	if ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	dispute.Status = bean.DISPUTE_STATUS_OPEN
+
+	offer.ActionUID = userId
+	notification.SendOfferNotification(offer)
+
+	return
+}
+
+func (s DisputeService) OpenOfferStoreShakeDispute(userId string, offerId string, offerShakeId string, body bean.DisputeRequest) (dispute bean.Dispute, ce SimpleContextError) {
+	offerPtr := GetOfferStore(*s.offerStoreDao, offerId, &ce)
+	if ce.HasError() {
+		return
+	}
+	offer := *offerPtr
+	offerShakePtr := GetOfferStoreShake(*s.offerStoreDao, offerId, offerShakeId, &ce)
+	if ce.HasError() {
+		return
+	}
+	offerShake := *offerShakePtr
+
+	if userId != offer.UID && userId != offerShake.UID {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+	if offerShake.Status != bean.OFFER_STORE_SHAKE_STATUS_SHAKE {
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
+
+	dispute = bean.Dispute{
+		Type:         bean.DISPUTE_TYPE_OFFER_STORE_SHAKE,
+		OfferId:      offer.Id,
+		OfferShakeId: offerShake.Id,
+		Currency:     offerShake.Currency,
+		UID:          userId,
+		Reason:       body.Reason,
+	}
+	if offerShake.Type == bean.OFFER_TYPE_SELL {
+		dispute.SellerUID, dispute.BuyerUID = offer.UID, offerShake.UID
+	} else {
+		dispute.SellerUID, dispute.BuyerUID = offerShake.UID, offer.UID
+	}
+
+	offerShake.Status = bean.OFFER_STORE_SHAKE_STATUS_DISPUTED
+	var err error
+	dispute, err = s.dao.AddOfferStoreShakeDispute(offer.Id, offerShake, dispute)
+	if ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	dispute.Status = bean.DISPUTE_STATUS_OPEN
+
+	offerShake.ActionUID = userId
+	notification.SendOfferStoreShakeNotification(offerShake, offer)
+
+	return
+}
+
+func (s DisputeService) GetDispute(userId string, disputeId string) (dispute bean.Dispute, ce SimpleContextError) {
+	disputeTO := s.dao.GetDispute(disputeId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, disputeTO); ce.HasError() {
+		return
+	}
+	dispute = disputeTO.Object.(bean.Dispute)
+
+	if !dispute.IsParty(userId) && !s.isArbitrator(userId) {
+		ce.SetStatusKey(api_error.InvalidRequestParam)
+	}
+
+	return
+}
+
+func (s DisputeService) ListDisputes(userId string, status string, limit int, startAt interface{}) (to dao.TransferObject, ce SimpleContextError) {
+	if !s.isArbitrator(userId) {
+		ce.SetStatusKey(api_error.NotArbitrator)
+		return
+	}
+	to = s.dao.ListDisputes(status, limit, startAt)
+	ce.FeedDaoTransfer(api_error.GetDataFailed, to)
+
+	return
+}
+
+// Rulings run the same complete and reject paths the parties would have, acting as the losing side
+func (s DisputeService) ResolveDispute(userId string, disputeId string, body bean.DisputeRulingRequest) (dispute bean.Dispute, ce SimpleContextError) {
+	if !s.isArbitrator(userId) {
+		ce.SetStatusKey(api_error.NotArbitrator)
+		return
+	}
+
+	return s.resolveDispute(userId, disputeId, body)
+}
+
+func (s DisputeService) resolveDispute(userId string, disputeId string, body bean.DisputeRulingRequest) (dispute bean.Dispute, ce SimpleContextError) {
+	disputeTO := s.dao.GetDispute(disputeId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, disputeTO); ce.HasError() {
+		return
+	}
+	dispute = disputeTO.Object.(bean.Dispute)
+
+	if dispute.Status != bean.DISPUTE_STATUS_OPEN {
+		ce.SetStatusKey(api_error.DisputeAlreadyResolved)
+		return
+	}
+
+	// Claim it before anything moves, a second arbitrator or a retried request must not pay out again.
+	// A ruling that stops half way through the contract call stays resolving until someone checks the chain
+	dispute.Ruling = body.Ruling
+	dispute.ArbitratorUID = userId
+	dispute, err := s.dao.ClaimDisputeRuling(dispute)
+	if ce.SetError(api_error.DisputeAlreadyResolved, err) {
+		return
+	}
+
+	if dispute.Type == bean.DISPUTE_TYPE_OFFER {
+		s.executeOfferRuling(dispute, body.Ruling, &ce)
+	} else {
+		s.executeOfferStoreShakeRuling(&dispute, body.Ruling, &ce)
+	}
+	if ce.HasError() {
+		s.dao.ReleaseDisputeRuling(dispute)
+		dispute.Status = bean.DISPUTE_STATUS_OPEN
+		return
+	}
+
+	dispute.Status = bean.DISPUTE_STATUS_RESOLVED
+	dispute.Note = body.Note
+	err = s.dao.UpdateDisputeRuling(dispute)
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
+
+func (s DisputeService) executeOfferRuling(dispute bean.Dispute, ruling string, ce *SimpleContextError) {
+	offerPtr := GetOffer(*s.offerDao, dispute.OfferId, ce)
+	if ce.HasError() {
+		return
+	}
+	if offerPtr.Status != bean.OFFER_STATUS_DISPUTED {
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
+
+	err := s.dao.UpdateDisputedOfferStatus(dispute.OfferId, bean.OFFER_STATUS_SHAKE)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+	var rulingCE SimpleContextError
+	if ruling == bean.DISPUTE_RULING_RELEASE {
+		_, rulingCE = OfferServiceInst.CompleteShakeOffer(dispute.SellerUID, dispute.OfferId)
+	} else {
+		_, rulingCE = OfferServiceInst.RejectShakeOffer(dispute.BuyerUID, dispute.OfferId)
+	}
+	if rulingCE.HasError() {
+		// Freeze it again, the parties must not settle it while the dispute is open
+		s.dao.UpdateDisputedOfferStatus(dispute.OfferId, bean.OFFER_STATUS_DISPUTED)
+		ce.FeedContextErrorDefault(rulingCE)
+	}
+}
+
+func (s DisputeService) executeOfferStoreShakeRuling(dispute *bean.Dispute, ruling string, ce *SimpleContextError) {
+	offerPtr := GetOfferStore(*s.offerStoreDao, dispute.OfferId, ce)
+	if ce.HasError() {
+		return
+	}
+	offer := *offerPtr
+	offerShakePtr := GetOfferStoreShake(*s.offerStoreDao, dispute.OfferId, dispute.OfferShakeId, ce)
+	if ce.HasError() {
+		return
+	}
+	itemPtr := GetOfferStoreItem(*s.offerStoreDao, dispute.OfferId, offerShakePtr.Currency, ce)
+	if ce.HasError() {
+		return
+	}
+	item := *itemPtr
+
+	offerShake := *offerShakePtr
+
+	switch offerShake.Status {
+	case bean.OFFER_STORE_SHAKE_STATUS_DISPUTED:
+		err := s.dao.UpdateDisputedOfferStoreShakeStatus(dispute.OfferId, dispute.OfferShakeId, bean.OFFER_STORE_SHAKE_STATUS_SHAKE)
+		if ce.SetError(api_error.UpdateDataFailed, err) {
+			return
+		}
+		var rulingCE SimpleContextError
+		if ruling == bean.DISPUTE_RULING_RELEASE {
+			offerShake, rulingCE = OfferStoreServiceInst.CompleteOfferStoreShake(dispute.SellerUID, dispute.OfferId, dispute.OfferShakeId)
+		} else {
+			offerShake, rulingCE = OfferStoreServiceInst.RejectOfferStoreShake(dispute.BuyerUID, dispute.OfferId, dispute.OfferShakeId)
+		}
+		if rulingCE.HasError() {
+			// Freeze it again, the parties must not settle it while the dispute is open
+			s.dao.UpdateDisputedOfferStoreShakeStatus(dispute.OfferId, dispute.OfferShakeId, bean.OFFER_STORE_SHAKE_STATUS_DISPUTED)
+			ce.FeedContextErrorDefault(rulingCE)
+			return
+		}
+	case bean.OFFER_STORE_SHAKE_STATUS_COMPLETING, bean.OFFER_STORE_SHAKE_STATUS_REJECTING:
+		// An earlier ruling got this far and only the contract call failed, retry just that
+		retryRuling := bean.DISPUTE_RULING_REFUND
+		if offerShake.Status == bean.OFFER_STORE_SHAKE_STATUS_COMPLETING {
+			retryRuling = bean.DISPUTE_RULING_RELEASE
+		}
+		if ruling != retryRuling {
+			ce.SetStatusKey(api_error.OfferStatusInvalid)
+			return
+		}
+	default:
+		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
+	}
+
+	// ETH is held by the shop contract and the losing side will not sign, so the exchange does.
+	// The on chain tracking record written by complete or reject finishes the shake
+	client := exchangehandshakeshop_service.ExchangeHandshakeShopClient{}
+	var txHash string
+	var err error
+	if offerShake.Status == bean.OFFER_STORE_SHAKE_STATUS_COMPLETING {
+		// Free start shakes completed by the shop owner are already released by the complete path
+		if item.FreeStart != "" && dispute.SellerUID == offer.UID {
+			return
+		}
+		address := offerShake.UserAddress
+		amount := offerShake.Amount
+		if address == "" || offerShake.Type == bean.OFFER_TYPE_BUY {
+			address = item.UserAddress
+		}
+		if offerShake.Type == bean.OFFER_TYPE_BUY {
+			amount = offerShake.TotalAmount
+		}
+		txHash, err = client.ReleasePartialFund(offerShake.OffChainId, offer.Hid, offer.UID, common.StringToDecimal(amount), address)
+	} else if offerShake.Status == bean.OFFER_STORE_SHAKE_STATUS_REJECTING {
+		txHash, err = client.Reject(offerShake.OffChainId, offer.Hid)
+	}
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		return
+	}
+	dispute.TxHash = txHash
+}
+
+func (s DisputeService) isArbitrator(userId string) bool {
+	if userId == "" {
+		return false
+	}
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_DISPUTE_ARBITRATORS)
+	if systemConfigTO.HasError() {
+		return false
+	}
+	for _, arbitrator := range strings.Split(systemConfigTO.Object.(bean.SystemConfig).Value, ",") {
+		if strings.TrimSpace(arbitrator) == userId {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type DisputeDaoFake struct {
+	dispute  bean.Dispute
+	claimErr error
+	claimed  []bean.Dispute
+	released []bean.Dispute
+	resolved []bean.Dispute
+}
+
+func (dao *DisputeDaoFake) AddOfferDispute(offer bean.Offer, dispute bean.Dispute) (bean.Dispute, error) {
+	return dispute, nil
+}
+func (dao *DisputeDaoFake) AddOfferStoreShakeDispute(offerStoreId string, offerShake bean.OfferStoreShake, dispute bean.Dispute) (bean.Dispute, error) {
+	return dispute, nil
+}
+func (dao *DisputeDaoFake) UpdateDisputedOfferStatus(offerId string, status string) error {
+	return nil
+}
+func (dao *DisputeDaoFake) UpdateDisputedOfferStoreShakeStatus(offerStoreId string, offerShakeId string, status string) error {
+	return nil
+}
+func (dao *DisputeDaoFake) ClaimDisputeRuling(dispute bean.Dispute) (bean.Dispute, error) {
+	dao.claimed = append(dao.claimed, dispute)
+	if dao.claimErr != nil {
+		return dispute, dao.claimErr
+	}
+	dispute.Status = bean.DISPUTE_STATUS_RESOLVING
+	return dispute, nil
+}
+func (dao *DisputeDaoFake) ReleaseDisputeRuling(dispute bean.Dispute) error {
+	dao.released = append(dao.released, dispute)
+	return nil
+}
+func (dao *DisputeDaoFake) UpdateDisputeRuling(dispute bean.Dispute) error {
+	dao.resolved = append(dao.resolved, dispute)
+	return nil
+}
+func (dao *DisputeDaoFake) GetDispute(disputeId string) (t dao.TransferObject) {
+	if dao.dispute.Id == disputeId {
+		t.Found = true
+		t.Object = dao.dispute
+	}
+	return
+}
+func (dao *DisputeDaoFake) ListDisputes(status string, limit int, startAt interface{}) (t dao.TransferObject) {
+	return
+}
+
+func disputeTestData(status string) bean.Dispute {
+	return bean.Dispute{
+		Id:        "dispute_1",
+		Type:      bean.DISPUTE_TYPE_OFFER,
+		OfferId:   "offer_1",
+		SellerUID: "1",
+		BuyerUID:  "2",
+		Status:    status,
+	}
+}
+
+func TestResolveDisputeNotArbitrator(t *testing.T) {
+	daoFake := &DisputeDaoFake{dispute: disputeTestData(bean.DISPUTE_STATUS_OPEN)}
+
+	serviceInst := DisputeService{dao: daoFake}
+	_, ce := serviceInst.ResolveDispute("", "dispute_1", bean.DisputeRulingRequest{Ruling: bean.DISPUTE_RULING_RELEASE})
+	assert.Equal(t, api_error.NotArbitrator, ce.StatusKey)
+	assert.Equal(t, 0, len(daoFake.claimed))
+}
+
+func TestResolveDisputeNotOpen(t *testing.T) {
+	for _, status := range []string{bean.DISPUTE_STATUS_RESOLVING, bean.DISPUTE_STATUS_RESOLVED} {
+		daoFake := &DisputeDaoFake{dispute: disputeTestData(status)}
+
+		serviceInst := DisputeService{dao: daoFake}
+		_, ce := serviceInst.resolveDispute("3", "dispute_1", bean.DisputeRulingRequest{Ruling: bean.DISPUTE_RULING_RELEASE})
+		assert.Equal(t, api_error.DisputeAlreadyResolved, ce.StatusKey)
+		assert.Equal(t, 0, len(daoFake.claimed))
+		assert.Equal(t, 0, len(daoFake.resolved))
+	}
+}
+
+// Another arbitrator claimed it between the read and the claim, nothing runs
+func TestResolveDisputeClaimedByAnotherArbitrator(t *testing.T) {
+	daoFake := &DisputeDaoFake{
+		dispute:  disputeTestData(bean.DISPUTE_STATUS_OPEN),
+		claimErr: errors.New("Dispute is not open"),
+	}
+
+	serviceInst := DisputeService{dao: daoFake}
+	_, ce := serviceInst.resolveDispute("3", "dispute_1", bean.DisputeRulingRequest{Ruling: bean.DISPUTE_RULING_REFUND})
+	assert.Equal(t, api_error.DisputeAlreadyResolved, ce.StatusKey)
+	assert.Equal(t, 1, len(daoFake.claimed))
+	assert.Equal(t, bean.DISPUTE_RULING_REFUND, daoFake.claimed[0].Ruling)
+	assert.Equal(t, "3", daoFake.claimed[0].ArbitratorUID)
+	assert.Equal(t, 0, len(daoFake.released))
+	assert.Equal(t, 0, len(daoFake.resolved))
+}
+
+func TestGetDisputeOnlyForParties(t *testing.T) {
+	daoFake := &DisputeDaoFake{dispute: disputeTestData(bean.DISPUTE_STATUS_OPEN)}
+	serviceInst := DisputeService{dao: daoFake}
+
+	dispute, ce := serviceInst.GetDispute("2", "dispute_1")
+	assert.False(t, ce.HasError())
+	assert.Equal(t, "dispute_1", dispute.Id)
+
+	_, ce = serviceInst.GetDispute("", "dispute_1")
+	assert.Equal(t, api_error.InvalidRequestParam, ce.StatusKey)
+
+	_, ce = serviceInst.GetDispute("2", "dispute_2")
+	assert.True(t, ce.HasError())
+}
@@ -15,6 +15,7 @@ const OfferTakerTakerRejected = "OfferTakerTakerRejected"
 const OfferTakerMakerRejected = "OfferTakerMakerRejected"
 const OfferWithdraw = "OfferWithdraw"
 const OrderInstantCCSuccess = "OrderInstantCCSuccess"
+const OrderInstantCCRefunded = "OrderInstantCCRefunded"
 const OfferStoreItemAdded = "OfferStoreItemAdded"
 const OfferStoreItemRemoved = "OfferStoreItemRemoved"
 const OfferStoreMakerSellShake = "OfferStoreMakerSellShake"
@@ -31,6 +32,7 @@ var TemplateName = map[string]string{
 	OfferSellingActive:       "offer-selling-active-",
 	OfferClosed:              "offer-closed-",
 	OrderInstantCCSuccess:    "order-instant-cc-success-",
+	OrderInstantCCRefunded:   "order-instant-cc-refunded-",
 	OfferTakerBuyShake:       "offer-taker-buy-shake-",
 	OfferMakerBuyShake:       "offer-maker-buy-shake-",
 	OfferTakerSellShake:      "offer-taker-sell-shake-",
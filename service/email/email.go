@@ -1,10 +1,7 @@
 package email
 
 import (
-	"bytes"
 	"github.com/ninjadotorg/handshake-exchange/api_error"
-	"github.com/ninjadotorg/handshake-exchange/integration/sendgrid_service"
-	"html/template"
 	"os"
 )
 
@@ -17,17 +14,37 @@ func SendSystemEmailWithTemplate(toName string, toAddress string, language strin
 }
 
 func SendEmailWithTemplate(fromName string, fromAddress string, toName string, toAddress string, language string, subject string, templateKey string, data interface{}) error {
-	templatePath := "./templates/" + TemplateName[templateKey] + language + ".html"
-
-	t, _ := template.ParseFiles(templatePath)
-	buffer := bytes.NewBufferString("")
-	err := t.Execute(buffer, data)
+	body, err := Render(templateKey, language, data)
 
 	if err == nil {
-		err = sendgrid_service.SendEmail(fromName, fromAddress, toName, toAddress, subject, buffer.String())
+		err = TransportInst.Send(Message{
+			FromName:    fromName,
+			FromAddress: fromAddress,
+			ToName:      toName,
+			ToAddress:   toAddress,
+			Subject:     subject,
+			Body:        body,
+		})
 	} else {
 		err = api_error.PropagateError(api_error.UnexpectedError, err)
 	}
 
 	return err
 }
+
+// Internal alerts, not localized, dropped when OPS_EMAIL_ADDRESS is not set
+func SendOpsAlertEmail(subject string, body string) error {
+	opsAddress := os.Getenv("OPS_EMAIL_ADDRESS")
+	if opsAddress == "" {
+		return nil
+	}
+
+	return TransportInst.Send(Message{
+		FromName:    os.Getenv("EMAIL_FROM_NAME"),
+		FromAddress: os.Getenv("EMAIL_FROM_ADDRESS"),
+		ToName:      "Ops",
+		ToAddress:   opsAddress,
+		Subject:     subject,
+		Body:        body,
+	})
+}
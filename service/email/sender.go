@@ -2,12 +2,12 @@ package email
 
 import (
 	"fmt"
-	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
 	"os"
 )
 
 func SendOfferBuyingActiveEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_buying_active_subject")
 
@@ -28,7 +28,7 @@ func SendOfferBuyingActiveEmail(language string, emailAddress string) error {
 }
 
 func SendOfferSellingActiveEmail(language, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_selling_active_subject")
 
@@ -49,7 +49,7 @@ func SendOfferSellingActiveEmail(language, emailAddress string) error {
 }
 
 func SendOfferClosedEmail(language, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_closed_subject")
 	host := os.Getenv("FRONTEND_HOST")
@@ -69,7 +69,7 @@ func SendOfferClosedEmail(language, emailAddress string) error {
 }
 
 func SendOfferMakerBuyShakeEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_maker_buy_shake_subject")
 
@@ -90,7 +90,7 @@ func SendOfferMakerBuyShakeEmail(language string, emailAddress string) error {
 }
 
 func SendOfferTakerBuyShakeEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_taker_buy_shake_subject")
 
@@ -111,7 +111,7 @@ func SendOfferTakerBuyShakeEmail(language string, emailAddress string) error {
 }
 
 func SendOfferMakerSellShakeEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_maker_sell_shake_subject")
 
@@ -132,7 +132,7 @@ func SendOfferMakerSellShakeEmail(language string, emailAddress string) error {
 }
 
 func SendOfferTakerSellShakeEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_taker_sell_shake_subject")
 
@@ -153,7 +153,7 @@ func SendOfferTakerSellShakeEmail(language string, emailAddress string) error {
 }
 
 func SendOfferMakerMakerRejectEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_maker_maker_rejected_subject")
 
@@ -173,16 +173,19 @@ func SendOfferMakerMakerRejectEmail(language string, emailAddress string) error
 		data)
 }
 
-func SendOfferTakerMakerRejectEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+// Action is what the maker wanted to do, buy or sell
+func SendOfferTakerMakerRejectEmail(language string, emailAddress string, action string) error {
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_taker_maker_rejected_subject")
 
 	host := os.Getenv("FRONTEND_HOST")
 	data := struct {
-		Url string
+		Url    string
+		Action string
 	}{
-		Url: fmt.Sprintf("%s/discover?id=6", host),
+		Url:    fmt.Sprintf("%s/discover?id=6", host),
+		Action: action,
 	}
 
 	return SendSystemEmailWithTemplate(
@@ -195,7 +198,7 @@ func SendOfferTakerMakerRejectEmail(language string, emailAddress string) error
 }
 
 func SendOfferMakerTakerRejectEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_maker_taker_rejected_subject")
 
@@ -216,7 +219,7 @@ func SendOfferMakerTakerRejectEmail(language string, emailAddress string) error
 }
 
 func SendOfferTakerTakerRejectEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_taker_taker_rejected_subject")
 
@@ -240,7 +243,7 @@ func SendOfferBuyCompleteEmail(language string, emailAddress string) error {
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_buy_completed_subject")
 
@@ -264,7 +267,7 @@ func SendOfferSellCompleteEmail(language string, emailAddress string) error {
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_sell_completed_subject")
 
@@ -289,7 +292,7 @@ func SendOfferWithdrawEmail(language string, emailAddress string,
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_withdraw_subject", map[string]string{
 		"Currency": currency,
@@ -313,7 +316,7 @@ func SendOfferWithdrawEmail(language string, emailAddress string,
 }
 
 func SendOrderInstantCCSuccessEmail(language string, emailAddress string, amount string, currency string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_order_instant_cc_success_subject")
 
@@ -336,8 +339,37 @@ func SendOrderInstantCCSuccessEmail(language string, emailAddress string, amount
 		data)
 }
 
+func SendOrderInstantCCRefundedEmail(language string, emailAddress string, amount string, currency string,
+	fiatAmount string, fiatCurrency string) error {
+	T := translation.Tfunc(language)
+
+	subject := T("email_order_instant_cc_refunded_subject")
+
+	data := struct {
+		Name         string
+		Currency     string
+		Amount       string
+		FiatAmount   string
+		FiatCurrency string
+	}{
+		Name:         emailAddress,
+		Currency:     currency,
+		Amount:       amount,
+		FiatAmount:   fiatAmount,
+		FiatCurrency: fiatCurrency,
+	}
+
+	return SendSystemEmailWithTemplate(
+		"",
+		emailAddress,
+		language,
+		subject,
+		OrderInstantCCRefunded,
+		data)
+}
+
 func SendOfferStoreItemAddedEmail(language string, emailAddress string, sellAmount string, buyAmount string, currency string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_item_added")
 
@@ -361,7 +393,7 @@ func SendOfferStoreItemAddedEmail(language string, emailAddress string, sellAmou
 }
 
 func SendOfferStoreItemRemovedEmail(language string, emailAddress string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_item_removed")
 
@@ -386,7 +418,7 @@ func SendOfferStoreMakerSellShakeEmail(language string, emailAddress string, amo
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_maker_sell_shake", map[string]string{
 		"Currency": currency,
@@ -421,7 +453,7 @@ func SendOfferStoreMakerBuyShakeEmail(language string, emailAddress string, amou
 		return nil
 	}
 
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_maker_buy_shake", map[string]string{
 		"Currency": currency,
@@ -455,7 +487,7 @@ func SendOfferStoreTakerSellShakeEmail(language string, emailAddress string, amo
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_taker_sell_shake", map[string]string{
 		"Currency": currency,
@@ -489,7 +521,7 @@ func SendOfferStoreTakerBuyShakeEmail(language string, emailAddress string, amou
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_taker_buy_shake", map[string]string{
 		"Currency": currency,
@@ -519,7 +551,7 @@ func SendOfferStoreTakerBuyShakeEmail(language string, emailAddress string, amou
 }
 
 func SendOfferStoreMakerCompleteEmail(language string, emailAddress string, amount string, currency string, username string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_maker_accept", map[string]string{
 		"Currency": currency,
@@ -546,7 +578,7 @@ func SendOfferStoreMakerCompleteEmail(language string, emailAddress string, amou
 
 func SendOfferStoreTakerCompleteEmail(language string, emailAddress string, amount string, currency string,
 	username string, usernameStore string, offerId string, offerShakeId string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_taker_accept", map[string]string{
 		"Currency": currency,
@@ -580,7 +612,7 @@ func SendOfferStoreMakerRejectEmail(language string, emailAddress string, userna
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_maker_reject", map[string]string{
 		"Username": username,
@@ -608,7 +640,7 @@ func SendOfferStoreTakerRejectEmail(language string, emailAddress string, userna
 	if emailAddress == "" {
 		return nil
 	}
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	subject := T("email_offer_store_taker_reject", map[string]string{
 		"Username": username,
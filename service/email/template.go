@@ -0,0 +1,111 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
+	"html/template"
+	"os"
+	"sort"
+)
+
+var TemplateDir = "./templates/"
+
+// Parsed once by LoadTemplates, keyed by template key and language
+var templates = map[string]*template.Template{}
+
+// Every template variable is set, so any template renders with it
+var SampleData = map[string]interface{}{
+	"Url":            "https://ninja.example/me",
+	"CreateOfferUrl": "https://ninja.example/create?id=6",
+	"ListOfferUrl":   "https://ninja.example/discover?id=6",
+	"Action":         "cancelled",
+	"Name":           "Ninja",
+	"Username":       "ninja",
+	"UsernameStore":  "ninja-store",
+	"Amount":         "0.5",
+	"BuyAmount":      "1",
+	"SellAmount":     "2",
+	"Currency":       "ETH",
+	"FiatAmount":     "250",
+	"FiatCurrency":   "USD",
+	"FiatPrice":      "500",
+	"Price":          "500",
+}
+
+// A missing en-US template or a template that does not parse fails the whole load
+func LoadTemplates() error {
+	parsed := map[string]*template.Template{}
+	for _, key := range TemplateKeys() {
+		for _, language := range translation.Languages {
+			templatePath := templateFilePath(key, language)
+			if _, err := os.Stat(templatePath); err != nil {
+				if language == translation.DEFAULT_LANGUAGE {
+					return fmt.Errorf("template %s is missing %s", key, templatePath)
+				}
+				continue
+			}
+			t, err := template.ParseFiles(templatePath)
+			if err != nil {
+				return err
+			}
+			parsed[templateCacheKey(key, language)] = t
+		}
+	}
+	templates = parsed
+
+	return nil
+}
+
+func MustLoadTemplates() {
+	if err := LoadTemplates(); err != nil {
+		panic(err)
+	}
+}
+
+func Render(templateKey string, language string, data interface{}) (string, error) {
+	t, ok := templates[templateCacheKey(templateKey, translation.Canonical(language))]
+	if !ok {
+		t, ok = templates[templateCacheKey(templateKey, translation.DEFAULT_LANGUAGE)]
+	}
+	if !ok {
+		return "", errors.New("email template not found " + templateKey)
+	}
+
+	buffer := bytes.NewBufferString("")
+	err := t.Execute(buffer, data)
+
+	return buffer.String(), err
+}
+
+func TemplateKeys() []string {
+	keys := make([]string, 0)
+	for key := range TemplateName {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func templateCacheKey(templateKey string, language string) string {
+	return templateKey + "|" + language
+}
+
+// Template files that do not exist, listed as key and expected path
+func MissingTemplates(language string) []string {
+	missing := make([]string, 0)
+	for _, key := range TemplateKeys() {
+		templatePath := templateFilePath(key, language)
+		if _, err := os.Stat(templatePath); err != nil {
+			missing = append(missing, key+" "+templatePath)
+		}
+	}
+
+	return missing
+}
+
+func templateFilePath(templateKey string, language string) string {
+	return TemplateDir + TemplateName[templateKey] + language + ".html"
+}
@@ -0,0 +1,53 @@
+package email
+
+import (
+	"flag"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestTemplatesMatchGolden(t *testing.T) {
+	TemplateDir = "../../templates/"
+	translation.MustLoadTranslations("../../translations")
+	assert.Nil(t, LoadTemplates())
+
+	for _, key := range TemplateKeys() {
+		for _, language := range translation.Languages {
+			if _, ok := templates[templateCacheKey(key, language)]; !ok {
+				continue
+			}
+			body, err := Render(key, language, SampleData)
+			assert.Nil(t, err, key)
+
+			goldenPath := filepath.Join("testdata", key+"."+language+".golden.html")
+			if *update {
+				ioutil.WriteFile(goldenPath, []byte(body), 0644)
+			}
+			expected, err := ioutil.ReadFile(goldenPath)
+			assert.Nil(t, err, goldenPath)
+			assert.Equal(t, string(expected), body, key)
+		}
+	}
+}
+
+func TestRenderFallsBackToDefaultLanguage(t *testing.T) {
+	TemplateDir = "../../templates/"
+	assert.Nil(t, LoadTemplates())
+
+	expected, err := Render(OfferBuyingActive, translation.DEFAULT_LANGUAGE, SampleData)
+	assert.Nil(t, err)
+	body, err := Render(OfferBuyingActive, "xx-XX", SampleData)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, body)
+}
+
+func TestLoadTemplatesFailsOnMissingTemplate(t *testing.T) {
+	TemplateDir = "./testdata/missing/"
+	assert.NotNil(t, LoadTemplates())
+	TemplateDir = "../../templates/"
+}
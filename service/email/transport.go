@@ -0,0 +1,111 @@
+package email
+
+import (
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/integration/sendgrid_service"
+	"github.com/ninjadotorg/handshake-exchange/integration/smtp_service"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const TRANSPORT_SENDGRID = "sendgrid"
+const TRANSPORT_SMTP = "smtp"
+const TRANSPORT_SINK = "sink"
+
+type Message struct {
+	FromName    string
+	FromAddress string
+	ToName      string
+	ToAddress   string
+	Subject     string
+	Body        string
+}
+
+type Transport interface {
+	Send(message Message) error
+}
+
+var TransportInst Transport = SendGridTransport{}
+
+func InitializeTransport(name string, sinkDir string) {
+	switch name {
+	case TRANSPORT_SMTP:
+		TransportInst = SMTPTransport{}
+	case TRANSPORT_SINK:
+		TransportInst = NewSinkTransport(sinkDir)
+	default:
+		TransportInst = SendGridTransport{}
+	}
+}
+
+type SendGridTransport struct {
+}
+
+func (t SendGridTransport) Send(message Message) error {
+	return sendgrid_service.SendEmail(message.FromName, message.FromAddress, message.ToName, message.ToAddress, message.Subject, message.Body)
+}
+
+type SMTPTransport struct {
+}
+
+func (t SMTPTransport) Send(message Message) error {
+	return smtp_service.SendEmail(message.FromName, message.FromAddress, message.ToName, message.ToAddress, message.Subject, message.Body)
+}
+
+// Development mailbox, keeps every message in memory and also writes it to dir when dir is set
+type SinkTransport struct {
+	dir      string
+	mutex    *sync.Mutex
+	messages *[]Message
+}
+
+func NewSinkTransport(dir string) SinkTransport {
+	messages := make([]Message, 0)
+	return SinkTransport{
+		dir:      dir,
+		mutex:    &sync.Mutex{},
+		messages: &messages,
+	}
+}
+
+func (t SinkTransport) Send(message Message) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	*t.messages = append(*t.messages, message)
+	if t.dir == "" {
+		return nil
+	}
+
+	fileName := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), message.ToAddress)
+	content := smtp_service.BuildMessage(message.FromName, message.FromAddress, message.ToName, message.ToAddress, message.Subject, message.Body)
+	return ioutil.WriteFile(filepath.Join(t.dir, fileName), []byte(content), 0644)
+}
+
+func (t SinkTransport) Messages() []Message {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	messages := make([]Message, len(*t.messages))
+	copy(messages, *t.messages)
+	return messages
+}
+
+func (t SinkTransport) MessagesTo(address string) []Message {
+	messages := make([]Message, 0)
+	for _, message := range t.Messages() {
+		if message.ToAddress == address {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func (t SinkTransport) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	*t.messages = make([]Message, 0)
+}
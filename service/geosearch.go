@@ -0,0 +1,71 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"sort"
+)
+
+type GeoSearchService struct {
+	dao *dao.GeoIndexDao
+}
+
+func (s GeoSearchService) Search(latitude float64, longitude float64, radius float64, currency string, offerType string,
+	fiatCurrency string, limit int) (entries []bean.GeoIndexEntry, ce SimpleContextError) {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		ce.SetStatusKey(api_error.InvalidQueryParam)
+		return
+	}
+	if radius <= 0 {
+		radius = bean.GEO_SEARCH_RADIUS_DEFAULT
+	}
+	if radius > bean.GEO_SEARCH_RADIUS_MAX {
+		radius = bean.GEO_SEARCH_RADIUS_MAX
+	}
+	if limit <= 0 {
+		limit = bean.GEO_SEARCH_LIMIT_DEFAULT
+	}
+
+	buckets := common.GeohashRadiusBuckets(latitude, longitude, radius)
+	candidates, err := s.dao.ListEntriesByGeohash(buckets)
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+
+	entries = make([]bean.GeoIndexEntry, 0)
+	for _, entry := range candidates {
+		if currency != "" && entry.Currency != currency {
+			continue
+		}
+		if offerType != "" && entry.Type != offerType {
+			continue
+		}
+		if fiatCurrency != "" && entry.FiatCurrency != fiatCurrency {
+			continue
+		}
+		// Buckets cover a box, drop the corners outside the circle
+		entry.Distance = common.GeoDistance(latitude, longitude, entry.Latitude, entry.Longitude)
+		if entry.Distance > radius {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Distance < entries[j].Distance
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return
+}
+
+func (s GeoSearchService) RebuildIndex() (count int, ce SimpleContextError) {
+	count, err := s.dao.RebuildEntries()
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
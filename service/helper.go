@@ -4,8 +4,32 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/api_error"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/shopspring/decimal"
 )
 
+func GetFiatCurrency(miscDao *dao.MiscDao, code string, ce *SimpleContextError) (currency bean.FiatCurrency) {
+	to := miscDao.GetFiatCurrencyFromCache(code)
+	if ce.SetError(api_error.GetDataFailed, to.Error) {
+		return
+	}
+	if to.Found {
+		currency = to.Object.(bean.FiatCurrency)
+	} else {
+		ce.SetStatusKey(api_error.UnsupportedFiatCurrency)
+	}
+
+	return
+}
+
+func RoundFiatAmount(code string, amount decimal.Decimal) decimal.Decimal {
+	to := dao.MiscDaoInst.GetFiatCurrencyFromCache(code)
+	if to.Found {
+		return to.Object.(bean.FiatCurrency).Round(amount)
+	}
+	// Keep old behaviour for records created before the currency was removed
+	return amount.Round(bean.USD.Decimal)
+}
+
 func GetProfile(dao dao.UserDaoInterface, userId string, ce *SimpleContextError) (profile *bean.Profile) {
 	to := dao.GetProfile(userId)
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
@@ -28,3 +28,63 @@ var OfferStoreServiceInst = OfferStoreService{
 	transDao: &dao.TransactionDaoInst,
 	offerDao: &dao.OfferDaoInst,
 }
+
+var PayoutServiceInst = PayoutService{
+	dao: &dao.MiscDaoInst,
+}
+
+var PriceOracleServiceInst = PriceOracleService{
+	miscDao: &dao.MiscDaoInst,
+}
+
+var QuoteServiceInst = QuoteService{
+	miscDao: &dao.MiscDaoInst,
+}
+
+var OrderBookServiceInst = OrderBookService{
+	dao:     &dao.OrderBookDaoInst,
+	miscDao: &dao.MiscDaoInst,
+}
+
+var GeoSearchServiceInst = GeoSearchService{
+	dao: &dao.GeoIndexDaoInst,
+}
+
+var TradingBotServiceInst = TradingBotService{
+	dao:      &dao.TradingBotDaoInst,
+	miscDao:  &dao.MiscDaoInst,
+	userDao:  &dao.UserDaoInst,
+	offerDao: &dao.OfferDaoInst,
+}
+
+var SearchReindexServiceInst = SearchReindexService{
+	dao:           &dao.SearchReindexDaoInst,
+	miscDao:       &dao.MiscDaoInst,
+	offerStoreDao: &dao.OfferStoreDaoInst,
+}
+
+var NotificationDeliveryServiceInst = NotificationDeliveryService{
+	dao: &dao.NotificationDaoInst,
+}
+
+var OutboxServiceInst = OutboxService{
+	dao:     &dao.OutboxDaoInst,
+	miscDao: &dao.MiscDaoInst,
+}
+
+var WebhookSubscriptionServiceInst = WebhookSubscriptionService{
+	dao: &dao.WebhookDaoInst,
+}
+
+var DisputeServiceInst = DisputeService{
+	dao:           &dao.DisputeDaoInst,
+	miscDao:       &dao.MiscDaoInst,
+	offerDao:      &dao.OfferDaoInst,
+	offerStoreDao: &dao.OfferStoreDaoInst,
+}
+
+var KYCServiceInst = KYCService{
+	dao:     &dao.KYCDaoInst,
+	miscDao: &dao.MiscDaoInst,
+	userDao: &dao.UserDaoInst,
+}
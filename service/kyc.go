@@ -0,0 +1,281 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/kyc"
+	"github.com/shopspring/decimal"
+	"log"
+	"strings"
+	"time"
+)
+
+type KYCService struct {
+	dao     dao.KYCDaoInterface
+	miscDao *dao.MiscDao
+	userDao dao.UserDaoInterface
+}
+
+// Documents ask for the full tier, identity data alone for basic
+func (s KYCService) SubmitKYC(userId string, body bean.KYCSubmissionRequest) (submission bean.KYCSubmission, ce SimpleContextError) {
+	profilePtr := GetProfile(s.userDao, userId, &ce)
+	if ce.HasError() {
+		return
+	}
+	profile := *profilePtr
+	profile.UserId = userId
+	if profile.KYCStatus == bean.KYC_STATUS_PENDING {
+		ce.SetStatusKey(api_error.KYCPending)
+		return
+	}
+
+	submission = bean.KYCSubmission{
+		UID:            userId,
+		Tier:           bean.KYC_TIER_BASIC,
+		FirstName:      body.FirstName,
+		LastName:       body.LastName,
+		DateOfBirth:    body.DateOfBirth,
+		Country:        body.Country,
+		Address:        body.Address,
+		DocumentNumber: body.DocumentNumber,
+		Documents:      body.Documents,
+		Verifier:       kyc.VerifierInst.Name(),
+	}
+	if len(body.Documents) > 0 {
+		if body.DocumentNumber == "" {
+			ce.SetStatusKey(api_error.InvalidRequestBody)
+			return
+		}
+		submission.Tier = bean.KYC_TIER_FULL
+	}
+	if submission.Tier <= profile.KYCTier {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+
+	profile.KYCStatus = bean.KYC_STATUS_PENDING
+	var err error
+	submission, err = s.dao.AddKYCSubmission(submission, profile)
+	if ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	submission.Status = bean.KYC_STATUS_PENDING
+	submission.CreatedAt = time.Now().UTC()
+
+	result, err := kyc.VerifierInst.Verify(submission)
+	if err != nil {
+		// Stays in the review queue
+		log.Println("KYC verification failed", submission.Id, err)
+		return
+	}
+	if result.Status != bean.KYC_STATUS_PENDING {
+		submission.VerifierRef = result.Reference
+		s.applyReview(&submission, profile, result.Status, result.Tier, result.Reason, "", &ce)
+	}
+
+	return
+}
+
+func (s KYCService) ListUserKYCSubmissions(userId string) (submissions []bean.KYCSubmission, ce SimpleContextError) {
+	to := s.dao.ListUserKYCSubmissions(userId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
+		return
+	}
+	submissions = make([]bean.KYCSubmission, 0)
+	for _, obj := range to.Objects {
+		submissions = append(submissions, obj.(bean.KYCSubmission))
+	}
+
+	return
+}
+
+// Country of the latest approved submission, empty when the user never passed KYC
+func (s KYCService) GetVerifiedCountry(userId string) string {
+	to := s.dao.ListUserKYCSubmissions(userId)
+	if to.HasError() {
+		return ""
+	}
+	for _, obj := range to.Objects {
+		submission := obj.(bean.KYCSubmission)
+		if submission.Status == bean.KYC_STATUS_APPROVED {
+			return submission.Country
+		}
+	}
+
+	return ""
+}
+
+func (s KYCService) GetKYCSubmission(userId string, submissionId string) (submission bean.KYCSubmission, ce SimpleContextError) {
+	submissionTO := s.dao.GetKYCSubmission(submissionId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, submissionTO); ce.HasError() {
+		return
+	}
+	submission = submissionTO.Object.(bean.KYCSubmission)
+
+	if submission.UID != userId && !s.isReviewer(userId) {
+		ce.SetStatusKey(api_error.InvalidRequestParam)
+	}
+
+	return
+}
+
+func (s KYCService) ListKYCSubmissions(userId string, status string, limit int, startAt interface{}) (to dao.TransferObject, ce SimpleContextError) {
+	if !s.isReviewer(userId) {
+		ce.SetStatusKey(api_error.NotKYCReviewer)
+		return
+	}
+	to = s.dao.ListKYCSubmissions(status, limit, startAt)
+	ce.FeedDaoTransfer(api_error.GetDataFailed, to)
+
+	return
+}
+
+func (s KYCService) ReviewKYCSubmission(userId string, submissionId string, body bean.KYCReviewRequest) (submission bean.KYCSubmission, ce SimpleContextError) {
+	if !s.isReviewer(userId) {
+		ce.SetStatusKey(api_error.NotKYCReviewer)
+		return
+	}
+	submissionTO := s.dao.GetKYCSubmission(submissionId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, submissionTO); ce.HasError() {
+		return
+	}
+	submission = submissionTO.Object.(bean.KYCSubmission)
+	if submission.UID == userId {
+		ce.SetStatusKey(api_error.KYCSelfReview)
+		return
+	}
+	if submission.Status != bean.KYC_STATUS_PENDING {
+		ce.SetStatusKey(api_error.KYCAlreadyReviewed)
+		return
+	}
+
+	profilePtr := GetProfile(s.userDao, submission.UID, &ce)
+	if ce.HasError() {
+		return
+	}
+	profile := *profilePtr
+	profile.UserId = submission.UID
+
+	s.applyReview(&submission, profile, body.Status, body.Tier, body.Reason, userId, &ce)
+
+	return
+}
+
+// A rejection or a lower grant never takes away a tier the user already has
+func (s KYCService) applyReview(submission *bean.KYCSubmission, profile bean.Profile, status string, tier int64, reason string,
+	reviewerId string, ce *SimpleContextError) {
+	submission.Status = status
+	submission.Reason = reason
+	submission.ReviewerUID = reviewerId
+	if status == bean.KYC_STATUS_APPROVED {
+		if tier > 0 {
+			submission.Tier = tier
+		}
+		if submission.Tier > profile.KYCTier {
+			profile.KYCTier = submission.Tier
+		}
+	}
+	profile.KYCStatus = status
+
+	err := s.dao.UpdateKYCReview(*submission, profile)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+	submission.ReviewedAt = time.Now().UTC()
+}
+
+func (s KYCService) ListTierLimits() (limits []bean.KYCTierLimit, ce SimpleContextError) {
+	limits = make([]bean.KYCTierLimit, 0)
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_KYC_TIER_LIMITS)
+	if systemConfigTO.HasError() {
+		return
+	}
+	err := json.Unmarshal([]byte(systemConfigTO.Object.(bean.SystemConfig).Value), &limits)
+	ce.SetError(api_error.GetDataFailed, err)
+
+	return
+}
+
+// Found is false when tiers are not configured
+func (s KYCService) GetTierLimit(tier int64) (limit bean.KYCTierLimit, found bool) {
+	limits, ce := s.ListTierLimits()
+	if ce.HasError() {
+		return
+	}
+	return findTierLimit(limits, tier)
+}
+
+// The highest configured tier the user has reached, below every configured tier the lowest one caps
+func findTierLimit(limits []bean.KYCTierLimit, tier int64) (limit bean.KYCTierLimit, found bool) {
+	var lowest bean.KYCTierLimit
+	for i, item := range limits {
+		if i == 0 || item.Tier < lowest.Tier {
+			lowest = item
+		}
+		if item.Tier <= tier && (!found || item.Tier > limit.Tier) {
+			limit = item
+			found = true
+		}
+	}
+	if !found && len(limits) > 0 {
+		return lowest, true
+	}
+
+	return
+}
+
+// Zero means the tier does not cap the CC limit level
+func (s KYCService) MaxCCLimitLevel(profile bean.Profile) int64 {
+	limit, found := s.GetTierLimit(profile.KYCTier)
+	if !found {
+		return 0
+	}
+	return limit.MaxCCLimitLevel
+}
+
+func (s KYCService) CheckOfferAmount(profile bean.Profile, currency string, amount decimal.Decimal, ce *SimpleContextError) {
+	limit, found := s.GetTierLimit(profile.KYCTier)
+	if found {
+		checkTierAmount(limit.OfferAmounts, currency, amount, ce)
+	}
+}
+
+func (s KYCService) CheckOfferStoreAmount(profile bean.Profile, currency string, amount decimal.Decimal, ce *SimpleContextError) {
+	limit, found := s.GetTierLimit(profile.KYCTier)
+	if found {
+		checkTierAmount(limit.OfferStoreAmounts, currency, amount, ce)
+	}
+}
+
+func checkTierAmount(amounts map[string]string, currency string, amount decimal.Decimal, ce *SimpleContextError) {
+	maxAmountStr, ok := amounts[currency]
+	if !ok {
+		return
+	}
+	maxAmount, err := decimal.NewFromString(maxAmountStr)
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+	if amount.GreaterThan(maxAmount) {
+		ce.SetStatusKey(api_error.KYCTierLimitExceeded)
+	}
+}
+
+func (s KYCService) isReviewer(userId string) bool {
+	if userId == "" {
+		return false
+	}
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_KYC_REVIEWERS)
+	if systemConfigTO.HasError() {
+		return false
+	}
+	for _, reviewer := range strings.Split(systemConfigTO.Object.(bean.SystemConfig).Value, ",") {
+		if strings.TrimSpace(reviewer) == userId {
+			return true
+		}
+	}
+
+	return false
+}
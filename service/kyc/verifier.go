@@ -0,0 +1,99 @@
+package kyc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"sync"
+)
+
+const VERIFIER_MANUAL = "manual"
+const VERIFIER_FAKE = "fake"
+
+// Document number the fake verifier rejects, anything else is approved at the tier asked for
+const FAKE_DOCUMENT_REJECTED = "REJECTED"
+
+// Pending leaves the submission in the review queue
+type Result struct {
+	Status    string
+	Tier      int64
+	Reference string
+	Reason    string
+}
+
+type Verifier interface {
+	Name() string
+	Verify(submission bean.KYCSubmission) (Result, error)
+}
+
+var VerifierInst Verifier = ManualVerifier{}
+
+func InitializeVerifier(name string) {
+	switch name {
+	case VERIFIER_FAKE:
+		VerifierInst = NewFakeVerifier()
+	default:
+		// Nobody is verified unless a reviewer looks at the submission
+		VerifierInst = ManualVerifier{}
+	}
+}
+
+type ManualVerifier struct {
+}
+
+func (v ManualVerifier) Name() string {
+	return VERIFIER_MANUAL
+}
+
+func (v ManualVerifier) Verify(submission bean.KYCSubmission) (Result, error) {
+	return Result{Status: bean.KYC_STATUS_PENDING}, nil
+}
+
+// Selected with KYC_VERIFIER=fake, it decides right away instead of queueing for a reviewer
+type FakeVerifier struct {
+	mutex       *sync.Mutex
+	submissions *[]bean.KYCSubmission
+}
+
+func NewFakeVerifier() FakeVerifier {
+	submissions := make([]bean.KYCSubmission, 0)
+	return FakeVerifier{
+		mutex:       &sync.Mutex{},
+		submissions: &submissions,
+	}
+}
+
+func (v FakeVerifier) Name() string {
+	return VERIFIER_FAKE
+}
+
+func (v FakeVerifier) Verify(submission bean.KYCSubmission) (result Result, err error) {
+	if submission.UID == "" {
+		err = errors.New("kyc submission has no user")
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	*v.submissions = append(*v.submissions, submission)
+
+	result.Reference = fmt.Sprintf("fake_kyc_%d", len(*v.submissions))
+	if submission.DocumentNumber == FAKE_DOCUMENT_REJECTED {
+		result.Status = bean.KYC_STATUS_REJECTED
+		result.Reason = "Document is rejected"
+		return
+	}
+	result.Status = bean.KYC_STATUS_APPROVED
+	result.Tier = submission.Tier
+
+	return
+}
+
+func (v FakeVerifier) Submissions() []bean.KYCSubmission {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	submissions := make([]bean.KYCSubmission, len(*v.submissions))
+	copy(submissions, *v.submissions)
+	return submissions
+}
@@ -0,0 +1,36 @@
+package kyc
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFakeVerifierApprovesRequestedTier(t *testing.T) {
+	verifier := NewFakeVerifier()
+
+	result, err := verifier.Verify(bean.KYCSubmission{UID: "1", Tier: bean.KYC_TIER_FULL, DocumentNumber: "B1234567"})
+	assert.Nil(t, err)
+	assert.Equal(t, bean.KYC_STATUS_APPROVED, result.Status)
+	assert.Equal(t, int64(bean.KYC_TIER_FULL), result.Tier)
+	assert.Equal(t, "fake_kyc_1", result.Reference)
+	assert.Equal(t, 1, len(verifier.Submissions()))
+}
+
+func TestFakeVerifierRejectsDocument(t *testing.T) {
+	verifier := NewFakeVerifier()
+
+	result, err := verifier.Verify(bean.KYCSubmission{UID: "1", Tier: bean.KYC_TIER_FULL, DocumentNumber: FAKE_DOCUMENT_REJECTED})
+	assert.Nil(t, err)
+	assert.Equal(t, bean.KYC_STATUS_REJECTED, result.Status)
+	assert.Equal(t, int64(bean.KYC_TIER_NONE), result.Tier)
+
+	_, err = verifier.Verify(bean.KYCSubmission{})
+	assert.NotNil(t, err)
+}
+
+func TestManualVerifierLeavesPending(t *testing.T) {
+	result, err := ManualVerifier{}.Verify(bean.KYCSubmission{UID: "1", Tier: bean.KYC_TIER_BASIC})
+	assert.Nil(t, err)
+	assert.Equal(t, bean.KYC_STATUS_PENDING, result.Status)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type KYCDaoFake struct {
+	reviewed []bean.KYCSubmission
+	profiles []bean.Profile
+}
+
+func (dao *KYCDaoFake) AddKYCSubmission(submission bean.KYCSubmission, profile bean.Profile) (bean.KYCSubmission, error) {
+	return submission, nil
+}
+func (dao *KYCDaoFake) UpdateKYCReview(submission bean.KYCSubmission, profile bean.Profile) error {
+	dao.reviewed = append(dao.reviewed, submission)
+	dao.profiles = append(dao.profiles, profile)
+	return nil
+}
+func (dao *KYCDaoFake) GetKYCSubmission(submissionId string) (t dao.TransferObject) {
+	return
+}
+func (dao *KYCDaoFake) ListKYCSubmissions(status string, limit int, startAt interface{}) (t dao.TransferObject) {
+	return
+}
+func (dao *KYCDaoFake) ListUserKYCSubmissions(userId string) (t dao.TransferObject) {
+	return
+}
+
+func TestApplyReviewApprovesRequestedTier(t *testing.T) {
+	daoFake := &KYCDaoFake{}
+	serviceInst := KYCService{dao: daoFake}
+
+	submission := bean.KYCSubmission{Id: "kyc_1", UID: "1", Tier: 2}
+	var ce SimpleContextError
+	serviceInst.applyReview(&submission, bean.Profile{UserId: "1", KYCTier: 1}, bean.KYC_STATUS_APPROVED, 0, "", "3", &ce)
+	assert.False(t, ce.HasError())
+	assert.Equal(t, int64(2), submission.Tier)
+	assert.Equal(t, "3", submission.ReviewerUID)
+	assert.Equal(t, int64(2), daoFake.profiles[0].KYCTier)
+	assert.Equal(t, bean.KYC_STATUS_APPROVED, daoFake.profiles[0].KYCStatus)
+}
+
+func TestApplyReviewLowerGrantKeepsTier(t *testing.T) {
+	daoFake := &KYCDaoFake{}
+	serviceInst := KYCService{dao: daoFake}
+
+	submission := bean.KYCSubmission{Id: "kyc_1", UID: "1", Tier: 2}
+	var ce SimpleContextError
+	serviceInst.applyReview(&submission, bean.Profile{UserId: "1", KYCTier: 2}, bean.KYC_STATUS_APPROVED, 1, "", "3", &ce)
+	assert.Equal(t, int64(1), submission.Tier)
+	assert.Equal(t, int64(2), daoFake.profiles[0].KYCTier)
+}
+
+func TestApplyReviewRejectionKeepsTier(t *testing.T) {
+	daoFake := &KYCDaoFake{}
+	serviceInst := KYCService{dao: daoFake}
+
+	submission := bean.KYCSubmission{Id: "kyc_1", UID: "1", Tier: 2}
+	var ce SimpleContextError
+	serviceInst.applyReview(&submission, bean.Profile{UserId: "1", KYCTier: 1}, bean.KYC_STATUS_REJECTED, 2, "Blurry", "3", &ce)
+	assert.Equal(t, "Blurry", submission.Reason)
+	assert.Equal(t, int64(1), daoFake.profiles[0].KYCTier)
+	assert.Equal(t, bean.KYC_STATUS_REJECTED, daoFake.profiles[0].KYCStatus)
+}
+
+func TestFindTierLimit(t *testing.T) {
+	limits := []bean.KYCTierLimit{
+		{Tier: 2, MaxCCLimitLevel: 5},
+		{Tier: 0, MaxCCLimitLevel: 1},
+		{Tier: 1, MaxCCLimitLevel: 3},
+	}
+
+	limit, found := findTierLimit(limits, 1)
+	assert.True(t, found)
+	assert.Equal(t, int64(3), limit.MaxCCLimitLevel)
+
+	limit, found = findTierLimit(limits, 5)
+	assert.True(t, found)
+	assert.Equal(t, int64(5), limit.MaxCCLimitLevel)
+
+	_, found = findTierLimit(nil, 1)
+	assert.False(t, found)
+}
+
+func TestFindTierLimitBelowEveryTier(t *testing.T) {
+	limits := []bean.KYCTierLimit{
+		{Tier: 2, MaxCCLimitLevel: 5},
+		{Tier: 1, MaxCCLimitLevel: 2},
+	}
+
+	limit, found := findTierLimit(limits, 0)
+	assert.True(t, found)
+	assert.Equal(t, int64(1), limit.Tier)
+	assert.Equal(t, int64(2), limit.MaxCCLimitLevel)
+}
+
+func TestCheckTierAmount(t *testing.T) {
+	amounts := map[string]string{bean.ETH.Code: "10"}
+
+	var ce SimpleContextError
+	checkTierAmount(amounts, bean.ETH.Code, decimal.NewFromFloat(10), &ce)
+	assert.False(t, ce.HasError())
+
+	checkTierAmount(amounts, bean.BTC.Code, decimal.NewFromFloat(100), &ce)
+	assert.False(t, ce.HasError())
+
+	checkTierAmount(amounts, bean.ETH.Code, decimal.NewFromFloat(10.5), &ce)
+	assert.Equal(t, api_error.KYCTierLimitExceeded, ce.StatusKey)
+}
@@ -0,0 +1,295 @@
+package notification
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"log"
+	"strconv"
+	"time"
+)
+
+type channelSender func(c chan error)
+
+// Sends every channel once and returns the errors in bean.NotificationChannels order,
+// failed channels are left to RetryNotificationDeliveries and dead lettered when they run out of attempts.
+// An empty outbox event key is the relay, which acknowledges its event itself
+func dispatch(delivery bean.NotificationDelivery, senders map[string]channelSender, recipients *audience, outboxEventKey string) []error {
+	errs := sendChannels(&delivery, senders, recipients, bean.NotificationChannels)
+
+	go func() {
+		scheduleRetry(&delivery, time.Now().UTC())
+
+		var err error
+		delivery, err = dao.NotificationDaoInst.AddNotificationDelivery(delivery)
+		if err != nil {
+			log.Println("Add notification delivery failed", err)
+			return
+		}
+		// The delivery record owns the retries from here, the outbox event is acknowledged
+		if outboxEventKey != "" {
+			if err := dao.OutboxDaoInst.AckOutboxEvent(outboxEventKey); err != nil {
+				log.Println("Ack outbox event failed", err)
+			}
+		}
+		if delivery.Status == bean.NOTIFICATION_DELIVERY_STATUS_FAILED {
+			if err := dao.NotificationDaoInst.AddNotificationDeadLetter(delivery); err != nil {
+				log.Println("Add notification dead letter failed", err)
+			}
+		}
+	}()
+
+	return errs
+}
+
+// Called by cron, sends the failed channels of every delivery whose next attempt is due
+func RetryNotificationDeliveries() (count int, err error) {
+	now := time.Now().UTC()
+	deliveries, err := dao.NotificationDaoInst.ListDueNotificationDeliveries(now, bean.NOTIFICATION_RETRY_LIMIT)
+	if err != nil {
+		return
+	}
+
+	leaseUntil := now.Add(bean.NOTIFICATION_RETRY_LEASE * time.Second)
+	for _, delivery := range deliveries {
+		if claimErr := dao.NotificationDaoInst.ClaimNotificationDeliveryRetry(delivery, leaseUntil); claimErr != nil {
+			// Another run has it
+			continue
+		}
+		retryDelivery(delivery)
+		count += 1
+	}
+
+	return
+}
+
+// Admin resend, one synchronous attempt on the channels that have not been delivered
+func ResendNotification(delivery bean.NotificationDelivery) (bean.NotificationDelivery, error) {
+	senders, recipients, err := getChannelSenders(delivery)
+	if err != nil {
+		return delivery, err
+	}
+	channels := delivery.FailedChannels()
+	sendChannels(&delivery, senders, recipients, channels)
+	for _, channel := range delivery.FailedChannels() {
+		// Nothing retries it now, so it stays dead lettered until the next resend
+		channelDelivery := delivery.Channels[channel]
+		channelDelivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_FAILED
+		delivery.Channels[channel] = channelDelivery
+	}
+	updateDeliveryStatus(&delivery)
+
+	if delivery.Status == bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED {
+		err = dao.NotificationDaoInst.UpdateNotificationDelivery(delivery)
+		if err == nil {
+			err = dao.NotificationDaoInst.RemoveNotificationDeadLetter(delivery.Id)
+		}
+	} else {
+		err = dao.NotificationDaoInst.AddNotificationDeadLetter(delivery)
+	}
+
+	return delivery, err
+}
+
+// Relay for an outbox event whose inline notification never got acknowledged.
+// It fails when no channel went through, channels that did leave the rest to the delivery retries
+func RelayOutboxEvent(event bean.OutboxEvent) error {
+	var errs []error
+	switch event.Type {
+	case bean.NOTIFICATION_TYPE_OFFER:
+		if event.Offer != nil {
+			errs = sendOfferNotification(*event.Offer, "")
+		}
+	case bean.NOTIFICATION_TYPE_INSTANT_OFFER:
+		if event.InstantOffer != nil {
+			errs = sendInstantOfferNotification(*event.InstantOffer, "")
+		}
+	case bean.NOTIFICATION_TYPE_OFFER_STORE:
+		if event.OfferStore != nil && event.OfferStoreItem != nil {
+			errs = sendOfferStoreNotification(*event.OfferStore, *event.OfferStoreItem, "")
+		}
+	case bean.NOTIFICATION_TYPE_OFFER_STORE_SHAKE:
+		if event.OfferStoreShake != nil {
+			// Shake writes do not always carry the store, load the current one
+			offerStoreTO := dao.OfferStoreDaoInst.GetOfferStore(event.ParentId)
+			if offerStoreTO.Error != nil {
+				return offerStoreTO.Error
+			}
+			if !offerStoreTO.Found {
+				return errors.New("outbox event offer store not found")
+			}
+			errs = sendOfferStoreShakeNotification(*event.OfferStoreShake, offerStoreTO.Object.(bean.OfferStore), "")
+		}
+	}
+	if errs == nil {
+		return errors.New("outbox event has no payload")
+	}
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+func sendChannels(delivery *bean.NotificationDelivery, senders map[string]channelSender, recipients *audience, channels []string) []error {
+	if delivery.Channels == nil {
+		delivery.Channels = map[string]bean.NotificationChannelDelivery{}
+	}
+
+	chans := make([]chan error, 0)
+	for _, channel := range channels {
+		c := make(chan error, 1)
+		go senders[channel](c)
+		chans = append(chans, c)
+	}
+
+	errs := make([]error, 0)
+	for index, channel := range channels {
+		err := <-chans[index]
+		errs = append(errs, err)
+
+		channelDelivery := delivery.Channels[channel]
+		channelDelivery.UpdatedAt = time.Now().UTC()
+		channelDelivery.DeferredUIDs, channelDelivery.DeferredUntil = recipients.deferredFor(channel)
+		if err == nil && len(channelDelivery.DeferredUIDs) > 0 {
+			// Held back by quiet hours, that is not a failed attempt
+			channelDelivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_RETRYING
+			channelDelivery.LastError = ""
+		} else if err == nil {
+			channelDelivery.Attempts += 1
+			channelDelivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED
+			channelDelivery.LastError = ""
+		} else {
+			// Everyone gets the retry, users still in quiet hours are deferred again then
+			channelDelivery.Attempts += 1
+			channelDelivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_RETRYING
+			channelDelivery.LastError = err.Error()
+			channelDelivery.DeferredUIDs = nil
+			channelDelivery.DeferredUntil = time.Time{}
+		}
+		delivery.Channels[channel] = channelDelivery
+	}
+	updateDeliveryStatus(delivery)
+
+	return errs
+}
+
+func retryDelivery(delivery bean.NotificationDelivery) {
+	senders, recipients, err := getChannelSenders(delivery)
+	if err == nil {
+		sendChannels(&delivery, senders, recipients, delivery.FailedChannels())
+		scheduleRetry(&delivery, time.Now().UTC())
+	} else {
+		// Nothing to send it with, it would only come back every lease
+		log.Println("Retry notification delivery failed", delivery.Id, err)
+		for _, channel := range delivery.FailedChannels() {
+			channelDelivery := delivery.Channels[channel]
+			channelDelivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_FAILED
+			delivery.Channels[channel] = channelDelivery
+		}
+		updateDeliveryStatus(&delivery)
+	}
+
+	if delivery.Status == bean.NOTIFICATION_DELIVERY_STATUS_FAILED {
+		err = dao.NotificationDaoInst.AddNotificationDeadLetter(delivery)
+	} else {
+		err = dao.NotificationDaoInst.UpdateNotificationDelivery(delivery)
+	}
+	if err != nil {
+		log.Println("Update notification delivery failed", delivery.Id, err)
+	}
+}
+
+func scheduleRetry(delivery *bean.NotificationDelivery, now time.Time) {
+	maxAttempts := getConfigNumber(bean.CONFIG_NOTIFICATION_RETRY_MAX_ATTEMPTS, bean.NOTIFICATION_RETRY_MAX_ATTEMPTS_DEFAULT)
+	backoff := getConfigNumber(bean.CONFIG_NOTIFICATION_RETRY_BACKOFF, bean.NOTIFICATION_RETRY_BACKOFF_DEFAULT)
+	setNextAttempt(delivery, maxAttempts, backoff, now)
+}
+
+// Channels out of attempts are failed, the rest are due after an exponential backoff, backoff, 2 * backoff, 4 * backoff...
+// Deferred channels are due when the quiet hours end, whichever channel is due first sets the next attempt
+func setNextAttempt(delivery *bean.NotificationDelivery, maxAttempts int, backoff int, now time.Time) {
+	attempts := 0
+	var deferredUntil time.Time
+	for channel, channelDelivery := range delivery.Channels {
+		if channelDelivery.Status != bean.NOTIFICATION_DELIVERY_STATUS_RETRYING {
+			continue
+		}
+		if len(channelDelivery.DeferredUIDs) > 0 {
+			if deferredUntil.IsZero() || channelDelivery.DeferredUntil.Before(deferredUntil) {
+				deferredUntil = channelDelivery.DeferredUntil
+			}
+		} else if channelDelivery.Attempts >= maxAttempts {
+			channelDelivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_FAILED
+			delivery.Channels[channel] = channelDelivery
+		} else if channelDelivery.Attempts > attempts {
+			attempts = channelDelivery.Attempts
+		}
+	}
+	updateDeliveryStatus(delivery)
+
+	if delivery.Status != bean.NOTIFICATION_DELIVERY_STATUS_RETRYING {
+		return
+	}
+	if attempts > 0 {
+		delivery.NextAttemptAt = now.Add(time.Duration(backoff<<uint(attempts-1)) * time.Second)
+	}
+	if !deferredUntil.IsZero() && (attempts == 0 || deferredUntil.Before(delivery.NextAttemptAt)) {
+		delivery.NextAttemptAt = deferredUntil
+	}
+}
+
+func updateDeliveryStatus(delivery *bean.NotificationDelivery) {
+	delivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED
+	for _, channelDelivery := range delivery.Channels {
+		if channelDelivery.Status == bean.NOTIFICATION_DELIVERY_STATUS_RETRYING {
+			delivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_RETRYING
+			return
+		}
+		if channelDelivery.Status == bean.NOTIFICATION_DELIVERY_STATUS_FAILED {
+			delivery.Status = bean.NOTIFICATION_DELIVERY_STATUS_FAILED
+		}
+	}
+}
+
+func getChannelSenders(delivery bean.NotificationDelivery) (map[string]channelSender, *audience, error) {
+	switch delivery.Type {
+	case bean.NOTIFICATION_TYPE_OFFER:
+		if delivery.Offer != nil {
+			recipients := newAudience(delivery.Channels, delivery.Offer.UID, delivery.Offer.ToUID)
+			return offerSenders(*delivery.Offer, recipients), recipients, nil
+		}
+	case bean.NOTIFICATION_TYPE_INSTANT_OFFER:
+		if delivery.InstantOffer != nil {
+			recipients := newAudience(delivery.Channels, delivery.InstantOffer.UID)
+			return instantOfferSenders(*delivery.InstantOffer, recipients), recipients, nil
+		}
+	case bean.NOTIFICATION_TYPE_OFFER_STORE:
+		if delivery.OfferStore != nil && delivery.OfferStoreItem != nil {
+			recipients := newAudience(delivery.Channels, delivery.OfferStore.UID)
+			return offerStoreSenders(*delivery.OfferStore, *delivery.OfferStoreItem, recipients), recipients, nil
+		}
+	case bean.NOTIFICATION_TYPE_OFFER_STORE_SHAKE:
+		if delivery.OfferStoreShake != nil && delivery.OfferStore != nil {
+			recipients := newAudience(delivery.Channels, delivery.OfferStoreShake.UID, delivery.OfferStore.UID)
+			return offerStoreShakeSenders(*delivery.OfferStoreShake, *delivery.OfferStore, recipients), recipients, nil
+		}
+	}
+
+	return nil, nil, errors.New("notification delivery has no payload")
+}
+
+func getConfigNumber(key string, defaultValue int) int {
+	systemConfigTO := dao.MiscDaoInst.GetSystemConfigFromCache(key)
+	if systemConfigTO.HasError() {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(systemConfigTO.Object.(bean.SystemConfig).Value)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
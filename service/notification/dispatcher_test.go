@@ -0,0 +1,205 @@
+package notification
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func succeed(c chan error) {
+	c <- nil
+}
+
+func fail(c chan error) {
+	c <- errors.New("down")
+}
+
+func TestSendChannelsLeavesFailedChannelsRetrying(t *testing.T) {
+	delivery := bean.NotificationDelivery{}
+	senders := map[string]channelSender{
+		bean.NOTIFICATION_CHANNEL_EMAIL:    succeed,
+		bean.NOTIFICATION_CHANNEL_FIREBASE: fail,
+	}
+
+	errs := sendChannels(&delivery, senders, newAudience(nil), []string{bean.NOTIFICATION_CHANNEL_EMAIL, bean.NOTIFICATION_CHANNEL_FIREBASE})
+	assert.Nil(t, errs[0])
+	assert.NotNil(t, errs[1])
+
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, delivery.Status)
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED, delivery.Channels[bean.NOTIFICATION_CHANNEL_EMAIL].Status)
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, delivery.Channels[bean.NOTIFICATION_CHANNEL_FIREBASE].Status)
+	assert.Equal(t, "down", delivery.Channels[bean.NOTIFICATION_CHANNEL_FIREBASE].LastError)
+	assert.Equal(t, []string{bean.NOTIFICATION_CHANNEL_FIREBASE}, delivery.FailedChannels())
+}
+
+func quietAudience(now time.Time, userIds ...string) *audience {
+	recipients := newAudience(nil)
+	recipients.now = now
+	for _, userId := range userIds {
+		recipients.profiles[userId] = bean.Profile{
+			NotificationPreferences: bean.NotificationPreferences{
+				QuietHours: &bean.NotificationQuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"},
+			},
+		}
+	}
+	return recipients
+}
+
+func TestAudienceDefersQuietPushesOnly(t *testing.T) {
+	now := time.Date(2018, 6, 1, 23, 30, 0, 0, time.UTC)
+	recipients := quietAudience(now, "maker")
+
+	assert.True(t, recipients.allows("maker", bean.NOTIFICATION_CHANNEL_EMAIL, bean.NOTIFICATION_CATEGORY_SHAKE))
+	assert.False(t, recipients.allows("maker", bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_SHAKE))
+	assert.True(t, recipients.allows("taker", bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_SHAKE))
+
+	userIds, until := recipients.deferredFor(bean.NOTIFICATION_CHANNEL_FCM)
+	assert.Equal(t, []string{"maker"}, userIds)
+	assert.Equal(t, time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC), until)
+	userIds, _ = recipients.deferredFor(bean.NOTIFICATION_CHANNEL_EMAIL)
+	assert.Empty(t, userIds)
+}
+
+// The retry of a deferred channel only goes to the users it was held back for
+func TestAudienceRetrySendsToDeferredUsersOnly(t *testing.T) {
+	recipients := newAudience(map[string]bean.NotificationChannelDelivery{
+		bean.NOTIFICATION_CHANNEL_FCM: {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, DeferredUIDs: []string{"maker"}},
+	})
+
+	assert.True(t, recipients.allows("maker", bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_COMPLETE))
+	assert.False(t, recipients.allows("taker", bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_COMPLETE))
+	assert.True(t, recipients.allows("taker", bean.NOTIFICATION_CHANNEL_SMS, bean.NOTIFICATION_CATEGORY_COMPLETE))
+}
+
+func TestSendChannelsDefersQuietChannel(t *testing.T) {
+	now := time.Date(2018, 6, 1, 23, 30, 0, 0, time.UTC)
+	recipients := quietAudience(now, "maker")
+	delivery := bean.NotificationDelivery{}
+	senders := map[string]channelSender{
+		bean.NOTIFICATION_CHANNEL_FCM: func(c chan error) {
+			recipients.allows("maker", bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_SHAKE)
+			c <- nil
+		},
+	}
+
+	sendChannels(&delivery, senders, recipients, []string{bean.NOTIFICATION_CHANNEL_FCM})
+
+	channelDelivery := delivery.Channels[bean.NOTIFICATION_CHANNEL_FCM]
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, channelDelivery.Status)
+	assert.Equal(t, 0, channelDelivery.Attempts)
+	assert.Equal(t, []string{"maker"}, channelDelivery.DeferredUIDs)
+	assert.Equal(t, time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC), channelDelivery.DeferredUntil)
+}
+
+func TestSetNextAttemptWaitsForQuietHoursToEnd(t *testing.T) {
+	now := time.Date(2018, 6, 1, 23, 30, 0, 0, time.UTC)
+	quietEnd := time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC)
+	delivery := bean.NotificationDelivery{
+		Channels: map[string]bean.NotificationChannelDelivery{
+			bean.NOTIFICATION_CHANNEL_EMAIL: {Status: bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED, Attempts: 1},
+			bean.NOTIFICATION_CHANNEL_FCM: {
+				Status:        bean.NOTIFICATION_DELIVERY_STATUS_RETRYING,
+				DeferredUIDs:  []string{"maker"},
+				DeferredUntil: quietEnd,
+			},
+		},
+	}
+	setNextAttempt(&delivery, 5, 2, now)
+
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, delivery.Status)
+	assert.Equal(t, quietEnd, delivery.NextAttemptAt)
+}
+
+// A failed channel due before the quiet hours end sets the next attempt
+func TestSetNextAttemptPrefersEarlierBackoff(t *testing.T) {
+	now := time.Date(2018, 6, 1, 23, 30, 0, 0, time.UTC)
+	delivery := bean.NotificationDelivery{
+		Channels: map[string]bean.NotificationChannelDelivery{
+			bean.NOTIFICATION_CHANNEL_SMS: {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: 1},
+			bean.NOTIFICATION_CHANNEL_FCM: {
+				Status:        bean.NOTIFICATION_DELIVERY_STATUS_RETRYING,
+				DeferredUIDs:  []string{"maker"},
+				DeferredUntil: time.Date(2018, 6, 2, 7, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	setNextAttempt(&delivery, 5, 2, now)
+
+	assert.Equal(t, now.Add(2*time.Second), delivery.NextAttemptAt)
+}
+
+func TestSetNextAttemptBacksOffExponentially(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	for attempts, wait := range map[int]time.Duration{1: 2, 2: 4, 3: 8, 4: 16} {
+		delivery := bean.NotificationDelivery{
+			Channels: map[string]bean.NotificationChannelDelivery{
+				bean.NOTIFICATION_CHANNEL_EMAIL: {Status: bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED, Attempts: 1},
+				bean.NOTIFICATION_CHANNEL_FCM:   {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: attempts},
+			},
+		}
+		setNextAttempt(&delivery, 5, 2, now)
+
+		assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, delivery.Status)
+		assert.Equal(t, now.Add(wait*time.Second), delivery.NextAttemptAt)
+	}
+}
+
+// The channel furthest along sets the pace
+func TestSetNextAttemptUsesHighestAttempts(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.NotificationDelivery{
+		Channels: map[string]bean.NotificationChannelDelivery{
+			bean.NOTIFICATION_CHANNEL_EMAIL: {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: 1},
+			bean.NOTIFICATION_CHANNEL_FCM:   {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: 3},
+		},
+	}
+	setNextAttempt(&delivery, 5, 2, now)
+
+	assert.Equal(t, now.Add(8*time.Second), delivery.NextAttemptAt)
+}
+
+func TestSetNextAttemptFailsChannelsOutOfAttempts(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.NotificationDelivery{
+		Channels: map[string]bean.NotificationChannelDelivery{
+			bean.NOTIFICATION_CHANNEL_EMAIL: {Status: bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED, Attempts: 1},
+			bean.NOTIFICATION_CHANNEL_FCM:   {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: 5},
+		},
+	}
+	setNextAttempt(&delivery, 5, 2, now)
+
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_FAILED, delivery.Status)
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_FAILED, delivery.Channels[bean.NOTIFICATION_CHANNEL_FCM].Status)
+	assert.True(t, delivery.NextAttemptAt.IsZero())
+}
+
+func TestSetNextAttemptKeepsRetryingChannelsWithAttemptsLeft(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.NotificationDelivery{
+		Channels: map[string]bean.NotificationChannelDelivery{
+			bean.NOTIFICATION_CHANNEL_EMAIL: {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: 5},
+			bean.NOTIFICATION_CHANNEL_FCM:   {Status: bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, Attempts: 2},
+		},
+	}
+	setNextAttempt(&delivery, 5, 2, now)
+
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_RETRYING, delivery.Status)
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_FAILED, delivery.Channels[bean.NOTIFICATION_CHANNEL_EMAIL].Status)
+	assert.Equal(t, now.Add(4*time.Second), delivery.NextAttemptAt)
+}
+
+func TestSetNextAttemptLeavesDeliveredAlone(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.NotificationDelivery{
+		Channels: map[string]bean.NotificationChannelDelivery{
+			bean.NOTIFICATION_CHANNEL_EMAIL: {Status: bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED, Attempts: 2},
+		},
+	}
+	setNextAttempt(&delivery, 5, 2, now)
+
+	assert.Equal(t, bean.NOTIFICATION_DELIVERY_STATUS_DELIVERED, delivery.Status)
+	assert.True(t, delivery.NextAttemptAt.IsZero())
+}
@@ -2,14 +2,14 @@ package notification
 
 import (
 	"fmt"
-	"github.com/nicksnyder/go-i18n/i18n"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/integration/fcm_service"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
 	"os"
 )
 
 func SendOrderInstantCCSuccessFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_order_instant_cc_success")
@@ -28,8 +28,28 @@ func SendOrderInstantCCSuccessFCM(language string, fcm string) error {
 	return fcm_service.SendFCM(fcmObj)
 }
 
+func SendOrderInstantCCRefundedFCM(language string, fcm string) error {
+	T := translation.Tfunc(language)
+
+	title := T("common_notification_title")
+	body := T("notification_order_instant_cc_refunded")
+	frontEndHost := os.Getenv("FRONTEND_HOST")
+	url := fmt.Sprintf("%s/%s", frontEndHost, "me")
+
+	fcmObj := bean.FCMObject{
+		Notification: bean.FCMNotificationObject{
+			Title:       title,
+			Body:        body,
+			ClickAction: url,
+		},
+		To: fcm,
+	}
+
+	return fcm_service.SendFCM(fcmObj)
+}
+
 func SendOfferMakerBuyShakeFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_maker_buy_shake")
@@ -50,7 +70,7 @@ func SendOfferMakerBuyShakeFCM(language string, fcm string) error {
 }
 
 func SendOfferMakerSellShakeFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_maker_sell_shake")
@@ -71,7 +91,7 @@ func SendOfferMakerSellShakeFCM(language string, fcm string) error {
 }
 
 func SendOfferMakerMakerRejectFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_maker_maker_reject")
@@ -92,7 +112,7 @@ func SendOfferMakerMakerRejectFCM(language string, fcm string) error {
 }
 
 func SendOfferTakerMakerRejectFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_taker_maker_reject")
@@ -113,7 +133,7 @@ func SendOfferTakerMakerRejectFCM(language string, fcm string) error {
 }
 
 func SendOfferMakerTakerRejectFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_maker_taker_reject")
@@ -134,7 +154,7 @@ func SendOfferMakerTakerRejectFCM(language string, fcm string) error {
 }
 
 func SendOfferTakerTakerRejectFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_taker_taker_reject")
@@ -155,7 +175,7 @@ func SendOfferTakerTakerRejectFCM(language string, fcm string) error {
 }
 
 func SendOfferSellCompleteFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_sell_completed")
@@ -175,7 +195,7 @@ func SendOfferSellCompleteFCM(language string, fcm string) error {
 }
 
 func SendOfferBuyCompleteFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_buy_completed")
@@ -195,7 +215,7 @@ func SendOfferBuyCompleteFCM(language string, fcm string) error {
 }
 
 func SendOfferStoreItemAddedFCM(language string, fcm string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_added")
@@ -215,7 +235,7 @@ func SendOfferStoreItemAddedFCM(language string, fcm string) error {
 }
 
 func SendOfferStoreMakerSellShakeFCM(language string, fcm string, chatUsername string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_maker_sell_shake")
@@ -235,7 +255,7 @@ func SendOfferStoreMakerSellShakeFCM(language string, fcm string, chatUsername s
 }
 
 func SendOfferStoreTakerSellShakeFCM(language string, fcm string, currency string, chatUsername string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_taker_sell_shake", map[string]string{
@@ -257,7 +277,7 @@ func SendOfferStoreTakerSellShakeFCM(language string, fcm string, currency strin
 }
 
 func SendOfferStoreMakerBuyShakeFCM(language string, fcm string, chatUsername string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_maker_buy_shake")
@@ -277,7 +297,7 @@ func SendOfferStoreMakerBuyShakeFCM(language string, fcm string, chatUsername st
 }
 
 func SendOfferStoreTakerBuyShakeFCM(language string, fcm string, currency string, chatUsername string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_taker_buy_shake", map[string]string{
@@ -299,7 +319,7 @@ func SendOfferStoreTakerBuyShakeFCM(language string, fcm string, currency string
 }
 
 func SendOfferStoreMakerRejectFCM(language string, fcm string, username string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_maker_reject", map[string]string{
@@ -321,7 +341,7 @@ func SendOfferStoreMakerRejectFCM(language string, fcm string, username string)
 }
 
 func SendOfferStoreTakerRejectFCM(language string, fcm string, username string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_taker_reject", map[string]string{
@@ -343,7 +363,7 @@ func SendOfferStoreTakerRejectFCM(language string, fcm string, username string)
 }
 
 func SendOfferStoreMakerCompleteFCM(language string, fcm string, currency string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_maker_accept", map[string]string{
@@ -365,7 +385,7 @@ func SendOfferStoreMakerCompleteFCM(language string, fcm string, currency string
 }
 
 func SendOfferStoreTakerCompleteFCM(language string, fcm string, currency string, offerId string, offerShakeId string) error {
-	T, _ := i18n.Tfunc(language)
+	T := translation.Tfunc(language)
 
 	title := T("common_notification_title")
 	body := T("notification_offer_store_taker_accept", map[string]string{
@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"sync"
+	"time"
+)
+
+// Everyone a notification goes to, each profile is read once per send
+type audience struct {
+	profiles map[string]bean.Profile
+	now      time.Time
+	// Set on a retry of a deferred channel, only these users are still owed it
+	pending map[string][]string
+
+	mutex    sync.Mutex
+	deferred map[string][]string
+	until    map[string]time.Time
+}
+
+// Channels carry the deferred users of an earlier attempt, nil on the first send.
+// Fails open, a profile read error should not swallow a trade notification
+func newAudience(channels map[string]bean.NotificationChannelDelivery, userIds ...string) *audience {
+	recipients := &audience{
+		profiles: map[string]bean.Profile{},
+		now:      time.Now().UTC(),
+		pending:  map[string][]string{},
+		deferred: map[string][]string{},
+		until:    map[string]time.Time{},
+	}
+	for _, userId := range userIds {
+		if _, ok := recipients.profiles[userId]; ok || userId == "" {
+			continue
+		}
+		profileTO := dao.UserDaoInst.GetProfile(userId)
+		if profileTO.Error == nil && profileTO.Found {
+			recipients.profiles[userId] = profileTO.Object.(bean.Profile)
+		}
+	}
+	for channel, channelDelivery := range channels {
+		if len(channelDelivery.DeferredUIDs) > 0 {
+			recipients.pending[channel] = channelDelivery.DeferredUIDs
+		}
+	}
+
+	return recipients
+}
+
+// Opted out users are dropped, users in their quiet hours are deferred until the end of them
+func (recipients *audience) allows(userId string, channel string, category string) bool {
+	if pending, ok := recipients.pending[channel]; ok && !containsUserId(pending, userId) {
+		return false
+	}
+	profile, ok := recipients.profiles[userId]
+	if !ok {
+		return true
+	}
+	preferences := profile.NotificationPreferences
+	if !preferences.Allows(channel, category) {
+		return false
+	}
+	if until, quiet := preferences.QuietUntil(channel, recipients.now); quiet {
+		recipients.mutex.Lock()
+		defer recipients.mutex.Unlock()
+		recipients.deferred[channel] = append(recipients.deferred[channel], userId)
+		if current, ok := recipients.until[channel]; !ok || until.After(current) {
+			recipients.until[channel] = until
+		}
+		return false
+	}
+
+	return true
+}
+
+func (recipients *audience) deferredFor(channel string) ([]string, time.Time) {
+	recipients.mutex.Lock()
+	defer recipients.mutex.Unlock()
+
+	return recipients.deferred[channel], recipients.until[channel]
+}
+
+// Objects without a language use the recipient's profile language, translation falls back to en-US after that
+func (recipients *audience) language(language string, userId string) string {
+	if language != "" {
+		return language
+	}
+
+	return recipients.profiles[userId].Language
+}
+
+func containsUserId(userIds []string, userId string) bool {
+	for _, item := range userIds {
+		if item == userId {
+			return true
+		}
+	}
+	return false
+}
+
+func offerNotificationCategory(status string) string {
+	switch status {
+	case bean.OFFER_STATUS_SHAKE:
+		return bean.NOTIFICATION_CATEGORY_SHAKE
+	case bean.OFFER_STATUS_COMPLETED:
+		return bean.NOTIFICATION_CATEGORY_COMPLETE
+	case bean.OFFER_STATUS_REJECTED:
+		return bean.NOTIFICATION_CATEGORY_REJECT
+	}
+	return bean.NOTIFICATION_CATEGORY_ACTIVITY
+}
+
+func offerStoreShakeNotificationCategory(status string) string {
+	switch status {
+	case bean.OFFER_STORE_SHAKE_STATUS_SHAKE:
+		return bean.NOTIFICATION_CATEGORY_SHAKE
+	case bean.OFFER_STORE_SHAKE_STATUS_COMPLETED:
+		return bean.NOTIFICATION_CATEGORY_COMPLETE
+	case bean.OFFER_STORE_SHAKE_STATUS_REJECTED:
+		return bean.NOTIFICATION_CATEGORY_REJECT
+	}
+	return bean.NOTIFICATION_CATEGORY_ACTIVITY
+}
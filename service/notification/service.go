@@ -3,32 +3,75 @@ package notification
 import (
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/dao"
-	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
 	"github.com/ninjadotorg/handshake-exchange/service/email"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
+	"github.com/ninjadotorg/handshake-exchange/service/sms"
+	"github.com/ninjadotorg/handshake-exchange/service/webhook"
 )
 
 func SendOfferNotification(offer bean.Offer) []error {
-	c := make(chan error)
-	go SendOfferToEmail(offer, c)
-	go SendOfferToFirebase(offer, c)
-	go SendOfferToSolr(offer, c)
-	go SendOfferToFCM(offer, c)
+	return sendOfferNotification(offer, bean.NewOutboxEventFromOffer(offer).Key)
+}
 
-	return []error{<-c, <-c, <-c, <-c}
+func sendOfferNotification(offer bean.Offer, outboxEventKey string) []error {
+	dao.OrderBookDaoInst.UpdateOfferEntry(offer)
+	dao.GeoIndexDaoInst.UpdateOfferEntry(offer)
+	recipients := newAudience(nil, offer.UID, offer.ToUID)
+	offer.Language = recipients.language(offer.Language, offer.UID)
+	offer.ToLanguage = recipients.language(offer.ToLanguage, offer.ToUID)
+	webhook.Emit(bean.NewWebhookEventFromOffer(offer))
+
+	delivery := bean.NotificationDelivery{
+		Type:  bean.NOTIFICATION_TYPE_OFFER,
+		RefId: offer.Id,
+		Offer: &offer,
+	}
+	return dispatch(delivery, offerSenders(offer, recipients), recipients, outboxEventKey)
+}
+
+func offerSenders(offer bean.Offer, recipients *audience) map[string]channelSender {
+	return map[string]channelSender{
+		bean.NOTIFICATION_CHANNEL_EMAIL:    func(c chan error) { SendOfferToEmail(offer, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_FIREBASE: func(c chan error) { SendOfferToFirebase(offer, c) },
+		bean.NOTIFICATION_CHANNEL_SOLR:     func(c chan error) { SendOfferToSolr(offer, c) },
+		bean.NOTIFICATION_CHANNEL_FCM:      func(c chan error) { SendOfferToFCM(offer, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_SMS:      func(c chan error) { SendOfferToSMS(offer, recipients, c) },
+	}
 }
 
 func SendInstantOfferNotification(offer bean.InstantOffer) []error {
-	c := make(chan error)
-	go SendInstantOfferToEmail(offer, c)
-	go SendInstantOfferToFirebase(offer, c)
-	go SendInstantOfferToSolr(offer, c)
-	go SendInstantOfferToFCM(offer, c)
+	return sendInstantOfferNotification(offer, bean.NewOutboxEventFromInstantOffer(offer).Key)
+}
+
+func sendInstantOfferNotification(offer bean.InstantOffer, outboxEventKey string) []error {
+	recipients := newAudience(nil, offer.UID)
+	offer.Language = recipients.language(offer.Language, offer.UID)
+	webhook.Emit(bean.NewWebhookEventFromInstantOffer(offer))
 
-	return []error{<-c, <-c, <-c, <-c}
+	delivery := bean.NotificationDelivery{
+		Type:         bean.NOTIFICATION_TYPE_INSTANT_OFFER,
+		RefId:        offer.Id,
+		InstantOffer: &offer,
+	}
+	return dispatch(delivery, instantOfferSenders(offer, recipients), recipients, outboxEventKey)
+}
+
+func instantOfferSenders(offer bean.InstantOffer, recipients *audience) map[string]channelSender {
+	return map[string]channelSender{
+		bean.NOTIFICATION_CHANNEL_EMAIL:    func(c chan error) { SendInstantOfferToEmail(offer, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_FIREBASE: func(c chan error) { SendInstantOfferToFirebase(offer, c) },
+		bean.NOTIFICATION_CHANNEL_SOLR:     func(c chan error) { SendInstantOfferToSolr(offer, c) },
+		bean.NOTIFICATION_CHANNEL_FCM:      func(c chan error) { SendInstantOfferToFCM(offer, recipients, c) },
+		// Instant offers carry no phone number
+		bean.NOTIFICATION_CHANNEL_SMS: func(c chan error) { c <- nil },
+	}
 }
 
-func SendOfferToEmail(offer bean.Offer, c chan error) {
+func SendOfferToEmail(offer bean.Offer, recipients *audience, c chan error) {
 	var err error
+	category := offerNotificationCategory(offer.Status)
+	makerAllowed := recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_EMAIL, category)
+	takerAllowed := recipients.allows(offer.ToUID, bean.NOTIFICATION_CHANNEL_EMAIL, category)
 	username := offer.Email
 	if username == "" {
 		username = offer.ContactPhone
@@ -45,7 +88,7 @@ func SendOfferToEmail(offer bean.Offer, c chan error) {
 	}
 
 	if offer.Status == bean.OFFER_STATUS_ACTIVE {
-		if offer.Email != "" {
+		if offer.Email != "" && makerAllowed {
 			if offer.Type == bean.OFFER_TYPE_BUY {
 				err = email.SendOfferBuyingActiveEmail(offer.Language, offer.Email)
 			} else {
@@ -57,50 +100,50 @@ func SendOfferToEmail(offer bean.Offer, c chan error) {
 		//	err = email.SendOfferClosedEmail(offer.Language, offer.Email)
 		//}
 	} else if offer.Status == bean.OFFER_STATUS_SHAKE {
-		if offer.Email != "" {
+		if offer.Email != "" && makerAllowed {
 			if offer.IsTypeSell() {
 				err = email.SendOfferMakerSellShakeEmail(offer.Language, offer.Email)
 			} else {
 				err = email.SendOfferMakerBuyShakeEmail(offer.Language, offer.Email)
 			}
 		}
-		if offer.ToEmail != "" {
+		if offer.ToEmail != "" && takerAllowed {
 			if offer.IsTypeSell() {
-				err = email.SendOfferTakerSellShakeEmail(offer.Language, offer.ToEmail)
+				err = email.SendOfferTakerSellShakeEmail(offer.ToLanguage, offer.ToEmail)
 			} else {
-				err = email.SendOfferTakerBuyShakeEmail(offer.Language, offer.ToEmail)
+				err = email.SendOfferTakerBuyShakeEmail(offer.ToLanguage, offer.ToEmail)
 			}
 		}
 	} else if offer.Status == bean.OFFER_STATUS_REJECTED {
 		if offer.UID == offer.ActionUID {
-			if offer.Email != "" {
+			if offer.Email != "" && makerAllowed {
 				err = email.SendOfferMakerMakerRejectEmail(offer.Language, offer.Email)
 			}
-			if offer.ToEmail != "" {
-				err = email.SendOfferTakerMakerRejectEmail(offer.Language, offer.ToEmail)
+			if offer.ToEmail != "" && takerAllowed {
+				err = email.SendOfferTakerMakerRejectEmail(offer.ToLanguage, offer.ToEmail, offer.Type)
 			}
 		} else {
-			if offer.Email != "" {
+			if offer.Email != "" && makerAllowed {
 				err = email.SendOfferMakerTakerRejectEmail(offer.Language, offer.Email)
 			}
-			if offer.ToEmail != "" {
-				err = email.SendOfferTakerTakerRejectEmail(offer.Language, offer.ToEmail)
+			if offer.ToEmail != "" && takerAllowed {
+				err = email.SendOfferTakerTakerRejectEmail(offer.ToLanguage, offer.ToEmail)
 			}
 		}
 	} else if offer.Status == bean.OFFER_STATUS_COMPLETED {
 		if offer.IsTypeSell() {
-			if offer.Email != "" {
+			if offer.Email != "" && makerAllowed {
 				err = email.SendOfferSellCompleteEmail(offer.Language, offer.Email)
 			}
-			if offer.ToEmail != "" {
-				err = email.SendOfferBuyCompleteEmail(offer.Language, offer.ToEmail)
+			if offer.ToEmail != "" && takerAllowed {
+				err = email.SendOfferBuyCompleteEmail(offer.ToLanguage, offer.ToEmail)
 			}
 		} else {
-			if offer.Email != "" {
+			if offer.Email != "" && makerAllowed {
 				err = email.SendOfferBuyCompleteEmail(offer.Language, offer.Email)
 			}
-			if offer.ToEmail != "" {
-				err = email.SendOfferSellCompleteEmail(offer.Language, offer.ToEmail)
+			if offer.ToEmail != "" && takerAllowed {
+				err = email.SendOfferSellCompleteEmail(offer.ToLanguage, offer.ToEmail)
 			}
 		}
 	}
@@ -114,19 +157,27 @@ func SendOfferToFirebase(offer bean.Offer, c chan error) {
 
 func SendOfferToSolr(offer bean.Offer, c chan error) {
 	// Always update
-	_, err := solr_service.UpdateObject(bean.NewSolrFromOffer(offer))
+	err := search.UpdateDocument(bean.NewSearchDocumentFromOffer(offer))
 	c <- err
 }
 
-func SendOfferToFCM(offer bean.Offer, c chan error) {
+func SendOfferToFCM(offer bean.Offer, recipients *audience, c chan error) {
 	var err error
+	category := offerNotificationCategory(offer.Status)
+	// Checked at the send, quiet hours only defer pushes that would actually go out
+	makerAllowed := func() bool {
+		return recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_FCM, category)
+	}
+	takerAllowed := func() bool {
+		return recipients.allows(offer.ToUID, bean.NOTIFICATION_CHANNEL_FCM, category)
+	}
 
 	if offer.Status == bean.OFFER_STATUS_ACTIVE {
 		// Not yet
 	} else if offer.Status == bean.OFFER_STATUS_CLOSED {
 		// Not yet
 	} else if offer.Status == bean.OFFER_STATUS_SHAKE {
-		if offer.FCM != "" {
+		if offer.FCM != "" && makerAllowed() {
 			if offer.IsTypeSell() {
 				err = SendOfferMakerSellShakeFCM(offer.Language, offer.FCM)
 			} else {
@@ -135,46 +186,91 @@ func SendOfferToFCM(offer bean.Offer, c chan error) {
 		}
 	} else if offer.Status == bean.OFFER_STATUS_REJECTED {
 		if offer.UID == offer.ActionUID {
-			if offer.FCM != "" {
+			if offer.FCM != "" && makerAllowed() {
 				err = SendOfferMakerMakerRejectFCM(offer.Language, offer.FCM)
 			}
-			if offer.ToFCM != "" {
-				err = SendOfferTakerMakerRejectFCM(offer.Language, offer.FCM)
+			if offer.ToFCM != "" && takerAllowed() {
+				err = SendOfferTakerMakerRejectFCM(offer.ToLanguage, offer.ToFCM)
 			}
 		} else {
-			if offer.FCM != "" {
+			if offer.FCM != "" && makerAllowed() {
 				err = SendOfferMakerTakerRejectFCM(offer.Language, offer.FCM)
 			}
-			if offer.ToFCM != "" {
-				err = SendOfferTakerTakerRejectFCM(offer.Language, offer.FCM)
+			if offer.ToFCM != "" && takerAllowed() {
+				err = SendOfferTakerTakerRejectFCM(offer.ToLanguage, offer.ToFCM)
 			}
 		}
 	} else if offer.Status == bean.OFFER_STATUS_COMPLETED {
 		if offer.IsTypeSell() {
-			if offer.FCM != "" {
+			if offer.FCM != "" && makerAllowed() {
 				err = SendOfferSellCompleteFCM(offer.Language, offer.FCM)
 			}
-			if offer.ToFCM != "" {
-				err = SendOfferBuyCompleteFCM(offer.Language, offer.ToFCM)
+			if offer.ToFCM != "" && takerAllowed() {
+				err = SendOfferBuyCompleteFCM(offer.ToLanguage, offer.ToFCM)
 			}
 		} else {
-			if offer.FCM != "" {
+			if offer.FCM != "" && makerAllowed() {
 				err = SendOfferBuyCompleteFCM(offer.Language, offer.FCM)
 			}
-			if offer.ToFCM != "" {
-				err = SendOfferSellCompleteFCM(offer.Language, offer.ToFCM)
+			if offer.ToFCM != "" && takerAllowed() {
+				err = SendOfferSellCompleteFCM(offer.ToLanguage, offer.ToFCM)
 			}
 		}
 	}
 	c <- err
 }
 
-func SendInstantOfferToEmail(offer bean.InstantOffer, c chan error) {
+// SMS only goes to phone-only users, everyone with an email already hears from us there
+func SendOfferToSMS(offer bean.Offer, recipients *audience, c chan error) {
+	var err error
+	category := offerNotificationCategory(offer.Status)
+	makerAllowed := func() bool {
+		return offer.Email == "" && offer.ContactPhone != "" &&
+			recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_SMS, category)
+	}
+	takerAllowed := func() bool {
+		return offer.ToEmail == "" && offer.ToContactPhone != "" &&
+			recipients.allows(offer.ToUID, bean.NOTIFICATION_CHANNEL_SMS, category)
+	}
+
+	if offer.Status == bean.OFFER_STATUS_ACTIVE {
+		if makerAllowed() {
+			err = sms.SendOfferActiveSMS(offer.Language, offer.ContactPhone)
+		}
+	} else if offer.Status == bean.OFFER_STATUS_SHAKE {
+		if makerAllowed() {
+			err = sms.SendOfferMakerShakeSMS(offer.Language, offer.ContactPhone)
+		}
+	} else if offer.Status == bean.OFFER_STATUS_REJECTED {
+		if makerAllowed() {
+			err = sms.SendOfferRejectSMS(offer.Language, offer.ContactPhone)
+		}
+		if takerAllowed() {
+			err = sms.SendOfferRejectSMS(offer.ToLanguage, offer.ToContactPhone)
+		}
+	} else if offer.Status == bean.OFFER_STATUS_COMPLETED {
+		if makerAllowed() {
+			err = sms.SendOfferCompleteSMS(offer.Language, offer.ContactPhone)
+		}
+		if takerAllowed() {
+			err = sms.SendOfferCompleteSMS(offer.ToLanguage, offer.ToContactPhone)
+		}
+	}
+	c <- err
+}
+
+func SendInstantOfferToEmail(offer bean.InstantOffer, recipients *audience, c chan error) {
 	var err error
+	allowed := recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_EMAIL, bean.NOTIFICATION_CATEGORY_ACTIVITY)
 	if offer.Status == bean.INSTANT_OFFER_STATUS_SUCCESS {
-		if offer.Email != "" {
+		if offer.Email != "" && allowed {
 			err = email.SendOrderInstantCCSuccessEmail(offer.Language, offer.Email, offer.Amount, offer.Currency)
 		}
+	} else if offer.Status == bean.INSTANT_OFFER_STATUS_REFUNDED || offer.Status == bean.INSTANT_OFFER_STATUS_PARTIALLY_REFUNDED {
+		if offer.Email != "" && allowed {
+			err = email.SendOrderInstantCCRefundedEmail(offer.Language, offer.Email, offer.Amount, offer.Currency,
+				offer.RefundedFiatAmount, offer.FiatCurrency)
+		}
 	}
 	c <- err
 }
@@ -186,33 +282,61 @@ func SendInstantOfferToFirebase(offer bean.InstantOffer, c chan error) {
 
 func SendInstantOfferToSolr(offer bean.InstantOffer, c chan error) {
 	// Always update
-	_, err := solr_service.UpdateObject(bean.NewSolrFromInstantOffer(offer))
+	err := search.UpdateDocument(bean.NewSearchDocumentFromInstantOffer(offer))
 	c <- err
 }
 
-func SendInstantOfferToFCM(offer bean.InstantOffer, c chan error) {
+func SendInstantOfferToFCM(offer bean.InstantOffer, recipients *audience, c chan error) {
 	var err error
+	allowed := func() bool {
+		return recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_ACTIVITY)
+	}
 	if offer.Status == bean.INSTANT_OFFER_STATUS_SUCCESS {
-		if offer.FCM != "" {
+		if offer.FCM != "" && allowed() {
 			err = SendOrderInstantCCSuccessFCM(offer.Language, offer.FCM)
 		}
+	} else if offer.Status == bean.INSTANT_OFFER_STATUS_REFUNDED || offer.Status == bean.INSTANT_OFFER_STATUS_PARTIALLY_REFUNDED {
+		if offer.FCM != "" && allowed() {
+			err = SendOrderInstantCCRefundedFCM(offer.Language, offer.FCM)
+		}
 	}
 	c <- err
 }
 
 func SendOfferStoreNotification(offer bean.OfferStore, offerItem bean.OfferStoreItem) []error {
-	c := make(chan error)
-	go SendOfferStoreToEmail(offer, offerItem, c)
-	go SendOfferStoreToFirebase(offer, offerItem, c)
-	go SendOfferStoreToSolr(offer, offerItem, c)
-	go SendOfferStoreToFCM(offer, offerItem, c)
+	return sendOfferStoreNotification(offer, offerItem, bean.NewOutboxEventFromOfferStore(offer, offerItem).Key)
+}
 
-	return []error{<-c, <-c, <-c, <-c}
+func sendOfferStoreNotification(offer bean.OfferStore, offerItem bean.OfferStoreItem, outboxEventKey string) []error {
+	dao.OrderBookDaoInst.UpdateOfferStoreEntries(offer, offerItem)
+	dao.GeoIndexDaoInst.UpdateOfferStoreEntries(offer, offerItem)
+	recipients := newAudience(nil, offer.UID)
+	offer.Language = recipients.language(offer.Language, offer.UID)
+	webhook.Emit(bean.NewWebhookEventFromOfferStore(offer, offerItem))
+
+	delivery := bean.NotificationDelivery{
+		Type:           bean.NOTIFICATION_TYPE_OFFER_STORE,
+		RefId:          offer.Id,
+		OfferStore:     &offer,
+		OfferStoreItem: &offerItem,
+	}
+	return dispatch(delivery, offerStoreSenders(offer, offerItem, recipients), recipients, outboxEventKey)
 }
 
-func SendOfferStoreToEmail(offer bean.OfferStore, offerItem bean.OfferStoreItem, c chan error) {
+func offerStoreSenders(offer bean.OfferStore, offerItem bean.OfferStoreItem, recipients *audience) map[string]channelSender {
+	return map[string]channelSender{
+		bean.NOTIFICATION_CHANNEL_EMAIL:    func(c chan error) { SendOfferStoreToEmail(offer, offerItem, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_FIREBASE: func(c chan error) { SendOfferStoreToFirebase(offer, offerItem, c) },
+		bean.NOTIFICATION_CHANNEL_SOLR:     func(c chan error) { SendOfferStoreToSolr(offer, offerItem, c) },
+		bean.NOTIFICATION_CHANNEL_FCM:      func(c chan error) { SendOfferStoreToFCM(offer, offerItem, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_SMS:      func(c chan error) { SendOfferStoreToSMS(offer, offerItem, recipients, c) },
+	}
+}
+
+func SendOfferStoreToEmail(offer bean.OfferStore, offerItem bean.OfferStoreItem, recipients *audience, c chan error) {
 	var err error
-	if offer.Email != "" {
+	allowed := recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_EMAIL, bean.NOTIFICATION_CATEGORY_ACTIVITY)
+	if offer.Email != "" && allowed {
 		if offerItem.Status == bean.OFFER_STORE_ITEM_STATUS_ACTIVE {
 			err = email.SendOfferStoreItemAddedEmail(offer.Language, offer.Email, offerItem.SellAmount, offerItem.BuyAmount, offerItem.Currency)
 		} else if offerItem.Status == bean.OFFER_STORE_ITEM_STATUS_CLOSED {
@@ -229,33 +353,76 @@ func SendOfferStoreToFirebase(offer bean.OfferStore, offerItem bean.OfferStoreIt
 
 func SendOfferStoreToSolr(offer bean.OfferStore, offerItem bean.OfferStoreItem, c chan error) {
 	// Always update
-	_, err := solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, offerItem))
+	err := search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, offerItem))
+	c <- err
+}
+
+func SendOfferStoreToFCM(offer bean.OfferStore, offerItem bean.OfferStoreItem, recipients *audience, c chan error) {
+	var err error
+	allowed := func() bool {
+		return recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_FCM, bean.NOTIFICATION_CATEGORY_ACTIVITY)
+	}
+	if offer.Email != "" && offerItem.Status == bean.OFFER_STORE_ITEM_STATUS_ACTIVE && allowed() {
+		SendOfferStoreItemAddedFCM(offer.Language, offer.FCM)
+	}
 	c <- err
 }
 
-func SendOfferStoreToFCM(offer bean.OfferStore, offerItem bean.OfferStoreItem, c chan error) {
+func SendOfferStoreToSMS(offer bean.OfferStore, offerItem bean.OfferStoreItem, recipients *audience, c chan error) {
 	var err error
-	if offer.Email != "" {
+	allowed := func() bool {
+		return recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_SMS, bean.NOTIFICATION_CATEGORY_ACTIVITY)
+	}
+
+	if offer.Email == "" && offer.ContactPhone != "" && allowed() {
 		if offerItem.Status == bean.OFFER_STORE_ITEM_STATUS_ACTIVE {
-			SendOfferStoreItemAddedFCM(offer.Language, offer.FCM)
+			err = sms.SendOfferStoreItemAddedSMS(offer.Language, offer.ContactPhone, offerItem.Currency)
 		} else if offerItem.Status == bean.OFFER_STORE_ITEM_STATUS_CLOSED {
+			err = sms.SendOfferStoreItemRemovedSMS(offer.Language, offer.ContactPhone)
 		}
 	}
 	c <- err
 }
 
 func SendOfferStoreShakeNotification(offer bean.OfferStoreShake, offerStore bean.OfferStore) []error {
-	c := make(chan error)
-	go SendOfferStoreShakeToEmail(offer, offerStore, c)
-	go SendOfferStoreShakeToFirebase(offer, offerStore, c)
-	go SendOfferStoreShakeToSolr(offer, offerStore, c)
-	go SendOfferStoreShakeToFCM(offer, offerStore, c)
+	return sendOfferStoreShakeNotification(offer, offerStore, bean.NewOutboxEventFromOfferStoreShake(offer, offerStore.Id).Key)
+}
+
+func sendOfferStoreShakeNotification(offer bean.OfferStoreShake, offerStore bean.OfferStore, outboxEventKey string) []error {
+	// Shakes change the store balance
+	if offerItem, ok := offerStore.ItemSnapshots[offer.Currency]; ok {
+		dao.OrderBookDaoInst.UpdateOfferStoreEntries(offerStore, offerItem)
+		dao.GeoIndexDaoInst.UpdateOfferStoreEntries(offerStore, offerItem)
+	}
+	recipients := newAudience(nil, offer.UID, offerStore.UID)
+	offer.Language = recipients.language(offer.Language, offer.UID)
+	offerStore.Language = recipients.language(offerStore.Language, offerStore.UID)
+	webhook.Emit(bean.NewWebhookEventFromOfferStoreShake(offer, offerStore.Id))
+
+	delivery := bean.NotificationDelivery{
+		Type:            bean.NOTIFICATION_TYPE_OFFER_STORE_SHAKE,
+		RefId:           offer.Id,
+		OfferStoreShake: &offer,
+		OfferStore:      &offerStore,
+	}
+	return dispatch(delivery, offerStoreShakeSenders(offer, offerStore, recipients), recipients, outboxEventKey)
+}
 
-	return []error{<-c, <-c, <-c, <-c}
+func offerStoreShakeSenders(offer bean.OfferStoreShake, offerStore bean.OfferStore, recipients *audience) map[string]channelSender {
+	return map[string]channelSender{
+		bean.NOTIFICATION_CHANNEL_EMAIL:    func(c chan error) { SendOfferStoreShakeToEmail(offer, offerStore, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_FIREBASE: func(c chan error) { SendOfferStoreShakeToFirebase(offer, offerStore, c) },
+		bean.NOTIFICATION_CHANNEL_SOLR:     func(c chan error) { SendOfferStoreShakeToSolr(offer, offerStore, c) },
+		bean.NOTIFICATION_CHANNEL_FCM:      func(c chan error) { SendOfferStoreShakeToFCM(offer, offerStore, recipients, c) },
+		bean.NOTIFICATION_CHANNEL_SMS:      func(c chan error) { SendOfferStoreShakeToSMS(offer, offerStore, recipients, c) },
+	}
 }
 
-func SendOfferStoreShakeToEmail(offer bean.OfferStoreShake, offerStore bean.OfferStore, c chan error) {
+func SendOfferStoreShakeToEmail(offer bean.OfferStoreShake, offerStore bean.OfferStore, recipients *audience, c chan error) {
 	var err error
+	category := offerStoreShakeNotificationCategory(offer.Status)
+	makerAllowed := recipients.allows(offerStore.UID, bean.NOTIFICATION_CHANNEL_EMAIL, category)
+	takerAllowed := recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_EMAIL, category)
 
 	username := offerStore.Username
 	if username == "" {
@@ -275,25 +442,41 @@ func SendOfferStoreShakeToEmail(offer bean.OfferStoreShake, offerStore bean.Offe
 	if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_PRE_SHAKE {
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_SHAKE {
 		if offer.Type == bean.OFFER_TYPE_BUY {
-			err = email.SendOfferStoreMakerBuyShakeEmail(offerStore.Language, offerStore.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, toUsername)
-			err = email.SendOfferStoreTakerBuyShakeEmail(offer.Language, offer.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, username)
+			if makerAllowed {
+				err = email.SendOfferStoreMakerBuyShakeEmail(offerStore.Language, offerStore.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, toUsername)
+			}
+			if takerAllowed {
+				err = email.SendOfferStoreTakerBuyShakeEmail(offer.Language, offer.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, username)
+			}
 		} else {
-			err = email.SendOfferStoreMakerSellShakeEmail(offerStore.Language, offerStore.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, toUsername)
-			err = email.SendOfferStoreTakerSellShakeEmail(offer.Language, offer.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, username)
+			if makerAllowed {
+				err = email.SendOfferStoreMakerSellShakeEmail(offerStore.Language, offerStore.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, toUsername)
+			}
+			if takerAllowed {
+				err = email.SendOfferStoreTakerSellShakeEmail(offer.Language, offer.Email, offer.Amount, offer.Currency, offer.FiatAmount, offer.FiatCurrency, username)
+			}
 		}
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_CANCELLED {
 
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_REJECTED {
 		if offer.ActionUID == offer.UID {
-			err = email.SendOfferStoreMakerRejectEmail(offerStore.Language, offerStore.Email, toUsername)
+			if makerAllowed {
+				err = email.SendOfferStoreMakerRejectEmail(offerStore.Language, offerStore.Email, toUsername)
+			}
 		} else {
-			err = email.SendOfferStoreTakerRejectEmail(offer.Language, offer.Email, username)
+			if takerAllowed {
+				err = email.SendOfferStoreTakerRejectEmail(offer.Language, offer.Email, username)
+			}
 		}
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_COMPLETED {
 		if offer.Type == bean.OFFER_TYPE_BUY {
-			err = email.SendOfferStoreMakerCompleteEmail(offerStore.Language, offerStore.Email, offer.Amount, offer.Currency, username)
+			if makerAllowed {
+				err = email.SendOfferStoreMakerCompleteEmail(offerStore.Language, offerStore.Email, offer.Amount, offer.Currency, username)
+			}
 		} else {
-			err = email.SendOfferStoreTakerCompleteEmail(offer.Language, offer.Email, offer.Amount, offer.Currency, toUsername, username, offerStore.Id, offer.Id)
+			if takerAllowed {
+				err = email.SendOfferStoreTakerCompleteEmail(offer.Language, offer.Email, offer.Amount, offer.Currency, toUsername, username, offerStore.Id, offer.Id)
+			}
 		}
 	}
 	c <- err
@@ -306,12 +489,19 @@ func SendOfferStoreShakeToFirebase(offer bean.OfferStoreShake, offerStore bean.O
 
 func SendOfferStoreShakeToSolr(offer bean.OfferStoreShake, offerStore bean.OfferStore, c chan error) {
 	// Always update
-	_, err := solr_service.UpdateObject(bean.NewSolrFromOfferStoreShake(offer, offerStore))
+	err := search.UpdateDocument(bean.NewSearchDocumentFromOfferStoreShake(offer, offerStore))
 	c <- err
 }
 
-func SendOfferStoreShakeToFCM(offer bean.OfferStoreShake, offerStore bean.OfferStore, c chan error) {
+func SendOfferStoreShakeToFCM(offer bean.OfferStoreShake, offerStore bean.OfferStore, recipients *audience, c chan error) {
 	var err error
+	category := offerStoreShakeNotificationCategory(offer.Status)
+	makerAllowed := func() bool {
+		return recipients.allows(offerStore.UID, bean.NOTIFICATION_CHANNEL_FCM, category)
+	}
+	takerAllowed := func() bool {
+		return recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_FCM, category)
+	}
 
 	username := offerStore.Username
 	if username == "" {
@@ -331,25 +521,93 @@ func SendOfferStoreShakeToFCM(offer bean.OfferStoreShake, offerStore bean.OfferS
 	if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_PRE_SHAKE {
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_SHAKE {
 		if offer.Type == bean.OFFER_TYPE_BUY {
-			err = SendOfferStoreMakerBuyShakeFCM(offerStore.Language, offerStore.FCM, offerStore.ChatUsername)
-			err = SendOfferStoreTakerBuyShakeFCM(offer.Language, offer.FCM, offer.Currency, offer.ChatUsername)
+			if makerAllowed() {
+				err = SendOfferStoreMakerBuyShakeFCM(offerStore.Language, offerStore.FCM, offerStore.ChatUsername)
+			}
+			if takerAllowed() {
+				err = SendOfferStoreTakerBuyShakeFCM(offer.Language, offer.FCM, offer.Currency, offer.ChatUsername)
+			}
 		} else {
-			err = SendOfferStoreMakerSellShakeFCM(offerStore.Language, offerStore.FCM, offerStore.ChatUsername)
-			err = SendOfferStoreTakerSellShakeFCM(offer.Language, offer.FCM, offer.Currency, offer.ChatUsername)
+			if makerAllowed() {
+				err = SendOfferStoreMakerSellShakeFCM(offerStore.Language, offerStore.FCM, offerStore.ChatUsername)
+			}
+			if takerAllowed() {
+				err = SendOfferStoreTakerSellShakeFCM(offer.Language, offer.FCM, offer.Currency, offer.ChatUsername)
+			}
 		}
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_CANCELLED {
 
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_REJECTED {
 		if offer.ActionUID == offer.UID {
-			err = SendOfferStoreMakerRejectFCM(offerStore.Language, offerStore.FCM, toUsername)
+			if makerAllowed() {
+				err = SendOfferStoreMakerRejectFCM(offerStore.Language, offerStore.FCM, toUsername)
+			}
+		} else {
+			if takerAllowed() {
+				err = SendOfferStoreTakerRejectFCM(offer.Language, offer.FCM, username)
+			}
+		}
+	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_COMPLETED {
+		if offer.Type == bean.OFFER_TYPE_BUY {
+			if makerAllowed() {
+				err = SendOfferStoreMakerCompleteFCM(offerStore.Language, offerStore.FCM, offer.Currency)
+			}
+		} else {
+			if takerAllowed() {
+				err = SendOfferStoreTakerCompleteFCM(offer.Language, offer.FCM, offer.Currency, offerStore.Id, offer.Id)
+			}
+		}
+	}
+	c <- err
+}
+
+func SendOfferStoreShakeToSMS(offer bean.OfferStoreShake, offerStore bean.OfferStore, recipients *audience, c chan error) {
+	var err error
+	category := offerStoreShakeNotificationCategory(offer.Status)
+	makerAllowed := func() bool {
+		return offerStore.Email == "" && offerStore.ContactPhone != "" &&
+			recipients.allows(offerStore.UID, bean.NOTIFICATION_CHANNEL_SMS, category)
+	}
+	takerAllowed := func() bool {
+		return offer.Email == "" && offer.ContactPhone != "" &&
+			recipients.allows(offer.UID, bean.NOTIFICATION_CHANNEL_SMS, category)
+	}
+
+	username := offerStore.Username
+	if username == "" {
+		username = offerStore.ContactPhone
+	}
+	toUsername := offer.Username
+	if toUsername == "" {
+		toUsername = offer.ContactPhone
+	}
+
+	if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_SHAKE {
+		if makerAllowed() {
+			err = sms.SendOfferStoreMakerShakeSMS(offerStore.Language, offerStore.ContactPhone, offer.Currency, toUsername)
+		}
+		if takerAllowed() {
+			err = sms.SendOfferStoreTakerShakeSMS(offer.Language, offer.ContactPhone, offer.Currency, username)
+		}
+	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_REJECTED {
+		if offer.ActionUID == offer.UID {
+			if makerAllowed() {
+				err = sms.SendOfferStoreRejectSMS(offerStore.Language, offerStore.ContactPhone, toUsername)
+			}
 		} else {
-			err = SendOfferStoreTakerRejectFCM(offer.Language, offer.FCM, username)
+			if takerAllowed() {
+				err = sms.SendOfferStoreRejectSMS(offer.Language, offer.ContactPhone, username)
+			}
 		}
 	} else if offer.Status == bean.OFFER_STORE_SHAKE_STATUS_COMPLETED {
 		if offer.Type == bean.OFFER_TYPE_BUY {
-			err = SendOfferStoreMakerCompleteFCM(offerStore.Language, offerStore.FCM, offer.Currency)
+			if makerAllowed() {
+				err = sms.SendOfferStoreCompleteSMS(offerStore.Language, offerStore.ContactPhone, offer.Currency)
+			}
 		} else {
-			err = SendOfferStoreTakerCompleteFCM(offer.Language, offer.FCM, offer.Currency, offerStore.Id, offer.Id)
+			if takerAllowed() {
+				err = sms.SendOfferStoreCompleteSMS(offer.Language, offer.ContactPhone, offer.Currency)
+			}
 		}
 	}
 	c <- err
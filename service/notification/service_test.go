@@ -0,0 +1,184 @@
+package notification
+
+import (
+	"encoding/json"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/service/email"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const makerEmail = "maker@ninja.example"
+const takerEmail = "taker@ninja.example"
+const makerFCM = "maker-fcm"
+const takerFCM = "taker-fcm"
+
+func setupEmailSink(t *testing.T) email.SinkTransport {
+	email.TemplateDir = "../../templates/"
+	translation.MustLoadTranslations("../../translations")
+	assert.Nil(t, email.LoadTemplates())
+
+	sink := email.NewSinkTransport("")
+	email.TransportInst = sink
+	return sink
+}
+
+const takerLanguage = "zh-HK"
+
+// en-US plus the zh-HK strings the taker tests look for
+func loadTakerTranslations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translations")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	defaultTranslations, err := ioutil.ReadFile("../../translations/en-US.flat.yaml")
+	assert.Nil(t, err)
+	takerTranslations := "email_offer_taker_maker_rejected_subject:\n  other: \"對方已取消交易\"\n" +
+		"notification_offer_buy_completed:\n  other: \"你的幣很快就會到達你的錢包\"\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "en-US.flat.yaml"), defaultTranslations, 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, takerLanguage+".flat.yaml"), []byte(takerTranslations), 0644))
+	translation.MustLoadTranslations(dir)
+}
+
+type fcmSink struct {
+	mutex    sync.Mutex
+	messages []bean.FCMObject
+}
+
+func (sink *fcmSink) MessagesTo(to string) []bean.FCMObject {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	messages := make([]bean.FCMObject, 0)
+	for _, message := range sink.messages {
+		if message.To == to {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Points the FCM service at a local server that keeps every push
+func setupFCMSink(t *testing.T) (*fcmSink, *httptest.Server) {
+	translation.MustLoadTranslations("../../translations")
+
+	sink := &fcmSink{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request bean.FCMRequest
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		sink.mutex.Lock()
+		sink.messages = append(sink.messages, request.Data)
+		sink.mutex.Unlock()
+	}))
+	os.Setenv("FCM_SERVICE_URL", server.URL)
+	return sink, server
+}
+
+// No UIDs, so preferences are not looked up
+func TestSendOfferToEmailRejectedShakeEmailsMakerAndTaker(t *testing.T) {
+	sink := setupEmailSink(t)
+
+	c := make(chan error, 1)
+	SendOfferToEmail(bean.Offer{
+		Status:   bean.OFFER_STATUS_REJECTED,
+		Email:    makerEmail,
+		ToEmail:  takerEmail,
+		Language: translation.DEFAULT_LANGUAGE,
+	}, newAudience(nil), c)
+	assert.Nil(t, <-c)
+
+	assert.Equal(t, 2, len(sink.Messages()))
+	assert.Equal(t, 1, len(sink.MessagesTo(makerEmail)))
+	assert.Equal(t, 1, len(sink.MessagesTo(takerEmail)))
+}
+
+func TestSendOfferToEmailActiveEmailsMakerOnly(t *testing.T) {
+	sink := setupEmailSink(t)
+
+	c := make(chan error, 1)
+	SendOfferToEmail(bean.Offer{
+		Status:   bean.OFFER_STATUS_ACTIVE,
+		Type:     bean.OFFER_TYPE_BUY,
+		Email:    makerEmail,
+		ToEmail:  takerEmail,
+		Language: translation.DEFAULT_LANGUAGE,
+	}, newAudience(nil), c)
+	assert.Nil(t, <-c)
+
+	assert.Equal(t, 1, len(sink.MessagesTo(makerEmail)))
+	assert.Equal(t, 0, len(sink.MessagesTo(takerEmail)))
+}
+
+func TestSendOfferToFCMRejectPushesMakerAndTaker(t *testing.T) {
+	for _, actionUID := range []string{"maker", "taker"} {
+		sink, server := setupFCMSink(t)
+
+		c := make(chan error, 1)
+		SendOfferToFCM(bean.Offer{
+			Status:    bean.OFFER_STATUS_REJECTED,
+			UID:       "maker",
+			ToUID:     "taker",
+			ActionUID: actionUID,
+			FCM:       makerFCM,
+			ToFCM:     takerFCM,
+		}, newAudience(nil), c)
+		assert.Nil(t, <-c)
+
+		assert.Equal(t, 1, len(sink.MessagesTo(makerFCM)))
+		assert.Equal(t, 1, len(sink.MessagesTo(takerFCM)))
+		server.Close()
+	}
+	os.Setenv("FCM_SERVICE_URL", "")
+}
+
+func TestSendOfferToEmailUsesTakerLanguage(t *testing.T) {
+	sink := setupEmailSink(t)
+	loadTakerTranslations(t)
+
+	c := make(chan error, 1)
+	SendOfferToEmail(bean.Offer{
+		Status:     bean.OFFER_STATUS_REJECTED,
+		Type:       bean.OFFER_TYPE_SELL,
+		UID:        "maker",
+		ToUID:      "taker",
+		ActionUID:  "maker",
+		Email:      makerEmail,
+		ToEmail:    takerEmail,
+		Language:   translation.DEFAULT_LANGUAGE,
+		ToLanguage: takerLanguage,
+	}, newAudience(nil), c)
+	assert.Nil(t, <-c)
+
+	assert.Equal(t, "You cancelled on Ninja", sink.MessagesTo(makerEmail)[0].Subject)
+	assert.Equal(t, "對方已取消交易", sink.MessagesTo(takerEmail)[0].Subject)
+}
+
+func TestSendOfferToFCMUsesTakerLanguage(t *testing.T) {
+	sink, server := setupFCMSink(t)
+	defer server.Close()
+	loadTakerTranslations(t)
+
+	c := make(chan error, 1)
+	SendOfferToFCM(bean.Offer{
+		Status:     bean.OFFER_STATUS_COMPLETED,
+		Type:       bean.OFFER_TYPE_SELL,
+		UID:        "maker",
+		ToUID:      "taker",
+		FCM:        makerFCM,
+		ToFCM:      takerFCM,
+		Language:   translation.DEFAULT_LANGUAGE,
+		ToLanguage: takerLanguage,
+	}, newAudience(nil), c)
+	assert.Nil(t, <-c)
+
+	assert.Equal(t, "你的幣很快就會到達你的錢包", sink.MessagesTo(takerFCM)[0].Notification.Body)
+	assert.NotEqual(t, "你的幣很快就會到達你的錢包", sink.MessagesTo(makerFCM)[0].Notification.Body)
+	os.Setenv("FCM_SERVICE_URL", "")
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/notification"
+)
+
+type NotificationDeliveryService struct {
+	dao *dao.NotificationDao
+}
+
+func (s NotificationDeliveryService) GetNotificationDelivery(deliveryId string) (delivery bean.NotificationDelivery, ce SimpleContextError) {
+	deliveryTO := s.dao.GetNotificationDelivery(deliveryId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, deliveryTO); ce.HasError() {
+		return
+	}
+	delivery = deliveryTO.Object.(bean.NotificationDelivery)
+
+	return
+}
+
+func (s NotificationDeliveryService) ResendNotification(deliveryId string) (delivery bean.NotificationDelivery, ce SimpleContextError) {
+	deadLetterTO := s.dao.GetNotificationDeadLetter(deliveryId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, deadLetterTO); ce.HasError() {
+		return
+	}
+	delivery = deadLetterTO.Object.(bean.NotificationDelivery)
+
+	var err error
+	delivery, err = notification.ResendNotification(delivery)
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
+
+func (s NotificationDeliveryService) RetryNotificationDeliveries() (count int, ce SimpleContextError) {
+	count, err := notification.RetryNotificationDeliveries()
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
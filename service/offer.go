@@ -2,7 +2,6 @@ package service
 
 import (
 	"fmt"
-	"github.com/go-errors/errors"
 	"github.com/ninjadotorg/handshake-exchange/api_error"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/common"
@@ -10,8 +9,8 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/integration/blockchainio_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/coinbase_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/crypto_service"
-	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
 	"github.com/ninjadotorg/handshake-exchange/service/notification"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
 	"github.com/shopspring/decimal"
 	"time"
 )
@@ -34,16 +33,16 @@ func (s OfferService) GetOffer(userId string, offerId string) (offer bean.Offer,
 	price := common.StringToDecimal(offer.Price)
 	percentage := common.StringToDecimal(offer.Percentage)
 
-	price, fiatPrice, fiatAmount, err := s.GetQuote(offer.Type, offer.Amount, offer.Currency, offer.FiatCurrency)
+	price, fiatPrice, fiatAmount, quoteCE := s.GetQuote(offer.Type, offer.Amount, offer.Currency, offer.FiatCurrency)
 	if offer.IsTypeSell() && price.Equal(common.Zero) {
-		if ce.SetError(api_error.GetDataFailed, err) {
+		if ce.FeedContextErrorDefault(quoteCE) {
 			return
 		}
 		markup := fiatAmount.Mul(percentage)
 		fiatAmount = fiatAmount.Add(markup)
 	}
-	offer.Price = common.DecimalToFiatString(fiatPrice)
-	offer.FiatAmount = common.DecimalToFiatString(fiatAmount)
+	offer.Price = RoundFiatAmount(offer.FiatCurrency, fiatPrice).String()
+	offer.FiatAmount = RoundFiatAmount(offer.FiatCurrency, fiatAmount).String()
 
 	return
 }
@@ -54,6 +53,9 @@ func (s OfferService) CreateOffer(userId string, offerBody bean.Offer) (offer be
 		ce.SetStatusKey(api_error.UnsupportedCurrency)
 		return
 	}
+	if GetFiatCurrency(s.miscDao, offerBody.FiatCurrency, &ce); ce.HasError() {
+		return
+	}
 
 	// Minimum amount
 	amount, errFmt := decimal.NewFromString(offerBody.Amount)
@@ -110,6 +112,9 @@ func (s OfferService) CreateOffer(userId string, offerBody bean.Offer) (offer be
 		ce.SetStatusKey(api_error.OfferActionLocked)
 		return
 	}
+	if KYCServiceInst.CheckOfferAmount(profile, currencyInst.Code, amount, &ce); ce.HasError() {
+		return
+	}
 	offerBody.UID = userId
 
 	transCountTO := s.transDao.GetTransactionCount(offerBody.UID, offerBody.Currency)
@@ -205,11 +210,8 @@ func (s OfferService) ActiveOnChainOffer(offerId string, hid int64) (offer bean.
 			return
 		}
 	} else if offer.Status == bean.OFFER_STATUS_PRE_SHAKING {
-		offer.Status = bean.OFFER_STATUS_PRE_SHAKE
-		_, ce = s.PreShakeOnChainOffer(offerId, hid)
-		if ce.HasError() {
-			return
-		}
+		// Pre shake writes and notifies on its own
+		return s.PreShakeOnChainOffer(offerId, hid)
 	}
 
 	notification.SendOfferNotification(offer)
@@ -294,7 +296,7 @@ func (s OfferService) ShakeOffer(userId string, offerId string, body bean.OfferS
 		return
 	}
 
-	s.setupOfferPrice(&offer, &ce)
+	quote := s.setupOfferPrice(&offer, userId, body.QuoteId, &ce)
 	if ce.HasError() {
 		return
 	}
@@ -326,7 +328,14 @@ func (s OfferService) ShakeOffer(userId string, offerId string, body bean.OfferS
 	offer.ToChatUsername = body.ChatUsername
 	offer.ToLanguage = body.Language
 	offer.ToFCM = body.FCM
+	offer.ToContactPhone = body.ContactPhone
 
+	if quote.Id != "" {
+		if ce.FeedContextErrorDefault(QuoteServiceInst.UseLockedQuote(quote.Id, quote.ExpiredAt)) {
+			return
+		}
+		defer QuoteServiceInst.ReleaseLockedQuoteOnError(quote.Id, &ce)
+	}
 	err := s.dao.UpdateOfferShaking(offer)
 	if ce.SetError(api_error.UpdateDataFailed, err) {
 		return
@@ -374,6 +383,7 @@ func (s OfferService) AcceptShakeOffer(userId string, offerId string) (offer bea
 
 	if offer.Status != bean.OFFER_STATUS_PRE_SHAKE {
 		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
 	}
 
 	if offer.Currency == bean.BTC.Code {
@@ -411,6 +421,7 @@ func (s OfferService) CancelShakeOffer(userId string, offerId string) (offer bea
 
 	if offer.Status != bean.OFFER_STATUS_PRE_SHAKE {
 		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
 	}
 
 	if offer.Currency == bean.BTC.Code {
@@ -488,6 +499,7 @@ func (s OfferService) RejectShakeOffer(userId string, offerId string) (offer bea
 
 	if offer.Status != bean.OFFER_STATUS_SHAKE {
 		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
 	}
 
 	if offer.Currency == bean.BTC.Code {
@@ -815,98 +827,74 @@ func (s OfferService) CompleteOnChainOffer(offerId string) (offer bean.Offer, ce
 }
 
 func (s OfferService) GetQuote(quoteType string, amountStr string, currency string, fiatCurrency string) (price decimal.Decimal, fiatPrice decimal.Decimal,
-	fiatAmount decimal.Decimal, err error) {
+	fiatAmount decimal.Decimal, ce SimpleContextError) {
 	amount, numberErr := decimal.NewFromString(amountStr)
+	if ce.SetError(api_error.InvalidNumber, numberErr) {
+		return
+	}
+	if GetFiatCurrency(s.miscDao, fiatCurrency, &ce); ce.HasError() {
+		return
+	}
 	to := dao.MiscDaoInst.GetCurrencyRateFromCache(bean.USD.Code, fiatCurrency)
-	if numberErr != nil {
-		err = numberErr
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
+		return
 	}
 	rate := to.Object.(bean.CurrencyRate)
 	rateNumber := decimal.NewFromFloat(rate.Rate)
 	tmpAmount := amount.Mul(rateNumber)
 
-	if quoteType == "buy" {
-		resp, errResp := coinbase_service.GetBuyPrice(currency)
-		err = errResp
-		if err != nil {
-			return
-		}
-		price = common.StringToDecimal(resp.Amount)
-		fiatPrice = price.Mul(rateNumber)
-		fiatAmount = tmpAmount.Mul(price)
-	} else if quoteType == "sell" {
-		resp, errResp := coinbase_service.GetSellPrice(currency)
-		err = errResp
-		if err != nil {
+	if quoteType == "buy" || quoteType == "sell" {
+		// Served from cache, refreshed by the crypto rates cron job
+		oraclePrice, priceCE := PriceOracleServiceInst.GetCachedPrice(currency, quoteType)
+		if ce.FeedContextErrorDefault(priceCE) {
 			return
 		}
-		price = common.StringToDecimal(resp.Amount)
+		price = decimal.NewFromFloat(oraclePrice.Price)
 		fiatPrice = price.Mul(rateNumber)
 		fiatAmount = tmpAmount.Mul(price)
 	} else {
-		err = errors.New(api_error.InvalidQueryParam)
+		ce.SetStatusKey(api_error.InvalidQueryParam)
 	}
 
 	return
 }
 
-func (s OfferService) GetAllQuotes(fiatCurrency string) []interface{} {
+func (s OfferService) GetAllQuotes(fiatCurrency string) (quotes []interface{}, ce SimpleContextError) {
 	type quoteStruct struct {
 		Type         string
 		Currency     string
 		FiatCurrency string
 		// FiatAmount   string
 		Price string
+		Stale bool
 	}
 
-	var quoteObj quoteStruct
-	quotes := make([]interface{}, 4)
-
-	quoteObj = quoteStruct{
-		Type:         bean.OFFER_TYPE_SELL,
-		Currency:     bean.BTC.Code,
-		FiatCurrency: fiatCurrency,
-	}
-	_, fiatPrice, _, _ := s.GetQuote(quoteObj.Type, "1", quoteObj.Currency, fiatCurrency)
-	quoteObj.Price = fiatPrice.Round(2).String()
-	// quote.FiatAmount = fiatAmount.Round(2).String()
-
-	quotes[0] = quoteObj
-
-	quoteObj = quoteStruct{
-		Type:         bean.OFFER_TYPE_BUY,
-		Currency:     bean.BTC.Code,
-		FiatCurrency: fiatCurrency,
-	}
-	_, fiatPrice, _, _ = s.GetQuote(quoteObj.Type, "1", quoteObj.Currency, fiatCurrency)
-	quoteObj.Price = fiatPrice.Round(2).String()
-	// quote.FiatAmount = fiatAmount.Round(2).String()
-
-	quotes[1] = quoteObj
-
-	quoteObj = quoteStruct{
-		Type:         bean.OFFER_TYPE_SELL,
-		Currency:     bean.ETH.Code,
-		FiatCurrency: fiatCurrency,
-	}
-	_, fiatPrice, _, _ = s.GetQuote(quoteObj.Type, "1", quoteObj.Currency, fiatCurrency)
-	quoteObj.Price = fiatPrice.Round(2).String()
-	// quote.FiatAmount = fiatAmount.Round(2).String()
-
-	quotes[2] = quoteObj
+	quotes = make([]interface{}, 0)
+	for _, currency := range []string{bean.BTC.Code, bean.ETH.Code} {
+		for _, quoteType := range []string{bean.OFFER_TYPE_SELL, bean.OFFER_TYPE_BUY} {
+			quoteObj := quoteStruct{
+				Type:         quoteType,
+				Currency:     currency,
+				FiatCurrency: fiatCurrency,
+			}
+			_, fiatPrice, _, quoteCE := s.GetQuote(quoteObj.Type, "1", quoteObj.Currency, fiatCurrency)
+			if quoteCE.StatusKey == api_error.PriceIsStale {
+				// Flag it and keep the other pairs, trading on it is refused anyway
+				quoteObj.Stale = true
+				quotes = append(quotes, quoteObj)
+				continue
+			}
+			if ce.FeedContextErrorDefault(quoteCE) {
+				return
+			}
+			quoteObj.Price = RoundFiatAmount(fiatCurrency, fiatPrice).String()
+			// quote.FiatAmount = fiatAmount.Round(2).String()
 
-	quoteObj = quoteStruct{
-		Type:         bean.OFFER_TYPE_BUY,
-		Currency:     bean.ETH.Code,
-		FiatCurrency: fiatCurrency,
+			quotes = append(quotes, quoteObj)
+		}
 	}
-	_, fiatPrice, _, _ = s.GetQuote(quoteObj.Type, "1", quoteObj.Currency, fiatCurrency)
-	quoteObj.Price = fiatPrice.Round(2).String()
-	// quote.FiatAmount = fiatAmount.Round(2).String()
 
-	quotes[3] = quoteObj
-
-	return quotes
+	return
 }
 
 func (s OfferService) FinishOfferConfirmingAddresses() (finishedInstantOffers []bean.Offer, ce SimpleContextError) {
@@ -955,8 +943,15 @@ func (s OfferService) FinishCryptoTransfer() (finishedInstantOffers []bean.Offer
 		return
 	} else {
 		for _, pendingOffer := range pendingOffers {
-			bodyTransaction, err := coinbase_service.GetTransaction(pendingOffer.ExternalId, pendingOffer.Currency)
-			if err == nil && bodyTransaction.Status == "completed" {
+			transferDone := false
+			if pendingOffer.Provider == bean.BTC_WALLET_BLOCKCHAINIO {
+				isSuccess, _, err := crypto_service.GetTransactionReceipt(pendingOffer.TxHash, pendingOffer.Currency)
+				transferDone = err == nil && isSuccess
+			} else {
+				bodyTransaction, err := coinbase_service.GetTransaction(pendingOffer.ExternalId, pendingOffer.Currency)
+				transferDone = err == nil && bodyTransaction.Status == "completed"
+			}
+			if transferDone {
 				completed := false
 				if pendingOffer.DataType == bean.OFFER_ADDRESS_MAP_OFFER {
 					_, ce := s.FinishOfferPendingTransfer(pendingOffer.DataRef)
@@ -970,7 +965,7 @@ func (s OfferService) FinishCryptoTransfer() (finishedInstantOffers []bean.Offer
 				}
 
 				if completed {
-					dao.OfferDaoInst.RemoveCryptoPendingTransfer(pendingOffer.TxHash)
+					dao.OfferDaoInst.RemoveCryptoPendingTransfer(pendingOffer.Id)
 				}
 			}
 		}
@@ -1002,7 +997,7 @@ func (s OfferService) SyncToSolr(offerId string) (offer bean.Offer, ce SimpleCon
 	if ce.HasError() {
 		return
 	}
-	solr_service.UpdateObject(bean.NewSolrFromOffer(offer))
+	search.UpdateDocument(bean.NewSearchDocumentFromOffer(offer))
 
 	return
 }
@@ -1045,17 +1040,34 @@ func (s OfferService) getOfferProfile(offer bean.Offer, profile bean.Profile, ce
 	return
 }
 
-func (s OfferService) setupOfferPrice(offer *bean.Offer, ce *SimpleContextError) {
-	price, fiatPrice, fiatAmount, err := s.GetQuote(offer.Type, offer.Amount, offer.Currency, offer.FiatCurrency)
-	if ce.SetError(api_error.GetDataFailed, err) {
-		return
+// Returns the matched quote, the caller uses it up once the shake is ready to be written
+func (s OfferService) setupOfferPrice(offer *bean.Offer, userId string, quoteId string, ce *SimpleContextError) (quote bean.LockedQuote) {
+	var price, fiatPrice, fiatAmount decimal.Decimal
+	if quoteId != "" {
+		// Honor the locked price until the quote expires
+		var quoteCE SimpleContextError
+		quote, quoteCE = QuoteServiceInst.GetMatchedLockedQuote(userId, quoteId, offer.Type, offer.Amount, offer.Currency, offer.FiatCurrency)
+		if ce.FeedContextErrorDefault(quoteCE) {
+			return
+		}
+		price = common.StringToDecimal(quote.PriceUSD)
+		fiatPrice = common.StringToDecimal(quote.Price)
+		fiatAmount = common.StringToDecimal(quote.FiatAmount)
+	} else {
+		var quoteCE SimpleContextError
+		price, fiatPrice, fiatAmount, quoteCE = s.GetQuote(offer.Type, offer.Amount, offer.Currency, offer.FiatCurrency)
+		if ce.FeedContextErrorDefault(quoteCE) {
+			return
+		}
 	}
 
-	offer.Price = fiatPrice.Round(2).String()
-	offer.PriceUSD = price.Round(2).String()
+	offer.Price = RoundFiatAmount(offer.FiatCurrency, fiatPrice).String()
+	offer.PriceUSD = price.Round(bean.USD.Decimal).String()
 	offer.PriceNumberUSD, _ = price.Float64()
 	offer.PriceNumber, _ = fiatPrice.Float64()
-	offer.FiatAmount = fiatAmount.Round(2).String()
+	offer.FiatAmount = RoundFiatAmount(offer.FiatCurrency, fiatAmount).String()
+
+	return
 }
 
 func (s OfferService) setupOfferAmount(offer *bean.Offer, ce *SimpleContextError) {
@@ -1094,28 +1106,28 @@ func (s OfferService) transferCrypto(offer *bean.Offer, userId string, ce *Simpl
 			//Transfer
 			description := fmt.Sprintf("Transfer to userId %s offerId %s status %s", userId, offer.Id, offer.Status)
 
-			var response1 interface{}
 			// var response2 interface{}
 			transferAmount := offer.Amount
 			if offer.IsTypeBuy() {
-				response1 = s.sendTransaction(offer.UserAddress, offer.TotalAmount, offer.Currency, description, offer.Id, *offer, ce)
 				transferAmount = offer.TotalAmount
-			} else {
-				response1 = s.sendTransaction(offer.UserAddress, offer.Amount, offer.Currency, description, offer.Id, *offer, ce)
 			}
-			if ce.HasError() {
-				return
+			// Only BTC is paid out from our wallets, it is sent later in a batch and the transfer log is written then
+			if offer.Currency == bean.BTC.Code {
+				payout, payoutCE := PayoutServiceInst.AddPayout(bean.CryptoPayout{
+					WalletProvider: offer.WalletProvider,
+					DataType:       bean.OFFER_ADDRESS_MAP_OFFER,
+					DataRef:        dao.GetOfferItemPath(offer.Id),
+					UID:            userId,
+					Address:        offer.UserAddress,
+					Description:    description,
+					Amount:         transferAmount,
+					Currency:       offer.Currency,
+				})
+				if ce.FeedContextError(api_error.AddDataFailed, payoutCE) {
+					return
+				}
+				offer.ProviderData = dao.GetCryptoPayoutItemPath(payout.Id)
 			}
-			s.miscDao.AddCryptoTransferLog(bean.CryptoTransferLog{
-				Provider:         offer.WalletProvider,
-				ProviderResponse: response1,
-				DataType:         bean.OFFER_ADDRESS_MAP_OFFER,
-				DataRef:          dao.GetOfferItemPath(offer.Id),
-				UID:              userId,
-				Description:      description,
-				Amount:           transferAmount,
-				Currency:         offer.Currency,
-			})
 
 			// Transfer reward
 			//if offer.RewardAddress != "" {
@@ -1128,7 +1140,6 @@ func (s OfferService) transferCrypto(offer *bean.Offer, userId string, ce *Simpl
 			//	return
 			//}
 			offer.Provider = bean.OFFER_PROVIDER_COINBASE
-			offer.ProviderData = response1
 			//externalId = coinbaseResponse.Id
 		} else {
 			ce.SetStatusKey(api_error.InvalidRequestBody)
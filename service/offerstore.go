@@ -2,7 +2,6 @@ package service
 
 import (
 	"fmt"
-	"github.com/go-errors/errors"
 	"github.com/ninjadotorg/handshake-exchange/api_error"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/common"
@@ -11,8 +10,8 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/integration/coinbase_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/ethereum_service"
 	"github.com/ninjadotorg/handshake-exchange/integration/exchangehandshakeshop_service"
-	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
 	"github.com/ninjadotorg/handshake-exchange/service/notification"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
 	"github.com/shopspring/decimal"
 	"strconv"
 	"strings"
@@ -188,6 +187,9 @@ func (s OfferStoreService) UpdateOfferStore(userId string, offerId string, body
 	if ce.HasError() {
 		return
 	}
+	if GetFiatCurrency(s.miscDao, body.Offer.FiatCurrency, &ce); ce.HasError() {
+		return
+	}
 	// Copy data
 	offer.FiatCurrency = body.Offer.FiatCurrency
 	offer.ContactPhone = body.Offer.ContactPhone
@@ -222,13 +224,13 @@ func (s OfferStoreService) UpdateOfferStore(userId string, offerId string, body
 	}
 
 	// Only sync to solr
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, item))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, item))
 
 	return
 }
 
 func (s OfferStoreService) RefillOfferStoreItem(userId string, offerId string, body bean.OfferStoreItem) (offer bean.OfferStore, ce SimpleContextError) {
-	_ = GetProfile(s.userDao, userId, &ce)
+	profile := GetProfile(s.userDao, userId, &ce)
 	if ce.HasError() {
 		return
 	}
@@ -253,6 +255,9 @@ func (s OfferStoreService) RefillOfferStoreItem(userId string, offerId string, b
 	if ce.HasError() {
 		return
 	}
+	if s.checkOfferStoreItemTier(*profile, item, &ce); ce.HasError() {
+		return
+	}
 
 	_, err := s.dao.UpdateRefillOfferStoreItem(offer, item)
 	if ce.SetError(api_error.UpdateDataFailed, err) {
@@ -264,7 +269,7 @@ func (s OfferStoreService) RefillOfferStoreItem(userId string, offerId string, b
 		return
 	}
 	// Only sync to solr and notification firebase
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, item))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, item))
 	dao.OfferStoreDaoInst.UpdateNotificationOfferStoreItem(offer, item)
 	offer.ItemSnapshots[item.Currency] = item
 
@@ -379,7 +384,7 @@ func (s OfferStoreService) RemoveOfferStoreItem(userId string, offerId string, c
 		item.Status = bean.OFFER_STORE_ITEM_STATUS_CLOSED
 		offer.ItemSnapshots[item.Currency] = item
 
-		err := s.dao.RemoveOfferStoreItem(offer, item, *profile)
+		err := s.dao.RemoveOfferStoreItem(offer, item, *profile, true)
 		if ce.SetError(api_error.DeleteDataFailed, err) {
 			return
 		}
@@ -446,7 +451,7 @@ func (s OfferStoreService) RemoveFailedOfferStoreItem(userId string, offerId str
 	item.Status = bean.OFFER_STORE_ITEM_STATUS_CLOSED
 	offer.ItemSnapshots[item.Currency] = *item
 
-	err := s.dao.RemoveOfferStoreItem(offer, *item, *profile)
+	err := s.dao.RemoveOfferStoreItem(offer, *item, *profile, false)
 	if ce.SetError(api_error.DeleteDataFailed, err) {
 		return
 	}
@@ -454,7 +459,7 @@ func (s OfferStoreService) RemoveFailedOfferStoreItem(userId string, offerId str
 	// Assign to correct flag
 	offer.ItemFlags[item.Currency] = item.Status != bean.OFFER_STORE_ITEM_STATUS_CLOSED
 	// Only sync to solr
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, *item))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, *item))
 
 	return
 }
@@ -489,7 +494,7 @@ func (s OfferStoreService) CancelRefillOfferStoreItem(userId string, offerId str
 	}
 
 	// Only sync to solr and notification firebase
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, *item))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, *item))
 	dao.OfferStoreDaoInst.UpdateNotificationOfferStoreItem(offer, *item)
 
 	return
@@ -515,12 +520,12 @@ func (s OfferStoreService) OpenCloseFailedOfferStore(userId, offerId string, cur
 		offer.Status = bean.OFFER_STORE_STATUS_ACTIVE
 	}
 	offer.ItemSnapshots[item.Currency] = item
-	err := s.dao.UpdateOfferStoreItemActive(offer, item)
+	err := s.dao.UpdateOfferStoreItemActive(offer, item, false)
 	if ce.SetError(api_error.UpdateDataFailed, err) {
 		return
 	}
 	// Only sync to solr
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, item))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, item))
 
 	return
 }
@@ -653,11 +658,17 @@ func (s OfferStoreService) CreateOfferStoreShake(userId string, offerId string,
 	offerShakeBody.Longitude = offer.Longitude
 	offerShakeBody.FreeStart = item.FreeStart
 
-	s.setupOfferShakePrice(&offerShakeBody, &ce)
+	quote := s.setupOfferShakePrice(&offerShakeBody, &ce)
 	s.setupOfferShakeAmount(&offerShakeBody, &ce)
 	if ce.HasError() {
 		return
 	}
+	if quote.Id != "" {
+		if ce.FeedContextErrorDefault(QuoteServiceInst.UseLockedQuote(quote.Id, quote.ExpiredAt)) {
+			return
+		}
+		defer QuoteServiceInst.ReleaseLockedQuoteOnError(quote.Id, &ce)
+	}
 
 	// Status of shake
 	if offerShakeBody.IsTypeSell() {
@@ -806,6 +817,7 @@ func (s OfferStoreService) CancelOfferStoreShake(userId string, offerId string,
 	}
 	if offerShake.Status != bean.OFFER_STORE_SHAKE_STATUS_PRE_SHAKE {
 		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
 	}
 
 	if offerShake.Currency == bean.ETH.Code {
@@ -880,6 +892,7 @@ func (s OfferStoreService) AcceptOfferStoreShake(userId string, offerId string,
 	}
 	if offerShake.Status != bean.OFFER_STORE_SHAKE_STATUS_PRE_SHAKE {
 		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
 	}
 
 	// Now accept always to SHAKE
@@ -933,6 +946,7 @@ func (s OfferStoreService) CompleteOfferStoreShake(userId string, offerId string
 
 	if offerShake.Status != bean.OFFER_STORE_SHAKE_STATUS_SHAKE {
 		ce.SetStatusKey(api_error.OfferStatusInvalid)
+		return
 	}
 
 	if offerShake.Currency == bean.ETH.Code {
@@ -1085,7 +1099,7 @@ func (s OfferStoreService) UpdateOnChainInitOfferStore(offerId string, hid int64
 		item.SubStatus = bean.OFFER_STORE_ITEM_STATUS_REFILLED
 	}
 	offer.ItemSnapshots[item.Currency] = item
-	err := s.dao.UpdateOfferStoreItemActive(offer, item)
+	err := s.dao.UpdateOfferStoreItemActive(offer, item, true)
 	if ce.SetError(api_error.UpdateDataFailed, err) {
 		return
 	}
@@ -1128,7 +1142,7 @@ func (s OfferStoreService) UpdateOnChainRefillBalanceOfferStore(offerId string,
 		return
 	}
 	// Only sync to solr and notification firebase
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, item))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, item))
 	dao.OfferStoreDaoInst.UpdateNotificationOfferStoreItem(offer, item)
 
 	return
@@ -1505,39 +1519,8 @@ func (s OfferStoreService) UpdateOfferStoreShakeLocation(userId string, offerId
 }
 
 func (s OfferStoreService) GetQuote(quoteType string, amountStr string, currency string, fiatCurrency string) (price decimal.Decimal, fiatPrice decimal.Decimal,
-	fiatAmount decimal.Decimal, err error) {
-	amount, numberErr := decimal.NewFromString(amountStr)
-	to := dao.MiscDaoInst.GetCurrencyRateFromCache(bean.USD.Code, fiatCurrency)
-	if numberErr != nil {
-		err = numberErr
-	}
-	rate := to.Object.(bean.CurrencyRate)
-	rateNumber := decimal.NewFromFloat(rate.Rate)
-	tmpAmount := amount.Mul(rateNumber)
-
-	if quoteType == "buy" {
-		resp, errResp := coinbase_service.GetBuyPrice(currency)
-		err = errResp
-		if err != nil {
-			return
-		}
-		price := common.StringToDecimal(resp.Amount)
-		fiatPrice = price.Mul(rateNumber)
-		fiatAmount = tmpAmount.Mul(price)
-	} else if quoteType == "sell" {
-		resp, errResp := coinbase_service.GetSellPrice(currency)
-		err = errResp
-		if err != nil {
-			return
-		}
-		price := common.StringToDecimal(resp.Amount)
-		fiatPrice = price.Mul(rateNumber)
-		fiatAmount = tmpAmount.Mul(price)
-	} else {
-		err = errors.New(api_error.InvalidQueryParam)
-	}
-
-	return
+	fiatAmount decimal.Decimal, ce SimpleContextError) {
+	return OfferServiceInst.GetQuote(quoteType, amountStr, currency, fiatCurrency)
 }
 
 func (s OfferStoreService) GetCurrentFreeStart(userId string, token string) (freeStart bean.OfferStoreFreeStart, ce SimpleContextError) {
@@ -1583,7 +1566,7 @@ func (s OfferStoreService) SyncOfferStoreToSolr(offerId string) (offer bean.Offe
 		return
 	}
 	offer = offerTO.Object.(bean.OfferStore)
-	solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, bean.OfferStoreItem{}))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStore(offer, bean.OfferStoreItem{}))
 
 	return
 }
@@ -1599,7 +1582,7 @@ func (s OfferStoreService) SyncOfferStoreShakeToSolr(offerId, offerShakeId strin
 		return
 	}
 	offerShake = offerShakeTO.Object.(bean.OfferStoreShake)
-	solr_service.UpdateObject(bean.NewSolrFromOfferStoreShake(offerShake, offer))
+	search.UpdateDocument(bean.NewSearchDocumentFromOfferStoreShake(offerShake, offer))
 
 	return
 }
@@ -1657,6 +1640,9 @@ func (s OfferStoreService) prepareOfferStore(offer *bean.OfferStore, item *bean.
 		ce.SetStatusKey(api_error.UnsupportedCurrency)
 		return
 	}
+	if GetFiatCurrency(s.miscDao, offer.FiatCurrency, ce); ce.HasError() {
+		return
+	}
 
 	if profile.ActiveOfferStores == nil {
 		profile.ActiveOfferStores = make(map[string]bool)
@@ -1675,6 +1661,9 @@ func (s OfferStoreService) prepareOfferStore(offer *bean.OfferStore, item *bean.
 	if ce.HasError() {
 		return
 	}
+	if s.checkOfferStoreItemTier(*profile, *item, ce); ce.HasError() {
+		return
+	}
 
 	s.generateSystemAddress(*offer, item, ce)
 
@@ -1722,6 +1711,14 @@ func (s OfferStoreService) prepareOfferStore(offer *bean.OfferStore, item *bean.
 	offer.ItemSnapshots[item.Currency] = *item
 }
 
+// Sell and buy sides are capped on their own, refills count towards the cap
+func (s OfferStoreService) checkOfferStoreItemTier(profile bean.Profile, item bean.OfferStoreItem, ce *SimpleContextError) {
+	if KYCServiceInst.CheckOfferStoreAmount(profile, item.Currency, common.StringToDecimal(item.SellAmount), ce); ce.HasError() {
+		return
+	}
+	KYCServiceInst.CheckOfferStoreAmount(profile, item.Currency, common.StringToDecimal(item.BuyAmount), ce)
+}
+
 func (s OfferStoreService) checkOfferStoreItemAmount(item *bean.OfferStoreItem, ce *SimpleContextError) {
 	// Minimum amount
 	sellAmount, errFmt := decimal.NewFromString(item.SellAmount)
@@ -1874,28 +1871,31 @@ func (s OfferStoreService) transferCrypto(offer *bean.OfferStore, offerShake *be
 			//Transfer
 			description := fmt.Sprintf("Transfer to userId %s offerShakeId %s status %s", actionUID, offerShake.Id, offerShake.Status)
 
-			var response1 interface{}
 			// var response2 interface{}
 			var userId string
 			transferAmount := offerShake.Amount
 			if offerShake.Type == bean.OFFER_TYPE_BUY {
-				response1 = s.sendTransaction(offerStoreItem.UserAddress, offerShake.TotalAmount, offerShake.Currency, description, offerShake.Id, offerStoreItem.WalletProvider, ce)
 				userId = offer.UID
 				transferAmount = offerShake.TotalAmount
 			} else {
-				response1 = s.sendTransaction(offerShake.UserAddress, offerShake.Amount, offerShake.Currency, description, offerShake.Id, offerStoreItem.WalletProvider, ce)
 				userId = offerShake.UID
 			}
-			s.miscDao.AddCryptoTransferLog(bean.CryptoTransferLog{
-				Provider:         offerStoreItem.WalletProvider,
-				ProviderResponse: response1,
-				DataType:         bean.OFFER_ADDRESS_MAP_OFFER_STORE_SHAKE,
-				DataRef:          dao.GetOfferStoreShakeItemPath(offer.Id, offerShake.Id),
-				UID:              userId,
-				Description:      description,
-				Amount:           transferAmount,
-				Currency:         offerShake.Currency,
-			})
+			// Only BTC is paid out from our wallets, it is sent later in a batch and the transfer log is written then
+			if offerShake.Currency == bean.BTC.Code {
+				_, payoutCE := PayoutServiceInst.AddPayout(bean.CryptoPayout{
+					WalletProvider: offerStoreItem.WalletProvider,
+					DataType:       bean.OFFER_ADDRESS_MAP_OFFER_STORE_SHAKE,
+					DataRef:        dao.GetOfferStoreShakeItemPath(offer.Id, offerShake.Id),
+					UID:            userId,
+					Address:        userAddress,
+					Description:    description,
+					Amount:         transferAmount,
+					Currency:       offerShake.Currency,
+				})
+				if ce.FeedContextError(api_error.AddDataFailed, payoutCE) {
+					return
+				}
+			}
 
 			// Transfer reward
 			//if offerStoreItem.RewardAddress != "" {
@@ -2047,18 +2047,34 @@ func (s OfferStoreService) updateFailedTransCount(offer bean.OfferStore, offerSh
 	return
 }
 
-func (s OfferStoreService) setupOfferShakePrice(offer *bean.OfferStoreShake, ce *SimpleContextError) {
+// Returns the matched quote, the caller uses it up once the shake is ready to be written
+func (s OfferStoreService) setupOfferShakePrice(offer *bean.OfferStoreShake, ce *SimpleContextError) (quote bean.LockedQuote) {
 	userOfferType := bean.OFFER_TYPE_SELL
 	if offer.IsTypeSell() {
 		userOfferType = bean.OFFER_TYPE_BUY
 	}
-	_, fiatPrice, fiatAmount, err := s.GetQuote(userOfferType, offer.Amount, offer.Currency, offer.FiatCurrency)
-	if ce.SetError(api_error.GetDataFailed, err) {
-		return
+	var fiatPrice, fiatAmount decimal.Decimal
+	if offer.QuoteId != "" {
+		// Honor the locked price until the quote expires
+		var quoteCE SimpleContextError
+		quote, quoteCE = QuoteServiceInst.GetMatchedLockedQuote(offer.UID, offer.QuoteId, userOfferType, offer.Amount, offer.Currency, offer.FiatCurrency)
+		if ce.FeedContextErrorDefault(quoteCE) {
+			return
+		}
+		fiatPrice = common.StringToDecimal(quote.Price)
+		fiatAmount = common.StringToDecimal(quote.FiatAmount)
+	} else {
+		var quoteCE SimpleContextError
+		_, fiatPrice, fiatAmount, quoteCE = s.GetQuote(userOfferType, offer.Amount, offer.Currency, offer.FiatCurrency)
+		if ce.FeedContextErrorDefault(quoteCE) {
+			return
+		}
 	}
 
-	offer.Price = fiatPrice.Round(2).String()
-	offer.FiatAmount = fiatAmount.Round(2).String()
+	offer.Price = RoundFiatAmount(offer.FiatCurrency, fiatPrice).String()
+	offer.FiatAmount = RoundFiatAmount(offer.FiatCurrency, fiatAmount).String()
+
+	return
 }
 
 func (s OfferStoreService) setupOfferShakeAmount(offerShake *bean.OfferStoreShake, ce *SimpleContextError) {
@@ -2273,16 +2289,3 @@ func (s OfferStoreService) ScriptUpdateTransactionCount() error {
 
 	return t.Error
 }
-
-func (s OfferStoreService) ScriptUpdateOfferStoreSolr() error {
-	t := s.dao.ListOfferStore()
-	if !t.HasError() {
-		for _, item := range t.Objects {
-			offer := item.(bean.OfferStore)
-			fmt.Printf("Updating store %s", offer.UID)
-			fmt.Println("")
-			solr_service.UpdateObject(bean.NewSolrFromOfferStore(offer, bean.OfferStoreItem{}))
-		}
-	}
-	return t.Error
-}
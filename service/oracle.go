@@ -0,0 +1,242 @@
+package service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/integration/coinapi_service"
+	"github.com/ninjadotorg/handshake-exchange/integration/coinbase_service"
+	"github.com/shopspring/decimal"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type PriceOracleService struct {
+	miscDao *dao.MiscDao
+}
+
+func (s PriceOracleService) GetPrice(currency string, priceType string) (price bean.OraclePrice, ce SimpleContextError) {
+	if priceType != bean.OFFER_TYPE_BUY && priceType != bean.OFFER_TYPE_SELL {
+		ce.SetStatusKey(api_error.InvalidQueryParam)
+		return
+	}
+
+	sources := s.getSources(currency, priceType)
+	if len(sources) == 0 {
+		ce.SetError(api_error.ExternalApiFailed, errors.New("no price source available"))
+		return
+	}
+
+	method := bean.PRICE_ORACLE_METHOD_MEDIAN
+	methodTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_PRICE_ORACLE_METHOD)
+	if !methodTO.HasError() {
+		method = methodTO.Object.(bean.SystemConfig).Value
+	}
+	maxDeviation, _ := decimal.NewFromString(bean.PRICE_ORACLE_MAX_DEVIATION_DEFAULT)
+	deviationTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_PRICE_ORACLE_MAX_DEVIATION)
+	if !deviationTO.HasError() {
+		if value, err := decimal.NewFromString(deviationTO.Object.(bean.SystemConfig).Value); err == nil {
+			maxDeviation = value
+		}
+	}
+	maxDeviationFloat, _ := maxDeviation.Float64()
+
+	price = AggregateOraclePrice(sources, method, maxDeviationFloat)
+	price.Currency = currency
+	price.Type = priceType
+	price.UpdatedAt = time.Now().UTC().Unix()
+
+	// Keep the latest result so we know which sources contributed
+	s.miscDao.UpdateOraclePrice(price)
+
+	return
+}
+
+func (s PriceOracleService) GetCachedPrice(currency string, priceType string) (price bean.OraclePrice, ce SimpleContextError) {
+	priceTO := s.miscDao.GetOraclePriceFromCache(currency, priceType)
+	if ce.SetError(api_error.GetDataFailed, priceTO.Error) {
+		return
+	}
+	if !priceTO.Found {
+		ce.SetStatusKey(api_error.PriceIsStale)
+		return
+	}
+	price = priceTO.Object.(bean.OraclePrice)
+
+	maxAge := bean.PRICE_MAX_AGE_DEFAULT
+	maxAgeTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_PRICE_MAX_AGE)
+	if !maxAgeTO.HasError() {
+		if value, err := strconv.Atoi(maxAgeTO.Object.(bean.SystemConfig).Value); err == nil && value > 0 {
+			maxAge = value
+		}
+	}
+	if time.Now().UTC().Unix()-price.UpdatedAt > int64(maxAge) {
+		ce.SetStatusKey(api_error.PriceIsStale)
+	}
+
+	return
+}
+
+func (s PriceOracleService) GetCryptoRate(currency string) (rate bean.CryptoRate, ce SimpleContextError) {
+	buyPrice, buyCE := s.GetPrice(currency, bean.OFFER_TYPE_BUY)
+	if ce.FeedContextError(api_error.ExternalApiFailed, buyCE) {
+		return
+	}
+	sellPrice, sellCE := s.GetPrice(currency, bean.OFFER_TYPE_SELL)
+	if ce.FeedContextError(api_error.ExternalApiFailed, sellCE) {
+		return
+	}
+
+	rate = bean.CryptoRate{
+		From:     currency,
+		To:       bean.USD.Code,
+		Buy:      buyPrice.Price,
+		Sell:     sellPrice.Price,
+		Exchange: bean.PRICE_ORACLE_EXCHANGE,
+	}
+	for _, source := range buyPrice.Sources {
+		rate.Sources = append(rate.Sources, source.Exchange)
+	}
+
+	return
+}
+
+// One currency failing does not hold back the others, it keeps its last rate until the next run
+func (s PriceOracleService) UpdateCryptoRates() (rates []bean.CryptoRate, ce SimpleContextError) {
+	rates = make([]bean.CryptoRate, 0)
+	var lastErr error
+	for _, currency := range []string{bean.BTC.Code, bean.ETH.Code, bean.LTC.Code, bean.BCH.Code} {
+		rate, rateCE := s.GetCryptoRate(currency)
+		if rateCE.HasError() {
+			lastErr = rateCE.CheckError()
+			if lastErr == nil {
+				lastErr = errors.New(rateCE.GetStatusKey())
+			}
+			log.Println("Update crypto rate failed", currency, lastErr)
+			continue
+		}
+		err := s.miscDao.UpdateCryptoRates(map[string][]bean.CryptoRate{currency: {rate}})
+		if err != nil {
+			lastErr = err
+			log.Println("Update crypto rate failed", currency, err)
+			continue
+		}
+		rates = append(rates, rate)
+	}
+	if len(rates) == 0 {
+		ce.SetError(api_error.ExternalApiFailed, lastErr)
+	}
+
+	return
+}
+
+func (s PriceOracleService) getSources(currency string, priceType string) []bean.OraclePriceSource {
+	sources := make([]bean.OraclePriceSource, 0)
+
+	var coinbaseResp bean.CoinbaseAmount
+	var err error
+	if priceType == bean.OFFER_TYPE_BUY {
+		coinbaseResp, err = coinbase_service.GetBuyPrice(currency)
+	} else {
+		coinbaseResp, err = coinbase_service.GetSellPrice(currency)
+	}
+	if err == nil {
+		if value, numberErr := decimal.NewFromString(coinbaseResp.Amount); numberErr == nil {
+			valueFloat, _ := value.Float64()
+			sources = append(sources, bean.OraclePriceSource{
+				Exchange: bean.PRICE_ORACLE_SOURCE_COINBASE,
+				Price:    valueFloat,
+			})
+		}
+	}
+
+	rates, err := coinapi_service.GetCurrencyExchangeRate(currency)
+	if err == nil {
+		for _, rate := range rates {
+			source := bean.OraclePriceSource{
+				Exchange: rate.Exchange,
+				Price:    rate.Buy,
+				Volume:   rate.BuyVolume,
+			}
+			if priceType == bean.OFFER_TYPE_SELL {
+				source.Price = rate.Sell
+				source.Volume = rate.SellVolume
+			}
+			if source.Price > 0 {
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	return sources
+}
+
+func AggregateOraclePrice(sources []bean.OraclePriceSource, method string, maxDeviation float64) (price bean.OraclePrice) {
+	price.Method = method
+	price.Sources = make([]bean.OraclePriceSource, 0)
+	price.Rejected = make([]bean.OraclePriceSource, 0)
+	if len(sources) == 0 {
+		return
+	}
+
+	// Reject anything too far from the median of all sources
+	median := medianOraclePrice(sources)
+	for _, source := range sources {
+		if median > 0 && math.Abs(source.Price-median)/median > maxDeviation {
+			price.Rejected = append(price.Rejected, source)
+		} else {
+			price.Sources = append(price.Sources, source)
+		}
+	}
+	// Sources disagree too much to tell which one is off, keep them all
+	if len(price.Sources) == 0 {
+		price.Sources = sources
+		price.Rejected = make([]bean.OraclePriceSource, 0)
+	}
+
+	if method == bean.PRICE_ORACLE_METHOD_VWAP {
+		totalVolume := 0.0
+		total := 0.0
+		weighted := make([]bean.OraclePriceSource, 0)
+		for _, source := range price.Sources {
+			if source.Volume <= 0 {
+				continue
+			}
+			totalVolume += source.Volume
+			total += source.Price * source.Volume
+			weighted = append(weighted, source)
+		}
+		if totalVolume > 0 {
+			// Sources without volume carry no weight, so they are not listed as contributing
+			price.Sources = weighted
+			price.Price = total / totalVolume
+			return
+		}
+	}
+	// Median, also used when no source reports volume
+	price.Method = bean.PRICE_ORACLE_METHOD_MEDIAN
+	price.Price = medianOraclePrice(price.Sources)
+
+	return
+}
+
+func medianOraclePrice(sources []bean.OraclePriceSource) float64 {
+	if len(sources) == 0 {
+		return 0
+	}
+	prices := make([]float64, len(sources))
+	for i, source := range sources {
+		prices[i] = source.Price
+	}
+	sort.Float64s(prices)
+
+	middle := len(prices) / 2
+	if len(prices)%2 == 0 {
+		return (prices[middle-1] + prices[middle]) / 2
+	}
+	return prices[middle]
+}
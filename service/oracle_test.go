@@ -0,0 +1,78 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAggregateOraclePriceMedian(t *testing.T) {
+	price := AggregateOraclePrice([]bean.OraclePriceSource{
+		{Exchange: "A", Price: 100},
+		{Exchange: "B", Price: 102},
+		{Exchange: "C", Price: 101},
+	}, bean.PRICE_ORACLE_METHOD_MEDIAN, 0.05)
+	assert.Equal(t, bean.PRICE_ORACLE_METHOD_MEDIAN, price.Method)
+	assert.Equal(t, float64(101), price.Price)
+	assert.Equal(t, 3, len(price.Sources))
+
+	price = AggregateOraclePrice([]bean.OraclePriceSource{
+		{Exchange: "A", Price: 100},
+		{Exchange: "B", Price: 102},
+	}, bean.PRICE_ORACLE_METHOD_MEDIAN, 0.05)
+	assert.Equal(t, float64(101), price.Price)
+}
+
+func TestAggregateOraclePriceRejectsOutlier(t *testing.T) {
+	price := AggregateOraclePrice([]bean.OraclePriceSource{
+		{Exchange: "A", Price: 100},
+		{Exchange: "B", Price: 101},
+		{Exchange: "C", Price: 102},
+		{Exchange: "D", Price: 150},
+	}, bean.PRICE_ORACLE_METHOD_MEDIAN, 0.05)
+	assert.Equal(t, float64(101), price.Price)
+	assert.Equal(t, 3, len(price.Sources))
+	assert.Equal(t, 1, len(price.Rejected))
+	assert.Equal(t, "D", price.Rejected[0].Exchange)
+}
+
+func TestAggregateOraclePriceKeepsAllWhenNoneAgree(t *testing.T) {
+	price := AggregateOraclePrice([]bean.OraclePriceSource{
+		{Exchange: "A", Price: 100},
+		{Exchange: "B", Price: 200},
+	}, bean.PRICE_ORACLE_METHOD_MEDIAN, 0.05)
+	assert.Equal(t, float64(150), price.Price)
+	assert.Equal(t, 2, len(price.Sources))
+	assert.Equal(t, 0, len(price.Rejected))
+}
+
+func TestAggregateOraclePriceVWAP(t *testing.T) {
+	price := AggregateOraclePrice([]bean.OraclePriceSource{
+		{Exchange: bean.PRICE_ORACLE_SOURCE_COINBASE, Price: 104},
+		{Exchange: "A", Price: 100, Volume: 3},
+		{Exchange: "B", Price: 102, Volume: 1},
+	}, bean.PRICE_ORACLE_METHOD_VWAP, 0.05)
+	assert.Equal(t, bean.PRICE_ORACLE_METHOD_VWAP, price.Method)
+	assert.Equal(t, 100.5, price.Price)
+	// No volume, no weight, not listed
+	assert.Equal(t, 2, len(price.Sources))
+	for _, source := range price.Sources {
+		assert.NotEqual(t, bean.PRICE_ORACLE_SOURCE_COINBASE, source.Exchange)
+	}
+}
+
+func TestAggregateOraclePriceVWAPWithoutVolumeFallsBackToMedian(t *testing.T) {
+	price := AggregateOraclePrice([]bean.OraclePriceSource{
+		{Exchange: "A", Price: 100},
+		{Exchange: "B", Price: 102},
+	}, bean.PRICE_ORACLE_METHOD_VWAP, 0.05)
+	assert.Equal(t, bean.PRICE_ORACLE_METHOD_MEDIAN, price.Method)
+	assert.Equal(t, float64(101), price.Price)
+	assert.Equal(t, 2, len(price.Sources))
+}
+
+func TestAggregateOraclePriceNoSources(t *testing.T) {
+	price := AggregateOraclePrice(nil, bean.PRICE_ORACLE_METHOD_MEDIAN, 0.05)
+	assert.Equal(t, float64(0), price.Price)
+	assert.Equal(t, 0, len(price.Sources))
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+type OrderBookService struct {
+	dao     *dao.OrderBookDao
+	miscDao *dao.MiscDao
+}
+
+func (s OrderBookService) GetOrderBook(currency string, fiatCurrency string) (orderBook bean.OrderBook, ce SimpleContextError) {
+	if bean.CurrencyMapping[currency].Code == "" {
+		ce.SetStatusKey(api_error.UnsupportedCurrency)
+		return
+	}
+	fiatCurrencyInst := GetFiatCurrency(s.miscDao, fiatCurrency, &ce)
+	if ce.HasError() {
+		return
+	}
+
+	// Takers buy from sell entries, so they pay the buy price
+	_, askPrice, _, quoteCE := OfferServiceInst.GetQuote(bean.OFFER_TYPE_BUY, "1", currency, fiatCurrency)
+	if ce.FeedContextErrorDefault(quoteCE) {
+		return
+	}
+	_, bidPrice, _, quoteCE := OfferServiceInst.GetQuote(bean.OFFER_TYPE_SELL, "1", currency, fiatCurrency)
+	if ce.FeedContextErrorDefault(quoteCE) {
+		return
+	}
+
+	entries, err := s.dao.ListEntries(currency)
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+
+	sellEntries := make([]bean.OrderBookEntry, 0)
+	buyEntries := make([]bean.OrderBookEntry, 0)
+	for _, entry := range entries {
+		if entry.FiatCurrency != fiatCurrency {
+			continue
+		}
+		if entry.Type == bean.OFFER_TYPE_SELL {
+			sellEntries = append(sellEntries, entry)
+		} else {
+			buyEntries = append(buyEntries, entry)
+		}
+	}
+
+	orderBook.Currency = currency
+	orderBook.FiatCurrency = fiatCurrency
+	orderBook.Sells = buildOrderBookLevels(sellEntries, askPrice, fiatCurrencyInst, true)
+	orderBook.Buys = buildOrderBookLevels(buyEntries, bidPrice, fiatCurrencyInst, false)
+	orderBook.UpdatedAt = time.Now().UTC().Unix()
+
+	return
+}
+
+func (s OrderBookService) RebuildOrderBook() (count int, ce SimpleContextError) {
+	count, err := s.dao.RebuildEntries()
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
+
+func buildOrderBookLevels(entries []bean.OrderBookEntry, price decimal.Decimal, fiatCurrency bean.FiatCurrency, ascending bool) []bean.OrderBookLevel {
+	type levelStruct struct {
+		price  decimal.Decimal
+		size   decimal.Decimal
+		stores map[string]bool
+	}
+
+	levelMap := map[string]*levelStruct{}
+	for _, entry := range entries {
+		percentage := common.StringToDecimal(entry.Percentage)
+		levelPrice := fiatCurrency.Round(price.Add(price.Mul(percentage)))
+		key := levelPrice.String()
+		level, ok := levelMap[key]
+		if !ok {
+			level = &levelStruct{price: levelPrice, size: common.Zero, stores: map[string]bool{}}
+			levelMap[key] = level
+		}
+		level.size = level.size.Add(common.StringToDecimal(entry.Amount))
+		level.stores[entry.Source+"."+entry.SourceId] = true
+	}
+
+	levels := make([]*levelStruct, 0)
+	for _, level := range levelMap {
+		levels = append(levels, level)
+	}
+	// Best price first
+	sort.Slice(levels, func(i, j int) bool {
+		if ascending {
+			return levels[i].price.LessThan(levels[j].price)
+		}
+		return levels[i].price.GreaterThan(levels[j].price)
+	})
+
+	result := make([]bean.OrderBookLevel, 0)
+	for _, level := range levels {
+		result = append(result, bean.OrderBookLevel{
+			Price:      fiatCurrency.FormatAmount(level.price),
+			Size:       level.size.String(),
+			StoreCount: len(level.stores),
+		})
+	}
+
+	return result
+}
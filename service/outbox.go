@@ -0,0 +1,65 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/notification"
+	"strconv"
+	"time"
+)
+
+type OutboxService struct {
+	dao     *dao.OutboxDao
+	miscDao *dao.MiscDao
+}
+
+// Called by cron, re-sends events the inline notification did not acknowledge, so delivery is at least once
+func (s OutboxService) RelayOutboxEvents() (count int, ce SimpleContextError) {
+	delay := s.getConfigNumber(bean.CONFIG_OUTBOX_RELAY_DELAY, bean.OUTBOX_RELAY_DELAY_DEFAULT)
+	// Leave the inline path time to acknowledge
+	createdBefore := time.Now().UTC().Add(-time.Duration(delay) * time.Second)
+
+	events, err := s.dao.ListPendingOutboxEvents(createdBefore, bean.OUTBOX_RELAY_LIMIT)
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+
+	for _, event := range events {
+		err := notification.RelayOutboxEvent(event)
+		if err == nil {
+			// The relay acknowledges its own event, the inline path may never come back for it
+			err = s.dao.RemoveOutboxEvent(event.Id)
+		}
+		if err == nil {
+			count += 1
+			continue
+		}
+
+		event.Attempts += 1
+		event.LastError = err.Error()
+		if event.Attempts >= bean.OUTBOX_EVENT_MAX_ATTEMPTS {
+			event.Status = bean.OUTBOX_EVENT_STATUS_FAILED
+		} else {
+			event.Status = bean.OUTBOX_EVENT_STATUS_PENDING
+		}
+		if ce.SetError(api_error.UpdateDataFailed, s.dao.UpdateOutboxEventAttempt(event)) {
+			return
+		}
+	}
+
+	return
+}
+
+func (s OutboxService) getConfigNumber(key string, defaultValue int) int {
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(key)
+	if systemConfigTO.HasError() {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(systemConfigTO.Object.(bean.SystemConfig).Value)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/checkout_service"
+	"github.com/shopspring/decimal"
+)
+
+type CheckoutGateway struct {
+}
+
+func (g CheckoutGateway) Name() string {
+	return bean.CC_PROVIDER_CHECKOUT
+}
+
+func (g CheckoutGateway) Tokenize(card bean.CreditCardInfo) (string, error) {
+	response, err := checkout_service.CreateCardToken(card.CCNum, card.ExpirationDate, card.CVV)
+	return response.Id, err
+}
+
+func (g CheckoutGateway) Authorize(request ChargeRequest) (result ChargeResult, err error) {
+	var response bean.CheckoutCardPaymentResponse
+	if request.CardId != "" {
+		response, err = checkout_service.ChargeCardId(request.UID, request.CardId, request.Amount, request.Statement, request.Description)
+	} else {
+		response, err = checkout_service.ChargeCardToken(request.UID, request.CardToken, request.Amount, request.Statement, request.Description)
+	}
+	if err != nil {
+		return
+	}
+	result = checkoutChargeResult(response)
+
+	return
+}
+
+func (g CheckoutGateway) VerifyAuthentication(paymentToken string) (result ChargeResult, err error) {
+	response, err := checkout_service.VerifyCharge(paymentToken)
+	if err != nil {
+		return
+	}
+	result = checkoutChargeResult(response)
+
+	return
+}
+
+func (g CheckoutGateway) Capture(chargeId string) (interface{}, error) {
+	return checkout_service.Capture(chargeId)
+}
+
+func (g CheckoutGateway) Void(chargeId string) (interface{}, error) {
+	response, err := checkout_service.Void(chargeId)
+	if err == nil && response.Status != bean.CHECKOUT_STATUS_VOIDED {
+		err = fmt.Errorf("checkout void %s is %s", chargeId, response.Status)
+	}
+	return response, err
+}
+
+func (g CheckoutGateway) Refund(chargeId string, amount decimal.Decimal) (interface{}, error) {
+	return checkout_service.RefundAmount(chargeId, amount)
+}
+
+func checkoutChargeResult(response bean.CheckoutCardPaymentResponse) (result ChargeResult) {
+	result.Id = response.Id
+	result.ProviderData = response
+	if response.Status == bean.CHECKOUT_STATUS_AUTHORISED {
+		result.Status = CHARGE_STATUS_AUTHORISED
+		result.Card.Id = response.Card.Id
+		result.Card.Last4 = response.Card.Last4
+		if len(response.Card.ExpiryYear) == 4 {
+			result.Card.ExpirationDate = fmt.Sprintf("%s/%s", response.Card.ExpiryMonth, response.Card.ExpiryYear[2:])
+		}
+	} else if response.RequiresAuthentication() {
+		result.Status = CHARGE_STATUS_PENDING_AUTHENTICATION
+		result.RedirectUrl = response.RedirectUrl
+	} else {
+		result.Status = CHARGE_STATUS_DECLINED
+	}
+
+	return
+}
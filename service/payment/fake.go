@@ -0,0 +1,171 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/shopspring/decimal"
+	"sync"
+)
+
+// Tokens the fake gateway treats specially, anything else is authorised straight away
+const FAKE_CARD_TOKEN_DECLINED = "fake_tok_declined"
+const FAKE_CARD_TOKEN_3DS = "fake_tok_3ds"
+
+const FAKE_CHARGE_STATUS_AUTHORISED = "authorised"
+const FAKE_CHARGE_STATUS_PENDING_AUTHENTICATION = "pending_authentication"
+const FAKE_CHARGE_STATUS_CAPTURED = "captured"
+const FAKE_CHARGE_STATUS_VOIDED = "voided"
+const FAKE_CHARGE_STATUS_REFUNDED = "refunded"
+
+type FakeCharge struct {
+	Id       string
+	UID      string
+	CardId   string
+	Amount   decimal.Decimal
+	Refunded decimal.Decimal
+	Status   string
+}
+
+// Registered with PAYMENT_FAKE_GATEWAY, charges only live in this process
+type FakeGateway struct {
+	mutex   *sync.Mutex
+	charges *[]FakeCharge
+}
+
+func NewFakeGateway() FakeGateway {
+	charges := make([]FakeCharge, 0)
+	return FakeGateway{
+		mutex:   &sync.Mutex{},
+		charges: &charges,
+	}
+}
+
+func (g FakeGateway) Name() string {
+	return bean.CC_PROVIDER_FAKE
+}
+
+func (g FakeGateway) Tokenize(card bean.CreditCardInfo) (string, error) {
+	if len(card.CCNum) < 4 {
+		return "", errors.New("card number is too short")
+	}
+	return fmt.Sprintf("fake_tok_%s", card.CCNum[len(card.CCNum)-4:]), nil
+}
+
+func (g FakeGateway) Authorize(request ChargeRequest) (result ChargeResult, err error) {
+	if request.CardToken == "" && request.CardId == "" {
+		err = errors.New("card is missing")
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	charge := FakeCharge{
+		Id:     fmt.Sprintf("fake_charge_%d", len(*g.charges)+1),
+		UID:    request.UID,
+		CardId: request.CardId,
+		Amount: request.Amount,
+		Status: FAKE_CHARGE_STATUS_AUTHORISED,
+	}
+	if charge.CardId == "" {
+		charge.CardId = fmt.Sprintf("fake_card_%d", len(*g.charges)+1)
+	}
+	result.Id = charge.Id
+
+	switch request.CardToken {
+	case FAKE_CARD_TOKEN_DECLINED:
+		result.Status = CHARGE_STATUS_DECLINED
+		return
+	case FAKE_CARD_TOKEN_3DS:
+		charge.Status = FAKE_CHARGE_STATUS_PENDING_AUTHENTICATION
+		result.Status = CHARGE_STATUS_PENDING_AUTHENTICATION
+		result.RedirectUrl = fmt.Sprintf("https://fake.gateway/3ds/%s", charge.Id)
+	default:
+		result.Status = CHARGE_STATUS_AUTHORISED
+		result.Card = fakeChargeCard(charge)
+	}
+	*g.charges = append(*g.charges, charge)
+	result.ProviderData = charge
+
+	return
+}
+
+func (g FakeGateway) VerifyAuthentication(paymentToken string) (result ChargeResult, err error) {
+	charge, err := g.transition(paymentToken, FAKE_CHARGE_STATUS_PENDING_AUTHENTICATION, FAKE_CHARGE_STATUS_AUTHORISED)
+	if err != nil {
+		return
+	}
+	result.Id = charge.Id
+	result.Status = CHARGE_STATUS_AUTHORISED
+	result.Card = fakeChargeCard(charge)
+	result.ProviderData = charge
+
+	return
+}
+
+func (g FakeGateway) Capture(chargeId string) (interface{}, error) {
+	return g.transition(chargeId, FAKE_CHARGE_STATUS_AUTHORISED, FAKE_CHARGE_STATUS_CAPTURED)
+}
+
+func (g FakeGateway) Void(chargeId string) (interface{}, error) {
+	return g.transition(chargeId, FAKE_CHARGE_STATUS_AUTHORISED, FAKE_CHARGE_STATUS_VOIDED)
+}
+
+// Partial refunds add up, the charge is refunded once all of it has been given back
+func (g FakeGateway) Refund(chargeId string, amount decimal.Decimal) (interface{}, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for i, charge := range *g.charges {
+		if charge.Id != chargeId {
+			continue
+		}
+		if charge.Status != FAKE_CHARGE_STATUS_CAPTURED {
+			return charge, fmt.Errorf("charge %s is %s, not %s", chargeId, charge.Status, FAKE_CHARGE_STATUS_CAPTURED)
+		}
+		refunded := charge.Refunded.Add(amount)
+		if refunded.GreaterThan(charge.Amount) {
+			return charge, fmt.Errorf("refund of %s is more than left on charge %s", amount.String(), chargeId)
+		}
+		(*g.charges)[i].Refunded = refunded
+		if refunded.Equal(charge.Amount) {
+			(*g.charges)[i].Status = FAKE_CHARGE_STATUS_REFUNDED
+		}
+		return (*g.charges)[i], nil
+	}
+	return FakeCharge{}, fmt.Errorf("charge %s not found", chargeId)
+}
+
+func (g FakeGateway) Charges() []FakeCharge {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	charges := make([]FakeCharge, len(*g.charges))
+	copy(charges, *g.charges)
+	return charges
+}
+
+func (g FakeGateway) transition(chargeId string, from string, to string) (FakeCharge, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for i, charge := range *g.charges {
+		if charge.Id != chargeId {
+			continue
+		}
+		if charge.Status != from {
+			return charge, fmt.Errorf("charge %s is %s, not %s", chargeId, charge.Status, from)
+		}
+		(*g.charges)[i].Status = to
+		return (*g.charges)[i], nil
+	}
+	return FakeCharge{}, fmt.Errorf("charge %s not found", chargeId)
+}
+
+func fakeChargeCard(charge FakeCharge) ChargeCard {
+	return ChargeCard{
+		Id:             charge.CardId,
+		Last4:          "4242",
+		ExpirationDate: "12/30",
+	}
+}
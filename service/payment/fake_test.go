@@ -0,0 +1,89 @@
+package payment
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFakeGatewayAuthorizeAndCapture(t *testing.T) {
+	gateway := NewFakeGateway()
+
+	token, err := gateway.Tokenize(bean.CreditCardInfo{CCNum: "4242424242424242", ExpirationDate: "12/30", CVV: "123"})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_tok_4242", token)
+
+	result, err := gateway.Authorize(ChargeRequest{UID: "1", CardToken: token, Amount: decimal.NewFromFloat(100)})
+	assert.Nil(t, err)
+	assert.Equal(t, CHARGE_STATUS_AUTHORISED, result.Status)
+	assert.NotEmpty(t, result.Card.Id)
+
+	_, err = gateway.Capture(result.Id)
+	assert.Nil(t, err)
+	_, err = gateway.Void(result.Id)
+	assert.NotNil(t, err)
+	_, err = gateway.Refund(result.Id, decimal.NewFromFloat(100))
+	assert.Nil(t, err)
+
+	charges := gateway.Charges()
+	assert.Equal(t, 1, len(charges))
+	assert.Equal(t, FAKE_CHARGE_STATUS_REFUNDED, charges[0].Status)
+}
+
+func TestFakeGatewayPartialRefund(t *testing.T) {
+	gateway := NewFakeGateway()
+
+	result, err := gateway.Authorize(ChargeRequest{UID: "1", CardToken: "fake_tok_4242", Amount: decimal.NewFromFloat(100)})
+	assert.Nil(t, err)
+	_, err = gateway.Capture(result.Id)
+	assert.Nil(t, err)
+
+	_, err = gateway.Refund(result.Id, decimal.NewFromFloat(40))
+	assert.Nil(t, err)
+	assert.Equal(t, FAKE_CHARGE_STATUS_CAPTURED, gateway.Charges()[0].Status)
+
+	_, err = gateway.Refund(result.Id, decimal.NewFromFloat(70))
+	assert.NotNil(t, err)
+
+	_, err = gateway.Refund(result.Id, decimal.NewFromFloat(60))
+	assert.Nil(t, err)
+	assert.Equal(t, FAKE_CHARGE_STATUS_REFUNDED, gateway.Charges()[0].Status)
+}
+
+func TestFakeGatewayDeclined(t *testing.T) {
+	gateway := NewFakeGateway()
+
+	result, err := gateway.Authorize(ChargeRequest{UID: "1", CardToken: FAKE_CARD_TOKEN_DECLINED, Amount: decimal.NewFromFloat(100)})
+	assert.Nil(t, err)
+	assert.Equal(t, CHARGE_STATUS_DECLINED, result.Status)
+	assert.Equal(t, 0, len(gateway.Charges()))
+}
+
+func TestFakeGatewayAuthentication(t *testing.T) {
+	gateway := NewFakeGateway()
+
+	result, err := gateway.Authorize(ChargeRequest{UID: "1", CardToken: FAKE_CARD_TOKEN_3DS, Amount: decimal.NewFromFloat(100)})
+	assert.Nil(t, err)
+	assert.Equal(t, CHARGE_STATUS_PENDING_AUTHENTICATION, result.Status)
+	assert.NotEmpty(t, result.RedirectUrl)
+
+	// Nothing to capture until the card holder is back
+	_, err = gateway.Capture(result.Id)
+	assert.NotNil(t, err)
+
+	verified, err := gateway.VerifyAuthentication(result.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, CHARGE_STATUS_AUTHORISED, verified.Status)
+
+	_, err = gateway.Void(result.Id)
+	assert.Nil(t, err)
+}
+
+func TestGetGatewayFallsBackToCheckout(t *testing.T) {
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, GetGateway("").Name())
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, GetGateway(bean.CC_PROVIDER_STRIPE).Name())
+
+	RegisterGateway(NewFakeGateway())
+	assert.Equal(t, bean.CC_PROVIDER_FAKE, GetGateway(bean.CC_PROVIDER_FAKE).Name())
+}
@@ -0,0 +1,88 @@
+package payment
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/shopspring/decimal"
+	"sync"
+)
+
+const CHARGE_STATUS_AUTHORISED = "authorised"
+const CHARGE_STATUS_PENDING_AUTHENTICATION = "pending_authentication"
+const CHARGE_STATUS_DECLINED = "declined"
+
+// CardToken is a one time token from the client or Tokenize, CardId a card the provider keeps for the user
+type ChargeRequest struct {
+	UID         string
+	CardToken   string
+	CardId      string
+	Amount      decimal.Decimal
+	Currency    string
+	Statement   string
+	Description string
+}
+
+type ChargeCard struct {
+	Id             string
+	Last4          string
+	ExpirationDate string
+	Country        string
+}
+
+type ChargeResult struct {
+	Id           string
+	Status       string
+	RedirectUrl  string
+	Card         ChargeCard
+	ProviderData interface{}
+}
+
+// Charges are authorised first and captured once the crypto is bought, Void releases an uncaptured charge
+// and Refund gives back part or all of a captured one
+type Gateway interface {
+	Name() string
+	Tokenize(card bean.CreditCardInfo) (string, error)
+	Authorize(request ChargeRequest) (ChargeResult, error)
+	VerifyAuthentication(paymentToken string) (ChargeResult, error)
+	Capture(chargeId string) (interface{}, error)
+	Void(chargeId string) (interface{}, error)
+	Refund(chargeId string, amount decimal.Decimal) (interface{}, error)
+}
+
+var gatewayMutex = &sync.RWMutex{}
+var gateways = map[string]Gateway{
+	bean.CC_PROVIDER_CHECKOUT: CheckoutGateway{},
+	bean.CC_PROVIDER_STRIPE:   StripeGateway{},
+}
+
+// The fake gateway approves everything, it is only registered when asked for
+func InitializeGateways(fakeEnabled bool) {
+	if fakeEnabled {
+		RegisterGateway(NewFakeGateway())
+	}
+}
+
+func RegisterGateway(gateway Gateway) {
+	gatewayMutex.Lock()
+	defer gatewayMutex.Unlock()
+
+	gateways[gateway.Name()] = gateway
+}
+
+// Transactions from before the abstraction have no provider and all went through Checkout
+func GetGateway(name string) Gateway {
+	gatewayMutex.RLock()
+	defer gatewayMutex.RUnlock()
+
+	if gateway, ok := gateways[name]; ok {
+		return gateway
+	}
+	return gateways[bean.CC_PROVIDER_CHECKOUT]
+}
+
+func HasGateway(name string) bool {
+	gatewayMutex.RLock()
+	defer gatewayMutex.RUnlock()
+
+	_, ok := gateways[name]
+	return ok
+}
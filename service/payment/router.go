@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/shopspring/decimal"
+	"hash/fnv"
+	"strings"
+)
+
+// Picks the provider for a new card, saved cards stay with the provider that issued them
+func Route(routing bean.PaymentRouting, userId string, country string, amount decimal.Decimal) string {
+	for _, rule := range routing.Rules {
+		if ruleMatches(rule, userId, country, amount) {
+			return rule.Provider
+		}
+	}
+	if routing.Default != "" {
+		return routing.Default
+	}
+	return bean.CC_PROVIDER_CHECKOUT
+}
+
+func ruleMatches(rule bean.PaymentRoutingRule, userId string, country string, amount decimal.Decimal) bool {
+	if len(rule.Countries) > 0 {
+		found := false
+		for _, ruleCountry := range rule.Countries {
+			if strings.EqualFold(ruleCountry, country) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.MinAmount != "" {
+		minAmount, err := decimal.NewFromString(rule.MinAmount)
+		if err != nil || amount.LessThan(minAmount) {
+			return false
+		}
+	}
+	if rule.MaxAmount != "" {
+		maxAmount, err := decimal.NewFromString(rule.MaxAmount)
+		if err != nil || amount.GreaterThan(maxAmount) {
+			return false
+		}
+	}
+	if rule.Percentage > 0 && userBucket(userId) >= rule.Percentage {
+		return false
+	}
+
+	return true
+}
+
+// Same user lands in the same bucket, so a split does not bounce anyone between providers
+func userBucket(userId string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(userId))
+	return int64(h.Sum32() % 100)
+}
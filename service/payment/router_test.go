@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRouteDefaultsToCheckout(t *testing.T) {
+	provider := Route(bean.PaymentRouting{}, "user_1", "US", decimal.NewFromFloat(100))
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, provider)
+
+	provider = Route(bean.PaymentRouting{Default: bean.CC_PROVIDER_STRIPE}, "user_1", "US", decimal.NewFromFloat(100))
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, provider)
+}
+
+func TestRouteByCountry(t *testing.T) {
+	routing := bean.PaymentRouting{
+		Default: bean.CC_PROVIDER_CHECKOUT,
+		Rules: []bean.PaymentRoutingRule{
+			{Provider: bean.CC_PROVIDER_STRIPE, Countries: []string{"US", "CA"}},
+		},
+	}
+
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, Route(routing, "user_1", "us", decimal.NewFromFloat(100)))
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, Route(routing, "user_1", "VN", decimal.NewFromFloat(100)))
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, Route(routing, "user_1", "", decimal.NewFromFloat(100)))
+}
+
+func TestRouteByAmount(t *testing.T) {
+	routing := bean.PaymentRouting{
+		Default: bean.CC_PROVIDER_CHECKOUT,
+		Rules: []bean.PaymentRoutingRule{
+			{Provider: bean.CC_PROVIDER_STRIPE, MinAmount: "500", MaxAmount: "1000"},
+		},
+	}
+
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, Route(routing, "user_1", "US", decimal.NewFromFloat(499.99)))
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, Route(routing, "user_1", "US", decimal.NewFromFloat(500)))
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, Route(routing, "user_1", "US", decimal.NewFromFloat(1000)))
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, Route(routing, "user_1", "US", decimal.NewFromFloat(1000.01)))
+}
+
+func TestRouteByPercentage(t *testing.T) {
+	routing := bean.PaymentRouting{
+		Default: bean.CC_PROVIDER_CHECKOUT,
+		Rules: []bean.PaymentRoutingRule{
+			{Provider: bean.CC_PROVIDER_STRIPE, Percentage: 50},
+		},
+	}
+
+	// user_1 hashes to bucket 14 and user_2 to bucket 95
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, Route(routing, "user_1", "US", decimal.NewFromFloat(100)))
+	assert.Equal(t, bean.CC_PROVIDER_CHECKOUT, Route(routing, "user_2", "US", decimal.NewFromFloat(100)))
+	assert.Equal(t, Route(routing, "user_1", "US", decimal.NewFromFloat(100)), Route(routing, "user_1", "VN", decimal.NewFromFloat(5)))
+}
+
+func TestRouteFirstMatchWins(t *testing.T) {
+	routing := bean.PaymentRouting{
+		Default: bean.CC_PROVIDER_CHECKOUT,
+		Rules: []bean.PaymentRoutingRule{
+			{Provider: bean.CC_PROVIDER_FAKE, Countries: []string{"VN"}, MaxAmount: "100"},
+			{Provider: bean.CC_PROVIDER_STRIPE, Countries: []string{"VN"}},
+		},
+	}
+
+	assert.Equal(t, bean.CC_PROVIDER_FAKE, Route(routing, "user_1", "VN", decimal.NewFromFloat(50)))
+	assert.Equal(t, bean.CC_PROVIDER_STRIPE, Route(routing, "user_1", "VN", decimal.NewFromFloat(150)))
+}
@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/stripe_service"
+	"github.com/shopspring/decimal"
+)
+
+const stripeChargeStatusSucceeded = "succeeded"
+
+type StripeGateway struct {
+}
+
+func (g StripeGateway) Name() string {
+	return bean.CC_PROVIDER_STRIPE
+}
+
+func (g StripeGateway) Tokenize(card bean.CreditCardInfo) (string, error) {
+	return stripe_service.CreateToken(card.CCNum, card.ExpirationDate, card.CVV)
+}
+
+// Stripe tokens are single use, so new cards are attached to a customer and the customer is the card to keep
+func (g StripeGateway) Authorize(request ChargeRequest) (result ChargeResult, err error) {
+	customerId := request.CardId
+	if customerId == "" {
+		customerId, err = stripe_service.CreateCustomer(fmt.Sprintf("User %s", request.UID), request.CardToken)
+		if err != nil {
+			return
+		}
+	}
+	charge, err := stripe_service.Charge("", customerId, request.Amount, request.Statement, request.Description)
+	if err != nil {
+		return
+	}
+
+	result.Id = charge.ID
+	result.ProviderData = charge
+	result.Status = CHARGE_STATUS_DECLINED
+	if charge.Status == stripeChargeStatusSucceeded {
+		result.Status = CHARGE_STATUS_AUTHORISED
+		result.Card.Id = customerId
+		if charge.Source != nil && charge.Source.Card != nil {
+			result.Card.Last4 = charge.Source.Card.LastFour
+			result.Card.ExpirationDate = fmt.Sprintf("%02d/%02d", charge.Source.Card.Month, charge.Source.Card.Year%100)
+			result.Card.Country = charge.Source.Card.Country
+		}
+	}
+
+	return
+}
+
+func (g StripeGateway) VerifyAuthentication(paymentToken string) (ChargeResult, error) {
+	return ChargeResult{}, errors.New("stripe charges do not use 3-D Secure redirects")
+}
+
+func (g StripeGateway) Capture(chargeId string) (interface{}, error) {
+	return stripe_service.Capture(chargeId)
+}
+
+// Refunding an uncaptured charge releases the authorisation
+func (g StripeGateway) Void(chargeId string) (interface{}, error) {
+	return stripe_service.Refund(chargeId)
+}
+
+func (g StripeGateway) Refund(chargeId string, amount decimal.Decimal) (interface{}, error) {
+	return stripe_service.RefundAmount(chargeId, amount)
+}
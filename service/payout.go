@@ -0,0 +1,158 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/integration/blockchainio_service"
+	"github.com/ninjadotorg/handshake-exchange/integration/coinbase_service"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"time"
+)
+
+type PayoutService struct {
+	dao *dao.MiscDao
+}
+
+func (s PayoutService) AddPayout(payoutBody bean.CryptoPayout) (payout bean.CryptoPayout, ce SimpleContextError) {
+	payout, err := s.dao.AddCryptoPayout(payoutBody)
+	ce.SetError(api_error.AddDataFailed, err)
+
+	return
+}
+
+// A failed send may still have gone out, so a payout is only queued again by hand after checking the wallet
+func (s PayoutService) RetryPayout(payoutId string) (payout bean.CryptoPayout, ce SimpleContextError) {
+	payout, err := s.dao.RetryCryptoPayout(payoutId)
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
+
+func (s PayoutService) SendPayoutBatches(currency string) (batches []bean.CryptoPayoutBatch, ce SimpleContextError) {
+	window := s.getConfigNumber(bean.CONFIG_BTC_PAYOUT_BATCH_WINDOW, bean.BTC_PAYOUT_BATCH_WINDOW_DEFAULT)
+	size := s.getConfigNumber(bean.CONFIG_BTC_PAYOUT_BATCH_SIZE, bean.BTC_PAYOUT_BATCH_SIZE_DEFAULT)
+
+	payouts, err := s.dao.ListPendingCryptoPayouts(currency)
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+	if len(payouts) == 0 {
+		return
+	}
+	// Wait for the window to fill up, unless there are already enough payouts for a batch
+	if len(payouts) < size && time.Now().UTC().Sub(payouts[0].CreatedAt).Seconds() < float64(window) {
+		return
+	}
+	// Another run may have picked some of them up already
+	payouts, err = s.dao.ClaimCryptoPayouts(payouts)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+
+	providerPayouts := map[string][]bean.CryptoPayout{}
+	for _, payout := range payouts {
+		providerPayouts[payout.WalletProvider] = append(providerPayouts[payout.WalletProvider], payout)
+	}
+
+	for walletProvider, items := range providerPayouts {
+		for start := 0; start < len(items); start += size {
+			end := start + size
+			if end > len(items) {
+				end = len(items)
+			}
+			batchItems := items[start:end]
+
+			if walletProvider == bean.BTC_WALLET_BLOCKCHAINIO {
+				batch, batchCE := s.sendBlockChainIOBatch(currency, batchItems)
+				if batchCE.HasError() {
+					ce = batchCE
+					continue
+				}
+				batches = append(batches, batch)
+			} else if walletProvider == bean.BTC_WALLET_COINBASE {
+				// Coinbase has no multi-output send, keep one transaction per payout
+				for _, payout := range batchItems {
+					batch, batchCE := s.sendCoinbasePayout(payout)
+					if batchCE.HasError() {
+						ce = batchCE
+						continue
+					}
+					batches = append(batches, batch)
+				}
+			} else {
+				s.dao.UpdateCryptoPayoutFailed(batchItems, api_error.InvalidConfig)
+			}
+		}
+	}
+
+	return
+}
+
+func (s PayoutService) sendBlockChainIOBatch(currency string, payouts []bean.CryptoPayout) (batch bean.CryptoPayoutBatch, ce SimpleContextError) {
+	total := common.Zero
+	recipients := map[string]decimal.Decimal{}
+	for _, payout := range payouts {
+		amount := common.StringToDecimal(payout.Amount)
+		if recipient, ok := recipients[payout.Address]; ok {
+			recipients[payout.Address] = recipient.Add(amount)
+		} else {
+			recipients[payout.Address] = amount
+		}
+		total = total.Add(amount)
+	}
+
+	client := blockchainio_service.BlockChainIOClient{}
+	txHash, err := client.SendManyTransaction(recipients)
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		s.dao.UpdateCryptoPayoutFailed(payouts, err.Error())
+		return
+	}
+
+	batch, err = s.dao.AddCryptoPayoutBatch(bean.CryptoPayoutBatch{
+		WalletProvider:   bean.BTC_WALLET_BLOCKCHAINIO,
+		Currency:         currency,
+		Amount:           total.String(),
+		ExternalId:       txHash,
+		TxHash:           txHash,
+		ProviderResponse: txHash,
+	}, payouts)
+	ce.SetError(api_error.AddDataFailed, err)
+
+	return
+}
+
+func (s PayoutService) sendCoinbasePayout(payout bean.CryptoPayout) (batch bean.CryptoPayoutBatch, ce SimpleContextError) {
+	response, err := coinbase_service.SendTransaction(payout.Address, payout.Amount, payout.Currency, payout.Description, payout.Id)
+	if ce.SetError(api_error.ExternalApiFailed, err) {
+		s.dao.UpdateCryptoPayoutFailed([]bean.CryptoPayout{payout}, err.Error())
+		return
+	}
+
+	batch, err = s.dao.AddCryptoPayoutBatch(bean.CryptoPayoutBatch{
+		WalletProvider:   bean.BTC_WALLET_COINBASE,
+		Currency:         payout.Currency,
+		Amount:           payout.Amount,
+		ExternalId:       response.Id,
+		TxHash:           response.Network.Hash,
+		ProviderResponse: response,
+	}, []bean.CryptoPayout{payout})
+	ce.SetError(api_error.AddDataFailed, err)
+
+	return
+}
+
+func (s PayoutService) getConfigNumber(key string, defaultValue int) int {
+	systemConfigTO := s.dao.GetSystemConfigFromCache(key)
+	if systemConfigTO.HasError() {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(systemConfigTO.Object.(bean.SystemConfig).Value)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/shopspring/decimal"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type QuoteService struct {
+	miscDao *dao.MiscDao
+}
+
+// Quotes are bound to the user who asked and can only be traded once
+func (s QuoteService) LockQuote(userId string, quote bean.LockedQuote) (lockedQuote bean.LockedQuote, ce SimpleContextError) {
+	signingKey := getQuoteSigningKey(&ce)
+	if ce.HasError() {
+		return
+	}
+	ttl := bean.QUOTE_TTL_DEFAULT
+	ttlTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_QUOTE_TTL)
+	if !ttlTO.HasError() {
+		if value, err := strconv.Atoi(ttlTO.Object.(bean.SystemConfig).Value); err == nil && value > 0 {
+			ttl = value
+		}
+	}
+
+	lockedQuote = quote
+	lockedQuote.Id = ""
+	lockedQuote.UID = userId
+	lockedQuote.ExpiredAt = time.Now().UTC().Unix() + int64(ttl)
+
+	b, err := json.Marshal(&lockedQuote)
+	if ce.SetError(api_error.UnexpectedError, err) {
+		return
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	lockedQuote.Id = payload + "." + signQuotePayload(signingKey, payload)
+
+	return
+}
+
+func (s QuoteService) GetLockedQuote(quoteId string) (quote bean.LockedQuote, ce SimpleContextError) {
+	signingKey := getQuoteSigningKey(&ce)
+	if ce.HasError() {
+		return
+	}
+	parts := strings.Split(quoteId, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(signQuotePayload(signingKey, parts[0]))) {
+		ce.SetStatusKey(api_error.InvalidQuote)
+		return
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		ce.SetStatusKey(api_error.InvalidQuote)
+		return
+	}
+	if err = json.Unmarshal(b, &quote); err != nil {
+		ce.SetStatusKey(api_error.InvalidQuote)
+		return
+	}
+	quote.Id = quoteId
+
+	if time.Now().UTC().Unix() > quote.ExpiredAt {
+		ce.SetStatusKey(api_error.QuoteExpired)
+	}
+
+	return
+}
+
+// Check the quote was issued to this user for the same trade they are making now, it is only used up by UseLockedQuote
+func (s QuoteService) GetMatchedLockedQuote(userId string, quoteId string, quoteType string, amount string, currency string,
+	fiatCurrency string) (quote bean.LockedQuote, ce SimpleContextError) {
+	quote, quoteCE := s.GetLockedQuote(quoteId)
+	if ce.FeedContextErrorDefault(quoteCE) {
+		return
+	}
+	if quote.UID == "" || quote.UID != userId || quote.Type != quoteType || quote.Currency != currency || quote.FiatCurrency != fiatCurrency {
+		ce.SetStatusKey(api_error.InvalidQuote)
+		return
+	}
+	quoteAmount, amountErr := decimal.NewFromString(quote.Amount)
+	bodyAmount, bodyAmountErr := decimal.NewFromString(amount)
+	if amountErr != nil || bodyAmountErr != nil || !quoteAmount.Equal(bodyAmount) {
+		ce.SetStatusKey(api_error.InvalidQuote)
+	}
+
+	return
+}
+
+// Claim the quote once the trade passed its checks, so two trades cannot share it
+func (s QuoteService) UseLockedQuote(quoteId string, expiredAt int64) (ce SimpleContextError) {
+	expiresIn := time.Duration(expiredAt-time.Now().UTC().Unix()+1) * time.Second
+	used, err := s.miscDao.UseLockedQuote(getQuoteSignature(quoteId), expiresIn)
+	if ce.SetError(api_error.UnexpectedError, err) {
+		return
+	}
+	if !used {
+		ce.SetStatusKey(api_error.InvalidQuote)
+	}
+
+	return
+}
+
+// Deferred right after UseLockedQuote, a trade that failed hands its quote back to the user
+func (s QuoteService) ReleaseLockedQuoteOnError(quoteId string, ce *SimpleContextError) {
+	if ce.HasError() {
+		s.miscDao.ReleaseLockedQuote(getQuoteSignature(quoteId))
+	}
+}
+
+func getQuoteSignature(quoteId string) string {
+	return quoteId[strings.LastIndex(quoteId, ".")+1:]
+}
+
+// Without a key anyone could sign their own price, so no quote is issued or accepted
+func getQuoteSigningKey(ce *SimpleContextError) string {
+	signingKey := os.Getenv("QUOTE_SIGNING_KEY")
+	if signingKey == "" {
+		ce.SetError(api_error.UnexpectedError, errors.New("QUOTE_SIGNING_KEY is not set"))
+	}
+	return signingKey
+}
+
+func signQuotePayload(signingKey string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func signTestQuote(signingKey string, quote bean.LockedQuote) string {
+	b, _ := json.Marshal(&quote)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + signQuotePayload(signingKey, payload)
+}
+
+func TestGetLockedQuoteWithoutSigningKey(t *testing.T) {
+	os.Setenv("QUOTE_SIGNING_KEY", "")
+	quoteId := signTestQuote("", bean.LockedQuote{UID: "1", ExpiredAt: time.Now().UTC().Unix() + 60})
+
+	_, ce := QuoteServiceInst.GetLockedQuote(quoteId)
+	assert.Equal(t, api_error.UnexpectedError, ce.StatusKey)
+
+	_, ce = QuoteServiceInst.LockQuote("1", bean.LockedQuote{})
+	assert.Equal(t, api_error.UnexpectedError, ce.StatusKey)
+}
+
+func TestGetLockedQuoteSignature(t *testing.T) {
+	os.Setenv("QUOTE_SIGNING_KEY", "key")
+	defer os.Setenv("QUOTE_SIGNING_KEY", "")
+
+	quoteId := signTestQuote("key", bean.LockedQuote{UID: "1", Amount: "1", ExpiredAt: time.Now().UTC().Unix() + 60})
+	quote, ce := QuoteServiceInst.GetLockedQuote(quoteId)
+	assert.False(t, ce.HasError())
+	assert.Equal(t, "1", quote.UID)
+
+	_, ce = QuoteServiceInst.GetLockedQuote(signTestQuote("other", bean.LockedQuote{UID: "1", ExpiredAt: time.Now().UTC().Unix() + 60}))
+	assert.Equal(t, api_error.InvalidQuote, ce.StatusKey)
+
+	_, ce = QuoteServiceInst.GetLockedQuote(signTestQuote("key", bean.LockedQuote{UID: "1", ExpiredAt: time.Now().UTC().Unix() - 1}))
+	assert.Equal(t, api_error.QuoteExpired, ce.StatusKey)
+}
+
+// Matching only checks the quote, a trade that fails its later checks can still use it
+func TestGetMatchedLockedQuoteDoesNotUseQuote(t *testing.T) {
+	os.Setenv("QUOTE_SIGNING_KEY", "key")
+	defer os.Setenv("QUOTE_SIGNING_KEY", "")
+
+	quoteId := signTestQuote("key", bean.LockedQuote{UID: "1", Type: bean.OFFER_TYPE_BUY, Amount: "1", Currency: bean.ETH.Code,
+		FiatCurrency: bean.USD.Code, ExpiredAt: time.Now().UTC().Unix() + 60})
+	for i := 0; i < 2; i++ {
+		quote, ce := QuoteServiceInst.GetMatchedLockedQuote("1", quoteId, bean.OFFER_TYPE_BUY, "1.0", bean.ETH.Code, bean.USD.Code)
+		assert.False(t, ce.HasError())
+		assert.Equal(t, quoteId, quote.Id)
+	}
+
+	_, ce := QuoteServiceInst.GetMatchedLockedQuote("1", quoteId, bean.OFFER_TYPE_BUY, "2", bean.ETH.Code, bean.USD.Code)
+	assert.Equal(t, api_error.InvalidQuote, ce.StatusKey)
+	_, ce = QuoteServiceInst.GetMatchedLockedQuote("2", quoteId, bean.OFFER_TYPE_BUY, "1", bean.ETH.Code, bean.USD.Code)
+	assert.Equal(t, api_error.InvalidQuote, ce.StatusKey)
+}
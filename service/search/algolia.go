@@ -0,0 +1,68 @@
+package search
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/algolia_service"
+)
+
+const ALGOLIA_OFFER_URI = "offers"
+const ALGOLIA_LOG_URI = "logs"
+
+type AlgoliaIndexer struct {
+}
+
+type algoliaGeoloc struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+type algoliaDocument struct {
+	bean.SearchDocument
+	ObjectID string         `json:"objectID"`
+	Geoloc   *algoliaGeoloc `json:"_geoloc,omitempty"`
+}
+
+type algoliaLog struct {
+	bean.SearchLog
+	ObjectID string `json:"objectID"`
+}
+
+func (i AlgoliaIndexer) UpdateDocument(doc bean.SearchDocument) error {
+	_, err := algolia_service.UpdateObject(ALGOLIA_OFFER_URI, newAlgoliaDocument(doc))
+	return err
+}
+
+func (i AlgoliaIndexer) UpdateDocuments(docs []bean.SearchDocument) error {
+	objects := make([]interface{}, 0)
+	for _, doc := range docs {
+		objects = append(objects, newAlgoliaDocument(doc))
+	}
+	_, err := algolia_service.UpdateObjects(ALGOLIA_OFFER_URI, objects)
+	return err
+}
+
+func (i AlgoliaIndexer) DeleteDocument(id string) error {
+	_, err := algolia_service.DeleteObject(ALGOLIA_OFFER_URI, bean.GetSearchObjectId(id))
+	return err
+}
+
+func (i AlgoliaIndexer) UpdateLog(log bean.SearchLog) error {
+	_, err := algolia_service.UpdateObject(ALGOLIA_LOG_URI, algoliaLog{SearchLog: log, ObjectID: log.Id})
+	return err
+}
+
+func (i AlgoliaIndexer) Search(query bean.SearchQuery) ([]bean.SearchDocument, error) {
+	// Clients query Algolia directly
+	return nil, ErrSearchNotSupported
+}
+
+func newAlgoliaDocument(doc bean.SearchDocument) algoliaDocument {
+	obj := algoliaDocument{
+		SearchDocument: doc,
+		ObjectID:       bean.GetSearchObjectId(doc.Id),
+	}
+	if doc.Location != nil {
+		obj.Geoloc = &algoliaGeoloc{Lat: doc.Location.Latitude, Lng: doc.Location.Longitude}
+	}
+	return obj
+}
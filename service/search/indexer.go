@@ -0,0 +1,70 @@
+package search
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+)
+
+const INDEXER_SOLR = "solr"
+const INDEXER_ALGOLIA = "algolia"
+const INDEXER_LOCAL = "local"
+
+var ErrSearchNotSupported = errors.New(api_error.SearchNotSupported)
+
+type Indexer interface {
+	UpdateDocument(doc bean.SearchDocument) error
+	UpdateDocuments(docs []bean.SearchDocument) error
+	DeleteDocument(id string) error
+	UpdateLog(log bean.SearchLog) error
+	Search(query bean.SearchQuery) ([]bean.SearchDocument, error)
+}
+
+var IndexerInst Indexer = SolrIndexer{}
+
+func InitializeIndexer(name string) {
+	switch name {
+	case INDEXER_ALGOLIA:
+		IndexerInst = AlgoliaIndexer{}
+	case INDEXER_LOCAL:
+		IndexerInst = NewLocalIndexer()
+	default:
+		// Solr is what the feed frontend reads from
+		IndexerInst = SolrIndexer{}
+	}
+}
+
+func UpdateDocument(doc bean.SearchDocument) error {
+	err := IndexerInst.UpdateDocument(doc)
+	if err == nil {
+		trackDocuments([]bean.SearchDocument{doc})
+	}
+	return err
+}
+
+func UpdateDocuments(docs []bean.SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	err := IndexerInst.UpdateDocuments(docs)
+	if err == nil {
+		trackDocuments(docs)
+	}
+	return err
+}
+
+func DeleteDocument(id string) error {
+	err := IndexerInst.DeleteDocument(id)
+	if err == nil {
+		untrackDocument(id)
+	}
+	return err
+}
+
+func UpdateLog(log bean.SearchLog) error {
+	return IndexerInst.UpdateLog(log)
+}
+
+func Search(query bean.SearchQuery) ([]bean.SearchDocument, error) {
+	return IndexerInst.Search(query)
+}
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"encoding/json"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Extra data fields worth matching in full-text queries
+var localSearchExtraFields = []string{"username", "chat_username", "physical_item", "currency", "fiat_currency", "type"}
+
+type LocalIndexer struct {
+	mutex     *sync.RWMutex
+	documents map[string]bean.SearchDocument
+	tokens    map[string]map[string]bool
+}
+
+func NewLocalIndexer() LocalIndexer {
+	return LocalIndexer{
+		mutex:     &sync.RWMutex{},
+		documents: map[string]bean.SearchDocument{},
+		tokens:    map[string]map[string]bool{},
+	}
+}
+
+func (i LocalIndexer) UpdateDocument(doc bean.SearchDocument) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.removeDocument(doc.Id)
+	i.documents[doc.Id] = doc
+	for _, token := range documentTokens(doc) {
+		ids, ok := i.tokens[token]
+		if !ok {
+			ids = map[string]bool{}
+			i.tokens[token] = ids
+		}
+		ids[doc.Id] = true
+	}
+
+	return nil
+}
+
+func (i LocalIndexer) UpdateDocuments(docs []bean.SearchDocument) error {
+	for _, doc := range docs {
+		i.UpdateDocument(doc)
+	}
+
+	return nil
+}
+
+func (i LocalIndexer) DeleteDocument(id string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.removeDocument(id)
+
+	return nil
+}
+
+func (i LocalIndexer) UpdateLog(log bean.SearchLog) error {
+	// Request logs are already in the log file
+	return nil
+}
+
+func (i LocalIndexer) Search(query bean.SearchQuery) ([]bean.SearchDocument, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	type resultStruct struct {
+		doc      bean.SearchDocument
+		distance float64
+	}
+
+	candidates := i.matchText(query.Text)
+	results := make([]resultStruct, 0)
+	for id := range candidates {
+		doc := i.documents[id]
+		if query.FeedType != "" && doc.FeedType != query.FeedType {
+			continue
+		}
+		if query.FiatCurrency != "" && doc.FiatCurrency != query.FiatCurrency {
+			continue
+		}
+		distance := 0.0
+		if query.Location != nil {
+			if doc.Location == nil {
+				continue
+			}
+			distance = common.GeoDistance(query.Location.Latitude, query.Location.Longitude, doc.Location.Latitude, doc.Location.Longitude)
+			if query.Radius > 0 && distance > query.Radius {
+				continue
+			}
+		}
+		results = append(results, resultStruct{doc: doc, distance: distance})
+	}
+
+	// Nearest first for geo queries, most recent first otherwise
+	sort.Slice(results, func(a, b int) bool {
+		if query.Location != nil && results[a].distance != results[b].distance {
+			return results[a].distance < results[b].distance
+		}
+		return results[a].doc.LastUpdateAt > results[b].doc.LastUpdateAt
+	})
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	docs := make([]bean.SearchDocument, 0)
+	for _, result := range results {
+		docs = append(docs, result.doc)
+	}
+
+	return docs, nil
+}
+
+func (i LocalIndexer) matchText(text string) map[string]bool {
+	queryTokens := tokenize(text)
+	matched := map[string]bool{}
+	if len(queryTokens) == 0 {
+		for id := range i.documents {
+			matched[id] = true
+		}
+		return matched
+	}
+
+	// Every query token has to prefix match at least one document token
+	for index, queryToken := range queryTokens {
+		tokenMatched := map[string]bool{}
+		for token, ids := range i.tokens {
+			if strings.HasPrefix(token, queryToken) {
+				for id := range ids {
+					if index == 0 || matched[id] {
+						tokenMatched[id] = true
+					}
+				}
+			}
+		}
+		matched = tokenMatched
+		if len(matched) == 0 {
+			break
+		}
+	}
+
+	return matched
+}
+
+func (i LocalIndexer) removeDocument(id string) {
+	doc, ok := i.documents[id]
+	if !ok {
+		return
+	}
+	for _, token := range documentTokens(doc) {
+		if ids, ok := i.tokens[token]; ok {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(i.tokens, token)
+			}
+		}
+	}
+	delete(i.documents, id)
+}
+
+func documentTokens(doc bean.SearchDocument) []string {
+	texts := make([]string, 0)
+	texts = append(texts, doc.TextSearch...)
+	texts = append(texts, doc.FeedType, doc.OfferType, doc.FiatCurrency)
+
+	var extraData map[string]interface{}
+	if json.Unmarshal([]byte(doc.ExtraData), &extraData) == nil {
+		for _, field := range localSearchExtraFields {
+			if value, ok := extraData[field].(string); ok {
+				texts = append(texts, value)
+			}
+		}
+	}
+
+	return tokenize(strings.Join(texts, " "))
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	seen := map[string]bool{}
+	tokens := make([]string, 0)
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			tokens = append(tokens, field)
+		}
+	}
+
+	return tokens
+}
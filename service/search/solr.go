@@ -0,0 +1,38 @@
+package search
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/integration/solr_service"
+)
+
+type SolrIndexer struct {
+}
+
+func (i SolrIndexer) UpdateDocument(doc bean.SearchDocument) error {
+	_, err := solr_service.UpdateObject(bean.NewSolrFromSearchDocument(doc))
+	return err
+}
+
+func (i SolrIndexer) UpdateDocuments(docs []bean.SearchDocument) error {
+	objects := make([]interface{}, 0)
+	for _, doc := range docs {
+		objects = append(objects, bean.NewSolrFromSearchDocument(doc))
+	}
+	_, err := solr_service.UpdateObjects(objects)
+	return err
+}
+
+func (i SolrIndexer) DeleteDocument(id string) error {
+	_, err := solr_service.DeleteObject(id)
+	return err
+}
+
+func (i SolrIndexer) UpdateLog(log bean.SearchLog) error {
+	_, err := solr_service.UpdateObject(bean.NewSolrFromSearchLog(log))
+	return err
+}
+
+func (i SolrIndexer) Search(query bean.SearchQuery) ([]bean.SearchDocument, error) {
+	// Clients query Solr directly
+	return nil, ErrSearchNotSupported
+}
@@ -0,0 +1,55 @@
+package search
+
+import (
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/service/cache"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Every indexed document id with its feed type, so a reindex can find orphans.
+// Orphan cleanup only covers ids in this hash, documents indexed before tracking
+// started or lost in a Redis flush are not enumerated from Solr or Algolia
+const TRACKED_DOCUMENTS_CACHE_KEY = "handshake_exchange.search_documents"
+
+type TrackedDocument struct {
+	FeedType  string
+	IndexedAt int64
+}
+
+func ListTrackedDocuments() (map[string]TrackedDocument, error) {
+	docs := map[string]TrackedDocument{}
+	values, err := cache.RedisClient.HGetAll(TRACKED_DOCUMENTS_CACHE_KEY).Result()
+	for id, value := range values {
+		var doc TrackedDocument
+		parts := strings.SplitN(value, "|", 2)
+		doc.FeedType = parts[0]
+		if len(parts) > 1 {
+			doc.IndexedAt, _ = strconv.ParseInt(parts[1], 10, 64)
+		}
+		docs[id] = doc
+	}
+
+	return docs, err
+}
+
+func trackDocuments(docs []bean.SearchDocument) {
+	if cache.RedisClient == nil {
+		return
+	}
+	now := time.Now().UTC().Unix()
+	fields := map[string]interface{}{}
+	for _, doc := range docs {
+		fields[doc.Id] = fmt.Sprintf("%s|%d", doc.FeedType, now)
+	}
+	cache.RedisClient.HMSet(TRACKED_DOCUMENTS_CACHE_KEY, fields)
+}
+
+func untrackDocument(id string) {
+	if cache.RedisClient == nil {
+		return
+	}
+	cache.RedisClient.HDel(TRACKED_DOCUMENTS_CACHE_KEY, id)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/search"
+	"strconv"
+	"time"
+)
+
+type SearchReindexService struct {
+	dao           *dao.SearchReindexDao
+	miscDao       *dao.MiscDao
+	offerStoreDao *dao.OfferStoreDao
+}
+
+func (s SearchReindexService) GetSearchReindexJob() (job bean.SearchReindexJob, ce SimpleContextError) {
+	jobTO := s.dao.GetLatestSearchReindexJob()
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, jobTO); ce.HasError() {
+		return
+	}
+	job = jobTO.Object.(bean.SearchReindexJob)
+
+	return
+}
+
+// Each call works through a bounded number of batches, the cron keeps calling until the job is completed
+func (s SearchReindexService) RunSearchReindex(deleteOrphans bool) (job bean.SearchReindexJob, ce SimpleContextError) {
+	jobTO := s.dao.GetLatestSearchReindexJob()
+	if ce.SetError(api_error.GetDataFailed, jobTO.Error) {
+		return
+	}
+	if jobTO.Found {
+		job = jobTO.Object.(bean.SearchReindexJob)
+	}
+	if !jobTO.Found || job.Status != bean.SEARCH_REINDEX_STATUS_RUNNING {
+		var err error
+		job, err = s.dao.AddSearchReindexJob(bean.SearchReindexJob{
+			Status:        bean.SEARCH_REINDEX_STATUS_RUNNING,
+			Phase:         bean.SEARCH_REINDEX_PHASE_OFFERS,
+			DeleteOrphans: deleteOrphans,
+			Errors:        make([]string, 0),
+		})
+		if ce.SetError(api_error.AddDataFailed, err) {
+			return
+		}
+		job.CreatedAt = time.Now().UTC()
+	}
+
+	batchSize := s.getConfigNumber(bean.CONFIG_SEARCH_REINDEX_BATCH_SIZE, bean.SEARCH_REINDEX_BATCH_SIZE_DEFAULT)
+	batches := s.getConfigNumber(bean.CONFIG_SEARCH_REINDEX_BATCHES_PER_RUN, bean.SEARCH_REINDEX_BATCHES_PER_RUN_DEFAULT)
+	for i := 0; i < batches && job.Status == bean.SEARCH_REINDEX_STATUS_RUNNING; i++ {
+		switch job.Phase {
+		case bean.SEARCH_REINDEX_PHASE_OFFERS:
+			s.reindexOffers(&job, batchSize)
+		case bean.SEARCH_REINDEX_PHASE_OFFER_STORES:
+			s.reindexOfferStores(&job, batchSize)
+		case bean.SEARCH_REINDEX_PHASE_ORPHANS:
+			s.deleteOrphans(&job, batchSize)
+		default:
+			s.completeJob(&job)
+		}
+
+		// Checkpoint every batch, so a crashed run resumes from the last cursor
+		err := s.dao.UpdateSearchReindexJob(job)
+		if ce.SetError(api_error.UpdateDataFailed, err) {
+			return
+		}
+	}
+
+	return
+}
+
+func (s SearchReindexService) reindexOffers(job *bean.SearchReindexJob, batchSize int) {
+	offers, err := s.dao.ListOfferPage(job.Cursor, batchSize)
+	if err != nil {
+		// Keep the cursor, the next run retries the same page
+		job.AddError(err)
+		return
+	}
+
+	docs := make([]bean.SearchDocument, 0)
+	ids := make([]string, 0)
+	for _, offer := range offers {
+		docs = append(docs, bean.NewSearchDocumentFromOffer(offer))
+		ids = append(ids, offer.Id)
+	}
+	if !s.indexBatch(job, docs, ids) {
+		return
+	}
+	job.OfferCount += int64(len(offers))
+
+	if len(offers) < batchSize {
+		job.Phase = bean.SEARCH_REINDEX_PHASE_OFFER_STORES
+		job.Cursor = ""
+	} else {
+		job.Cursor = offers[len(offers)-1].Id
+	}
+}
+
+func (s SearchReindexService) reindexOfferStores(job *bean.SearchReindexJob, batchSize int) {
+	offers, err := s.dao.ListOfferStorePage(job.Cursor, batchSize)
+	if err != nil {
+		job.AddError(err)
+		return
+	}
+
+	docs := make([]bean.SearchDocument, 0)
+	ids := make([]string, 0)
+	shakeCount := 0
+	for _, offer := range offers {
+		docs = append(docs, bean.NewSearchDocumentFromOfferStore(offer, bean.OfferStoreItem{}))
+		ids = append(ids, offer.Id)
+
+		offerShakes, err := s.offerStoreDao.ListOfferStoreShake(offer.Id)
+		if err != nil {
+			// Unlisted shakes would not be seen and the orphan phase would delete them, retry the page
+			job.AddError(err)
+			return
+		}
+		for _, offerShake := range offerShakes {
+			docs = append(docs, bean.NewSearchDocumentFromOfferStoreShake(offerShake, offer))
+			ids = append(ids, offerShake.Id)
+		}
+		shakeCount += len(offerShakes)
+	}
+	if !s.indexBatch(job, docs, ids) {
+		return
+	}
+	job.OfferStoreShakeCount += int64(shakeCount)
+	job.OfferStoreCount += int64(len(offers))
+
+	if len(offers) < batchSize {
+		job.Cursor = ""
+		if job.DeleteOrphans {
+			job.Phase = bean.SEARCH_REINDEX_PHASE_ORPHANS
+		} else {
+			s.completeJob(job)
+		}
+	} else {
+		job.Cursor = offers[len(offers)-1].Id
+	}
+}
+
+// Only documents in the tracker are candidates, see search.ListTrackedDocuments
+func (s SearchReindexService) deleteOrphans(job *bean.SearchReindexJob, batchSize int) {
+	trackedDocs, err := search.ListTrackedDocuments()
+	if err != nil {
+		job.AddError(err)
+		return
+	}
+	seen, err := s.dao.ListSeenDocumentIds(job.Id)
+	if err != nil {
+		job.AddError(err)
+		return
+	}
+
+	orphanIds := make([]string, 0)
+	for id, trackedDoc := range trackedDocs {
+		if len(orphanIds) >= batchSize {
+			break
+		}
+		if !bean.IsSearchReindexFeedType(trackedDoc.FeedType) || seen[bean.GetSearchDocumentSourceId(id)] {
+			continue
+		}
+		// Indexed while the job was running, so it cannot be stale
+		if trackedDoc.IndexedAt >= job.CreatedAt.Unix() {
+			continue
+		}
+		orphanIds = append(orphanIds, id)
+	}
+
+	failedIds := make([]string, 0)
+	for _, id := range orphanIds {
+		if err := search.DeleteDocument(id); err != nil {
+			job.AddError(err)
+			failedIds = append(failedIds, bean.GetSearchDocumentSourceId(id))
+			continue
+		}
+		job.OrphanCount += 1
+	}
+	// Failed ids are reported once, not retried forever
+	if err := s.dao.AddSeenDocumentIds(job.Id, failedIds); err != nil {
+		job.AddError(err)
+	}
+
+	if len(orphanIds) < batchSize {
+		s.completeJob(job)
+	}
+}
+
+// False when the ids could not be marked seen, the caller keeps the cursor so the page is retried
+func (s SearchReindexService) indexBatch(job *bean.SearchReindexJob, docs []bean.SearchDocument, ids []string) bool {
+	if err := search.UpdateDocuments(docs); err != nil {
+		job.AddError(err)
+	}
+	// Seen means it exists in the DAO, even if the indexer rejected it
+	if err := s.dao.AddSeenDocumentIds(job.Id, ids); err != nil {
+		job.AddError(err)
+		return false
+	}
+	return true
+}
+
+func (s SearchReindexService) completeJob(job *bean.SearchReindexJob) {
+	job.Status = bean.SEARCH_REINDEX_STATUS_COMPLETED
+	job.Cursor = ""
+	if err := s.dao.RemoveSeenDocumentIds(job.Id); err != nil {
+		job.AddError(err)
+	}
+}
+
+func (s SearchReindexService) getConfigNumber(key string, defaultValue int) int {
+	systemConfigTO := s.miscDao.GetSystemConfigFromCache(key)
+	if systemConfigTO.HasError() {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(systemConfigTO.Object.(bean.SystemConfig).Value)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/integration/twilio_service"
+	"sync"
+)
+
+const PROVIDER_TWILIO = "twilio"
+const PROVIDER_FAKE = "fake"
+
+type Provider interface {
+	SendSMS(to string, body string) error
+}
+
+var ProviderInst Provider = NoopProvider{}
+
+// Without SMS_PROVIDER messages are dropped, the fake only records them when asked for
+func InitializeProvider(name string) {
+	switch name {
+	case PROVIDER_TWILIO:
+		ProviderInst = TwilioProvider{}
+	case PROVIDER_FAKE:
+		ProviderInst = NewFakeProvider()
+	default:
+		ProviderInst = NoopProvider{}
+	}
+}
+
+type NoopProvider struct {
+}
+
+func (p NoopProvider) SendSMS(to string, body string) error {
+	return nil
+}
+
+type TwilioProvider struct {
+}
+
+func (p TwilioProvider) SendSMS(to string, body string) error {
+	return twilio_service.SendMessage(to, body)
+}
+
+type FakeMessage struct {
+	To   string
+	Body string
+}
+
+// Messages returns what would have been sent
+type FakeProvider struct {
+	mutex    *sync.Mutex
+	messages *[]FakeMessage
+}
+
+func NewFakeProvider() FakeProvider {
+	messages := make([]FakeMessage, 0)
+	return FakeProvider{
+		mutex:    &sync.Mutex{},
+		messages: &messages,
+	}
+}
+
+func (p FakeProvider) SendSMS(to string, body string) error {
+	if to == "" {
+		return errors.New("sms recipient is empty")
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	*p.messages = append(*p.messages, FakeMessage{To: to, Body: body})
+
+	return nil
+}
+
+func (p FakeProvider) Messages() []FakeMessage {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	messages := make([]FakeMessage, len(*p.messages))
+	copy(messages, *p.messages)
+	return messages
+}
@@ -0,0 +1,46 @@
+package sms
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInitializeProvider(t *testing.T) {
+	defer func() { ProviderInst = NoopProvider{} }()
+
+	InitializeProvider("")
+	assert.IsType(t, NoopProvider{}, ProviderInst)
+
+	InitializeProvider("unknown")
+	assert.IsType(t, NoopProvider{}, ProviderInst)
+
+	InitializeProvider(PROVIDER_FAKE)
+	assert.IsType(t, FakeProvider{}, ProviderInst)
+
+	InitializeProvider(PROVIDER_TWILIO)
+	assert.IsType(t, TwilioProvider{}, ProviderInst)
+}
+
+func TestFakeProviderRecordsMessages(t *testing.T) {
+	provider := NewFakeProvider()
+
+	assert.Nil(t, provider.SendSMS("+84900000001", "Offer is active"))
+	assert.Nil(t, provider.SendSMS("+84900000002", "Offer is completed"))
+
+	messages := provider.Messages()
+	assert.Equal(t, []FakeMessage{
+		{To: "+84900000001", Body: "Offer is active"},
+		{To: "+84900000002", Body: "Offer is completed"},
+	}, messages)
+
+	// Callers get a copy
+	messages[0].Body = ""
+	assert.Equal(t, "Offer is active", provider.Messages()[0].Body)
+}
+
+func TestFakeProviderRejectsEmptyRecipient(t *testing.T) {
+	provider := NewFakeProvider()
+
+	assert.NotNil(t, provider.SendSMS("", "Offer is active"))
+	assert.Equal(t, 0, len(provider.Messages()))
+}
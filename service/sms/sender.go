@@ -0,0 +1,71 @@
+package sms
+
+import (
+	"fmt"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
+	"os"
+)
+
+// Templates are kept short to fit a single SMS segment
+func sendTemplate(language string, phone string, key string, data map[string]string) error {
+	T := translation.Tfunc(language)
+
+	if data == nil {
+		data = map[string]string{}
+	}
+	data["Url"] = fmt.Sprintf("%s/me", os.Getenv("FRONTEND_HOST"))
+
+	return ProviderInst.SendSMS(phone, T(key, data))
+}
+
+func SendOfferActiveSMS(language string, phone string) error {
+	return sendTemplate(language, phone, "sms_offer_active", nil)
+}
+
+func SendOfferMakerShakeSMS(language string, phone string) error {
+	return sendTemplate(language, phone, "sms_offer_maker_shake", nil)
+}
+
+func SendOfferRejectSMS(language string, phone string) error {
+	return sendTemplate(language, phone, "sms_offer_reject", nil)
+}
+
+func SendOfferCompleteSMS(language string, phone string) error {
+	return sendTemplate(language, phone, "sms_offer_complete", nil)
+}
+
+func SendOfferStoreItemAddedSMS(language string, phone string, currency string) error {
+	return sendTemplate(language, phone, "sms_offer_store_item_added", map[string]string{
+		"Currency": currency,
+	})
+}
+
+func SendOfferStoreItemRemovedSMS(language string, phone string) error {
+	return sendTemplate(language, phone, "sms_offer_store_item_removed", nil)
+}
+
+func SendOfferStoreMakerShakeSMS(language string, phone string, currency string, username string) error {
+	return sendTemplate(language, phone, "sms_offer_store_maker_shake", map[string]string{
+		"Currency": currency,
+		"Username": username,
+	})
+}
+
+func SendOfferStoreTakerShakeSMS(language string, phone string, currency string, username string) error {
+	return sendTemplate(language, phone, "sms_offer_store_taker_shake", map[string]string{
+		"Currency": currency,
+		"Username": username,
+	})
+}
+
+func SendOfferStoreRejectSMS(language string, phone string, username string) error {
+	return sendTemplate(language, phone, "sms_offer_store_reject", map[string]string{
+		"Username": username,
+	})
+}
+
+func SendOfferStoreCompleteSMS(language string, phone string, currency string) error {
+	return sendTemplate(language, phone, "sms_offer_store_complete", map[string]string{
+		"Currency": currency,
+	})
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/common"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/shopspring/decimal"
+	"math/rand"
+	"time"
+)
+
+type TradingBotService struct {
+	dao      *dao.TradingBotDao
+	miscDao  *dao.MiscDao
+	userDao  *dao.UserDao
+	offerDao *dao.OfferDao
+}
+
+func (s TradingBotService) ListTradingBots() (bots []bean.TradingBot, ce SimpleContextError) {
+	bots, err := s.dao.ListTradingBots()
+	ce.SetError(api_error.GetDataFailed, err)
+
+	return
+}
+
+func (s TradingBotService) GetTradingBot(botId string) (bot bean.TradingBot, ce SimpleContextError) {
+	botTO := s.dao.GetTradingBot(botId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, botTO) {
+		return
+	}
+	if !botTO.Found {
+		ce.SetStatusKey(api_error.ResourceNotFound)
+		return
+	}
+	bot = botTO.Object.(bean.TradingBot)
+
+	return
+}
+
+func (s TradingBotService) AddTradingBot(body bean.TradingBot) (bot bean.TradingBot, ce SimpleContextError) {
+	if s.validateTradingBot(&body, &ce); ce.HasError() {
+		return
+	}
+	if GetProfile(s.userDao, body.UID, &ce); ce.HasError() {
+		return
+	}
+
+	body.Status = bean.TRADING_BOT_STATUS_IDLE
+	if !body.Enabled {
+		body.Status = bean.TRADING_BOT_STATUS_DISABLED
+	}
+	bot, err := s.dao.AddTradingBot(body)
+	if ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	bot.CreatedAt = time.Now().UTC()
+
+	return
+}
+
+func (s TradingBotService) UpdateTradingBot(botId string, body bean.TradingBot) (bot bean.TradingBot, ce SimpleContextError) {
+	bot, botCE := s.GetTradingBot(botId)
+	if ce.FeedContextErrorDefault(botCE) {
+		return
+	}
+
+	// Account, currency and type are fixed, create another bot to change them
+	bot.Enabled = body.Enabled
+	bot.FiatCurrency = body.FiatCurrency
+	bot.MinAmount = body.MinAmount
+	bot.MaxAmount = body.MaxAmount
+	bot.Price = body.Price
+	bot.Duration = body.Duration
+	bot.Address = body.Address
+	bot.AutoComplete = body.AutoComplete
+	if s.validateTradingBot(&bot, &ce); ce.HasError() {
+		return
+	}
+
+	err := s.dao.UpdateTradingBot(bot)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+	if !bot.Enabled {
+		s.closeTradingBotOffer(&bot)
+		bot.Status = bean.TRADING_BOT_STATUS_DISABLED
+		s.dao.UpdateTradingBotStatus(bot)
+	}
+
+	return
+}
+
+func (s TradingBotService) RemoveTradingBot(botId string) (bot bean.TradingBot, ce SimpleContextError) {
+	bot, botCE := s.GetTradingBot(botId)
+	if ce.FeedContextErrorDefault(botCE) {
+		return
+	}
+
+	s.closeTradingBotOffer(&bot)
+	err := s.dao.RemoveTradingBot(bot)
+	ce.SetError(api_error.DeleteDataFailed, err)
+
+	return
+}
+
+func (s TradingBotService) GetTradingBotStatus(botId string) (status bean.TradingBotStatus, ce SimpleContextError) {
+	bot, botCE := s.GetTradingBot(botId)
+	if ce.FeedContextErrorDefault(botCE) {
+		return
+	}
+	status.Bot = bot
+
+	if bot.OfferId != "" {
+		offerTO := s.offerDao.GetOffer(bot.OfferId)
+		if ce.FeedDaoTransfer(api_error.GetDataFailed, offerTO) {
+			return
+		}
+		if offerTO.Found {
+			status.Offer = offerTO.Object.(bean.Offer)
+		}
+	}
+
+	pendingOffers, err := s.dao.ListTradingBotPendingOffers(botId)
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+	status.PendingOffers = pendingOffers
+
+	return
+}
+
+func (s TradingBotService) RefreshTradingBots() (bots []bean.TradingBot, ce SimpleContextError) {
+	allBots, err := s.dao.ListTradingBots()
+	if ce.SetError(api_error.GetDataFailed, err) {
+		return
+	}
+
+	for _, bot := range allBots {
+		if !bot.Enabled {
+			continue
+		}
+		s.refreshTradingBot(&bot)
+		bots = append(bots, bot)
+	}
+
+	return
+}
+
+func (s TradingBotService) refreshTradingBot(bot *bean.TradingBot) {
+	bot.LastError = ""
+
+	pendingOffers, err := s.dao.ListTradingBotPendingOffers(bot.Id)
+	if err != nil {
+		bot.Status = bean.TRADING_BOT_STATUS_FAILED
+		bot.LastError = err.Error()
+		s.dao.UpdateTradingBotStatus(*bot)
+		return
+	}
+
+	currentOfferDone := bot.OfferId == ""
+	currentOfferTracked := false
+	for _, pendingOffer := range pendingOffers {
+		offer, done := s.processTradingBotOffer(bot, pendingOffer.Offer)
+		if done {
+			s.dao.RemoveTradingBotPendingOffer(bot.Id, pendingOffer.Offer)
+		}
+		if pendingOffer.Offer == bot.OfferId {
+			currentOfferTracked = true
+			currentOfferDone = done
+			bot.OfferStatus = offer.Status
+		}
+	}
+	if !currentOfferDone && !currentOfferTracked {
+		// Only move on once the untracked offer is known to be finished
+		offer, done := s.processTradingBotOffer(bot, bot.OfferId)
+		currentOfferDone = done
+		bot.OfferStatus = offer.Status
+		if !done && s.dao.AddTradingBotPendingOffer(*bot, offer) != nil {
+			bot.Status = bean.TRADING_BOT_STATUS_FAILED
+			s.dao.UpdateTradingBotStatus(*bot)
+			return
+		}
+	}
+
+	if currentOfferDone {
+		s.createTradingBotOffer(bot)
+		return
+	}
+
+	bot.Status = getTradingBotStatus(bot.OfferStatus)
+	s.dao.UpdateTradingBotStatus(*bot)
+}
+
+func (s TradingBotService) processTradingBotOffer(bot *bean.TradingBot, offerId string) (offer bean.Offer, done bool) {
+	offerTO := s.offerDao.GetOffer(offerId)
+	if offerTO.HasError() {
+		// Nothing left to manage if the offer is gone
+		done = offerTO.Error == nil
+		return
+	}
+	offer = offerTO.Object.(bean.Offer)
+
+	var ce SimpleContextError
+	switch offer.Status {
+	case bean.OFFER_STATUS_PRE_SHAKE:
+		offer, ce = OfferServiceInst.AcceptShakeOffer(bot.UID, offer.Id)
+	case bean.OFFER_STATUS_SHAKE:
+		// Only the side paying out the crypto completes, that is the bot on its sell offers.
+		// Otherwise shaken offers wait for the owner to confirm fiat was received
+		if bot.AutoComplete && offer.IsTypeSell() {
+			offer, ce = OfferServiceInst.CompleteShakeOffer(bot.UID, offer.Id)
+		}
+	case bean.OFFER_STATUS_ACTIVE:
+		duration := bot.Duration
+		if duration <= 0 {
+			duration = bean.TRADING_BOT_DURATION_DEFAULT
+		}
+		if offer.Id != bot.OfferId || time.Now().UTC().Sub(offer.CreatedAt).Seconds() > float64(duration) {
+			offer, ce = OfferServiceInst.CloseOffer(bot.UID, offer.Id)
+		}
+	}
+	if ce.HasError() {
+		bot.LastError = ce.Error.Error()
+		return
+	}
+
+	switch offer.Status {
+	case bean.OFFER_STATUS_CLOSED, bean.OFFER_STATUS_COMPLETED, bean.OFFER_STATUS_REJECTED,
+		bean.OFFER_STATUS_CANCELLED, bean.OFFER_STATUS_CREATE_FAILED:
+		done = true
+	}
+
+	return
+}
+
+func (s TradingBotService) createTradingBotOffer(bot *bean.TradingBot) {
+	offerBody := bean.Offer{
+		Type:         bot.Type,
+		Currency:     bot.Currency,
+		FiatCurrency: bot.FiatCurrency,
+		Amount:       getTradingBotAmount(*bot).String(),
+		Percentage:   bot.Price,
+	}
+	if offerBody.IsTypeBuy() {
+		offerBody.UserAddress = bot.Address
+	} else {
+		offerBody.RefundAddress = bot.Address
+	}
+
+	offer, ce := OfferServiceInst.CreateOffer(bot.UID, offerBody)
+	if ce.HasError() {
+		bot.Status = bean.TRADING_BOT_STATUS_FAILED
+		bot.LastError = ce.Error.Error()
+		bot.OfferId = ""
+		bot.OfferStatus = ""
+		s.dao.UpdateTradingBotStatus(*bot)
+		return
+	}
+
+	previousOfferId := bot.OfferId
+	bot.OfferId = offer.Id
+	bot.OfferStatus = offer.Status
+	bot.Status = getTradingBotStatus(offer.Status)
+	err := s.dao.AddTradingBotPendingOffer(*bot, offer)
+	if err != nil {
+		// Nothing would track the new offer, so take it down instead of leaving it open
+		OfferServiceInst.CloseOffer(bot.UID, offer.Id)
+		bot.Status = bean.TRADING_BOT_STATUS_FAILED
+		bot.LastError = err.Error()
+		bot.OfferId = previousOfferId
+		bot.OfferStatus = ""
+	}
+}
+
+func (s TradingBotService) closeTradingBotOffer(bot *bean.TradingBot) {
+	if bot.OfferId == "" {
+		return
+	}
+	offerTO := s.offerDao.GetOffer(bot.OfferId)
+	if offerTO.HasError() {
+		return
+	}
+	offer := offerTO.Object.(bean.Offer)
+	if offer.Status == bean.OFFER_STATUS_ACTIVE {
+		offer, _ = OfferServiceInst.CloseOffer(bot.UID, offer.Id)
+	}
+	bot.OfferStatus = offer.Status
+}
+
+func (s TradingBotService) validateTradingBot(bot *bean.TradingBot, ce *SimpleContextError) {
+	// Only BTC offers are off-chain, ETH offers need the owner to sign
+	if bot.Currency != bean.BTC.Code {
+		ce.SetStatusKey(api_error.UnsupportedCurrency)
+		return
+	}
+	if GetFiatCurrency(s.miscDao, bot.FiatCurrency, ce); ce.HasError() {
+		return
+	}
+	if bot.Type != bean.OFFER_TYPE_BUY && bot.Type != bean.OFFER_TYPE_SELL {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+	if bot.Address == "" {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+
+	minAmount, errFmt := decimal.NewFromString(bot.MinAmount)
+	if ce.SetError(api_error.InvalidRequestBody, errFmt) {
+		return
+	}
+	maxAmount, errFmt := decimal.NewFromString(bot.MaxAmount)
+	if ce.SetError(api_error.InvalidRequestBody, errFmt) {
+		return
+	}
+	if minAmount.LessThan(bean.MIN_BTC) {
+		ce.SetStatusKey(api_error.AmountIsTooSmall)
+		return
+	}
+	if maxAmount.LessThan(minAmount) {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+	if bot.Price != "" {
+		_, errFmt := decimal.NewFromString(bot.Price)
+		if ce.SetError(api_error.InvalidRequestBody, errFmt) {
+			return
+		}
+	}
+	if bot.Duration <= 0 {
+		bot.Duration = bean.TRADING_BOT_DURATION_DEFAULT
+	}
+}
+
+func getTradingBotAmount(bot bean.TradingBot) decimal.Decimal {
+	minAmount := common.StringToDecimal(bot.MinAmount)
+	maxAmount := common.StringToDecimal(bot.MaxAmount)
+	amount := minAmount.Add(maxAmount.Sub(minAmount).Mul(decimal.NewFromFloat(rand.Float64())))
+
+	return amount.Round(bean.CurrencyMapping[bot.Currency].Decimal)
+}
+
+func getTradingBotStatus(offerStatus string) string {
+	switch offerStatus {
+	case bean.OFFER_STATUS_CREATED:
+		return bean.TRADING_BOT_STATUS_WAITING_DEPOSIT
+	case bean.OFFER_STATUS_ACTIVE:
+		return bean.TRADING_BOT_STATUS_ACTIVE
+	case "":
+		return bean.TRADING_BOT_STATUS_IDLE
+	}
+	return bean.TRADING_BOT_STATUS_TRADING
+}
@@ -0,0 +1,125 @@
+package translation
+
+import (
+	"github.com/nicksnyder/go-i18n/i18n"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const DEFAULT_LANGUAGE = "en-US"
+
+// Languages as spelled in the translation file names, en-US.flat.yaml gives en-US
+var Languages = []string{DEFAULT_LANGUAGE}
+
+func LoadTranslations(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	jsonPaths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	paths = append(paths, jsonPaths...)
+
+	// A language can ship as both .yaml and .json, the bundle merges them
+	languages := make([]string, 0)
+	loaded := map[string]bool{}
+	for _, path := range paths {
+		if err := i18n.LoadTranslationFile(path); err != nil {
+			return err
+		}
+		language := LanguageFromPath(path)
+		if !loaded[languageTag(language)] {
+			loaded[languageTag(language)] = true
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	Languages = languages
+
+	return nil
+}
+
+func MustLoadTranslations(dir string) {
+	if err := LoadTranslations(dir); err != nil {
+		panic(err)
+	}
+}
+
+func LanguageFromPath(path string) string {
+	return strings.SplitN(filepath.Base(path), ".", 2)[0]
+}
+
+func IsSupported(language string) bool {
+	for _, item := range Languages {
+		if strings.EqualFold(item, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches case insensitively, then by base language, so zh-hk and zh both find zh-HK
+func Canonical(language string) string {
+	if language == "" {
+		return DEFAULT_LANGUAGE
+	}
+	for _, item := range Languages {
+		if strings.EqualFold(item, language) {
+			return item
+		}
+	}
+	base := strings.ToLower(strings.SplitN(language, "-", 2)[0])
+	for _, item := range Languages {
+		if strings.ToLower(strings.SplitN(item, "-", 2)[0]) == base {
+			return item
+		}
+	}
+
+	return DEFAULT_LANGUAGE
+}
+
+// Same as i18n.Tfunc, but a key missing in the language falls back to en-US instead of printing the key
+func Tfunc(language string) i18n.TranslateFunc {
+	T, err := i18n.Tfunc(Canonical(language), DEFAULT_LANGUAGE)
+	defaultT, defaultErr := i18n.Tfunc(DEFAULT_LANGUAGE)
+	if err != nil {
+		T = defaultT
+	}
+	if defaultErr != nil {
+		return T
+	}
+
+	return func(translationID string, args ...interface{}) string {
+		value := T(translationID, args...)
+		if value == translationID {
+			value = defaultT(translationID, args...)
+		}
+		return value
+	}
+}
+
+// Keys present in en-US but not in the language
+func MissingTranslationIds(language string) []string {
+	ids := map[string]bool{}
+	for _, id := range i18n.LanguageTranslationIDs(languageTag(language)) {
+		ids[id] = true
+	}
+
+	missing := make([]string, 0)
+	for _, id := range i18n.LanguageTranslationIDs(languageTag(DEFAULT_LANGUAGE)) {
+		if !ids[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// The bundle keys translations by the normalized tag, en-US is stored as en-us
+func languageTag(language string) string {
+	return strings.ToLower(strings.Replace(language, "_", "-", -1))
+}
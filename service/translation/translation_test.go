@@ -0,0 +1,66 @@
+package translation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadTestTranslations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translations")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"en-US.flat.yaml": "greeting:\n  other: \"Hello {{.Name}}\"\nfarewell:\n  other: \"Goodbye\"\n",
+		"en-US.json":      `[{"id": "thanks", "translation": "Thank you"}]`,
+		"zh-HK.flat.yaml": "greeting:\n  other: \"你好 {{.Name}}\"\n",
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	assert.Nil(t, LoadTranslations(dir))
+}
+
+func TestLoadTranslationsListsEachLanguageOnce(t *testing.T) {
+	loadTestTranslations(t)
+
+	assert.Equal(t, []string{"en-US", "zh-HK"}, Languages)
+	assert.True(t, IsSupported("zh-hk"))
+	assert.False(t, IsSupported("vi"))
+}
+
+func TestCanonical(t *testing.T) {
+	loadTestTranslations(t)
+
+	assert.Equal(t, "zh-HK", Canonical("zh-HK"))
+	assert.Equal(t, "zh-HK", Canonical("zh-hk"))
+	assert.Equal(t, "zh-HK", Canonical("zh"))
+	assert.Equal(t, "zh-HK", Canonical("zh-TW"))
+	assert.Equal(t, "en-US", Canonical("en-GB"))
+	assert.Equal(t, DEFAULT_LANGUAGE, Canonical("vi-VN"))
+	assert.Equal(t, DEFAULT_LANGUAGE, Canonical(""))
+}
+
+func TestTfuncFallsBackToDefaultLanguage(t *testing.T) {
+	loadTestTranslations(t)
+
+	T := Tfunc("zh-HK")
+	assert.Equal(t, "你好 Ninja", T("greeting", map[string]interface{}{"Name": "Ninja"}))
+	// Missing in zh-HK
+	assert.Equal(t, "Goodbye", T("farewell"))
+	assert.Equal(t, "Thank you", T("thanks"))
+	// Missing everywhere
+	assert.Equal(t, "unknown_key", T("unknown_key"))
+
+	assert.Equal(t, "Goodbye", Tfunc("vi-VN")("farewell"))
+}
+
+func TestMissingTranslationIds(t *testing.T) {
+	loadTestTranslations(t)
+
+	assert.Equal(t, []string{"farewell", "thanks"}, MissingTranslationIds("zh-HK"))
+	assert.Empty(t, MissingTranslationIds(DEFAULT_LANGUAGE))
+}
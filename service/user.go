@@ -6,6 +6,7 @@ import (
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/common"
 	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/translation"
 	"github.com/shopspring/decimal"
 	"os"
 	"strconv"
@@ -36,19 +37,54 @@ func (s UserService) GetCCLimitLevel(userId string) (limit bean.UserCreditCardLi
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
 		return
 	}
-	profile := to.Object.(bean.Profile)
-	to = s.dao.GetCCLimit(userId, profile.CreditCard.Token)
+	profile, _ := to.Object.(bean.Profile)
 
-	if to.Error != nil || !to.Found {
-		limit, to.Error = s.GetUserCCLimitFirstLevel()
-		if to.Error != nil {
-			to.SetError(api_error.GetDataFailed, to.Error)
+	limit, found, err := s.getUserCCLimit(userId)
+	if err != nil || !found {
+		limit, err = s.GetUserCCLimitFirstLevel()
+		if ce.SetError(api_error.GetDataFailed, err) {
+			return
 		}
-		to.Found = true
+	}
+	s.capCCLimitLevel(profile, &limit, &ce)
 
-	} else {
-		limit = to.Object.(bean.UserCreditCardLimit)
+	return
+}
+
+// A level reached before the verification tier dropped or got capped only allows the tier's level
+func (s UserService) capCCLimitLevel(profile bean.Profile, limit *bean.UserCreditCardLimit, ce *SimpleContextError) {
+	tierLevel := KYCServiceInst.MaxCCLimitLevel(profile)
+	if tierLevel <= 0 || limit.Level <= tierLevel {
+		return
+	}
+	cacheTO := s.miscDao.GetCCLimitByLevelFromCache(strconv.Itoa(int(tierLevel)))
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, cacheTO) {
+		return
+	}
+	limit.Level = tierLevel
+	limit.Limit = cacheTO.Object.(bean.CCLimit).Limit
+}
+
+// Sums what was spent on every card, the level, limit and period come from the highest level card
+func (s UserService) getUserCCLimit(userId string) (limit bean.UserCreditCardLimit, found bool, err error) {
+	to := s.dao.ListCCLimits(userId)
+	if to.Error != nil {
+		err = to.Error
+		return
+	}
+
+	amount := common.Zero
+	for _, obj := range to.Objects {
+		cardLimit := obj.(bean.UserCreditCardLimit)
+		cardAmount, _ := decimal.NewFromString(cardLimit.Amount)
+		amount = amount.Add(cardAmount)
+		if !found || cardLimit.Level > limit.Level {
+			limit = cardLimit
+		}
+		found = true
 	}
+	limit.Id = ""
+	limit.Amount = amount.String()
 
 	return
 }
@@ -73,19 +109,37 @@ func (s UserService) GetUserCCLimitFirstLevel() (limit bean.UserCreditCardLimit,
 }
 
 func (s UserService) UpgradeCCLimitLevel(userId string) (ce SimpleContextError) {
-	to := s.dao.GetProfile(userId)
+	to := s.dao.ListCCLimits(userId)
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
 		return
 	}
-	profile := to.Object.(bean.Profile)
-	to = s.dao.GetCCLimit(userId, profile.CreditCard.Token)
-	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
+	// Every card moves to the new level together
+	limitIds := make([]string, 0)
+	var creditCardLimit bean.UserCreditCardLimit
+	for _, obj := range to.Objects {
+		cardLimit := obj.(bean.UserCreditCardLimit)
+		if len(limitIds) == 0 || cardLimit.Level > creditCardLimit.Level {
+			creditCardLimit = cardLimit
+		}
+		limitIds = append(limitIds, cardLimit.Id)
+	}
+	if len(limitIds) == 0 {
 		return
 	}
-	creditCardLimit := to.Object.(bean.UserCreditCardLimit)
 	finalLevel, _ := strconv.Atoi(os.Getenv("MAX_CC_LIMIT_LEVEL"))
-	if creditCardLimit.Level < int64(finalLevel) {
+	maxLevel := int64(finalLevel)
+	// Time only moves the level up as far as the verification tier allows
+	profileTO := s.dao.GetProfile(userId)
+	if profileTO.Error == nil && profileTO.Found {
+		tierLevel := KYCServiceInst.MaxCCLimitLevel(profileTO.Object.(bean.Profile))
+		if tierLevel > 0 && tierLevel < maxLevel {
+			maxLevel = tierLevel
+		}
+	}
+	if creditCardLimit.Level < maxLevel {
 		creditCardLimit.Level += 1
+	} else if creditCardLimit.Level > maxLevel {
+		creditCardLimit.Level = maxLevel
 	} else {
 		// Reset the last limit
 	}
@@ -103,7 +157,7 @@ func (s UserService) UpgradeCCLimitLevel(userId string) (ce SimpleContextError)
 	duration := creditCardLimit.Duration * int64(time.Hour*24)
 	creditCardLimit.EndDate = time.Now().UTC().Add(time.Duration(duration))
 
-	err := s.dao.UpgradeCCLimitLevel(userId, profile.CreditCard.Token, creditCardLimit)
+	err := s.dao.UpgradeCCLimitLevel(userId, limitIds, creditCardLimit)
 	if ce.SetError(api_error.UpdateDataFailed, err) {
 		return
 	}
@@ -116,19 +170,18 @@ func (s UserService) CheckCCLimit(userId string, amountStr string) (ce SimpleCon
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
 		return
 	}
-	profile := to.Object.(bean.Profile)
-	to = s.dao.GetCCLimit(userId, profile.CreditCard.Token)
+	profile, _ := to.Object.(bean.Profile)
 
-	var err error
-	var creditCardLimit bean.UserCreditCardLimit
-	if to.Error != nil || !to.Found {
+	creditCardLimit, found, err := s.getUserCCLimit(userId)
+	if err != nil || !found {
 		// First time
 		creditCardLimit, err = s.GetUserCCLimitFirstLevel()
 		if ce.SetError(api_error.GetDataFailed, err) {
 			return
 		}
-	} else {
-		creditCardLimit = to.Object.(bean.UserCreditCardLimit)
+	}
+	if s.capCCLimitLevel(profile, &creditCardLimit, &ce); ce.HasError() {
+		return
 	}
 
 	currentAmount, _ := decimal.NewFromString(creditCardLimit.Amount)
@@ -160,6 +213,187 @@ func (s UserService) UpdateUserCCLimitTracks() (ce SimpleContextError) {
 	return
 }
 
+func (s UserService) ListCreditCards(userId string) (cards []bean.UserCreditCard, ce SimpleContextError) {
+	profileTO := s.dao.GetProfile(userId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, profileTO); ce.HasError() {
+		return
+	}
+	profile := profileTO.Object.(bean.Profile)
+
+	to := s.dao.ListCreditCards(userId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, to) {
+		return
+	}
+	cards = make([]bean.UserCreditCard, 0)
+	for _, obj := range to.Objects {
+		cards = append(cards, obj.(bean.UserCreditCard))
+	}
+
+	// Profiles from before the wallet only have the one card, it becomes the default of the wallet
+	if len(cards) == 0 && profile.CreditCard.Token != "" {
+		card, err := s.migrateProfileCreditCard(userId, profile.CreditCard)
+		if ce.SetError(api_error.AddDataFailed, err) {
+			return
+		}
+		cards = append(cards, card)
+	}
+
+	return
+}
+
+func (s UserService) migrateProfileCreditCard(userId string, card bean.UserCreditCard) (bean.UserCreditCard, error) {
+	card.Default = true
+
+	// The old limit is keyed by the token and keeps counting, the card starts its own at the same level
+	var limit bean.UserCreditCardLimit
+	var err error
+	to := s.dao.GetCCLimit(userId, card.Token)
+	found := to.Error == nil && to.Found
+	if found {
+		limit = to.Object.(bean.UserCreditCardLimit)
+	} else {
+		limit, err = s.GetUserCCLimitFirstLevel()
+		if err != nil {
+			return card, err
+		}
+	}
+	limit.Amount = common.Zero.String()
+
+	return s.dao.MigrateProfileCreditCard(userId, card, limit, !found)
+}
+
+// Empty card id is the default card
+func (s UserService) GetCreditCard(userId string, cardId string) (card bean.UserCreditCard, ce SimpleContextError) {
+	if cardId != "" {
+		to := s.dao.GetCreditCard(userId, cardId)
+		if ce.FeedDaoTransfer(api_error.GetDataFailed, to); ce.HasError() {
+			return
+		}
+		card = to.Object.(bean.UserCreditCard)
+		return
+	}
+
+	cards, cardsCE := s.ListCreditCards(userId)
+	if ce.FeedContextErrorDefault(cardsCE); ce.HasError() {
+		return
+	}
+	for _, item := range cards {
+		if item.Default {
+			card = item
+			return
+		}
+	}
+	ce.SetStatusKey(api_error.InvalidCC)
+
+	return
+}
+
+func (s UserService) GetCreditCardByToken(userId string, token string) (card bean.UserCreditCard, ce SimpleContextError) {
+	cards, cardsCE := s.ListCreditCards(userId)
+	if ce.FeedContextErrorDefault(cardsCE); ce.HasError() {
+		return
+	}
+	for _, item := range cards {
+		if item.Token == token {
+			card = item
+			return
+		}
+	}
+	ce.SetStatusKey(api_error.InvalidCC)
+
+	return
+}
+
+// The first card becomes the default, a card already in the wallet is returned as it is
+func (s UserService) AddCreditCard(userId string, card bean.UserCreditCard) (bean.UserCreditCard, error) {
+	cards, cardsCE := s.ListCreditCards(userId)
+	if cardsCE.HasError() {
+		return card, cardsCE.CheckError()
+	}
+	for _, item := range cards {
+		if item.CCNumber == card.CCNumber && item.ExpirationDate == card.ExpirationDate && item.Provider == card.Provider {
+			return item, nil
+		}
+	}
+	card.Default = len(cards) == 0
+
+	limit, found, err := s.getUserCCLimit(userId)
+	if err != nil {
+		return card, err
+	}
+	if !found {
+		limit, err = s.GetUserCCLimitFirstLevel()
+		if err != nil {
+			return card, err
+		}
+	}
+	limit.Amount = common.Zero.String()
+
+	return s.dao.AddCreditCard(userId, card, limit, !found)
+}
+
+func (s UserService) RemoveCreditCard(userId string, cardId string) (ce SimpleContextError) {
+	cards, cardsCE := s.ListCreditCards(userId)
+	if ce.FeedContextErrorDefault(cardsCE); ce.HasError() {
+		return
+	}
+
+	var card *bean.UserCreditCard
+	var newDefault *bean.UserCreditCard
+	for i := range cards {
+		if cards[i].Id == cardId {
+			card = &cards[i]
+		} else if newDefault == nil {
+			newDefault = &cards[i]
+		}
+	}
+	if card == nil {
+		ce.NotFound = true
+		return
+	}
+	if !card.Default {
+		newDefault = nil
+	}
+
+	err := s.dao.RemoveCreditCard(userId, *card, newDefault)
+	if ce.SetError(api_error.DeleteDataFailed, err) {
+		return
+	}
+
+	return
+}
+
+func (s UserService) SetDefaultCreditCard(userId string, cardId string) (card bean.UserCreditCard, ce SimpleContextError) {
+	cards, cardsCE := s.ListCreditCards(userId)
+	if ce.FeedContextErrorDefault(cardsCE); ce.HasError() {
+		return
+	}
+
+	found := false
+	previousId := ""
+	for _, item := range cards {
+		if item.Id == cardId {
+			card = item
+			found = true
+		}
+		if item.Default {
+			previousId = item.Id
+		}
+	}
+	if !found {
+		ce.NotFound = true
+		return
+	}
+
+	err := s.dao.SetDefaultCreditCard(userId, card, previousId)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+	card.Default = true
+
+	return
+}
+
 func (s UserService) UpdateOfferRejectLock(profile bean.Profile) (ce SimpleContextError) {
 	systemConfigTO := s.miscDao.GetSystemConfigFromCache(bean.CONFIG_OFFER_REJECT_LOCK)
 	if ce.FeedDaoTransfer(api_error.GetDataFailed, systemConfigTO) {
@@ -180,6 +414,48 @@ func (s UserService) UpdateOfferRejectLock(profile bean.Profile) (ce SimpleConte
 	return
 }
 
+func (s UserService) UpdateNotificationPreferences(userId string, preferences bean.NotificationPreferences) (profile bean.Profile, ce SimpleContextError) {
+	if ce.SetError(api_error.InvalidRequestBody, preferences.Validate()) {
+		return
+	}
+
+	profileTO := s.dao.GetProfile(userId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, profileTO); ce.HasError() {
+		return
+	}
+	profile = profileTO.Object.(bean.Profile)
+
+	err := s.dao.UpdateProfileNotificationPreferences(userId, preferences)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+	profile.NotificationPreferences = preferences
+
+	return
+}
+
+func (s UserService) UpdateProfileLanguage(userId string, language string) (profile bean.Profile, ce SimpleContextError) {
+	if !translation.IsSupported(language) {
+		ce.SetStatusKey(api_error.InvalidRequestBody)
+		return
+	}
+
+	profileTO := s.dao.GetProfile(userId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, profileTO); ce.HasError() {
+		return
+	}
+	profile = profileTO.Object.(bean.Profile)
+	profile.UserId = userId
+	profile.Language = translation.Canonical(language)
+
+	err := s.dao.UpdateProfileLanguage(profile)
+	if ce.SetError(api_error.UpdateDataFailed, err) {
+		return
+	}
+
+	return
+}
+
 func (s UserService) CheckOfferLocked(profile bean.Profile) bool {
 	// now - created at < duration
 	return int64(time.Now().UTC().Sub(profile.OfferRejectLock.CreatedAt).Minutes()) < profile.OfferRejectLock.Duration
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/ninjadotorg/handshake-exchange/api_error"
 	"github.com/ninjadotorg/handshake-exchange/bean"
 	"github.com/ninjadotorg/handshake-exchange/dao"
 	"github.com/shopspring/decimal"
@@ -18,7 +19,22 @@ func (dao UserDaoFake) GetProfile(userId string) (t dao.TransferObject) {
 func (dao UserDaoFake) AddProfile(profile bean.Profile) error {
 	return nil
 }
-func (dao UserDaoFake) UpdateProfileCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit) error {
+func (dao UserDaoFake) ListCreditCards(userId string) (t dao.TransferObject) {
+	return
+}
+func (dao UserDaoFake) GetCreditCard(userId string, cardId string) (t dao.TransferObject) {
+	return
+}
+func (dao UserDaoFake) AddCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit, resetTrack bool) (bean.UserCreditCard, error) {
+	return creditCard, nil
+}
+func (dao UserDaoFake) MigrateProfileCreditCard(userId string, creditCard bean.UserCreditCard, userCCLimit bean.UserCreditCardLimit, resetTrack bool) (bean.UserCreditCard, error) {
+	return creditCard, nil
+}
+func (dao UserDaoFake) RemoveCreditCard(userId string, creditCard bean.UserCreditCard, newDefault *bean.UserCreditCard) error {
+	return nil
+}
+func (dao UserDaoFake) SetDefaultCreditCard(userId string, creditCard bean.UserCreditCard, previousId string) error {
 	return nil
 }
 func (dao UserDaoFake) UpdateProfileOfferRejectLock(profile bean.Profile) error {
@@ -33,10 +49,19 @@ func (dao UserDaoFake) UpdateUserCCLimitTracks() (userIds []string, t dao.Transf
 func (dao UserDaoFake) GetCCLimit(userId string, token string) (t dao.TransferObject) {
 	return
 }
+func (dao UserDaoFake) ListCCLimits(userId string) (t dao.TransferObject) {
+	return
+}
 func (dao UserDaoFake) GetUserCCLimitEndTracks() (t dao.TransferObject) {
 	return
 }
-func (dao UserDaoFake) UpgradeCCLimitLevel(userId string, token string, limit bean.UserCreditCardLimit) error {
+func (dao UserDaoFake) UpgradeCCLimitLevel(userId string, limitIds []string, limit bean.UserCreditCardLimit) error {
+	return nil
+}
+func (dao UserDaoFake) UpdateProfileNotificationPreferences(userId string, preferences bean.NotificationPreferences) error {
+	return nil
+}
+func (dao UserDaoFake) UpdateProfileLanguage(profile bean.Profile) error {
 	return nil
 }
 
@@ -52,3 +77,57 @@ func TestAddProfileSuccess(t *testing.T) {
 	err := serviceInst.AddProfile(profile)
 	assert.Equal(t, nil, err)
 }
+
+func TestUpdateNotificationPreferencesInvalidChannel(t *testing.T) {
+	serviceInst := UserService{
+		dao: &UserDaoFake{},
+	}
+
+	_, ce := serviceInst.UpdateNotificationPreferences("1", bean.NotificationPreferences{
+		Channels: map[string]bool{"pigeon": false},
+	})
+	assert.Equal(t, true, ce.HasError())
+	assert.Equal(t, api_error.InvalidRequestBody, ce.StatusKey)
+}
+
+func TestUpdateNotificationPreferencesProfileNotFound(t *testing.T) {
+	serviceInst := UserService{
+		dao: &UserDaoFake{},
+	}
+
+	_, ce := serviceInst.UpdateNotificationPreferences("1", bean.NotificationPreferences{
+		Channels: map[string]bool{bean.NOTIFICATION_CHANNEL_EMAIL: false},
+	})
+	assert.Equal(t, true, ce.HasError())
+}
+
+type UserDaoCCLimitFake struct {
+	UserDaoFake
+	limits []bean.UserCreditCardLimit
+}
+
+func (dao UserDaoCCLimitFake) ListCCLimits(userId string) (t dao.TransferObject) {
+	t.Found = true
+	for _, limit := range dao.limits {
+		t.Objects = append(t.Objects, limit)
+	}
+	return
+}
+
+func TestGetUserCCLimitSumsAllCards(t *testing.T) {
+	serviceInst := UserService{
+		dao: &UserDaoCCLimitFake{
+			limits: []bean.UserCreditCardLimit{
+				{Id: "card1", Level: 1, Amount: "100", Limit: 500},
+				{Id: "card2", Level: 2, Amount: "250.5", Limit: 1000},
+			},
+		},
+	}
+
+	limit, found, err := serviceInst.getUserCCLimit("1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, found)
+	assert.Equal(t, int64(2), limit.Level)
+	assert.Equal(t, int64(1000), limit.Limit)
+	assert.Equal(t, "350.5", limit.Amount)
+}
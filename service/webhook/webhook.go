@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/levigross/grequests"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"log"
+	"strconv"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Fans the event out to every active subscription listening for it, failed deliveries are left to RetryDeliveries
+func Emit(event bean.WebhookEvent) {
+	if event.Type == "" {
+		return
+	}
+
+	go func() {
+		subscriptionsTO := dao.WebhookDaoInst.ListWebhookSubscriptions()
+		if subscriptionsTO.HasError() {
+			log.Println("List webhook subscriptions failed", subscriptionsTO.Error)
+			return
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Println("Marshal webhook event failed", err)
+			return
+		}
+
+		for _, item := range subscriptionsTO.Objects {
+			subscription := item.(bean.WebhookSubscription)
+			if !subscription.Subscribes(event.Type) {
+				continue
+			}
+			delivery, err := dao.WebhookDaoInst.AddWebhookDelivery(bean.WebhookDelivery{
+				SubscriptionId: subscription.Id,
+				EventId:        event.Id,
+				EventType:      event.Type,
+				Url:            subscription.Url,
+				Payload:        string(body),
+				Status:         bean.WEBHOOK_DELIVERY_STATUS_RETRYING,
+				// Keeps the retry cron off it while the first attempt is in flight
+				NextAttemptAt: time.Now().UTC().Add(bean.WEBHOOK_RETRY_LEASE * time.Second),
+			})
+			if err != nil {
+				log.Println("Add webhook delivery failed", err)
+				continue
+			}
+			go deliver(subscription, delivery)
+		}
+	}()
+}
+
+// Called by cron, resends the deliveries of active subscriptions whose next attempt is due
+func RetryDeliveries() (count int, err error) {
+	subscriptionsTO := dao.WebhookDaoInst.ListWebhookSubscriptions()
+	if subscriptionsTO.HasError() {
+		err = subscriptionsTO.Error
+		return
+	}
+
+	now := time.Now().UTC()
+	leaseUntil := now.Add(bean.WEBHOOK_RETRY_LEASE * time.Second)
+	for _, item := range subscriptionsTO.Objects {
+		subscription := item.(bean.WebhookSubscription)
+		deliveries, listErr := dao.WebhookDaoInst.ListDueWebhookDeliveries(subscription.Id, now, bean.WEBHOOK_RETRY_LIMIT)
+		if listErr != nil {
+			log.Println("List due webhook deliveries failed", subscription.Id, listErr)
+			err = listErr
+			continue
+		}
+
+		for _, delivery := range deliveries {
+			if claimErr := dao.WebhookDaoInst.ClaimWebhookDeliveryRetry(delivery, leaseUntil); claimErr != nil {
+				// Another run has it
+				continue
+			}
+			deliver(subscription, delivery)
+			count += 1
+		}
+	}
+
+	return
+}
+
+func deliver(subscription bean.WebhookSubscription, delivery bean.WebhookDelivery) {
+	maxAttempts := getConfigNumber(bean.CONFIG_WEBHOOK_RETRY_MAX_ATTEMPTS, bean.WEBHOOK_RETRY_MAX_ATTEMPTS_DEFAULT)
+	backoff := getConfigNumber(bean.CONFIG_WEBHOOK_RETRY_BACKOFF, bean.WEBHOOK_RETRY_BACKOFF_DEFAULT)
+
+	responseCode, err := send(subscription, delivery)
+	recordAttempt(&delivery, responseCode, err, maxAttempts, backoff, time.Now().UTC())
+
+	if err := dao.WebhookDaoInst.UpdateWebhookDelivery(delivery); err != nil {
+		log.Println("Update webhook delivery failed", err)
+	}
+}
+
+// A failed attempt is due again after an exponential backoff, backoff, 2 * backoff, 4 * backoff...
+func recordAttempt(delivery *bean.WebhookDelivery, responseCode int, err error, maxAttempts int, backoff int, now time.Time) {
+	delivery.Attempts++
+	delivery.ResponseCode = responseCode
+	delivery.NextAttemptAt = time.Time{}
+	if err == nil {
+		delivery.Status = bean.WEBHOOK_DELIVERY_STATUS_DELIVERED
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = bean.WEBHOOK_DELIVERY_STATUS_FAILED
+	} else {
+		delivery.Status = bean.WEBHOOK_DELIVERY_STATUS_RETRYING
+		delivery.NextAttemptAt = now.Add(time.Duration(backoff<<uint(delivery.Attempts-1)) * time.Second)
+	}
+}
+
+// Any 2xx acknowledges the delivery
+func send(subscription bean.WebhookSubscription, delivery bean.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().UTC().Unix(), 10)
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"Content-Type":                "application/json",
+			bean.WEBHOOK_SIGNATURE_HEADER: Sign(subscription.Secret, timestamp, []byte(delivery.Payload)),
+			bean.WEBHOOK_TIMESTAMP_HEADER: timestamp,
+			bean.WEBHOOK_EVENT_HEADER:     delivery.EventType,
+			bean.WEBHOOK_DELIVERY_HEADER:  delivery.Id,
+		},
+		JSON:           []byte(delivery.Payload),
+		RequestTimeout: requestTimeout,
+	}
+
+	resp, err := grequests.Post(subscription.Url, ro)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint responded %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Partners recompute HMAC-SHA256 over "{timestamp}.{body}" with their secret and compare,
+// the timestamp lets them reject replays
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func getConfigNumber(key string, defaultValue int) int {
+	systemConfigTO := dao.MiscDaoInst.GetSystemConfigFromCache(key)
+	if systemConfigTO.HasError() {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(systemConfigTO.Object.(bean.SystemConfig).Value)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
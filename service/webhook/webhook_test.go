@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"errors"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	signature := Sign("whsec", "1700000000", []byte(`{"id":"offer_1_active"}`))
+	assert.Equal(t, "sha256=d402ae0ebc61d967dbf0a7126be819f0ee1e4df12cf0a37dd2869f825fcef80f", signature)
+}
+
+func TestSendSignsPayload(t *testing.T) {
+	payload := `{"id":"offer_1_active","type":"offer.active"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp := r.Header.Get(bean.WEBHOOK_TIMESTAMP_HEADER)
+
+		assert.Equal(t, payload, string(body))
+		assert.Equal(t, bean.WEBHOOK_EVENT_OFFER_ACTIVE, r.Header.Get(bean.WEBHOOK_EVENT_HEADER))
+		assert.Equal(t, "delivery_1", r.Header.Get(bean.WEBHOOK_DELIVERY_HEADER))
+		assert.Equal(t, Sign("whsec", timestamp, body), r.Header.Get(bean.WEBHOOK_SIGNATURE_HEADER))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	responseCode, err := send(
+		bean.WebhookSubscription{Url: server.URL, Secret: "whsec"},
+		bean.WebhookDelivery{Id: "delivery_1", EventType: bean.WEBHOOK_EVENT_OFFER_ACTIVE, Payload: payload})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, responseCode)
+}
+
+func TestSendFailsOnNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	responseCode, err := send(bean.WebhookSubscription{Url: server.URL}, bean.WebhookDelivery{Payload: "{}"})
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, responseCode)
+}
+
+func TestRecordAttemptBacksOffExponentially(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.WebhookDelivery{Status: bean.WEBHOOK_DELIVERY_STATUS_RETRYING}
+
+	for _, wait := range []time.Duration{5, 10, 20, 40} {
+		recordAttempt(&delivery, http.StatusBadGateway, errors.New("webhook endpoint responded 502"), 5, 5, now)
+		assert.Equal(t, bean.WEBHOOK_DELIVERY_STATUS_RETRYING, delivery.Status)
+		assert.Equal(t, now.Add(wait*time.Second), delivery.NextAttemptAt)
+	}
+	assert.Equal(t, 4, delivery.Attempts)
+	assert.Equal(t, http.StatusBadGateway, delivery.ResponseCode)
+	assert.Equal(t, "webhook endpoint responded 502", delivery.LastError)
+}
+
+func TestRecordAttemptFailsOutOfAttempts(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.WebhookDelivery{Status: bean.WEBHOOK_DELIVERY_STATUS_RETRYING, Attempts: 4, NextAttemptAt: now}
+
+	recordAttempt(&delivery, 0, errors.New("timeout"), 5, 5, now)
+	assert.Equal(t, bean.WEBHOOK_DELIVERY_STATUS_FAILED, delivery.Status)
+	assert.Equal(t, 5, delivery.Attempts)
+	assert.True(t, delivery.NextAttemptAt.IsZero())
+}
+
+func TestRecordAttemptDelivered(t *testing.T) {
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	delivery := bean.WebhookDelivery{
+		Status:        bean.WEBHOOK_DELIVERY_STATUS_RETRYING,
+		Attempts:      2,
+		LastError:     "timeout",
+		NextAttemptAt: now,
+	}
+
+	recordAttempt(&delivery, http.StatusOK, nil, 5, 5, now)
+	assert.Equal(t, bean.WEBHOOK_DELIVERY_STATUS_DELIVERED, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, "", delivery.LastError)
+	assert.True(t, delivery.NextAttemptAt.IsZero())
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/ninjadotorg/handshake-exchange/api_error"
+	"github.com/ninjadotorg/handshake-exchange/bean"
+	"github.com/ninjadotorg/handshake-exchange/dao"
+	"github.com/ninjadotorg/handshake-exchange/service/webhook"
+)
+
+type WebhookSubscriptionService struct {
+	dao *dao.WebhookDao
+}
+
+// The secret is only returned here, partners keep it to verify signatures
+func (s WebhookSubscriptionService) AddSubscription(body bean.WebhookSubscription) (subscription bean.WebhookSubscription, ce SimpleContextError) {
+	for _, event := range body.Events {
+		if !containsWebhookEvent(event) {
+			ce.SetStatusKey(api_error.InvalidRequestBody)
+			return
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); ce.SetError(api_error.AddDataFailed, err) {
+		return
+	}
+	body.Secret = hex.EncodeToString(secret)
+
+	var err error
+	subscription, err = s.dao.AddWebhookSubscription(body)
+	ce.SetError(api_error.AddDataFailed, err)
+
+	return
+}
+
+func (s WebhookSubscriptionService) ListSubscriptions() (subscriptions []bean.WebhookSubscription, ce SimpleContextError) {
+	subscriptionsTO := s.dao.ListWebhookSubscriptions()
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, subscriptionsTO); ce.HasError() {
+		return
+	}
+
+	subscriptions = make([]bean.WebhookSubscription, 0)
+	for _, item := range subscriptionsTO.Objects {
+		subscription := item.(bean.WebhookSubscription)
+		subscription.Secret = ""
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return
+}
+
+func (s WebhookSubscriptionService) RemoveSubscription(subscriptionId string) (ce SimpleContextError) {
+	subscriptionTO := s.dao.GetWebhookSubscription(subscriptionId)
+	if ce.FeedDaoTransfer(api_error.GetDataFailed, subscriptionTO); ce.HasError() {
+		return
+	}
+
+	err := s.dao.RemoveWebhookSubscription(subscriptionId)
+	ce.SetError(api_error.DeleteDataFailed, err)
+
+	return
+}
+
+func (s WebhookSubscriptionService) RetryWebhookDeliveries() (count int, ce SimpleContextError) {
+	count, err := webhook.RetryDeliveries()
+	ce.SetError(api_error.UpdateDataFailed, err)
+
+	return
+}
+
+func containsWebhookEvent(event string) bool {
+	for _, item := range bean.WebhookEvents {
+		if item == event {
+			return true
+		}
+	}
+	return false
+}
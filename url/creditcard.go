@@ -15,6 +15,9 @@ func (url CreditCardUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("", func(context *gin.Context) {
 		creditCardApi.PayInstantOffer(context)
 	})
+	group.POST("/authenticate", func(context *gin.Context) {
+		creditCardApi.AuthenticateInstantOffer(context)
+	})
 	group.GET("/:offerId", func(context *gin.Context) {
 		creditCardApi.GetInstantOffers(context)
 	})
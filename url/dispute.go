@@ -0,0 +1,26 @@
+package url
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api"
+)
+
+type DisputeUrl struct {
+}
+
+func (url DisputeUrl) Create(router *gin.Engine) *gin.RouterGroup {
+	group := router.Group("/disputes")
+
+	disputeApi := api.DisputeApi{}
+	group.GET("", func(context *gin.Context) {
+		disputeApi.ListDisputes(context)
+	})
+	group.GET("/:disputeId", func(context *gin.Context) {
+		disputeApi.GetDispute(context)
+	})
+	group.POST("/:disputeId/resolve", func(context *gin.Context) {
+		disputeApi.ResolveDispute(context)
+	})
+
+	return group
+}
@@ -13,6 +13,8 @@ func (url InfoUrl) Create(router *gin.Engine) *gin.RouterGroup {
 
 	miscApi := api.MiscApi{}
 	creditCardAPi := api.CreditCardApi{}
+	geoSearchApi := api.GeoSearchApi{}
+	searchApi := api.SearchApi{}
 
 	group.GET("/currency-rates/:currency", func(context *gin.Context) {
 		miscApi.GetCurrencyRate(context)
@@ -23,6 +25,9 @@ func (url InfoUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.GET("/crypto-rates-all/:currency", func(context *gin.Context) {
 		miscApi.GetCryptoRateAll(context)
 	})
+	group.GET("/oracle-prices/:currency", func(context *gin.Context) {
+		miscApi.GetOraclePrices(context)
+	})
 	group.GET("/system-fees/:feeKey", func(context *gin.Context) {
 		miscApi.GetSystemFee(context)
 	})
@@ -32,12 +37,24 @@ func (url InfoUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.GET("/instant-buy/price", func(context *gin.Context) {
 		creditCardAPi.GetProposeInstantOffer(context)
 	})
+	group.GET("/instant-buy/gateway", func(context *gin.Context) {
+		creditCardAPi.GetCCGateway(context)
+	})
 	group.GET("/crypto-quote", func(context *gin.Context) {
 		miscApi.GetCryptoQuote(context)
 	})
 	group.GET("/crypto-quotes", func(context *gin.Context) {
 		miscApi.GetAllCryptoQuotes(context)
 	})
+	group.GET("/geo-search", func(context *gin.Context) {
+		geoSearchApi.Search(context)
+	})
+	group.GET("/search", func(context *gin.Context) {
+		searchApi.Search(context)
+	})
+	group.GET("/fiat-currencies", func(context *gin.Context) {
+		miscApi.GetFiatCurrencies(context)
+	})
 	group.GET("/cc-limits", func(context *gin.Context) {
 		miscApi.GetCCLimits(context)
 	})
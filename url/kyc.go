@@ -0,0 +1,35 @@
+package url
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api"
+)
+
+type KYCUrl struct {
+}
+
+func (url KYCUrl) Create(router *gin.Engine) *gin.RouterGroup {
+	group := router.Group("/kyc")
+
+	kycApi := api.KYCApi{}
+	group.POST("", func(context *gin.Context) {
+		kycApi.SubmitKYC(context)
+	})
+	group.GET("", func(context *gin.Context) {
+		kycApi.ListUserKYCSubmissions(context)
+	})
+	group.GET("/tiers", func(context *gin.Context) {
+		kycApi.ListTierLimits(context)
+	})
+	group.GET("/submissions", func(context *gin.Context) {
+		kycApi.ListKYCSubmissions(context)
+	})
+	group.GET("/submissions/:submissionId", func(context *gin.Context) {
+		kycApi.GetKYCSubmission(context)
+	})
+	group.POST("/submissions/:submissionId/review", func(context *gin.Context) {
+		kycApi.ReviewKYCSubmission(context)
+	})
+
+	return group
+}
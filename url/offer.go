@@ -12,6 +12,7 @@ func (url OfferUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group := router.Group("/offers")
 
 	offerApi := api.OfferApi{}
+	disputeApi := api.DisputeApi{}
 	group.POST("", func(context *gin.Context) {
 		offerApi.CreateOffer(context)
 	})
@@ -39,6 +40,9 @@ func (url OfferUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("/:offerId/cancel", func(context *gin.Context) {
 		offerApi.CancelShakeOffer(context)
 	})
+	group.POST("/:offerId/dispute", func(context *gin.Context) {
+		disputeApi.OpenOfferDispute(context)
+	})
 	group.POST("/:offerId/onchain-tracking", func(context *gin.Context) {
 		offerApi.OnChainOfferTracking(context)
 	})
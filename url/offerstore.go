@@ -12,6 +12,7 @@ func (url OfferStoreUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group := router.Group("/offer-stores")
 
 	offerApi := api.OfferStoreApi{}
+	disputeApi := api.DisputeApi{}
 	group.POST("", func(context *gin.Context) {
 		offerApi.CreateOfferStore(context)
 	})
@@ -54,6 +55,9 @@ func (url OfferStoreUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("/:offerId/shakes/:offerShakeId/cancel", func(context *gin.Context) {
 		offerApi.CancelOfferStoreShake(context)
 	})
+	group.POST("/:offerId/shakes/:offerShakeId/dispute", func(context *gin.Context) {
+		disputeApi.OpenOfferStoreShakeDispute(context)
+	})
 	group.POST("/:offerId/shakes/:offerShakeId/onchain-tracking", func(context *gin.Context) {
 		offerApi.OnChainOfferStoreShakeTracking(context)
 	})
@@ -0,0 +1,20 @@
+package url
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ninjadotorg/handshake-exchange/api"
+)
+
+type OrderBookUrl struct {
+}
+
+func (url OrderBookUrl) Create(router *gin.Engine) *gin.RouterGroup {
+	group := router.Group("/orderbook")
+
+	orderBookApi := api.OrderBookApi{}
+	group.GET("/:currency/:fiat", func(context *gin.Context) {
+		orderBookApi.GetOrderBook(context)
+	})
+
+	return group
+}
@@ -15,6 +15,14 @@ func (url CronJobUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	coinbaseApi := api.CoinbaseApi{}
 	onChainApi := api.OnChainApi{}
 	blockchainIoApi := api.BlockChainApi{}
+	checkoutApi := api.CheckoutApi{}
+	tradingBotApi := api.TradingBotApi{}
+	orderBookApi := api.OrderBookApi{}
+	geoSearchApi := api.GeoSearchApi{}
+	notificationApi := api.NotificationApi{}
+	emailTemplateApi := api.EmailTemplateApi{}
+	webhookApi := api.WebhookApi{}
+	creditCardApi := api.CreditCardApi{}
 
 	// CRON JOB
 	group.POST("/currency-rates", func(context *gin.Context) {
@@ -33,6 +41,23 @@ func (url CronJobUrl) Create(router *gin.Engine) *gin.RouterGroup {
 		miscApi.FinishOfferConfirmingAddresses(context)
 	})
 	// CRON JOB
+	group.POST("/send-crypto-payouts", func(context *gin.Context) {
+		miscApi.SendCryptoPayouts(context)
+	})
+	group.POST("/crypto-payouts/:payoutId/retry", func(context *gin.Context) {
+		miscApi.RetryCryptoPayout(context)
+	})
+	group.POST("/rebuild-order-book", func(context *gin.Context) {
+		orderBookApi.RebuildOrderBook(context)
+	})
+	group.POST("/rebuild-geo-index", func(context *gin.Context) {
+		geoSearchApi.RebuildIndex(context)
+	})
+	// CRON JOB
+	group.POST("/refresh-trading-bots", func(context *gin.Context) {
+		tradingBotApi.RefreshTradingBots(context)
+	})
+	// CRON JOB
 	//group.POST("/transfer-tracking", func(context *gin.Context) {
 	//	miscApi.UpdateTransferTracking(context)
 	//})
@@ -104,6 +129,9 @@ func (url CronJobUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("/blockchainio/callback", func(context *gin.Context) {
 		blockchainIoApi.ReceiveCallback(context)
 	})
+	group.POST("/checkout/callback", func(context *gin.Context) {
+		checkoutApi.ReceiveCallback(context)
+	})
 	group.POST("/system-fees", func(context *gin.Context) {
 		miscApi.UpdateSystemFee(context)
 	})
@@ -113,6 +141,9 @@ func (url CronJobUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("/cc-limits", func(context *gin.Context) {
 		miscApi.UpdateCCLimits(context)
 	})
+	group.POST("/fiat-currencies", func(context *gin.Context) {
+		miscApi.UpdateFiatCurrencies(context)
+	})
 	group.POST("/sync-to-offer-solr/:offerId", func(context *gin.Context) {
 		miscApi.SyncOfferToSolr(context)
 	})
@@ -131,8 +162,70 @@ func (url CronJobUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("/start-app", func(context *gin.Context) {
 		miscApi.StartApp(context)
 	})
-	group.POST("/script-update-xyz-123", func(context *gin.Context) {
-		miscApi.ScriptUpdateAllOfferStoreSolr(context)
+	group.POST("/search-reindex", func(context *gin.Context) {
+		miscApi.RunSearchReindex(context)
+	})
+	group.GET("/search-reindex", func(context *gin.Context) {
+		miscApi.GetSearchReindex(context)
+	})
+	group.GET("/notification-dead-letters", func(context *gin.Context) {
+		notificationApi.ListDeadLetters(context)
+	})
+	group.POST("/notification-dead-letters/:deliveryId/resend", func(context *gin.Context) {
+		notificationApi.ResendDeadLetter(context)
+	})
+	group.GET("/notification-deliveries/:deliveryId", func(context *gin.Context) {
+		notificationApi.GetDelivery(context)
+	})
+	group.POST("/relay-outbox", func(context *gin.Context) {
+		notificationApi.RelayOutbox(context)
+	})
+	// CRON JOB
+	group.POST("/retry-notification-deliveries", func(context *gin.Context) {
+		notificationApi.RetryDeliveries(context)
+	})
+	group.GET("/email-templates", func(context *gin.Context) {
+		emailTemplateApi.ListTemplates(context)
+	})
+	group.GET("/email-templates/:templateKey/preview", func(context *gin.Context) {
+		emailTemplateApi.PreviewTemplate(context)
+	})
+	group.POST("/instant-offers/:userId/:offerId/refund", func(context *gin.Context) {
+		creditCardApi.RefundInstantOffer(context)
+	})
+	group.POST("/webhook-subscriptions", func(context *gin.Context) {
+		webhookApi.AddSubscription(context)
+	})
+	group.GET("/webhook-subscriptions", func(context *gin.Context) {
+		webhookApi.ListSubscriptions(context)
+	})
+	group.DELETE("/webhook-subscriptions/:subscriptionId", func(context *gin.Context) {
+		webhookApi.RemoveSubscription(context)
+	})
+	group.GET("/webhook-subscriptions/:subscriptionId/deliveries", func(context *gin.Context) {
+		webhookApi.ListDeliveries(context)
+	})
+	// CRON JOB
+	group.POST("/retry-webhook-deliveries", func(context *gin.Context) {
+		webhookApi.RetryDeliveries(context)
+	})
+	group.GET("/trading-bots", func(context *gin.Context) {
+		tradingBotApi.ListTradingBots(context)
+	})
+	group.POST("/trading-bots", func(context *gin.Context) {
+		tradingBotApi.AddTradingBot(context)
+	})
+	group.GET("/trading-bots/:botId", func(context *gin.Context) {
+		tradingBotApi.GetTradingBot(context)
+	})
+	group.PUT("/trading-bots/:botId", func(context *gin.Context) {
+		tradingBotApi.UpdateTradingBot(context)
+	})
+	group.DELETE("/trading-bots/:botId", func(context *gin.Context) {
+		tradingBotApi.RemoveTradingBot(context)
+	})
+	group.GET("/trading-bots/:botId/status", func(context *gin.Context) {
+		tradingBotApi.GetTradingBotStatus(context)
 	})
 
 	return group
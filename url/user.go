@@ -18,12 +18,27 @@ func (url UserUrl) Create(router *gin.Engine) *gin.RouterGroup {
 	group.POST("/profile", func(context *gin.Context) {
 		profileApi.AddProfile(context)
 	})
+	group.PUT("/profile", func(context *gin.Context) {
+		profileApi.UpdateProfile(context)
+	})
 	group.POST("/profile/offline/:offline", func(context *gin.Context) {
 		profileApi.UpdateProfileOffline(context)
 	})
 	group.GET("/profile/cc-limit", func(context *gin.Context) {
 		profileApi.GetCCLimit(context)
 	})
+	group.GET("/profile/credit-cards", func(context *gin.Context) {
+		profileApi.ListCreditCards(context)
+	})
+	group.POST("/profile/credit-cards", func(context *gin.Context) {
+		profileApi.AddCreditCard(context)
+	})
+	group.DELETE("/profile/credit-cards/:cardId", func(context *gin.Context) {
+		profileApi.RemoveCreditCard(context)
+	})
+	group.POST("/profile/credit-cards/:cardId/default", func(context *gin.Context) {
+		profileApi.SetDefaultCreditCard(context)
+	})
 	group.GET("/transactions", func(context *gin.Context) {
 		profileApi.ListTransactions(context)
 	})